@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sahithyandev/faa/internal/certexport"
+	"github.com/sahithyandev/faa/internal/config"
+	"github.com/sahithyandev/faa/internal/daemon"
+	"github.com/sahithyandev/faa/internal/proxy"
+	"github.com/sahithyandev/faa/internal/setup"
+)
+
+// caInfoOutput is the machine-readable form of 'faa ca info'
+type caInfoOutput struct {
+	SchemaVersion int                    `json:"schemaVersion"`
+	Source        string                 `json:"source"`
+	Root          *proxy.CertificateInfo `json:"root"`
+	Intermediate  *proxy.CertificateInfo `json:"intermediate,omitempty"`
+	TrustStores   []caTrustStore         `json:"trustStores"`
+	Warnings      []string               `json:"warnings"`
+}
+
+// Where the CA comes from, as 'faa ca info' reports it
+const (
+	caSourceLocal  = "local"  // created by Caddy
+	caSourceConfig = "config" // set by tls.ca in config.json
+)
+
+// caTrustStore is whether a trust store has the CA, as 'faa setup --check'
+// reports it
+type caTrustStore struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func handleCA(args []string) int {
+	if len(args) == 0 {
+		printError("A subcommand is required. Usage: faa ca <info|path|export|rotate>")
+		return ExitUsage
+	}
+
+	switch args[0] {
+	case "info":
+		return handleCAInfo(args[1:])
+	case "path":
+		return handleCAPath(args[1:])
+	case "export":
+		return handleCAExport(args[1:])
+	case "rotate":
+		return handleCARotate(args[1:])
+	default:
+		printError("Unknown ca subcommand: %s", args[0])
+		return ExitUsage
+	}
+}
+
+func handleCAInfo(args []string) int {
+	format, rest, err := parseOutputFlags(args)
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
+	}
+	if len(rest) > 0 {
+		return format.fail(ExitUsage, "Unknown option: %s", rest[0])
+	}
+
+	caPath, err := proxy.GetCAPath()
+	if err != nil {
+		return format.fail(ExitError, "Failed to get CA certificate path: %v", err)
+	}
+	root, err := proxy.ReadCertificateInfo(caPath)
+	if os.IsNotExist(err) {
+		return format.fail(ExitNotFound, "CA certificate not yet exported; it is created when the daemon starts")
+	} else if err != nil {
+		return format.fail(ExitError, "Failed to read CA certificate: %v", err)
+	}
+
+	output := caInfoOutput{
+		SchemaVersion: schemaVersion,
+		Source:        caSourceLocal,
+		Root:          root,
+		TrustStores:   caTrustStores(),
+		Warnings:      proxy.CAExpiryWarnings(configuredCA(), time.Now()),
+	}
+	if configuredCA() {
+		// A configured CA signs certificates with its root
+		output.Source = caSourceConfig
+	} else if interPath, err := proxy.GetCaddyIntermediatePath(); err == nil {
+		// The intermediate is in Caddy's data directory, which belongs to
+		// root when the daemon runs as a LaunchDaemon
+		output.Intermediate, _ = proxy.ReadCertificateInfo(interPath)
+	}
+	if output.Warnings == nil {
+		output.Warnings = []string{}
+	}
+
+	if format.structured() {
+		return format.emit(output)
+	}
+
+	if output.Source == caSourceConfig {
+		printCertificateInfo("Root CA (tls.ca in config.json)", root)
+	} else {
+		printCertificateInfo("Root CA", root)
+	}
+	if output.Intermediate != nil {
+		fmt.Println()
+		printCertificateInfo("Intermediate CA", output.Intermediate)
+	}
+	if len(output.TrustStores) > 0 {
+		fmt.Println()
+		fmt.Println("Trust stores:")
+		for _, store := range output.TrustStores {
+			fmt.Printf("  %s %-5s %s\n", trustStoreMarker(store.Status), store.Name, store.Detail)
+		}
+	}
+	if len(output.Warnings) > 0 {
+		fmt.Println()
+		for _, warning := range output.Warnings {
+			fmt.Printf("⚠ Warning: %s\n", warning)
+		}
+	}
+	return ExitSuccess
+}
+
+// printCertificateInfo prints a CA certificate for 'faa ca info'
+func printCertificateInfo(title string, cert *proxy.CertificateInfo) {
+	fmt.Printf("%s:\n", title)
+	fmt.Printf("  Subject:  %s\n", cert.Subject)
+	fmt.Printf("  SHA-256:  %s\n", cert.SHA256)
+	fmt.Printf("  Valid:    %s to %s\n", cert.NotBefore.Local().Format(time.DateTime), cert.NotAfter.Local().Format(time.DateTime))
+	fmt.Printf("  Path:     %s\n", cert.Path)
+}
+
+// caTrustStores checks which trust stores have the CA, the way
+// 'faa setup --check --ca-only' does
+func caTrustStores() []caTrustStore {
+	stores := []caTrustStore{}
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		return stores
+	}
+
+	report, _ := setup.Run(setup.Options{Check: true, CAOnly: true, Output: io.Discard})
+	if report == nil {
+		return stores
+	}
+	for _, step := range report.Steps {
+		switch step.Name {
+		case setup.StepCA, setup.StepNSS, setup.StepJava:
+			stores = append(stores, caTrustStore{Name: step.Name, Status: step.Status, Detail: step.Detail})
+		}
+	}
+	return stores
+}
+
+// trustStoreMarker returns the symbol shown for a trust store's status
+func trustStoreMarker(status string) string {
+	switch status {
+	case setup.StatusOK, setup.StatusChanged:
+		return "✓"
+	case setup.StatusSkipped:
+		return "-"
+	default:
+		return "✗"
+	}
+}
+
+// caExportAlias names the root in exported trust stores
+const caExportAlias = "faa-root-ca"
+
+func handleCAExport(args []string) int {
+	opts, positional, err := parseExportArgs(args, []string{exportPEM, exportDER, exportPKCS12, exportJKS, exportMobileConfig})
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
+	}
+	if len(positional) > 0 {
+		printError("Unexpected argument: %s", positional[0])
+		return ExitUsage
+	}
+
+	caPath, err := proxy.GetCAPath()
+	if err != nil {
+		printError("Failed to get CA certificate path: %v", err)
+		return ExitError
+	}
+	pemData, err := os.ReadFile(caPath)
+	if os.IsNotExist(err) {
+		printError("CA certificate not yet exported; it is created when the daemon starts")
+		return ExitNotFound
+	} else if err != nil {
+		printError("Failed to read CA certificate: %v", err)
+		return ExitError
+	}
+	certs, err := certexport.ParseCertificates(pemData)
+	if err != nil {
+		printError("Failed to read CA certificate: %v", err)
+		return ExitError
+	}
+	root := certs[0]
+
+	var data []byte
+	switch opts.format {
+	case exportPEM:
+		data = pemData
+	case exportDER:
+		data = root.Raw
+	case exportPKCS12:
+		data, err = certexport.PKCS12TrustStore(root, caExportAlias, opts.password)
+	case exportJKS:
+		data, err = certexport.JKSTrustStore(root, caExportAlias, opts.password, time.Now())
+	case exportMobileConfig:
+		data, err = certexport.MobileConfig(root, "faa development CA")
+	}
+	if err != nil {
+		printError("Failed to encode CA certificate: %v", err)
+		return ExitError
+	}
+
+	output := opts.output
+	if output == "" {
+		output = caExportAlias + exportExtensions[opts.format]
+	}
+	if err := writeExport(output, data, 0644); err != nil {
+		printError("Failed to write %s: %v", output, err)
+		return ExitError
+	}
+	if output == "-" {
+		return ExitSuccess
+	}
+
+	fmt.Printf("Wrote the root CA %s to %s\n", root.Subject.CommonName, output)
+	for _, line := range caExportHints(opts, output, caPath) {
+		fmt.Printf("  %s\n", line)
+	}
+	return ExitSuccess
+}
+
+// caExportHints returns how to install a root exported to path from the
+// PEM file at caPath
+func caExportHints(opts exportOptions, path, caPath string) []string {
+	switch opts.format {
+	case exportDER:
+		return []string{
+			"Android: Settings > Security > Encryption & credentials > Install a certificate > CA certificate",
+			"Windows: certutil -addstore -f Root " + path,
+		}
+	case exportPKCS12, exportJKS:
+		storeType := "PKCS12"
+		if opts.format == exportJKS {
+			storeType = "JKS"
+		}
+		return []string{
+			fmt.Sprintf("Alias: %s, password: %s", caExportAlias, opts.password),
+			fmt.Sprintf("Java: -Djavax.net.ssl.trustStore=%s -Djavax.net.ssl.trustStoreType=%s -Djavax.net.ssl.trustStorePassword=%s", path, storeType, opts.password),
+		}
+	case exportMobileConfig:
+		return []string{
+			"iOS: open the profile on the device, install it under Settings > General > VPN & Device Management,",
+			"then turn on full trust under Settings > General > About > Certificate Trust Settings",
+			"iOS Simulator: drag the profile onto the simulator, or run: xcrun simctl keychain booted add-root-cert " + caPath,
+		}
+	}
+	return nil
+}
+
+func handleCARotate(args []string) int {
+	yes := false
+	for _, arg := range args {
+		switch arg {
+		case "-y", "--yes":
+			yes = true
+		default:
+			printError("Unknown option: %s", arg)
+			return ExitUsage
+		}
+	}
+
+	if configuredCA() {
+		path, _ := config.Path()
+		printError("The CA is set by tls.ca in %s; faa can't rotate it. Point tls.ca at a new CA and restart the daemon.", path)
+		return ExitError
+	}
+
+	client, err := daemon.Connect()
+	if err != nil {
+		printError("Daemon is not running. Start it with: faa daemon")
+		return ExitDaemonNotRunning
+	}
+	defer client.Close()
+
+	caPath, err := proxy.GetCAPath()
+	if err != nil {
+		printError("Failed to get CA certificate path: %v", err)
+		return ExitError
+	}
+	oldRoot, err := os.ReadFile(caPath)
+	if err != nil && !os.IsNotExist(err) {
+		printError("Failed to read CA certificate: %v", err)
+		return ExitError
+	}
+	if old, err := proxy.ReadCertificateInfo(caPath); err == nil {
+		fmt.Printf("Current root CA: %s\n", old.Subject)
+		fmt.Printf("  SHA-256: %s\n", old.SHA256)
+		fmt.Println()
+	}
+
+	fmt.Println("This creates a new local CA and reissues every certificate. Browsers and")
+	fmt.Println("tools distrust faa's sites until they trust the new root, which 'faa setup'")
+	fmt.Println("offers next. The old root is removed from the trust stores setup added it to.")
+	fmt.Println()
+	if !yes {
+		fmt.Print("Rotate the CA? (y/N): ")
+		response, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			printError("Failed to read input: %v", err)
+			return ExitError
+		}
+		response = strings.ToLower(strings.TrimSpace(response))
+		if response != "y" && response != "yes" {
+			fmt.Println("Rotation cancelled.")
+			return ExitSuccess
+		}
+	}
+
+	// Keep the old root so it can be found in trust stores once the
+	// exported copy is replaced
+	var oldRootPath string
+	if len(oldRoot) > 0 {
+		file, err := os.CreateTemp("", "faa-old-root-*.pem")
+		if err == nil {
+			_, err = file.Write(oldRoot)
+			file.Close()
+			defer os.Remove(file.Name())
+		}
+		if err != nil {
+			printError("Failed to save the old CA certificate: %v", err)
+			return ExitError
+		}
+		oldRootPath = file.Name()
+	}
+
+	root, err := client.RotateCA()
+	if err != nil {
+		printError("Failed to rotate CA: %v", err)
+		return ExitDaemonRequest
+	}
+	fmt.Printf("✓ New root CA: %s\n", root.Subject)
+	fmt.Printf("  SHA-256: %s\n", root.SHA256)
+	fmt.Printf("  Valid until: %s\n", root.NotAfter.Local().Format(time.DateOnly))
+
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		return ExitSuccess
+	}
+
+	if oldRootPath != "" {
+		fmt.Println()
+		fmt.Println("Removing the old root from trust stores...")
+		if err := setup.RemoveCA(oldRootPath, setup.Options{Yes: yes}); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			fmt.Fprintln(os.Stderr, "Run 'faa setup --uninstall' to see the remaining changes.")
+		}
+	}
+
+	fmt.Println()
+	report, err := setup.Run(setup.Options{Yes: yes, CAOnly: true})
+	if err != nil {
+		printError("Failed to trust the new CA: %v", err)
+		return ExitError
+	}
+	if len(report.Incomplete()) == 0 {
+		fmt.Println("Restart your browsers to pick up the new root.")
+	}
+	return ExitSuccess
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sahithyandev/faa/internal/proxy"
+	"github.com/sahithyandev/faa/internal/setup"
+)
+
+func TestCAUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"bogus"},
+		{"info", "--bogus"},
+		{"rotate", "--bogus"},
+		{"export", "--format", "bogus"},
+		{"export", "extra"},
+	} {
+		if exitCode := handleCA(args); exitCode != ExitUsage {
+			t.Errorf("handleCA(%v) = %d, want %d", args, exitCode, ExitUsage)
+		}
+	}
+}
+
+func TestCAInfoNotExported(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	exitCode := handleCA([]string{"info", "--json"})
+
+	w.Close()
+	os.Stdout = oldStdout
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+
+	if exitCode != ExitNotFound {
+		t.Errorf("exit code = %d, want %d", exitCode, ExitNotFound)
+	}
+	var doc errorOutput
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if doc.Error.Code != "not_found" {
+		t.Errorf("error code = %q, want not_found", doc.Error.Code)
+	}
+}
+
+func TestCAExport(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if exitCode := handleCA([]string{"export"}); exitCode != ExitNotFound {
+		t.Errorf("exit code before the CA is exported = %d, want %d", exitCode, ExitNotFound)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "faa test root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	caPath, _ := proxy.GetCAPath()
+	os.MkdirAll(filepath.Dir(caPath), 0755)
+	if err := os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatalf("Failed to write CA: %v", err)
+	}
+
+	dir := t.TempDir()
+	for _, format := range []string{"pem", "der", "p12", "jks", "mobileconfig"} {
+		path := filepath.Join(dir, "root."+format)
+		if exitCode := handleCA([]string{"export", "--format", format, "-o", path}); exitCode != ExitSuccess {
+			t.Errorf("export --format %s = %d, want %d", format, exitCode, ExitSuccess)
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("Failed to read the %s export: %v", format, err)
+			continue
+		}
+
+		switch format {
+		case "der":
+			if !bytes.Equal(data, der) {
+				t.Error("DER export isn't the root certificate")
+			}
+		case "pem":
+			if block, _ := pem.Decode(data); block == nil || !bytes.Equal(block.Bytes, der) {
+				t.Error("PEM export isn't the root certificate")
+			}
+		case "jks":
+			if !bytes.HasPrefix(data, []byte{0xFE, 0xED, 0xFE, 0xED}) {
+				t.Error("JKS export doesn't start with the key store magic")
+			}
+		case "mobileconfig":
+			if !strings.Contains(string(data), "com.apple.security.root") {
+				t.Error("profile doesn't install a root certificate")
+			}
+		}
+	}
+}
+
+func TestCARotateDaemonNotRunning(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if exitCode := handleCA([]string{"rotate", "--yes"}); exitCode != ExitDaemonNotRunning {
+		t.Errorf("exit code = %d, want %d", exitCode, ExitDaemonNotRunning)
+	}
+}
+
+func TestTrustStoreMarker(t *testing.T) {
+	tests := map[string]string{
+		setup.StatusOK:      "✓",
+		setup.StatusChanged: "✓",
+		setup.StatusSkipped: "-",
+		setup.StatusMissing: "✗",
+		setup.StatusFailed:  "✗",
+	}
+	for status, expected := range tests {
+		if got := trustStoreMarker(status); got != expected {
+			t.Errorf("trustStoreMarker(%q) = %q, want %q", status, got, expected)
+		}
+	}
+}
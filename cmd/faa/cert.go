@@ -0,0 +1,289 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sahithyandev/faa/internal/certexport"
+	"github.com/sahithyandev/faa/internal/daemon"
+)
+
+// defaultClientCertDays is how long 'faa cert client' certificates are
+// valid by default
+const defaultClientCertDays = 365
+
+// Formats 'faa ca export' and 'faa cert export' write
+const (
+	exportPEM          = "pem"
+	exportDER          = "der"
+	exportPKCS12       = "p12"
+	exportJKS          = "jks"
+	exportMobileConfig = "mobileconfig"
+)
+
+// exportExtensions are the file extensions of the export formats. DER
+// certificates use .cer, which Windows and Android both open.
+var exportExtensions = map[string]string{
+	exportPEM:          ".pem",
+	exportDER:          ".cer",
+	exportPKCS12:       ".p12",
+	exportJKS:          ".jks",
+	exportMobileConfig: ".mobileconfig",
+}
+
+// exportOptions holds the parsed arguments of 'faa ca export' and
+// 'faa cert export'
+type exportOptions struct {
+	format   string
+	output   string // file to write, "-" for stdout, or empty for the default name
+	password string
+}
+
+// parseExportArgs parses export options, accepting the given formats, the
+// first of which is the default. Positional arguments are returned as is.
+func parseExportArgs(args []string, formats []string) (exportOptions, []string, error) {
+	opts := exportOptions{format: formats[0], password: certexport.DefaultPassword}
+	var positional []string
+	passwordSet := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--format":
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("%s requires one of: %s", arg, strings.Join(formats, ", "))
+			}
+			i++
+			opts.format = strings.ToLower(args[i])
+		case arg == "-o" || arg == "--output":
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("%s requires a file name", arg)
+			}
+			i++
+			opts.output = args[i]
+		case arg == "--password":
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("%s requires a password", arg)
+			}
+			i++
+			opts.password = args[i]
+			passwordSet = true
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			return opts, nil, fmt.Errorf("unknown option: %s", arg)
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	if !slices.Contains(formats, opts.format) {
+		return opts, nil, fmt.Errorf("unsupported format: %s (use %s)", opts.format, strings.Join(formats, ", "))
+	}
+	if passwordSet && opts.format != exportPKCS12 && opts.format != exportJKS {
+		return opts, nil, fmt.Errorf("--password only applies to the p12 and jks formats")
+	}
+	if opts.password == "" {
+		return opts, nil, fmt.Errorf("--password must not be empty")
+	}
+	return opts, positional, nil
+}
+
+// writeExport writes data to path, or to stdout when path is "-"
+func writeExport(path string, data []byte, perm os.FileMode) error {
+	if path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, perm)
+}
+
+func handleCert(args []string) int {
+	if len(args) == 0 {
+		printError("A subcommand is required. Usage: faa cert export <host> | faa cert client <name>")
+		return ExitUsage
+	}
+
+	switch args[0] {
+	case "export":
+		return handleCertExport(args[1:])
+	case "client":
+		return handleCertClient(args[1:])
+	default:
+		printError("Unknown cert subcommand: %s", args[0])
+		return ExitUsage
+	}
+}
+
+func handleCertExport(args []string) int {
+	opts, positional, err := parseExportArgs(args, []string{exportPEM, exportPKCS12, exportJKS})
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
+	}
+	if len(positional) != 1 {
+		printError("A host is required. Usage: faa cert export <host> [--format pem|p12|jks]")
+		return ExitUsage
+	}
+
+	client, err := daemon.Connect()
+	if err != nil {
+		printError("Daemon is not running. Start it with: faa daemon")
+		return ExitDaemonNotRunning
+	}
+	defer client.Close()
+
+	cert, err := client.GetCertificate(positional[0])
+	if err != nil {
+		printError("Failed to get certificate: %v", err)
+		return ExitDaemonRequest
+	}
+	output := opts.output
+	if output == "" {
+		output = cert.Host + exportExtensions[opts.format]
+	}
+	written, leaf, exitCode := writeCertificate(opts, output, cert.Host, cert.Certificate, cert.Key)
+	if exitCode != ExitSuccess || written == nil {
+		return exitCode
+	}
+
+	fmt.Printf("Wrote the certificate and key for %s to %s\n", cert.Host, strings.Join(written, " and "))
+	if opts.format != exportPEM {
+		fmt.Printf("  Alias: %s, password: %s\n", strings.ToLower(cert.Host), opts.password)
+	}
+	fmt.Printf("  Valid until %s; faa renews it before then, so export it again after that.\n",
+		leaf.NotAfter.Local().Format(time.DateTime))
+	return ExitSuccess
+}
+
+// writeCertificate writes a PEM certificate chain and key to output in the
+// format of opts. It returns the files written, which is none for stdout,
+// and the leaf certificate. PEM goes to two files, the key next to the
+// certificate as mkcert names them.
+func writeCertificate(opts exportOptions, output, alias, certPEM, keyPEM string) (written []string, leaf *x509.Certificate, exitCode int) {
+	chain, err := certexport.ParseCertificates([]byte(certPEM))
+	if err != nil {
+		printError("Failed to read certificate: %v", err)
+		return nil, nil, ExitError
+	}
+	key, err := certexport.ParsePrivateKey([]byte(keyPEM))
+	if err != nil {
+		printError("Failed to read key: %v", err)
+		return nil, nil, ExitError
+	}
+
+	if opts.format == exportPEM {
+		if output == "-" {
+			if err := writeExport(output, []byte(certPEM+keyPEM), 0600); err != nil {
+				printError("Failed to write output: %v", err)
+				return nil, nil, ExitError
+			}
+			return nil, chain[0], ExitSuccess
+		}
+		keyPath := strings.TrimSuffix(output, ".pem") + "-key.pem"
+		if err := writeExport(output, []byte(certPEM), 0644); err != nil {
+			printError("Failed to write %s: %v", output, err)
+			return nil, nil, ExitError
+		}
+		if err := writeExport(keyPath, []byte(keyPEM), 0600); err != nil {
+			printError("Failed to write %s: %v", keyPath, err)
+			return nil, nil, ExitError
+		}
+		return []string{output, keyPath}, chain[0], ExitSuccess
+	}
+
+	var data []byte
+	if opts.format == exportPKCS12 {
+		data, err = certexport.PKCS12(key, chain, alias, opts.password)
+	} else {
+		data, err = certexport.JKS(key, chain, alias, opts.password, time.Now())
+	}
+	if err != nil {
+		printError("Failed to encode certificate: %v", err)
+		return nil, nil, ExitError
+	}
+	if err := writeExport(output, data, 0600); err != nil {
+		printError("Failed to write %s: %v", output, err)
+		return nil, nil, ExitError
+	}
+	if output == "-" {
+		return nil, chain[0], ExitSuccess
+	}
+	return []string{output}, chain[0], ExitSuccess
+}
+
+func handleCertClient(args []string) int {
+	days := defaultClientCertDays
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		if args[i] != "--days" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if i+1 >= len(args) {
+			printError("--days requires a number of days")
+			return ExitUsage
+		}
+		i++
+		n, err := strconv.Atoi(args[i])
+		if err != nil || n <= 0 {
+			printError("Invalid --days: %s", args[i])
+			return ExitUsage
+		}
+		days = n
+	}
+
+	opts, positional, err := parseExportArgs(rest, []string{exportPEM, exportPKCS12, exportJKS})
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
+	}
+	if len(positional) != 1 || strings.TrimSpace(positional[0]) == "" {
+		printError("A name is required. Usage: faa cert client <name> [--days <n>] [--format pem|p12|jks]")
+		return ExitUsage
+	}
+	name := positional[0]
+
+	client, err := daemon.Connect()
+	if err != nil {
+		printError("Daemon is not running. Start it with: faa daemon")
+		return ExitDaemonNotRunning
+	}
+	defer client.Close()
+
+	cert, err := client.IssueClientCertificate(name, days)
+	if err != nil {
+		printError("Failed to issue client certificate: %v", err)
+		return ExitDaemonRequest
+	}
+	output := opts.output
+	if output == "" {
+		output = clientCertFileName(name) + "-client" + exportExtensions[opts.format]
+	}
+	written, leaf, exitCode := writeCertificate(opts, output, clientCertFileName(name), cert.Certificate, cert.Key)
+	if exitCode != ExitSuccess || written == nil {
+		return exitCode
+	}
+
+	fmt.Printf("Wrote a client certificate for %s to %s\n", name, strings.Join(written, " and "))
+	if opts.format != exportPEM {
+		fmt.Printf("  Alias: %s, password: %s\n", strings.ToLower(clientCertFileName(name)), opts.password)
+	}
+	fmt.Printf("  Valid until %s for hosts with tls.clientAuth in config.json\n",
+		leaf.NotAfter.Local().Format(time.DateTime))
+	return ExitSuccess
+}
+
+// clientCertFileName turns a client name into a file name, replacing
+// characters other than letters, digits, dots, dashes and underscores
+func clientCertFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune(".-_", r) {
+			return r
+		}
+		return '-'
+	}, name)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseExportArgs(t *testing.T) {
+	formats := []string{exportPEM, exportPKCS12, exportJKS}
+	tests := []struct {
+		args       []string
+		want       exportOptions
+		positional int
+		wantErr    bool
+	}{
+		{args: []string{"app"}, want: exportOptions{format: "pem", password: "changeit"}, positional: 1},
+		{args: []string{"app", "--format", "P12", "-o", "-"}, want: exportOptions{format: "p12", output: "-", password: "changeit"}, positional: 1},
+		{args: []string{"--format", "jks", "--password", "s3cret", "app"}, want: exportOptions{format: "jks", password: "s3cret"}, positional: 1},
+		{args: []string{"--format", "der"}, wantErr: true},
+		{args: []string{"--format"}, wantErr: true},
+		{args: []string{"-o"}, wantErr: true},
+		{args: []string{"--password", "s3cret"}, wantErr: true},
+		{args: []string{"--format", "p12", "--password", ""}, wantErr: true},
+		{args: []string{"--bogus"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, positional, err := parseExportArgs(tt.args, formats)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseExportArgs(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if got != tt.want || len(positional) != tt.positional {
+			t.Errorf("parseExportArgs(%v) = %+v, %v, want %+v with %d arguments", tt.args, got, positional, tt.want, tt.positional)
+		}
+	}
+}
+
+func TestCertUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"bogus"},
+		{"export"},
+		{"export", "app", "other"},
+		{"export", "app", "--format", "mobileconfig"},
+		{"client"},
+		{"client", "alice", "bob"},
+		{"client", "alice", "--days"},
+		{"client", "alice", "--days", "0"},
+		{"client", "alice", "--days", "year"},
+		{"client", "alice", "--format", "der"},
+	} {
+		if exitCode := handleCert(args); exitCode != ExitUsage {
+			t.Errorf("handleCert(%v) = %d, want %d", args, exitCode, ExitUsage)
+		}
+	}
+}
+
+func TestCertExportDaemonNotRunning(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if exitCode := handleCert([]string{"export", "app"}); exitCode != ExitDaemonNotRunning {
+		t.Errorf("exit code = %d, want %d", exitCode, ExitDaemonNotRunning)
+	}
+}
+
+func TestCertClientDaemonNotRunning(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if exitCode := handleCert([]string{"client", "alice", "--days", "30"}); exitCode != ExitDaemonNotRunning {
+		t.Errorf("exit code = %d, want %d", exitCode, ExitDaemonNotRunning)
+	}
+}
+
+func TestClientCertFileName(t *testing.T) {
+	tests := map[string]string{
+		"alice":            "alice",
+		"ci-runner_2.test": "ci-runner_2.test",
+		"Alice Smith":      "Alice-Smith",
+		"../etc/passwd":    "..-etc-passwd",
+	}
+	for name, want := range tests {
+		if got := clientCertFileName(name); got != want {
+			t.Errorf("clientCertFileName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+
+	"github.com/sahithyandev/faa/internal/daemon"
+	"github.com/sahithyandev/faa/internal/proxy"
+)
+
+// chaosOptions holds the parsed arguments of 'faa chaos'
+type chaosOptions struct {
+	host string
+	off  bool
+	rule proxy.ChaosRule
+
+	// set is true when any fault option was given
+	set bool
+}
+
+// chaosOutput is the machine-readable form of 'faa chaos'
+type chaosOutput struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	Host          string            `json:"host"`
+	Rules         []proxy.ChaosRule `json:"rules"`
+}
+
+// parseChaosArgs parses the arguments of 'faa chaos' after output flags
+// have been removed
+func parseChaosArgs(args []string) (chaosOptions, error) {
+	var opts chaosOptions
+	var positional []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+			continue
+		}
+
+		if i+1 >= len(args) {
+			return opts, fmt.Errorf("%s requires a value", arg)
+		}
+		i++
+		value := args[i]
+
+		var err error
+		switch arg {
+		case "--path":
+			if !strings.HasPrefix(value, "/") {
+				return opts, fmt.Errorf("--path must start with /")
+			}
+			opts.rule.Path = value
+			continue
+		case "--latency":
+			opts.rule.Latency, err = parseChaosDuration(value)
+		case "--jitter":
+			opts.rule.Jitter, err = parseChaosDuration(value)
+		case "--bandwidth":
+			opts.rule.Bandwidth, err = parseBandwidth(value)
+		case "--error-rate":
+			opts.rule.ErrorRate, err = parseRate(value)
+		case "--error-status":
+			opts.rule.ErrorStatus, err = strconv.Atoi(value)
+		case "--reset-rate":
+			opts.rule.ResetRate, err = parseRate(value)
+		default:
+			return opts, fmt.Errorf("unknown option: %s", arg)
+		}
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s: %w", arg, err)
+		}
+		opts.set = true
+	}
+
+	if len(positional) == 0 {
+		return opts, fmt.Errorf("a host is required. Usage: faa chaos <host> [off] [options]")
+	}
+	if len(positional) > 2 || (len(positional) == 2 && positional[1] != "off") {
+		return opts, fmt.Errorf("unexpected argument: %s", positional[len(positional)-1])
+	}
+	opts.host = positional[0]
+	opts.off = len(positional) == 2
+
+	if opts.off && opts.set {
+		return opts, fmt.Errorf("'off' cannot be combined with fault options")
+	}
+	if opts.rule.ErrorStatus != 0 && opts.rule.ErrorRate == 0 {
+		return opts, fmt.Errorf("--error-status requires --error-rate")
+	}
+	if err := opts.rule.Validate(); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// parseChaosDuration parses a duration such as 300ms or 2s
+func parseChaosDuration(value string) (caddy.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return caddy.Duration(d), nil
+}
+
+// parseRate parses a fraction (0.1) or percentage (10%) into 0..1
+func parseRate(value string) (float64, error) {
+	percent := strings.HasSuffix(value, "%")
+	rate, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("expected a fraction like 0.1 or a percentage like 10%%")
+	}
+	if percent {
+		rate /= 100
+	}
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("must be between 0 and 1 (0%% and 100%%)")
+	}
+	return rate, nil
+}
+
+// parseBandwidth parses bytes per second with an optional k or m suffix
+// (1024-based), such as 500, 50k or 1m
+func parseBandwidth(value string) (int64, error) {
+	multiplier := int64(1)
+	number := strings.ToLower(value)
+	switch {
+	case strings.HasSuffix(number, "k"):
+		multiplier = 1024
+		number = strings.TrimSuffix(number, "k")
+	case strings.HasSuffix(number, "m"):
+		multiplier = 1024 * 1024
+		number = strings.TrimSuffix(number, "m")
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("expected bytes per second like 500, 50k or 1m")
+	}
+	return n * multiplier, nil
+}
+
+// updateChaosRules returns rules with the rule for the same path replaced
+// by rule, or removed when remove is set
+func updateChaosRules(rules []proxy.ChaosRule, rule proxy.ChaosRule, remove bool) []proxy.ChaosRule {
+	updated := make([]proxy.ChaosRule, 0, len(rules)+1)
+	for _, existing := range rules {
+		if existing.Path != rule.Path {
+			updated = append(updated, existing)
+		}
+	}
+	if !remove {
+		updated = append(updated, rule)
+	}
+	return updated
+}
+
+func handleChaos(args []string) int {
+	format, rest, err := parseOutputFlags(args)
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
+	}
+
+	opts, err := parseChaosArgs(rest)
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
+	}
+
+	client, err := daemon.Connect()
+	if err != nil {
+		return format.fail(ExitDaemonNotRunning, "Daemon is not running. Start it with: faa daemon")
+	}
+	defer client.Close()
+
+	current, err := client.GetChaos(opts.host)
+	if err != nil {
+		return format.fail(ExitDaemonRequest, "Failed to get chaos rules: %v", err)
+	}
+
+	rules := current.Rules
+	if opts.off || opts.set {
+		if opts.off && opts.rule.Path == "" {
+			rules = nil
+		} else {
+			rules = updateChaosRules(rules, opts.rule, opts.off)
+		}
+		if err := client.SetChaos(opts.host, rules); err != nil {
+			return format.fail(ExitDaemonRequest, "Failed to update chaos rules: %v", err)
+		}
+		// Read back the rules in the order the proxy applies them
+		if current, err = client.GetChaos(opts.host); err != nil {
+			return format.fail(ExitDaemonRequest, "Failed to get chaos rules: %v", err)
+		}
+		rules = current.Rules
+	}
+
+	if format.structured() {
+		if rules == nil {
+			rules = []proxy.ChaosRule{}
+		}
+		return format.emit(chaosOutput{
+			SchemaVersion: schemaVersion,
+			Host:          current.Host,
+			Rules:         rules,
+		})
+	}
+
+	printChaosRules(os.Stdout, current.Host, rules)
+	return ExitSuccess
+}
+
+// printChaosRules prints the fault injection rules for host
+func printChaosRules(w io.Writer, host string, rules []proxy.ChaosRule) {
+	if len(rules) == 0 {
+		fmt.Fprintf(w, "No faults injected for %s\n", host)
+		return
+	}
+
+	fmt.Fprintf(w, "Faults injected for %s:\n", host)
+	for _, rule := range rules {
+		fmt.Fprintf(w, "  %s\n", formatChaosRule(rule))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Turn them off with: faa chaos %s off\n", host)
+}
+
+// formatChaosRule describes a rule on one line
+func formatChaosRule(rule proxy.ChaosRule) string {
+	path := rule.Path
+	if path == "" {
+		path = "all paths"
+	}
+
+	var faults []string
+	if rule.Latency > 0 || rule.Jitter > 0 {
+		latency := "latency " + time.Duration(rule.Latency).String()
+		if rule.Jitter > 0 {
+			latency += " +0-" + time.Duration(rule.Jitter).String()
+		}
+		faults = append(faults, latency)
+	}
+	if rule.Bandwidth > 0 {
+		faults = append(faults, "bandwidth "+formatBytes(uint64(rule.Bandwidth))+"/s")
+	}
+	if rule.ErrorRate > 0 {
+		status := rule.ErrorStatus
+		if status == 0 {
+			status = proxy.DefaultChaosStatus
+		}
+		faults = append(faults, fmt.Sprintf("%s errors %d", formatRate(rule.ErrorRate), status))
+	}
+	if rule.ResetRate > 0 {
+		faults = append(faults, formatRate(rule.ResetRate)+" resets")
+	}
+	if len(faults) == 0 {
+		faults = append(faults, "no faults")
+	}
+
+	return fmt.Sprintf("%-16s %s", path, strings.Join(faults, ", "))
+}
+
+// formatRate formats a 0..1 rate as a percentage
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(rate*100, 'f', -1, 64) + "%"
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+
+	"github.com/sahithyandev/faa/internal/proxy"
+)
+
+func TestParseChaosArgs(t *testing.T) {
+	opts, err := parseChaosArgs([]string{"app", "--path", "/api/*", "--latency", "300ms", "--jitter", "100ms",
+		"--bandwidth", "50k", "--error-rate", "10%", "--error-status", "502", "--reset-rate", "0.05"})
+	if err != nil {
+		t.Fatalf("parseChaosArgs() failed: %v", err)
+	}
+	want := proxy.ChaosRule{
+		Path:        "/api/*",
+		Latency:     caddy.Duration(300 * time.Millisecond),
+		Jitter:      caddy.Duration(100 * time.Millisecond),
+		Bandwidth:   50 * 1024,
+		ErrorRate:   0.1,
+		ErrorStatus: 502,
+		ResetRate:   0.05,
+	}
+	if opts.host != "app" || opts.off || !opts.set || opts.rule != want {
+		t.Errorf("opts = %+v", opts)
+	}
+
+	opts, err = parseChaosArgs([]string{"app"})
+	if err != nil || opts.set || opts.off {
+		t.Errorf("parseChaosArgs(app) = %+v, %v; want a plain query", opts, err)
+	}
+
+	opts, err = parseChaosArgs([]string{"app", "off", "--path", "/api/*"})
+	if err != nil || !opts.off || opts.rule.Path != "/api/*" {
+		t.Errorf("parseChaosArgs(app off --path) = %+v, %v", opts, err)
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"app", "on"},
+		{"app", "off", "--latency", "1s"},
+		{"app", "--latency"},
+		{"app", "--latency", "-1s"},
+		{"app", "--path", "api"},
+		{"app", "--error-status", "500"},
+		{"app", "--error-rate", "10%", "--error-status", "200"},
+		{"app", "--unknown", "1"},
+	} {
+		if _, err := parseChaosArgs(args); err == nil {
+			t.Errorf("parseChaosArgs(%q) should fail", args)
+		}
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64
+	}{
+		{"0.25", 0.25},
+		{"25%", 0.25},
+		{"100%", 1},
+		{"0", 0},
+	}
+	for _, tt := range tests {
+		if got, err := parseRate(tt.value); err != nil || got != tt.want {
+			t.Errorf("parseRate(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+		}
+	}
+	for _, value := range []string{"", "abc", "150%", "1.5", "-0.1"} {
+		if _, err := parseRate(value); err == nil {
+			t.Errorf("parseRate(%q) should fail", value)
+		}
+	}
+}
+
+func TestParseBandwidth(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"500", 500},
+		{"50k", 50 * 1024},
+		{"2M", 2 * 1024 * 1024},
+	}
+	for _, tt := range tests {
+		if got, err := parseBandwidth(tt.value); err != nil || got != tt.want {
+			t.Errorf("parseBandwidth(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+		}
+	}
+	for _, value := range []string{"", "k", "0", "-5", "10g"} {
+		if _, err := parseBandwidth(value); err == nil {
+			t.Errorf("parseBandwidth(%q) should fail", value)
+		}
+	}
+}
+
+func TestUpdateChaosRules(t *testing.T) {
+	rules := []proxy.ChaosRule{
+		{ErrorRate: 0.1},
+		{Path: "/api/*", Bandwidth: 1024},
+	}
+
+	updated := updateChaosRules(rules, proxy.ChaosRule{Path: "/api/*", ResetRate: 0.5}, false)
+	if len(updated) != 2 || updated[1].ResetRate != 0.5 || updated[1].Bandwidth != 0 {
+		t.Errorf("Replacing a rule = %+v", updated)
+	}
+
+	updated = updateChaosRules(rules, proxy.ChaosRule{Path: "/api/*"}, true)
+	if len(updated) != 1 || updated[0].Path != "" {
+		t.Errorf("Removing a rule = %+v", updated)
+	}
+
+	// The input is left untouched
+	if rules[1].Bandwidth != 1024 {
+		t.Error("updateChaosRules() modified its input")
+	}
+}
+
+func TestFormatChaosRule(t *testing.T) {
+	got := formatChaosRule(proxy.ChaosRule{
+		Path:      "/api/*",
+		Latency:   caddy.Duration(300 * time.Millisecond),
+		Jitter:    caddy.Duration(100 * time.Millisecond),
+		ErrorRate: 0.1,
+		ResetRate: 0.05,
+	})
+	for _, want := range []string{"/api/*", "latency 300ms +0-100ms", "10% errors 503", "5% resets"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("formatChaosRule() = %q, missing %q", got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	printChaosRules(&buf, "app.local", nil)
+	if !strings.Contains(buf.String(), "No faults injected for app.local") {
+		t.Errorf("printChaosRules() = %q", buf.String())
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sahithyandev/faa/internal/daemon"
+)
+
+const (
+	// defaultDaemonLogLines is how many lines 'faa daemon logs' prints
+	defaultDaemonLogLines = 50
+
+	// daemonLogPollInterval is how often 'faa daemon logs -f' checks for
+	// new lines
+	daemonLogPollInterval = 250 * time.Millisecond
+)
+
+// daemonLogsOptions holds the parsed arguments of 'faa daemon logs'
+type daemonLogsOptions struct {
+	follow bool
+	lines  int
+	raw    bool
+}
+
+// parseDaemonLogsArgs parses the arguments of 'faa daemon logs'
+func parseDaemonLogsArgs(args []string) (daemonLogsOptions, error) {
+	opts := daemonLogsOptions{lines: defaultDaemonLogLines}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-f", "--follow":
+			opts.follow = true
+		case "--json":
+			opts.raw = true
+		case "-n", "--lines":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("%s requires a number", arg)
+			}
+			i++
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n < 0 {
+				return opts, fmt.Errorf("%s must be a non-negative integer", arg)
+			}
+			opts.lines = n
+		default:
+			return opts, fmt.Errorf("unknown option: %s", arg)
+		}
+	}
+	return opts, nil
+}
+
+func handleDaemonLogs(args []string) int {
+	opts, err := parseDaemonLogsArgs(args)
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
+	}
+
+	logPath, err := daemon.DaemonLogPath()
+	if err != nil {
+		printError("Failed to get daemon log path: %v", err)
+		return ExitError
+	}
+
+	offset, err := printDaemonLogTail(os.Stdout, logPath, opts)
+	if err != nil {
+		printError("%v", err)
+		return ExitError
+	}
+	if !opts.follow {
+		return ExitSuccess
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	ticker := time.NewTicker(daemonLogPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return ExitSuccess
+		case <-ticker.C:
+			offset, err = printDaemonLogFrom(os.Stdout, logPath, offset, opts)
+			if err != nil {
+				printError("%v", err)
+				return ExitError
+			}
+		}
+	}
+}
+
+// printDaemonLogTail prints the last opts.lines lines of the log and
+// returns the offset to follow it from
+func printDaemonLogTail(w io.Writer, path string, opts daemonLogsOptions) (int64, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read daemon log: %w", err)
+	}
+
+	// A line still being written is printed once it is complete
+	complete := strings.LastIndex(string(data), "\n") + 1
+	var lines []string
+	if complete > 0 {
+		lines = strings.Split(string(data[:complete-1]), "\n")
+	}
+	if len(lines) > opts.lines {
+		lines = lines[len(lines)-opts.lines:]
+	}
+	for _, line := range lines {
+		fmt.Fprintln(w, formatDaemonLogLine(line, opts.raw))
+	}
+	return int64(complete), nil
+}
+
+// printDaemonLogFrom prints the complete lines added to the log after
+// offset and returns the new offset. A log that shrank was rotated, so it
+// is read from the start.
+func printDaemonLogFrom(w io.Writer, path string, offset int64, opts daemonLogsOptions) (int64, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return offset, fmt.Errorf("failed to open daemon log: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return offset, fmt.Errorf("failed to stat daemon log: %w", err)
+	}
+	if info.Size() < offset {
+		offset = 0
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return offset, fmt.Errorf("failed to seek daemon log: %w", err)
+	}
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// Leave a partial line for the next poll
+			return offset, nil
+		}
+		offset += int64(len(line))
+		fmt.Fprintln(w, formatDaemonLogLine(strings.TrimSuffix(line, "\n"), opts.raw))
+	}
+}
+
+// formatDaemonLogLine formats a JSON log line as
+// "2006-01-02 15:04:05 LEVEL message key=value ...". Lines that aren't JSON,
+// and every line when raw is set, are returned as they are.
+func formatDaemonLogLine(line string, raw bool) string {
+	var entry map[string]interface{}
+	if raw || json.Unmarshal([]byte(line), &entry) != nil {
+		return line
+	}
+
+	timestamp := ""
+	if value, ok := entry["time"].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			timestamp = t.Local().Format("2006-01-02 15:04:05")
+		} else {
+			timestamp = value
+		}
+	}
+	level, _ := entry["level"].(string)
+	message, _ := entry["msg"].(string)
+
+	keys := make([]string, 0, len(entry))
+	for key := range entry {
+		switch key {
+		case "time", "level", "msg":
+		default:
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %-5s %s", timestamp, strings.ToUpper(level), message)
+	for _, key := range keys {
+		value := entry[key]
+		text, ok := value.(string)
+		if !ok {
+			data, _ := json.Marshal(value)
+			text = string(data)
+		}
+		if strings.ContainsAny(text, " \"=") {
+			text = strconv.Quote(text)
+		}
+		fmt.Fprintf(&b, " %s=%s", key, text)
+	}
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDaemonLogsArgs(t *testing.T) {
+	opts, err := parseDaemonLogsArgs(nil)
+	if err != nil {
+		t.Fatalf("parseDaemonLogsArgs() failed: %v", err)
+	}
+	if opts.follow || opts.raw || opts.lines != defaultDaemonLogLines {
+		t.Errorf("Unexpected defaults: %+v", opts)
+	}
+
+	opts, err = parseDaemonLogsArgs([]string{"-f", "-n", "10", "--json"})
+	if err != nil {
+		t.Fatalf("parseDaemonLogsArgs() failed: %v", err)
+	}
+	if !opts.follow || !opts.raw || opts.lines != 10 {
+		t.Errorf("Unexpected options: %+v", opts)
+	}
+
+	for _, args := range [][]string{{"-n"}, {"-n", "-1"}, {"--lines", "many"}, {"--since"}} {
+		if _, err := parseDaemonLogsArgs(args); err == nil {
+			t.Errorf("parseDaemonLogsArgs(%v) should fail", args)
+		}
+	}
+}
+
+func TestFormatDaemonLogLine(t *testing.T) {
+	ts := time.Date(2026, 3, 1, 12, 30, 45, 0, time.UTC)
+	line := `{"time":"` + ts.Format(time.RFC3339Nano) + `","level":"WARN","msg":"failed to export CA certificate","error":"no such file","attempts":3}`
+
+	got := formatDaemonLogLine(line, false)
+	want := ts.Local().Format("2006-01-02 15:04:05") + ` WARN  failed to export CA certificate attempts=3 error="no such file"`
+	if got != want {
+		t.Errorf("formatDaemonLogLine() = %q, want %q", got, want)
+	}
+
+	if got := formatDaemonLogLine(line, true); got != line {
+		t.Errorf("Raw lines should be unchanged, got %q", got)
+	}
+	if got := formatDaemonLogLine("panic: oops", false); got != "panic: oops" {
+		t.Errorf("Lines that aren't JSON should be unchanged, got %q", got)
+	}
+}
+
+func TestPrintDaemonLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.log")
+	opts := daemonLogsOptions{lines: 2, raw: true}
+
+	// A missing log has no lines
+	var out bytes.Buffer
+	offset, err := printDaemonLogTail(&out, path, opts)
+	if err != nil || offset != 0 || out.Len() != 0 {
+		t.Fatalf("printDaemonLogTail() = %d, %v, %q for a missing log", offset, err, out.String())
+	}
+
+	if err := os.WriteFile(path, []byte("one\ntwo\nthree\nfou"), 0644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+	offset, err = printDaemonLogTail(&out, path, opts)
+	if err != nil {
+		t.Fatalf("printDaemonLogTail() failed: %v", err)
+	}
+	if out.String() != "two\nthree\n" {
+		t.Errorf("Tail = %q, want the last 2 complete lines", out.String())
+	}
+	if offset != int64(len("one\ntwo\nthree\n")) {
+		t.Errorf("Offset = %d, want the end of the last complete line", offset)
+	}
+
+	// Following picks up the rest of the partial line
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	file.WriteString("r\nfive\n")
+	file.Close()
+
+	out.Reset()
+	offset, err = printDaemonLogFrom(&out, path, offset, opts)
+	if err != nil {
+		t.Fatalf("printDaemonLogFrom() failed: %v", err)
+	}
+	if out.String() != "four\nfive\n" {
+		t.Errorf("Follow = %q, want the new lines", out.String())
+	}
+
+	// A rotated log is read from the start
+	if err := os.WriteFile(path, []byte("six\n"), 0644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+	out.Reset()
+	if _, err := printDaemonLogFrom(&out, path, offset, opts); err != nil {
+		t.Fatalf("printDaemonLogFrom() failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "six") {
+		t.Errorf("Follow after rotation = %q, want the new file", out.String())
+	}
+}
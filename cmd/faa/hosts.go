@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+
+	"github.com/sahithyandev/faa/internal/config"
+	"github.com/sahithyandev/faa/internal/daemon"
+	"github.com/sahithyandev/faa/internal/hostsfile"
+)
+
+// hostsFile is the hosts file 'faa hosts' manages; a variable so tests can
+// use a temporary one. 'faa hosts apply' always writes the system one.
+var hostsFile = hostsfile.Path
+
+func handleHosts(args []string) int {
+	if len(args) == 0 {
+		return handleHostsStatus()
+	}
+
+	switch args[0] {
+	case "status":
+		if len(args) > 1 {
+			printError("Unknown option: %s", args[1])
+			return ExitUsage
+		}
+		return handleHostsStatus()
+	case "sync", "clean":
+		dryRun := false
+		for _, arg := range args[1:] {
+			if arg != "--dry-run" {
+				printError("Unknown option: %s", arg)
+				return ExitUsage
+			}
+			dryRun = true
+		}
+		if args[0] == "clean" {
+			return updateHosts(nil, dryRun)
+		}
+		names, err := routeHostNames()
+		if err != nil {
+			printError("%v", err)
+			return ExitError
+		}
+		return updateHosts(names, dryRun)
+	case "apply":
+		if len(args) > 1 {
+			printError("Unknown option: %s", args[1])
+			return ExitUsage
+		}
+		return handleHostsApply(os.Stdin)
+	default:
+		printError("Unknown hosts subcommand: %s", args[0])
+		return ExitUsage
+	}
+}
+
+// routeHostNames returns the names the hosts block should have
+func routeHostNames() ([]string, error) {
+	registry, err := daemon.NewRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open registry: %w", err)
+	}
+	names, err := registry.HostNames()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load routes: %w", err)
+	}
+	return names, nil
+}
+
+// handleHostsStatus shows the block in the hosts file and whether it
+// matches the routes
+func handleHostsStatus() int {
+	content, err := os.ReadFile(hostsFile)
+	if err != nil {
+		printError("Failed to read %s: %v", hostsFile, err)
+		return ExitError
+	}
+	names, err := routeHostNames()
+	if err != nil {
+		printError("%v", err)
+		return ExitError
+	}
+	current := hostsfile.Names(content)
+
+	cfg, err := config.Load()
+	if err != nil {
+		printError("Failed to load config: %v", err)
+		return ExitError
+	}
+	if cfg.Hosts.Enabled {
+		fmt.Printf("The daemon keeps faa's block in %s in sync with the routes.\n", hostsFile)
+	} else {
+		fmt.Printf("The daemon doesn't update %s; set hosts.enabled in config.json to let it.\n", hostsFile)
+	}
+	fmt.Println()
+
+	if len(current) == 0 {
+		fmt.Printf("%s has no faa block.\n", hostsFile)
+	} else {
+		fmt.Printf("faa's block in %s:\n", hostsFile)
+		for _, name := range current {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+	if !reflect.DeepEqual(current, names) && len(current)+len(names) > 0 {
+		fmt.Println()
+		fmt.Println("The block doesn't match the routes; run 'faa hosts sync' to update it.")
+	}
+	return ExitSuccess
+}
+
+// updateHosts replaces the block in the hosts file with one for names, or
+// removes it if there are none. With dryRun it prints the change instead.
+func updateHosts(names []string, dryRun bool) int {
+	content, err := os.ReadFile(hostsFile)
+	if err != nil {
+		printError("Failed to read %s: %v", hostsFile, err)
+		return ExitError
+	}
+	if string(hostsfile.Update(content, names)) == string(content) {
+		fmt.Printf("✓ %s is up to date\n", hostsFile)
+		return ExitSuccess
+	}
+
+	if dryRun {
+		if len(names) == 0 {
+			fmt.Printf("Would remove faa's block from %s.\n", hostsFile)
+		} else {
+			fmt.Printf("Would write this block to %s:\n\n", hostsFile)
+			fmt.Print(hostsfile.Block(names))
+		}
+		return ExitSuccess
+	}
+
+	if err := writeHosts(names); err != nil {
+		printError("Failed to update %s: %v", hostsFile, err)
+		return ExitError
+	}
+	if len(names) == 0 {
+		fmt.Printf("✓ Removed faa's block from %s\n", hostsFile)
+	} else {
+		fmt.Printf("✓ Updated %s with %d names\n", hostsFile, len(names))
+	}
+	return ExitSuccess
+}
+
+// writeHosts writes the block directly if the hosts file is writable, and
+// otherwise through 'sudo faa hosts apply'
+func writeHosts(names []string) error {
+	if hostsfile.Writable(hostsFile) {
+		_, err := hostsfile.Apply(hostsFile, names)
+		return err
+	}
+	if hostsFile != hostsfile.Path {
+		return errors.New("permission denied")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("sudo", executable, "hosts", "apply")
+	cmd.Stdin = strings.NewReader(strings.Join(names, "\n"))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// handleHostsApply is the privileged helper: it reads names from input, one
+// per line, and writes them as the block in the system hosts file. Only
+// names under .localhost and .local are accepted, so the daemon can be
+// allowed to run it through sudo without a password.
+func handleHostsApply(input io.Reader) int {
+	var names []string
+	scanner := bufio.NewScanner(io.LimitReader(input, hostsfile.MaxNames*256))
+	for scanner.Scan() {
+		if name := strings.TrimSpace(scanner.Text()); name != "" {
+			names = append(names, name)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		printError("Failed to read names: %v", err)
+		return ExitError
+	}
+
+	if _, err := hostsfile.Apply(hostsfile.Path, names); err != nil {
+		printError("Failed to update %s: %v", hostsfile.Path, err)
+		return ExitError
+	}
+	return ExitSuccess
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sahithyandev/faa/internal/daemon"
+	"github.com/sahithyandev/faa/internal/hostsfile"
+)
+
+func TestHostsSync(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "hosts")
+	original := "127.0.0.1\tlocalhost\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to write hosts file: %v", err)
+	}
+	defer func(previous string) { hostsFile = previous }(hostsFile)
+	hostsFile = path
+
+	registry, err := daemon.NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+	if err := registry.UpsertRoute("app.localhost", 3000); err != nil {
+		t.Fatalf("UpsertRoute() failed: %v", err)
+	}
+
+	read := func() string {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read hosts file: %v", err)
+		}
+		return string(content)
+	}
+
+	// A dry run changes nothing
+	if exitCode := handleHosts([]string{"sync", "--dry-run"}); exitCode != ExitSuccess {
+		t.Fatalf("hosts sync --dry-run = %d", exitCode)
+	}
+	if read() != original {
+		t.Error("Expected hosts sync --dry-run not to change the file")
+	}
+
+	if exitCode := handleHosts([]string{"sync"}); exitCode != ExitSuccess {
+		t.Fatalf("hosts sync = %d", exitCode)
+	}
+	if got := hostsfile.Names([]byte(read())); !reflect.DeepEqual(got, []string{"app.local", "app.localhost"}) {
+		t.Errorf("names after sync = %v", got)
+	}
+	if exitCode := handleHosts(nil); exitCode != ExitSuccess {
+		t.Errorf("hosts status = %d", exitCode)
+	}
+
+	if exitCode := handleHosts([]string{"clean"}); exitCode != ExitSuccess {
+		t.Fatalf("hosts clean = %d", exitCode)
+	}
+	if read() != original {
+		t.Errorf("hosts file after clean = %q, want %q", read(), original)
+	}
+}
+
+func TestHostsUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"bogus"},
+		{"sync", "--force"},
+		{"status", "extra"},
+		{"apply", "/tmp/hosts"},
+	} {
+		if exitCode := handleHosts(args); exitCode != ExitUsage {
+			t.Errorf("handleHosts(%v) = %d, want %d", args, exitCode, ExitUsage)
+		}
+	}
+}
+
+func TestHostsApplyRejectsNames(t *testing.T) {
+	// Names are checked before the system hosts file is touched
+	for _, input := range []string{"example.com\n", "app.localhost\n10.0.0.1 example.com\n"} {
+		if exitCode := handleHostsApply(strings.NewReader(input)); exitCode != ExitError {
+			t.Errorf("hosts apply with %q = %d, want %d", input, exitCode, ExitError)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sahithyandev/faa/internal/daemon"
+	"github.com/sahithyandev/faa/internal/har"
+	"github.com/sahithyandev/faa/internal/proxy"
+)
+
+// inspectOptions holds the parsed arguments of 'faa inspect'
+type inspectOptions struct {
+	host   string
+	action string // "list", "on", "off", "clear" or "show"
+	id     uint64
+	limit  int
+	har    bool
+	output string
+}
+
+// inspectOutput is the machine-readable form of 'faa inspect <host>'
+type inspectOutput struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	Host          string           `json:"host"`
+	Enabled       bool             `json:"enabled"`
+	Exchanges     []proxy.Exchange `json:"exchanges"`
+}
+
+// exchangeOutput is the machine-readable form of 'faa inspect <host> <id>'
+type exchangeOutput struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	Exchange      *proxy.Exchange `json:"exchange"`
+}
+
+// parseInspectArgs parses the arguments of 'faa inspect' after output flags
+// have been removed
+func parseInspectArgs(args []string) (inspectOptions, error) {
+	opts := inspectOptions{action: "list"}
+	var positional []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--har":
+			opts.har = true
+		case arg == "-o" || arg == "--output":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("%s requires a file name", arg)
+			}
+			i++
+			opts.output = args[i]
+		case arg == "-n" || arg == "--limit":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("%s requires a number", arg)
+			}
+			i++
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n < 0 {
+				return opts, fmt.Errorf("%s must be a non-negative integer", arg)
+			}
+			opts.limit = n
+		case strings.HasPrefix(arg, "-"):
+			return opts, fmt.Errorf("unknown option: %s", arg)
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	if len(positional) == 0 {
+		return opts, fmt.Errorf("a host is required. Usage: faa inspect <host> [on|off|clear|<id>]")
+	}
+	if len(positional) > 2 {
+		return opts, fmt.Errorf("unexpected argument: %s", positional[2])
+	}
+	opts.host = positional[0]
+
+	if len(positional) == 2 {
+		switch positional[1] {
+		case "on", "off", "clear":
+			opts.action = positional[1]
+		default:
+			id, err := strconv.ParseUint(positional[1], 10, 64)
+			if err != nil || id == 0 {
+				return opts, fmt.Errorf("unknown inspect command: %s", positional[1])
+			}
+			opts.action = "show"
+			opts.id = id
+		}
+	}
+
+	if opts.output != "" && !opts.har {
+		return opts, fmt.Errorf("-o can only be used with --har")
+	}
+	if opts.har && opts.action != "list" && opts.action != "show" {
+		return opts, fmt.Errorf("--har cannot be used with '%s'", opts.action)
+	}
+	return opts, nil
+}
+
+func handleInspect(args []string) int {
+	format, rest, err := parseOutputFlags(args)
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
+	}
+
+	opts, err := parseInspectArgs(rest)
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
+	}
+	if opts.har && format.structured() {
+		printError("--har cannot be combined with --json or --format")
+		return ExitUsage
+	}
+
+	client, err := daemon.Connect()
+	if err != nil {
+		return format.fail(ExitDaemonNotRunning, "Daemon is not running. Start it with: faa daemon")
+	}
+	defer client.Close()
+
+	switch opts.action {
+	case "on", "off":
+		if err := client.SetCapture(opts.host, opts.action == "on"); err != nil {
+			return format.fail(ExitDaemonRequest, "Failed to update capture: %v", err)
+		}
+		if opts.action == "on" {
+			fmt.Printf("Capturing traffic for %s. Browse it with: faa inspect %s\n", opts.host, opts.host)
+		} else {
+			fmt.Printf("Stopped capturing traffic for %s\n", opts.host)
+		}
+		return ExitSuccess
+
+	case "clear":
+		if err := client.ClearCaptures(opts.host); err != nil {
+			return format.fail(ExitDaemonRequest, "Failed to clear captures: %v", err)
+		}
+		fmt.Printf("Cleared captured traffic for %s\n", opts.host)
+		return ExitSuccess
+
+	case "show":
+		exchange, err := client.GetCapture(opts.id)
+		if err != nil {
+			return format.fail(ExitDaemonRequest, "Failed to get capture: %v", err)
+		}
+		if exchange == nil {
+			return format.fail(ExitNotFound, "No captured exchange with id %d", opts.id)
+		}
+		if opts.har {
+			return writeHAR(opts.output, har.FromExchanges([]proxy.Exchange{*exchange}))
+		}
+		if format.structured() {
+			return format.emit(exchangeOutput{SchemaVersion: schemaVersion, Exchange: exchange})
+		}
+		printExchange(os.Stdout, exchange)
+		return ExitSuccess
+	}
+
+	captures, err := client.ListCaptures(opts.host, opts.limit)
+	if err != nil {
+		return format.fail(ExitDaemonRequest, "Failed to list captures: %v", err)
+	}
+	if captures.Exchanges == nil {
+		captures.Exchanges = []proxy.Exchange{}
+	}
+
+	if opts.har {
+		return writeHAR(opts.output, har.FromExchanges(captures.Exchanges))
+	}
+	if format.structured() {
+		return format.emit(inspectOutput{
+			SchemaVersion: schemaVersion,
+			Host:          captures.Host,
+			Enabled:       captures.Enabled,
+			Exchanges:     captures.Exchanges,
+		})
+	}
+
+	printExchanges(os.Stdout, captures)
+	return ExitSuccess
+}
+
+// writeHAR writes a HAR document to path, or to stdout if path is empty
+func writeHAR(path string, doc *har.HAR) int {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		printError("Failed to encode HAR: %v", err)
+		return ExitError
+	}
+	data = append(data, '\n')
+
+	if path == "" {
+		if _, err := os.Stdout.Write(data); err != nil {
+			printError("Failed to write output: %v", err)
+			return ExitError
+		}
+		return ExitSuccess
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		printError("Failed to write %s: %v", path, err)
+		return ExitError
+	}
+	fmt.Printf("Wrote %d entries to %s\n", len(doc.Log.Entries), path)
+	return ExitSuccess
+}
+
+// printExchanges prints a table of captured exchanges, oldest first
+func printExchanges(w io.Writer, captures *daemon.ListCapturesResponseData) {
+	if !captures.Enabled {
+		fmt.Fprintf(w, "Capture is off for %s. Turn it on with: faa inspect %s on\n", captures.Host, captures.Host)
+		if len(captures.Exchanges) == 0 {
+			return
+		}
+		fmt.Fprintln(w)
+	}
+	if len(captures.Exchanges) == 0 {
+		fmt.Fprintf(w, "No traffic captured for %s yet\n", captures.Host)
+		return
+	}
+
+	fmt.Fprintf(w, "%-6s %-8s %-7s %-6s %8s %7s  %s\n", "ID", "TIME", "METHOD", "STATUS", "DURATION", "SIZE", "URL")
+	for _, exchange := range captures.Exchanges {
+		status := strconv.Itoa(exchange.Status)
+		if exchange.Error != "" {
+			status += "!"
+		}
+		fmt.Fprintf(w, "%-6d %-8s %-7s %-6s %8s %7s  %s\n",
+			exchange.ID,
+			exchange.Time.Local().Format("15:04:05"),
+			exchange.Method,
+			status,
+			formatDuration(exchange.DurationMs),
+			formatBytes(uint64(exchange.ResponseBodySize)),
+			stripControl(exchange.URL),
+		)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Show one with: faa inspect %s <id>\n", captures.Host)
+}
+
+// printExchange prints the full request and response of an exchange
+func printExchange(w io.Writer, exchange *proxy.Exchange) {
+	fmt.Fprintf(w, "#%d  %s  %s  from %s\n\n",
+		exchange.ID, exchange.Time.Local().Format(time.RFC3339), formatDuration(exchange.DurationMs), exchange.RemoteAddr)
+
+	fmt.Fprintf(w, "%s https://%s%s %s\n", exchange.Method, exchange.Host, exchange.URL, exchange.Proto)
+	printHeaders(w, exchange.RequestHeaders)
+	printBody(w, exchange.RequestHeaders, exchange.RequestBody, exchange.RequestBodySize, exchange.RequestBodyTruncated)
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%s %d %s\n", exchange.Proto, exchange.Status, http.StatusText(exchange.Status))
+	printHeaders(w, exchange.ResponseHeaders)
+	printBody(w, exchange.ResponseHeaders, exchange.ResponseBody, exchange.ResponseBodySize, exchange.ResponseBodyTruncated)
+
+	if exchange.Error != "" {
+		fmt.Fprintf(w, "\nError: %s\n", exchange.Error)
+	}
+}
+
+// printHeaders prints headers sorted by name
+func printHeaders(w io.Writer, headers http.Header) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, value := range headers[name] {
+			fmt.Fprintf(w, "%s: %s\n", name, value)
+		}
+	}
+}
+
+// printBody prints a captured body, decoding gzip and summarising binary data
+func printBody(w io.Writer, headers http.Header, body []byte, size int64, truncated bool) {
+	if size == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+
+	decoded, ok := har.DecodeBody(headers, body, truncated)
+	if !ok || !har.IsText(headers.Get("Content-Type"), decoded) {
+		fmt.Fprintf(w, "[%s of binary data]\n", formatBytes(uint64(size)))
+		return
+	}
+
+	// Escape sequences in captured bodies must not reach the terminal
+	for _, line := range strings.Split(strings.TrimRight(string(decoded), "\r\n"), "\n") {
+		fmt.Fprintln(w, stripControl(line))
+	}
+	if truncated {
+		fmt.Fprintf(w, "[truncated: showing %s of %s]\n", formatBytes(uint64(len(body))), formatBytes(uint64(size)))
+	}
+}
+
+// formatDuration formats a duration in milliseconds for display
+func formatDuration(ms float64) string {
+	if ms >= 1000 {
+		return fmt.Sprintf("%.2fs", ms/1000)
+	}
+	return fmt.Sprintf("%.1fms", ms)
+}
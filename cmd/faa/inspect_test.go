@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sahithyandev/faa/internal/daemon"
+	"github.com/sahithyandev/faa/internal/proxy"
+)
+
+func TestParseInspectArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    inspectOptions
+		wantErr bool
+	}{
+		{args: []string{"app"}, want: inspectOptions{host: "app", action: "list"}},
+		{args: []string{"app", "on"}, want: inspectOptions{host: "app", action: "on"}},
+		{args: []string{"app", "clear"}, want: inspectOptions{host: "app", action: "clear"}},
+		{args: []string{"app", "42"}, want: inspectOptions{host: "app", action: "show", id: 42}},
+		{args: []string{"app", "--har", "-o", "out.har"}, want: inspectOptions{host: "app", action: "list", har: true, output: "out.har"}},
+		{args: []string{"app", "-n", "5"}, want: inspectOptions{host: "app", action: "list", limit: 5}},
+		{args: []string{}, wantErr: true},
+		{args: []string{"app", "bogus"}, wantErr: true},
+		{args: []string{"app", "0"}, wantErr: true},
+		{args: []string{"app", "1", "2"}, wantErr: true},
+		{args: []string{"app", "-o", "out.har"}, wantErr: true},
+		{args: []string{"app", "on", "--har"}, wantErr: true},
+		{args: []string{"app", "--bogus"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseInspectArgs(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseInspectArgs(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseInspectArgs(%v) = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestPrintExchanges(t *testing.T) {
+	var buf bytes.Buffer
+	printExchanges(&buf, &daemon.ListCapturesResponseData{Host: "app.localhost"})
+	if !strings.Contains(buf.String(), "faa inspect app.localhost on") {
+		t.Errorf("Disabled capture should explain how to enable it:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	printExchanges(&buf, &daemon.ListCapturesResponseData{
+		Host:    "app.localhost",
+		Enabled: true,
+		Exchanges: []proxy.Exchange{
+			{ID: 3, Time: time.Now(), Method: "POST", URL: "/webhook", Status: 502, Error: "refused", DurationMs: 4.2},
+		},
+	})
+	output := buf.String()
+	for _, want := range []string{"ID", "POST", "502!", "4.2ms", "/webhook"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestPrintExchange(t *testing.T) {
+	var buf bytes.Buffer
+	printExchange(&buf, &proxy.Exchange{
+		ID:                    7,
+		Time:                  time.Now(),
+		Host:                  "app.localhost",
+		Method:                "POST",
+		URL:                   "/login",
+		Proto:                 "HTTP/2.0",
+		RequestHeaders:        http.Header{"Content-Type": {"application/json"}},
+		RequestBody:           []byte("{\"user\":\"a\"}\n\x1b[31mred"),
+		RequestBodySize:       100,
+		RequestBodyTruncated:  true,
+		Status:                200,
+		ResponseHeaders:       http.Header{"Content-Type": {"image/png"}},
+		ResponseBody:          []byte{0x89, 'P', 'N', 'G', 0},
+		ResponseBodySize:      2048,
+		ResponseBodyTruncated: false,
+	})
+
+	output := buf.String()
+	for _, want := range []string{
+		"POST https://app.localhost/login HTTP/2.0",
+		"Content-Type: application/json",
+		`{"user":"a"}`,
+		"[truncated:",
+		"HTTP/2.0 200 OK",
+		"[2K of binary data]",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Output does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "\x1b") {
+		t.Errorf("Escape sequences from bodies must be stripped:\n%q", output)
+	}
+}
@@ -3,16 +3,26 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/sahithyandev/faa/internal/config"
 	"github.com/sahithyandev/faa/internal/daemon"
 	"github.com/sahithyandev/faa/internal/devproc"
 	"github.com/sahithyandev/faa/internal/lock"
+	"github.com/sahithyandev/faa/internal/logging"
 	"github.com/sahithyandev/faa/internal/port"
 	"github.com/sahithyandev/faa/internal/project"
 	"github.com/sahithyandev/faa/internal/proxy"
@@ -20,9 +30,29 @@ import (
 	"github.com/sahithyandev/faa/internal/version"
 )
 
+// Exit codes are part of faa's scripting interface and must stay stable.
+// They are documented in the README under "Exit codes".
 const (
+	// ExitSuccess indicates the command completed successfully
 	ExitSuccess = 0
-	ExitError   = 1
+
+	// ExitError indicates a generic failure not covered by a more specific code
+	ExitError = 1
+
+	// ExitUsage indicates invalid arguments or flags
+	ExitUsage = 2
+
+	// ExitDaemonNotRunning indicates the daemon could not be reached
+	ExitDaemonNotRunning = 3
+
+	// ExitDaemonRequest indicates the daemon was reached but rejected the request
+	ExitDaemonRequest = 4
+
+	// ExitNotFound indicates a requested resource (e.g. the CA certificate) does not exist
+	ExitNotFound = 5
+
+	// ExitCheckFailed indicates one or more doctor checks failed
+	ExitCheckFailed = 6
 
 	// daemonStartupTimeout is the maximum time to wait for daemon to start
 	daemonStartupTimeout = 5 * time.Second
@@ -48,8 +78,31 @@ func run(args []string) int {
 		return ExitSuccess
 	}
 
+	// Collect output flags given before the subcommand (e.g. "faa --json status")
+	// so they can be forwarded to the subcommand
+	var globalFlags []string
+	for len(args) > 0 && isOutputFlag(args[0]) {
+		globalFlags = append(globalFlags, args[0])
+		if args[0] == "--format" && len(args) > 1 {
+			globalFlags = append(globalFlags, args[1])
+			args = args[1:]
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		printError("No command specified. Usage: faa [options] <command> [args...]")
+		return ExitUsage
+	}
+
 	subcommand := args[0]
-	subArgs := args[1:]
+	// Output options are only forwarded to commands that have structured
+	// output; anything else, such as the dev server's arguments for run,
+	// must not see them
+	if len(globalFlags) > 0 && !outputCommands[subcommand] {
+		printError("Output options are not supported for '%s'", subcommand)
+		return ExitUsage
+	}
+	subArgs := append(args[1:len(args):len(args)], globalFlags...)
 
 	// Check for help flag in subcommand args
 	if len(subArgs) > 0 && (subArgs[0] == "-h" || subArgs[0] == "--help") {
@@ -69,12 +122,34 @@ func run(args []string) int {
 		return handleRun(subArgs)
 	case "status":
 		return handleStatus(subArgs)
+	case "processes":
+		return handleProcesses(subArgs)
+	case "top":
+		return handleTop(subArgs)
+	case "doctor":
+		return handleDoctor(subArgs)
 	case "stop":
 		return handleStop(subArgs)
 	case "routes":
 		return handleRoutes(subArgs)
 	case "ca-path":
 		return handleCAPath(subArgs)
+	case "ca":
+		return handleCA(subArgs)
+	case "cert":
+		return handleCert(subArgs)
+	case "api":
+		return handleAPI(subArgs)
+	case "dashboard":
+		return handleDashboard(subArgs)
+	case "inspect":
+		return handleInspect(subArgs)
+	case "replay":
+		return handleReplay(subArgs)
+	case "chaos":
+		return handleChaos(subArgs)
+	case "hosts":
+		return handleHosts(subArgs)
 	case "clean":
 		return handleClean(subArgs)
 	default:
@@ -83,6 +158,22 @@ func run(args []string) int {
 	}
 }
 
+// outputCommands are the subcommands that accept --json and --format
+var outputCommands = map[string]bool{
+	"version":   true,
+	"setup":     true,
+	"status":    true,
+	"processes": true,
+	"doctor":    true,
+	"routes":    true,
+	"ca-path":   true,
+	"ca":        true,
+	"api":       true,
+	"inspect":   true,
+	"replay":    true,
+	"chaos":     true,
+}
+
 // printError prints a formatted error message to stderr
 // This provides consistent error formatting across all commands
 func printError(format string, args ...interface{}) {
@@ -93,22 +184,47 @@ func printUsage() {
 	fmt.Println("Usage: faa [options] <command> [args...]")
 	fmt.Println()
 	fmt.Println("Options:")
-	fmt.Println("  -h, --help    Show this help message")
+	fmt.Println("  -h, --help         Show this help message")
+	fmt.Println("  --json             Print machine-readable JSON (same as --format json)")
+	fmt.Println("  --format <format>  Output format: text, json, or template=<Go template>")
 	fmt.Println()
 	fmt.Println("Commands:")
 	fmt.Println("  version       Show version information")
 	fmt.Println("  setup         Setup the development environment")
-	fmt.Println("  daemon        Start the daemon process")
+	fmt.Println("  daemon        Start the daemon process, or read its log with 'daemon logs'")
 	fmt.Println("  run           Run a command or project (default)")
 	fmt.Println("  status        Show daemon status, routes, and running processes")
+	fmt.Println("  processes     List running processes")
+	fmt.Println("  top           Live full-screen view of running projects")
+	fmt.Println("  doctor        Diagnose common problems")
 	fmt.Println("  stop          Stop the daemon")
 	fmt.Println("  routes        Display configured routes")
-	fmt.Println("  ca-path       Show the path to the CA certificate")
+	fmt.Println("  ca            Show, locate, export or rotate the local CA")
+	fmt.Println("  cert          Export a site's certificate or issue client certificates")
+	fmt.Println("  ca-path       Show the path to the CA certificate (same as 'ca path')")
+	fmt.Println("  api           Show the HTTP management API URL and token")
+	fmt.Println("  dashboard     Open the web dashboard in a browser")
+	fmt.Println("  inspect       Capture and browse traffic to a host")
+	fmt.Println("  replay        Re-send a captured request and diff the response")
+	fmt.Println("  chaos         Inject latency and failures into traffic to a host")
+	fmt.Println("  hosts         Show or update faa's block of route names in /etc/hosts")
 	fmt.Println("  clean         Remove all faa configurations and caches")
 	fmt.Println()
 	fmt.Println("If <command> is not a recognized subcommand, it is treated as:")
 	fmt.Println("  faa run -- <command> [args...]")
 	fmt.Println()
+	fmt.Println("Output options apply to: version, setup, status, processes, routes, doctor,")
+	fmt.Println("ca info, ca path, ca-path, api, inspect, replay, chaos. Other commands reject them.")
+	fmt.Println()
+	fmt.Println("Exit codes:")
+	fmt.Println("  0  Success")
+	fmt.Println("  1  General error")
+	fmt.Println("  2  Invalid usage")
+	fmt.Println("  3  Daemon is not running")
+	fmt.Println("  4  Daemon rejected the request")
+	fmt.Println("  5  Requested resource not found")
+	fmt.Println("  6  One or more doctor checks failed")
+	fmt.Println()
 	fmt.Println("Use 'faa <command> -h' for more information about a command.")
 }
 
@@ -121,21 +237,41 @@ func printSubcommandHelp(subcommand string) {
 		fmt.Println("build date, and platform/architecture.")
 		fmt.Println()
 		fmt.Println("Options:")
-		fmt.Println("  -h, --help    Show this help message")
+		fmt.Println("  -h, --help         Show this help message")
+		fmt.Println("  --json             Print machine-readable JSON")
+		fmt.Println("  --format <format>  Output format: text, json, or template=<Go template>")
 	case "setup":
 		fmt.Println("Usage: faa setup [options]")
+		fmt.Println("       faa setup --uninstall [--dry-run] [-y]")
 		fmt.Println()
-		fmt.Println("Setup the development environment.")
+		fmt.Println("Setup the development environment, or undo the system changes it made.")
 		fmt.Println()
 		fmt.Println("Options:")
-		fmt.Println("  -h, --help    Show this help message")
+		fmt.Println("  -h, --help         Show this help message")
+		fmt.Println("  -y, --yes          Answer yes to every prompt")
+		fmt.Println("  --check            Report what is missing without changing anything;")
+		fmt.Println("                     exits with 6 if anything is")
+		fmt.Println("  --skip-ports       Skip letting the proxy bind ports 80 and 443")
+		fmt.Println("  --skip-service     Skip installing the systemd service or LaunchDaemon")
+		fmt.Println("  --skip-ca          Skip trusting the CA certificate, in browsers and Java too")
+		fmt.Println("  --skip-dns         Skip pointing the system resolver at faa for dns.domains")
+		fmt.Println("  --skip-hosts       Skip letting the daemon update /etc/hosts")
+		fmt.Println("  --ca-only          Only trust the CA certificate")
+		fmt.Println("  --json             Print a JSON report of what was or would be changed")
+		fmt.Println("  --format <format>  Output format: text, json, or template=<Go template>")
+		fmt.Println("  --uninstall        Undo the system changes recorded by setup")
+		fmt.Println("  --dry-run          With --uninstall, list the changes and commands without running them")
 	case "daemon":
 		fmt.Println("Usage: faa daemon [options]")
+		fmt.Println("       faa daemon logs [-f] [-n <lines>] [--json]")
 		fmt.Println()
-		fmt.Println("Start the daemon process.")
+		fmt.Println("Start the daemon process, or print its log (~/.config/faa/daemon.log).")
 		fmt.Println()
 		fmt.Println("Options:")
-		fmt.Println("  -h, --help    Show this help message")
+		fmt.Println("  -h, --help         Show this help message")
+		fmt.Println("  -f, --follow       Keep printing lines as they are logged")
+		fmt.Println("  -n, --lines <n>    Number of lines to print (default 50)")
+		fmt.Println("  --json             Print the log lines as they are stored")
 	case "run":
 		fmt.Println("Usage: faa run [options] [-- <command> [args...]]")
 		fmt.Println()
@@ -149,7 +285,48 @@ func printSubcommandHelp(subcommand string) {
 		fmt.Println("Show status of running projects.")
 		fmt.Println()
 		fmt.Println("Options:")
+		fmt.Println("  -h, --help         Show this help message")
+		fmt.Println("  -w, --watch        Keep running and redraw on every daemon event")
+		fmt.Println("  --json             Print machine-readable JSON")
+		fmt.Println("  --format <format>  Output format: text, json, or template=<Go template>")
+	case "processes":
+		fmt.Println("Usage: faa processes [options]")
+		fmt.Println()
+		fmt.Println("List development processes registered with the daemon.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fmt.Println("  -h, --help         Show this help message")
+		fmt.Println("  --json             Print machine-readable JSON")
+		fmt.Println("  --format <format>  Output format: text, json, or template=<Go template>")
+	case "top":
+		fmt.Println("Usage: faa top")
+		fmt.Println()
+		fmt.Println("Show every running project in a live, full-screen view with its host,")
+		fmt.Println("port, PID, readiness, CPU and memory use, request rate and last error.")
+		fmt.Println("CPU and memory are read from /proc and shown as '-' where unavailable.")
+		fmt.Println()
+		fmt.Println("Keys:")
+		fmt.Println("  up/down, k/j  Select a project")
+		fmt.Println("  l, enter      Show or hide the selected project's log")
+		fmt.Println("  r             Restart the selected project")
+		fmt.Println("  s             Stop the selected project")
+		fmt.Println("  o             Open the selected project in a browser")
+		fmt.Println("  q             Quit (or leave the log view)")
+		fmt.Println()
+		fmt.Println("Options:")
 		fmt.Println("  -h, --help    Show this help message")
+	case "doctor":
+		fmt.Println("Usage: faa doctor [options]")
+		fmt.Println()
+		fmt.Println("Diagnose common problems with the faa installation.")
+		fmt.Println()
+		fmt.Println("Checks the configuration directory, the daemon, privileged port")
+		fmt.Println("binding and the CA certificate. Exits with code 6 if any check fails.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fmt.Println("  -h, --help         Show this help message")
+		fmt.Println("  --json             Print machine-readable JSON")
+		fmt.Println("  --format <format>  Output format: text, json, or template=<Go template>")
 	case "stop":
 		fmt.Println("Usage: faa stop [options]")
 		fmt.Println()
@@ -164,7 +341,67 @@ func printSubcommandHelp(subcommand string) {
 		fmt.Println("Display route information.")
 		fmt.Println()
 		fmt.Println("Options:")
-		fmt.Println("  -h, --help    Show this help message")
+		fmt.Println("  -h, --help         Show this help message")
+		fmt.Println("  --json             Print machine-readable JSON")
+		fmt.Println("  --format <format>  Output format: text, json, or template=<Go template>")
+	case "ca":
+		fmt.Println("Usage: faa ca info [options]")
+		fmt.Println("       faa ca path [options]")
+		fmt.Println("       faa ca export [--format <format>] [-o <file>] [--password <password>]")
+		fmt.Println("       faa ca rotate [-y]")
+		fmt.Println()
+		fmt.Println("Manage the local CA that signs certificates for faa's sites. When tls.ca")
+		fmt.Println("in config.json sets an existing CA, such as mkcert's, it is shown instead")
+		fmt.Println("and can't be rotated.")
+		fmt.Println()
+		fmt.Println("Subcommands:")
+		fmt.Println("  info     Show the root and intermediate certificates, their SHA-256")
+		fmt.Println("           fingerprints and validity, and which trust stores have the root")
+		fmt.Println("  path     Show the path to the CA certificate")
+		fmt.Println("  export   Write the root certificate in a format devices and runtimes import:")
+		fmt.Println("           pem (default), der for Android and Windows, p12 or jks trust")
+		fmt.Println("           stores for Java, or mobileconfig for iOS and macOS. The file is")
+		fmt.Println("           named faa-root-ca with the format's extension unless -o is given")
+		fmt.Println("  rotate   Create a new CA, reissue certificates, trust the new root and")
+		fmt.Println("           remove the old one from trust stores; needs the daemon")
+		fmt.Println()
+		fmt.Println("Options:")
+		fmt.Println("  -h, --help             Show this help message")
+		fmt.Println("  -y, --yes              With rotate, answer yes to every prompt")
+		fmt.Println("  --json                 Print machine-readable JSON (info and path)")
+		fmt.Println("  --format <format>      Output format for info and path: text, json, or")
+		fmt.Println("                         template=<Go template>; with export, pem, der, p12,")
+		fmt.Println("                         jks or mobileconfig")
+		fmt.Println("  -o, --output <file>    With export, the file to write, or - for stdout")
+		fmt.Println("  --password <password>  With export, the p12 or jks password (default: changeit)")
+	case "cert":
+		fmt.Println("Usage: faa cert export <host> [--format pem|p12|jks] [-o <file>] [--password <password>]")
+		fmt.Println("       faa cert client <name> [--days <n>] [--format pem|p12|jks] [-o <file>] [--password <password>]")
+		fmt.Println()
+		fmt.Println("Subcommands:")
+		fmt.Println("  export   Write the certificate and private key faa serves for a routed")
+		fmt.Println("           host, so a test device or another server can present it. The")
+		fmt.Println("           certificate is issued first if the host has none yet")
+		fmt.Println("  client   Issue a client certificate for <name> from faa's CA, for hosts")
+		fmt.Println("           that tls.clientAuth in config.json requires or accepts one for.")
+		fmt.Println("           It is written to <name>-client.pem and <name>-client-key.pem")
+		fmt.Println()
+		fmt.Println("Both need the daemon.")
+		fmt.Println()
+		fmt.Println("Formats:")
+		fmt.Println("  pem   The certificate in <host>.pem and the key in <host>-key.pem (default)")
+		fmt.Println("  p12   A PKCS #12 file with the key and certificate, as <host>.p12")
+		fmt.Println("  jks   A Java KeyStore with the key and certificate, as <host>.jks")
+		fmt.Println()
+		fmt.Println("Site certificates from the local CA are short-lived; export again once")
+		fmt.Println("faa renews them.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fmt.Println("  -h, --help             Show this help message")
+		fmt.Println("  --days <n>             With client, days it is valid (default: 365)")
+		fmt.Println("  --format <format>      The format to write")
+		fmt.Println("  -o, --output <file>    The file to write, or - for stdout")
+		fmt.Println("  --password <password>  The p12 or jks password (default: changeit)")
 	case "ca-path":
 		fmt.Println("Usage: faa ca-path [options]")
 		fmt.Println()
@@ -175,7 +412,114 @@ func printSubcommandHelp(subcommand string) {
 		fmt.Println("be used to trust HTTPS connections to local development domains.")
 		fmt.Println()
 		fmt.Println("Options:")
+		fmt.Println("  -h, --help         Show this help message")
+		fmt.Println("  --json             Print machine-readable JSON")
+		fmt.Println("  --format <format>  Output format: text, json, or template=<Go template>")
+	case "api":
+		fmt.Println("Usage: faa api [token] [options]")
+		fmt.Println()
+		fmt.Println("Show the HTTP management API URL and where its token is stored.")
+		fmt.Println("With 'token', print only the token (creating it if needed).")
+		fmt.Println()
+		fmt.Println("The API is disabled by default. Enable it in ~/.config/faa/config.json:")
+		fmt.Println(`  {"api": {"enabled": true}}`)
+		fmt.Println()
+		fmt.Println("Options:")
+		fmt.Println("  -h, --help         Show this help message")
+		fmt.Println("  --json             Print machine-readable JSON")
+		fmt.Println("  --format <format>  Output format: text, json, or template=<Go template>")
+	case "dashboard":
+		fmt.Println("Usage: faa dashboard [options]")
+		fmt.Println()
+		fmt.Println("Open the web dashboard (https://faa.localhost by default) in a browser.")
+		fmt.Println("The dashboard lists routes and projects with their uptime, readiness,")
+		fmt.Println("recent requests and log output, and can restart or stop projects.")
+		fmt.Println()
+		fmt.Println("It is served by the management API; see 'faa api -h' to enable it.")
+		fmt.Println()
+		fmt.Println("Options:")
 		fmt.Println("  -h, --help    Show this help message")
+		fmt.Println("  --no-open     Print the dashboard URL instead of opening it")
+	case "inspect":
+		fmt.Println("Usage: faa inspect <host> [on|off|clear|<id>] [options]")
+		fmt.Println()
+		fmt.Println("Capture requests to a host and browse them. Capture is off by default;")
+		fmt.Println("while on, the proxy keeps the last 200 exchanges per host in memory with")
+		fmt.Println("their headers, timing, status and the first 64KB of each body.")
+		fmt.Println()
+		fmt.Println("  faa inspect <host>          List captured exchanges")
+		fmt.Println("  faa inspect <host> on       Start capturing traffic")
+		fmt.Println("  faa inspect <host> off      Stop capturing traffic")
+		fmt.Println("  faa inspect <host> clear    Discard captured exchanges")
+		fmt.Println("  faa inspect <host> <id>     Show one exchange in full")
+		fmt.Println()
+		fmt.Println("Options:")
+		fmt.Println("  -h, --help         Show this help message")
+		fmt.Println("  -n, --limit <n>    Only list the latest n exchanges")
+		fmt.Println("  --har              Export as HAR 1.2 for browser devtools")
+		fmt.Println("  -o <file>          Write the HAR export to a file instead of stdout")
+		fmt.Println("  --json             Print machine-readable JSON")
+		fmt.Println("  --format <format>  Output format: text, json, or template=<Go template>")
+	case "replay":
+		fmt.Println("Usage: faa replay <id> [options]")
+		fmt.Println()
+		fmt.Println("Re-send a request captured with 'faa inspect' to the host's current")
+		fmt.Println("dev server and show how the response differs from the original.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fmt.Println("  -h, --help           Show this help message")
+		fmt.Println("  -X <method>          Replace the request method")
+		fmt.Println("  -H <'Name: value'>   Set a header; 'Name:' removes it (repeatable)")
+		fmt.Println("  -d <data>            Replace the body; @file reads a file, @- stdin")
+		fmt.Println("  --json               Print machine-readable JSON")
+		fmt.Println("  --format <format>    Output format: text, json, or template=<Go template>")
+	case "chaos":
+		fmt.Println("Usage: faa chaos <host> [off] [options]")
+		fmt.Println()
+		fmt.Println("Simulate slow networks and failures for a host. Without options, show")
+		fmt.Println("the current rules. Each --path has its own rule; a rule without --path")
+		fmt.Println("applies to every other request. The most specific path wins.")
+		fmt.Println()
+		fmt.Println("  faa chaos <host> --latency 500ms --jitter 200ms")
+		fmt.Println("  faa chaos <host> --path '/api/*' --error-rate 10% --error-status 502")
+		fmt.Println("  faa chaos <host> off --path '/api/*'   Remove one rule")
+		fmt.Println("  faa chaos <host> off                   Remove every rule")
+		fmt.Println()
+		fmt.Println("Options:")
+		fmt.Println("  -h, --help              Show this help message")
+		fmt.Println("  --path <pattern>        Only apply to matching paths (trailing * for prefix)")
+		fmt.Println("  --latency <duration>    Delay before forwarding, e.g. 300ms")
+		fmt.Println("  --jitter <duration>     Random extra delay of up to this much")
+		fmt.Println("  --bandwidth <rate>      Limit responses to bytes per second, e.g. 50k")
+		fmt.Println("  --error-rate <rate>     Answer this share of requests with an error, e.g. 10%")
+		fmt.Println("  --error-status <code>   Status of injected errors (default 503)")
+		fmt.Println("  --reset-rate <rate>     Reset this share of connections without a response")
+		fmt.Println("  --json                  Print machine-readable JSON")
+		fmt.Println("  --format <format>       Output format: text, json, or template=<Go template>")
+	case "hosts":
+		fmt.Println("Usage: faa hosts [status]")
+		fmt.Println("       faa hosts sync [--dry-run]")
+		fmt.Println("       faa hosts clean [--dry-run]")
+		fmt.Println()
+		fmt.Println("Manage a block in /etc/hosts that maps every route's .localhost and .local")
+		fmt.Println("names to 127.0.0.1 and ::1, for tools that don't resolve them themselves.")
+		fmt.Println("With hosts.enabled set in config.json, the daemon updates it when routes")
+		fmt.Println("change; 'faa setup' lets it do so through sudo without a password.")
+		fmt.Println()
+		fmt.Println("Subcommands:")
+		fmt.Println("  status   Show the names in the block and whether they match the routes")
+		fmt.Println("           (default)")
+		fmt.Println("  sync     Write the block for the current routes")
+		fmt.Println("  clean    Remove the block")
+		fmt.Println("  apply    Write the names read from stdin, one per line; the helper the")
+		fmt.Println("           daemon runs as root")
+		fmt.Println()
+		fmt.Println("Lines outside the block are never changed. sync and clean use sudo when")
+		fmt.Println("/etc/hosts isn't writable.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fmt.Println("  -h, --help    Show this help message")
+		fmt.Println("  --dry-run     Print the change without making it")
 	case "clean":
 		fmt.Println("Usage: faa clean [options]")
 		fmt.Println()
@@ -197,33 +541,207 @@ func printSubcommandHelp(subcommand string) {
 }
 
 func handleVersion(args []string) int {
+	format, _, err := parseOutputFlags(args)
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
+	}
+
+	if format.structured() {
+		return format.emit(versionOutput{
+			SchemaVersion: schemaVersion,
+			Version:       version.Version,
+			Commit:        version.Commit,
+			Date:          version.Date,
+			OS:            runtime.GOOS,
+			Arch:          runtime.GOARCH,
+		})
+	}
+
 	fmt.Println(version.Info())
 	return ExitSuccess
 }
 
 func handleSetup(args []string) int {
-	if err := setup.Run(); err != nil {
-		printError("Setup failed: %v", err)
-		return ExitError
+	format, rest, err := parseOutputFlags(args)
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
 	}
-	return ExitSuccess
+
+	var opts setup.Options
+	uninstall := false
+	dryRun := false
+	for _, arg := range rest {
+		switch arg {
+		case "-y", "--yes":
+			opts.Yes = true
+		case "--skip-ports":
+			opts.SkipPorts = true
+		case "--skip-service":
+			opts.SkipService = true
+		case "--skip-ca":
+			opts.SkipCA = true
+		case "--skip-dns":
+			opts.SkipDNS = true
+		case "--skip-hosts":
+			opts.SkipHosts = true
+		case "--ca-only":
+			opts.CAOnly = true
+		case "--check":
+			opts.Check = true
+		case "--uninstall":
+			uninstall = true
+		case "--dry-run":
+			dryRun = true
+		default:
+			return format.fail(ExitUsage, "Unknown option: %s", arg)
+		}
+	}
+	if opts.CAOnly && (opts.SkipCA || opts.SkipPorts || opts.SkipService || opts.SkipDNS || opts.SkipHosts) {
+		return format.fail(ExitUsage, "--ca-only can't be combined with --skip-* options")
+	}
+
+	if uninstall {
+		if format.structured() {
+			return format.fail(ExitUsage, "--uninstall doesn't support --json or --format")
+		}
+		if opts.SkipPorts || opts.SkipService || opts.SkipCA || opts.SkipDNS || opts.SkipHosts || opts.CAOnly {
+			return format.fail(ExitUsage, "--uninstall undoes every recorded change and can't be combined with --skip-* or --ca-only")
+		}
+		opts.Check = opts.Check || dryRun
+		if err := setup.Uninstall(opts); err != nil {
+			printError("Uninstall failed: %v", err)
+			return ExitError
+		}
+		return ExitSuccess
+	}
+	if dryRun {
+		return format.fail(ExitUsage, "--dry-run can only be used with --uninstall; use --check to check setup")
+	}
+
+	// Progress goes to stderr so stdout only has the report
+	if format.structured() {
+		opts.Output = os.Stderr
+	}
+
+	report, err := setup.Run(opts)
+	if err != nil {
+		return format.fail(ExitError, "Setup failed: %v", err)
+	}
+
+	exitCode := ExitSuccess
+	if opts.Check && !report.Complete() {
+		exitCode = ExitCheckFailed
+	}
+	if format.structured() {
+		if code := format.emit(setupOutput{
+			SchemaVersion: schemaVersion,
+			OS:            report.OS,
+			Check:         report.Check,
+			Complete:      report.Complete(),
+			Steps:         report.Steps,
+		}); code != ExitSuccess {
+			return code
+		}
+	}
+	return exitCode
 }
 
 func handleDaemon(args []string) int {
+	if len(args) > 0 && args[0] == "logs" {
+		return handleDaemonLogs(args[1:])
+	}
+	if len(args) > 0 {
+		printError("Unknown option: %s", args[0])
+		return ExitUsage
+	}
+
+	// Log to daemon.log as well as stderr, which is discarded when the
+	// daemon is started in the background
+	logPath, err := daemon.DaemonLogPath()
+	if err != nil {
+		printError("Failed to get daemon log path: %v", err)
+		return ExitError
+	}
+	logFile, err := logging.OpenRotatingFile(logPath)
+	if err != nil {
+		printError("Failed to open daemon log: %v", err)
+		return ExitError
+	}
+	defer logFile.Close()
+	logOutput := io.MultiWriter(logFile, os.Stderr)
+	level := new(slog.LevelVar)
+	slog.SetDefault(logging.New(logOutput, level))
+
+	// Load user configuration
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("failed to load configuration", "error", err)
+		return ExitError
+	}
+	logLevel, err := logging.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		slog.Error("invalid log settings", "error", err)
+		return ExitError
+	}
+	level.Set(logLevel)
+
 	// Create registry
 	registry, err := daemon.NewRegistry()
 	if err != nil {
-		printError("Failed to create registry: %v", err)
+		slog.Error("failed to create registry", "error", err)
 		return ExitError
 	}
 
 	// Create proxy
-	p := proxy.New()
+	p := proxy.NewWithPorts(cfg.Proxy.ListenPorts())
+	if err := p.SetLogOutput(logOutput, logLevel); err != nil {
+		slog.Error("invalid proxy settings", "error", err)
+		return ExitError
+	}
+	if err := p.SetURLPort(cfg.Proxy.URLPort()); err != nil {
+		slog.Error("invalid proxy settings", "error", err)
+		return ExitError
+	}
+	err = p.SetStreamOptions(proxy.StreamOptions{
+		Timeout:       time.Duration(cfg.Proxy.StreamTimeout),
+		FlushInterval: time.Duration(cfg.Proxy.FlushInterval),
+	})
+	if err != nil {
+		slog.Error("invalid proxy settings", "error", err)
+		return ExitError
+	}
+	if err := p.SetTLSOptions(tlsOptions(cfg.TLS)); err != nil {
+		slog.Error("invalid TLS settings", "error", err)
+		return ExitError
+	}
+	p.SetDomains(cfg.DNS.Domains)
+
+	admin := cfg.Proxy.Admin
+	if admin == "" {
+		adminSocket, err := daemon.AdminSocketPath()
+		if err != nil {
+			slog.Error("failed to get admin socket path", "error", err)
+			return ExitError
+		}
+		admin = "unix/" + adminSocket
+	}
+	if err := p.SetAdmin(admin); err != nil {
+		slog.Error("invalid proxy settings", "error", err)
+		return ExitError
+	}
 
 	// Start proxy
 	ctx := context.Background()
 	if err := p.Start(ctx); err != nil {
-		printError("Failed to start proxy: %v", err)
+		if errors.Is(err, proxy.ErrAdminInUse) {
+			path, _ := config.Path()
+			slog.Error("failed to start proxy", "error", err,
+				"hint", fmt.Sprintf(`set "proxy": {"admin": "off"} or another address in %s`, path))
+		} else {
+			slog.Error("failed to start proxy", "error", err)
+		}
 		return ExitError
 	}
 	defer p.Stop()
@@ -231,8 +749,9 @@ func handleDaemon(args []string) int {
 	// Create and start daemon
 	// The daemon will load routes and export CA after applying them
 	d := daemon.New(registry, p)
+	d.SetConfig(cfg)
 	if err := d.Start(); err != nil {
-		printError("Failed to start daemon: %v", err)
+		slog.Error("failed to start daemon", "error", err)
 		return ExitError
 	}
 
@@ -257,6 +776,16 @@ func isDaemonRunning() bool {
 
 // startDaemonInBackground starts the daemon process in the background
 func startDaemonInBackground() error {
+	// Prefer the systemd user service installed by setup, which keeps the
+	// daemon's output in the journal
+	if runtime.GOOS == "linux" && setup.SystemdServiceInstalled() {
+		cmd := exec.Command("systemctl", "--user", "start", "faa.service")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to start faa.service: %v: %s", err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+
 	// Get the path to the current executable
 	execPath, err := os.Executable()
 	if err != nil {
@@ -267,7 +796,7 @@ func startDaemonInBackground() error {
 	cmd := exec.Command(execPath, "daemon")
 
 	// Redirect output to /dev/null to suppress daemon output
-	// Users can check daemon status with 'faa status' or manually run 'faa daemon' to see output
+	// The daemon also logs to daemon.log, which users can read with 'faa daemon logs'
 	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
 	if err != nil {
 		return fmt.Errorf("failed to open /dev/null: %w", err)
@@ -314,7 +843,7 @@ func ensureDaemonRunning() error {
 		time.Sleep(daemonStartupRetryDelay)
 	}
 
-	return fmt.Errorf("daemon failed to start within %v. The daemon may require elevated permissions. Try running 'faa setup' to configure permissions, or start the daemon manually with 'faa daemon'", daemonStartupTimeout)
+	return fmt.Errorf("daemon failed to start within %v. The daemon may require elevated permissions. Check 'faa daemon logs', try running 'faa setup' to configure permissions, or start the daemon manually with 'faa daemon'", daemonStartupTimeout)
 }
 
 func handleRun(args []string) int {
@@ -342,7 +871,7 @@ func handleRun(args []string) int {
 	// Validate command
 	if len(command) == 0 {
 		printError("No command specified. Usage: faa run -- <command> [args...]")
-		return ExitError
+		return ExitUsage
 	}
 
 	// Find project root and name
@@ -375,14 +904,14 @@ func handleRun(args []string) int {
 	// Ensure daemon is running (start it if needed)
 	if err := ensureDaemonRunning(); err != nil {
 		printError("Failed to ensure daemon is running: %v", err)
-		return ExitError
+		return ExitDaemonNotRunning
 	}
 
 	// Connect to daemon
 	client, err := daemon.Connect()
 	if err != nil {
 		printError("Failed to connect to daemon: %v", err)
-		return ExitError
+		return ExitDaemonNotRunning
 	}
 	defer client.Close()
 
@@ -390,21 +919,21 @@ func handleRun(args []string) int {
 	existingProc, err := client.GetProcess(proj.Root)
 	if err != nil {
 		printError("Failed to check for existing process: %v", err)
-		return ExitError
+		return ExitDaemonRequest
 	}
 
 	// If process exists, check if it's still alive
 	if existingProc != nil {
 		if devproc.IsAlive(existingProc.PID) {
 			// Process is still running
-			fmt.Printf("Already running: https://%s (PID %d, port %d)\n",
-				existingProc.Host, existingProc.PID, existingProc.Port)
+			fmt.Printf("Already running: %s (PID %d, port %d)\n",
+				siteURL(existingProc.Host), existingProc.PID, existingProc.Port)
 			return ExitSuccess
 		}
 		// Process is dead, clean it up
 		if err := client.ClearProcess(proj.Root); err != nil {
 			printError("Failed to clear dead process: %v", err)
-			return ExitError
+			return ExitDaemonRequest
 		}
 	}
 
@@ -412,7 +941,7 @@ func handleRun(args []string) int {
 	existingPort, err := client.GetRoute(host)
 	if err != nil {
 		printError("Failed to check for existing route: %v", err)
-		return ExitError
+		return ExitDaemonRequest
 	}
 
 	// Determine final port: use existing if available, else compute stable port
@@ -432,71 +961,249 @@ func handleRun(args []string) int {
 	// Inject port into command
 	cmdWithPort, env := devproc.InjectPort(command, finalPort)
 
+	// Let the dev server make HTTPS requests to other local projects,
+	// without overriding the user's own settings
+	for key, value := range proxy.CAEnv() {
+		if _, set := os.LookupEnv(key); !set {
+			env[key] = value
+		}
+	}
+
 	// Call daemon upsert_route
 	if err := client.UpsertRoute(host, finalPort); err != nil {
 		printError("Failed to upsert route: %v", err)
-		return ExitError
+		return ExitDaemonRequest
+	}
+
+	// Copy the dev server output to a log file for the dashboard and 'faa top'
+	var logWriter io.Writer
+	logFile, err := daemon.OpenLog(host)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Output will not be logged: %v\n", err)
+	} else {
+		defer logFile.Close()
+		logWriter = logFile
+	}
+
+	// Listen for restart and stop requests from the dashboard and other
+	// clients
+	restarts := make(chan struct{}, 1)
+	stops := make(chan struct{}, 1)
+	if sub, err := daemon.Subscribe(daemon.EventRestartRequested, daemon.EventStopRequested); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Restart and stop requests will be ignored: %v\n", err)
+	} else {
+		defer sub.Close()
+		go forwardControlRequests(sub, proj.Root, restarts, stops)
 	}
 
-	// Start dev server with signal handler
-	proc, err := devproc.StartWithSignalHandler(cmdWithPort, proj.Root, env)
+	// Forward SIGINT and SIGTERM to the dev server's process group
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	// Start dev server and record it in the daemon registry
+	proc, err := startDevServer(client, cmdWithPort, env, proj.Root, host, finalPort, logWriter)
 	if err != nil {
-		printError("Failed to start dev server: %v", err)
+		printError("%v", err)
 		return ExitError
 	}
 
-	// Record process in daemon registry
-	startedAt := time.Now()
+	// Print URL and PID
+	fmt.Printf("Started: %s (PID %d, port %d)\n", siteURL(host), proc.PID, finalPort)
+
+	stopRequested := false
+	for {
+		select {
+		case sig := <-sigChan:
+			fmt.Fprintf(os.Stderr, "\nReceived signal %v, terminating process...\n", sig)
+			if err := proc.Stop(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error stopping process: %v\n", err)
+			}
+
+		case <-stops:
+			fmt.Println("Stop requested, stopping dev server...")
+			stopRequested = true
+			if err := proc.Stop(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error stopping process: %v\n", err)
+			}
+
+		case <-restarts:
+			fmt.Println("Restart requested, restarting dev server...")
+			if err := proc.Stop(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error stopping process: %v\n", err)
+			}
+			<-proc.Wait
+
+			if logFile != nil {
+				fmt.Fprintf(logFile, "\n[faa] restarted at %s\n\n", time.Now().Format(time.RFC3339))
+			}
+
+			proc, err = startDevServer(client, cmdWithPort, env, proj.Root, host, finalPort, logWriter)
+			if err != nil {
+				printError("%v", err)
+				if clearErr := client.ClearProcess(proj.Root); clearErr != nil {
+					fmt.Fprintf(os.Stderr, "Warning: Failed to clear process from registry during cleanup: %v\n", clearErr)
+				}
+				return ExitError
+			}
+			fmt.Printf("Restarted: %s (PID %d, port %d)\n", siteURL(host), proc.PID, finalPort)
+
+		case err := <-proc.Wait:
+			// Clear process from registry
+			if clearErr := client.ClearProcess(proj.Root); clearErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to clear process from registry during cleanup: %v\n", clearErr)
+			}
+
+			// Return appropriate exit code; a requested stop isn't a failure
+			if stopRequested {
+				fmt.Println("Stopped")
+				return ExitSuccess
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Process exited with error: %v\n", err)
+				return ExitError
+			}
+
+			fmt.Println("Process exited successfully")
+			return ExitSuccess
+		}
+	}
+}
+
+// startDevServer starts the dev server and registers it with the daemon.
+// If registration fails the dev server is stopped again.
+func startDevServer(client *daemon.Client, command []string, env map[string]string, projectRoot, host string, port int, log io.Writer) (*devproc.Process, error) {
+	proc, err := devproc.StartWithLog(command, projectRoot, env, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start dev server: %w", err)
+	}
+
 	if err := client.SetProcess(&daemon.SetProcessData{
-		ProjectRoot: proj.Root,
+		ProjectRoot: projectRoot,
 		PID:         proc.PID,
 		Host:        host,
-		Port:        finalPort,
-		StartedAt:   startedAt,
+		Port:        port,
+		StartedAt:   time.Now(),
 	}); err != nil {
-		printError("Failed to register process: %v", err)
 		// Try to stop the process we just started
 		if stopErr := proc.Stop(); stopErr != nil {
 			fmt.Fprintf(os.Stderr, "Warning: Failed to stop process after registration failure: %v\n", stopErr)
 		}
-		return ExitError
+		return nil, fmt.Errorf("failed to register process: %w", err)
 	}
 
-	// Print URL and PID
-	fmt.Printf("Started: https://%s (PID %d, port %d)\n", host, proc.PID, finalPort)
+	return proc, nil
+}
+
+// forwardControlRequests signals restarts and stops for every
+// restart_requested and stop_requested event about projectRoot until the
+// subscription ends
+func forwardControlRequests(sub *daemon.Subscription, projectRoot string, restarts, stops chan<- struct{}) {
+	for {
+		event, err := sub.Next()
+		if err != nil {
+			return
+		}
 
-	// Wait for process to exit
-	err = <-proc.Wait
+		var data daemon.ProcessEventData
+		if err := json.Unmarshal(event.Data, &data); err != nil || data.ProjectRoot != projectRoot {
+			continue
+		}
 
-	// Clear process from registry
-	if clearErr := client.ClearProcess(proj.Root); clearErr != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Failed to clear process from registry during cleanup: %v\n", clearErr)
+		signals := restarts
+		if event.Type == daemon.EventStopRequested {
+			signals = stops
+		}
+		// A request is already pending if the channel is full
+		select {
+		case signals <- struct{}{}:
+		default:
+		}
 	}
+}
 
-	// Return appropriate exit code
+func handleStatus(args []string) int {
+	format, rest, err := parseOutputFlags(args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Process exited with error: %v\n", err)
-		return ExitError
+		printError("%v", err)
+		return ExitUsage
 	}
 
-	fmt.Println("Process exited successfully")
-	return ExitSuccess
-}
+	watch := false
+	for _, arg := range rest {
+		if arg == "-w" || arg == "--watch" {
+			watch = true
+		}
+	}
 
-func handleStatus(args []string) int {
 	// Connect to daemon
 	client, err := daemon.Connect()
 	if err != nil {
-		printError("Daemon is not running. Start it with: faa daemon")
-		return ExitError
+		return format.fail(ExitDaemonNotRunning, "Daemon is not running. Start it with: faa daemon")
 	}
 	defer client.Close()
 
+	if watch {
+		return watchStatus(client, format)
+	}
+
 	// Get status from daemon
 	status, err := client.Status()
 	if err != nil {
-		printError("Failed to get status: %v", err)
-		return ExitError
+		return format.fail(ExitDaemonRequest, "Failed to get status: %v", err)
+	}
+
+	return printStatus(format, status)
+}
+
+// watchStatus prints the status and reprints it every time the daemon
+// publishes an event, until the daemon goes away or the user interrupts.
+// Structured formats print one compact document per line.
+func watchStatus(client *daemon.Client, format outputFormat) int {
+	sub, err := daemon.Subscribe()
+	if err != nil {
+		return format.fail(ExitDaemonRequest, "Failed to subscribe to daemon events: %v", err)
+	}
+	defer sub.Close()
+
+	format.compact = true
+	for {
+		status, err := client.Status()
+		if err != nil {
+			return format.fail(ExitDaemonRequest, "Failed to get status: %v", err)
+		}
+
+		if !format.structured() {
+			// Clear the screen and move the cursor home before redrawing
+			fmt.Print("\033[H\033[2J")
+		}
+		if code := printStatus(format, status); code != ExitSuccess {
+			return code
+		}
+
+		if _, err := sub.Next(); err != nil {
+			return format.fail(ExitDaemonNotRunning, "Lost connection to daemon: %v", err)
+		}
+	}
+}
+
+// printStatus renders a status response in the selected format
+func printStatus(format outputFormat, status *daemon.StatusResponseData) int {
+	sortRoutes(status.Routes)
+	sortProcesses(status.Processes)
+
+	if format.structured() {
+		ready := status.Ready
+		if ready == nil {
+			ready = map[string]bool{}
+		}
+		return format.emit(statusOutput{
+			SchemaVersion: schemaVersion,
+			Daemon:        daemonOutput{Running: true},
+			Routes:        status.Routes,
+			Processes:     status.Processes,
+			Ready:         ready,
+		})
 	}
 
 	// Print daemon status
@@ -516,39 +1223,220 @@ func handleStatus(args []string) int {
 
 	// Print processes
 	fmt.Println("Running Processes:")
-	if len(status.Processes) == 0 {
+	printProcesses(status.Processes)
+
+	return ExitSuccess
+}
+
+func handleProcesses(args []string) int {
+	format, _, err := parseOutputFlags(args)
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
+	}
+
+	// Connect to daemon
+	client, err := daemon.Connect()
+	if err != nil {
+		return format.fail(ExitDaemonNotRunning, "Daemon is not running. Start it with: faa daemon")
+	}
+	defer client.Close()
+
+	// Processes are part of the status response, which also cleans up stale entries
+	status, err := client.Status()
+	if err != nil {
+		return format.fail(ExitDaemonRequest, "Failed to get processes: %v", err)
+	}
+	sortProcesses(status.Processes)
+
+	if format.structured() {
+		return format.emit(processesOutput{
+			SchemaVersion: schemaVersion,
+			Processes:     status.Processes,
+		})
+	}
+
+	printProcesses(status.Processes)
+	return ExitSuccess
+}
+
+// userConfig returns the settings, or the defaults if config.json can't
+// be read; the daemon reports the error when it starts
+var userConfig = sync.OnceValue(func() *config.Config {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.Default()
+	}
+	return cfg
+})
+
+// proxyConfig returns the proxy settings
+func proxyConfig() config.ProxyConfig {
+	return userConfig().Proxy
+}
+
+// configuredCA reports whether certificates are issued by a CA set in
+// config.json rather than Caddy's local one
+func configuredCA() bool {
+	return userConfig().TLS.CA != nil
+}
+
+// tlsOptions converts the TLS settings to the proxy's options
+func tlsOptions(cfg config.TLSConfig) proxy.TLSOptions {
+	var opts proxy.TLSOptions
+	if cfg.CA != nil {
+		opts.CA = &proxy.KeyPair{Certificate: cfg.CA.Cert, Key: cfg.CA.Key}
+	}
+	for _, pair := range cfg.Certificates {
+		opts.Certificates = append(opts.Certificates, proxy.KeyPair{Certificate: pair.Cert, Key: pair.Key})
+	}
+	if cfg.ClientAuth != nil {
+		opts.ClientAuth = make(map[string]proxy.ClientAuthMode, len(cfg.ClientAuth.Hosts))
+		for host, mode := range cfg.ClientAuth.Hosts {
+			opts.ClientAuth[host] = proxy.ClientAuthMode(mode)
+		}
+		opts.ClientSubjectHeader = cfg.ClientAuth.Header
+	}
+	return opts
+}
+
+// siteURL returns the URL a routed host is served at
+func siteURL(host string) string {
+	return proxyConfig().URL(host)
+}
+
+// printProcesses prints processes in the human-readable status format
+func printProcesses(processes []*daemon.Process) {
+	if len(processes) == 0 {
 		fmt.Println("  No processes running")
+		return
+	}
+	for _, proc := range processes {
+		fmt.Printf("  PID %d: %s (%s, port %d)\n",
+			proc.PID, proc.ProjectRoot, siteURL(proc.Host), proc.Port)
+	}
+}
+
+func handleDoctor(args []string) int {
+	format, _, err := parseOutputFlags(args)
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
+	}
+
+	checks := runDoctorChecks()
+
+	ok := true
+	for _, check := range checks {
+		if check.Status == "fail" {
+			ok = false
+		}
+	}
+
+	exitCode := ExitSuccess
+	if !ok {
+		exitCode = ExitCheckFailed
+	}
+
+	if format.structured() {
+		if code := format.emit(doctorOutput{
+			SchemaVersion: schemaVersion,
+			Ok:            ok,
+			Checks:        checks,
+		}); code != ExitSuccess {
+			return code
+		}
+		return exitCode
+	}
+
+	for _, check := range checks {
+		symbol := "✓"
+		switch check.Status {
+		case "warn":
+			symbol = "⚠"
+		case "fail":
+			symbol = "✗"
+		}
+		fmt.Printf("%s %s: %s\n", symbol, check.Name, check.Message)
+	}
+
+	return exitCode
+}
+
+// runDoctorChecks runs each diagnostic check and returns the results in order
+func runDoctorChecks() []doctorCheck {
+	var checks []doctorCheck
+
+	// Configuration directory
+	configDir, err := daemon.ConfigDir()
+	if err != nil {
+		checks = append(checks, doctorCheck{"config_dir", "fail", err.Error()})
+	} else if info, err := os.Stat(configDir); os.IsNotExist(err) {
+		checks = append(checks, doctorCheck{"config_dir", "warn", fmt.Sprintf("%s does not exist yet (created when the daemon starts)", configDir)})
+	} else if err != nil {
+		checks = append(checks, doctorCheck{"config_dir", "fail", err.Error()})
+	} else if !info.IsDir() {
+		checks = append(checks, doctorCheck{"config_dir", "fail", fmt.Sprintf("%s is not a directory", configDir)})
+	} else {
+		checks = append(checks, doctorCheck{"config_dir", "ok", configDir})
+	}
+
+	// Daemon and proxy ports. When the daemon is running it owns the
+	// ports, so binding is only checked when it is not.
+	httpPort, httpsPort := proxyConfig().ListenPorts()
+	running := isDaemonRunning()
+	if running {
+		checks = append(checks, doctorCheck{"daemon", "ok", "running"})
+		checks = append(checks, doctorCheck{"ports", "ok", fmt.Sprintf("ports %d and %d are held by the daemon", httpPort, httpsPort)})
 	} else {
-		for _, proc := range status.Processes {
-			fmt.Printf("  PID %d: %s (https://%s, port %d)\n",
-				proc.PID, proc.ProjectRoot, proc.Host, proc.Port)
+		checks = append(checks, doctorCheck{"daemon", "warn", "not running (it starts automatically with 'faa run')"})
+		if port.IsPortFree(httpPort) && port.IsPortFree(httpsPort) {
+			checks = append(checks, doctorCheck{"ports", "ok", fmt.Sprintf("can bind to ports %d and %d", httpPort, httpsPort)})
+		} else {
+			checks = append(checks, doctorCheck{"ports", "fail", fmt.Sprintf("cannot bind to ports %d and %d (in use or missing permission); run 'faa setup'", httpPort, httpsPort)})
 		}
 	}
 
-	return ExitSuccess
+	// CA certificate
+	caPath, err := proxy.GetCAPath()
+	if err != nil {
+		checks = append(checks, doctorCheck{"ca_certificate", "fail", err.Error()})
+	} else if _, err := os.Stat(caPath); os.IsNotExist(err) {
+		checks = append(checks, doctorCheck{"ca_certificate", "warn", "not yet exported (created when the daemon starts)"})
+	} else if err != nil {
+		checks = append(checks, doctorCheck{"ca_certificate", "fail", err.Error()})
+	} else if warnings := proxy.CAExpiryWarnings(configuredCA(), time.Now()); len(warnings) > 0 {
+		checks = append(checks, doctorCheck{"ca_certificate", "warn", strings.Join(warnings, "; ")})
+	} else {
+		checks = append(checks, doctorCheck{"ca_certificate", "ok", caPath})
+	}
+
+	return checks
 }
 
 func handleStop(args []string) int {
 	// Parse flags
 	clearRoutes := false
 	for _, arg := range args {
-		if arg == "--clear-routes" {
-			clearRoutes = true
+		if arg != "--clear-routes" {
+			printError("Unknown option: %s", arg)
+			return ExitUsage
 		}
+		clearRoutes = true
 	}
 
 	// Connect to daemon
 	client, err := daemon.Connect()
 	if err != nil {
 		printError("Daemon is not running")
-		return ExitError
+		return ExitDaemonNotRunning
 	}
 	defer client.Close()
 
 	// Send stop request
 	if err := client.Stop(clearRoutes); err != nil {
 		printError("Failed to stop daemon: %v", err)
-		return ExitError
+		return ExitDaemonRequest
 	}
 
 	fmt.Println("Daemon shutdown requested")
@@ -560,19 +1448,34 @@ func handleStop(args []string) int {
 }
 
 func handleRoutes(args []string) int {
+	format, _, err := parseOutputFlags(args)
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
+	}
+
 	// Connect to daemon
 	client, err := daemon.Connect()
 	if err != nil {
-		printError("Daemon is not running. Start it with: faa daemon")
-		return ExitError
+		return format.fail(ExitDaemonNotRunning, "Daemon is not running. Start it with: faa daemon")
 	}
 	defer client.Close()
 
 	// Get routes from daemon
 	routes, err := client.ListRoutes()
 	if err != nil {
-		printError("Failed to get routes: %v", err)
-		return ExitError
+		return format.fail(ExitDaemonRequest, "Failed to get routes: %v", err)
+	}
+	sortRoutes(routes)
+
+	if format.structured() {
+		if routes == nil {
+			routes = []daemon.Route{}
+		}
+		return format.emit(routesOutput{
+			SchemaVersion: schemaVersion,
+			Routes:        routes,
+		})
 	}
 
 	// Print routes
@@ -590,24 +1493,40 @@ func handleRoutes(args []string) int {
 }
 
 func handleCAPath(args []string) int {
+	format, _, err := parseOutputFlags(args)
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
+	}
+
 	// Get the CA certificate path
 	caPath, err := proxy.GetCAPath()
 	if err != nil {
-		printError("Failed to get CA certificate path: %v", err)
-		return ExitError
+		return format.fail(ExitError, "Failed to get CA certificate path: %v", err)
 	}
 
 	// Check if the certificate exists
-	if _, err := os.Stat(caPath); os.IsNotExist(err) {
+	_, statErr := os.Stat(caPath)
+	if statErr != nil && !os.IsNotExist(statErr) {
+		return format.fail(ExitError, "Failed to check CA certificate: %v", statErr)
+	}
+	exists := statErr == nil
+
+	if format.structured() {
+		return format.emit(caPathOutput{
+			SchemaVersion: schemaVersion,
+			Path:          caPath,
+			Exists:        exists,
+		})
+	}
+
+	if !exists {
 		fmt.Println("CA certificate not yet exported.")
 		fmt.Printf("Path: %s\n", caPath)
 		fmt.Println()
 		fmt.Println("The certificate will be created when you start the daemon.")
 		fmt.Println("After starting the daemon, the certificate will be available at this path.")
 		return ExitSuccess
-	} else if err != nil {
-		printError("Failed to check CA certificate: %v", err)
-		return ExitError
 	}
 
 	// Certificate exists
@@ -615,13 +1534,142 @@ func handleCAPath(args []string) int {
 	return ExitSuccess
 }
 
+func handleAPI(args []string) int {
+	format, rest, err := parseOutputFlags(args)
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
+	}
+
+	if len(rest) > 0 && rest[0] == "token" {
+		token, err := daemon.ReadAPIToken()
+		if err != nil {
+			return format.fail(ExitError, "Failed to read API token: %v", err)
+		}
+		fmt.Println(token)
+		return ExitSuccess
+	}
+	if len(rest) > 0 {
+		printError("Unknown api command: %s", rest[0])
+		return ExitUsage
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return format.fail(ExitError, "Failed to load configuration: %v", err)
+	}
+
+	tokenPath, err := daemon.APITokenPath()
+	if err != nil {
+		return format.fail(ExitError, "Failed to get API token path: %v", err)
+	}
+
+	url := cfg.Proxy.URL(cfg.API.Host) + "/api"
+
+	if format.structured() {
+		return format.emit(apiOutput{
+			SchemaVersion: schemaVersion,
+			Enabled:       cfg.API.Enabled,
+			URL:           url,
+			TokenPath:     tokenPath,
+		})
+	}
+
+	if !cfg.API.Enabled {
+		fmt.Println("The management API is disabled.")
+		fmt.Println()
+		fmt.Println("Enable it in ~/.config/faa/config.json and restart the daemon:")
+		fmt.Println(`  {"api": {"enabled": true}}`)
+		return ExitSuccess
+	}
+
+	fmt.Printf("URL:   %s\n", url)
+	fmt.Printf("Spec:  %s/openapi.json\n", url)
+	fmt.Printf("Token: %s\n", tokenPath)
+	fmt.Println()
+	fmt.Println("Example:")
+	fmt.Printf("  curl -H \"Authorization: Bearer $(faa api token)\" %s/status\n", url)
+	return ExitSuccess
+}
+
+func handleDashboard(args []string) int {
+	noOpen := false
+	for _, arg := range args {
+		switch arg {
+		case "--no-open":
+			noOpen = true
+		default:
+			printError("Unknown option: %s", arg)
+			return ExitUsage
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		printError("Failed to load configuration: %v", err)
+		return ExitError
+	}
+
+	if !cfg.API.Enabled {
+		printError("The dashboard is served by the management API, which is disabled.\n" +
+			"Enable it in ~/.config/faa/config.json and restart the daemon:\n" +
+			`  {"api": {"enabled": true}}`)
+		return ExitError
+	}
+
+	if !isDaemonRunning() {
+		printError("Daemon is not running. Start it with 'faa daemon'")
+		return ExitDaemonNotRunning
+	}
+
+	token, err := daemon.ReadAPIToken()
+	if err != nil {
+		printError("Failed to read API token: %v", err)
+		return ExitError
+	}
+
+	// The token travels in the URL fragment, which browsers never send to the server
+	url := fmt.Sprintf("%s/#token=%s", cfg.Proxy.URL(cfg.API.Host), token)
+
+	if noOpen {
+		fmt.Println(url)
+		return ExitSuccess
+	}
+
+	fmt.Printf("Opening %s/\n", cfg.Proxy.URL(cfg.API.Host))
+	if err := openBrowser(url); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to open a browser: %v\n", err)
+		fmt.Println(url)
+	}
+	return ExitSuccess
+}
+
+// openBrowser opens url in the user's default browser
+func openBrowser(url string) error {
+	name := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		name = "open"
+	}
+
+	cmd := exec.Command(name, url)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() {
+		_ = cmd.Wait()
+	}()
+	return nil
+}
+
 func handleClean(args []string) int {
 	// Parse flags
 	skipConfirmation := false
 	for _, arg := range args {
-		if arg == "-y" || arg == "--yes" {
-			skipConfirmation = true
+		if arg != "-y" && arg != "--yes" {
+			printError("Unknown option: %s", arg)
+			return ExitUsage
 		}
+		skipConfirmation = true
 	}
 
 	// Check if daemon is running and warn user
@@ -648,6 +1696,14 @@ func handleClean(args []string) int {
 	fmt.Printf("  - %s (Caddy configuration cache)\n", caddyConfigDir)
 	fmt.Println()
 
+	// The setup manifest is in the faa configuration directory, so the
+	// system changes it records can't be undone after cleaning
+	if manifest, err := setup.LoadManifest(); err == nil && len(manifest.Changes) > 0 {
+		fmt.Printf("Warning: 'faa setup' made %d system change(s) that are recorded in the faa configuration.\n", len(manifest.Changes))
+		fmt.Println("Run 'faa setup --uninstall' first to undo them.")
+		fmt.Println()
+	}
+
 	// Ask for confirmation unless -y flag is provided
 	if !skipConfirmation {
 		fmt.Print("Are you sure you want to continue? (y/N): ")
@@ -415,3 +415,53 @@ func TestCleanCommandHelp(t *testing.T) {
 		t.Fatalf("clean --help failed with exit code %d", exitCode)
 	}
 }
+
+// TestSetupUsageErrors tests that conflicting setup options are rejected
+// before anything runs
+func TestSetupUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"--bogus"},
+		{"--ca-only", "--skip-ca"},
+		{"--ca-only", "--skip-ports"},
+		{"--ca-only", "--skip-dns"},
+		{"--ca-only", "--skip-hosts"},
+		{"--dry-run"},
+		{"--uninstall", "--json"},
+		{"--uninstall", "--skip-ca"},
+	} {
+		if exitCode := handleSetup(args); exitCode != ExitUsage {
+			t.Errorf("handleSetup(%v) = %d, want %d", args, exitCode, ExitUsage)
+		}
+	}
+}
+
+// TestExitCodes tests that every command reports misuse and a stopped
+// daemon with the documented exit codes
+func TestExitCodes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("FAA_SOCKET_DIR", "")
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"run"}, ExitUsage},
+		{[]string{"run", "--"}, ExitUsage},
+		{[]string{"stop", "--bogus"}, ExitUsage},
+		{[]string{"clean", "--bogus"}, ExitUsage},
+		{[]string{"daemon", "--bogus"}, ExitUsage},
+		{[]string{"top", "--bogus"}, ExitUsage},
+		{[]string{"--format", "yaml", "status"}, ExitUsage},
+		{[]string{"stop"}, ExitDaemonNotRunning},
+		{[]string{"status"}, ExitDaemonNotRunning},
+		{[]string{"routes"}, ExitDaemonNotRunning},
+		{[]string{"processes"}, ExitDaemonNotRunning},
+		{[]string{"chaos", "app"}, ExitDaemonNotRunning},
+		{[]string{"inspect", "app"}, ExitDaemonNotRunning},
+	}
+	for _, tt := range tests {
+		if exitCode := run(tt.args); exitCode != tt.want {
+			t.Errorf("run(%v) = %d, want %d", tt.args, exitCode, tt.want)
+		}
+	}
+}
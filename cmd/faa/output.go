@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/sahithyandev/faa/internal/daemon"
+	"github.com/sahithyandev/faa/internal/setup"
+)
+
+// schemaVersion is the version of the machine-readable output format.
+// It is bumped whenever a field is removed or changes meaning; new fields
+// may be added without a version bump.
+const schemaVersion = 1
+
+// outputKind selects how a command renders its result
+type outputKind int
+
+const (
+	outputText outputKind = iota
+	outputJSON
+	outputTemplate
+)
+
+// outputFormat describes the requested output format for a command
+type outputFormat struct {
+	kind outputKind
+	tmpl *template.Template
+
+	// compact prints JSON on a single line, for streaming output
+	compact bool
+}
+
+// errorOutput is the JSON document printed when a command fails in JSON mode
+type errorOutput struct {
+	SchemaVersion int         `json:"schemaVersion"`
+	Error         errorDetail `json:"error"`
+}
+
+// errorDetail describes a failure in machine-readable form
+type errorDetail struct {
+	Code     string `json:"code"`
+	ExitCode int    `json:"exitCode"`
+	Message  string `json:"message"`
+}
+
+// isOutputFlag reports whether arg is one of the output format flags
+func isOutputFlag(arg string) bool {
+	return arg == "--json" || arg == "--format" || strings.HasPrefix(arg, "--format=")
+}
+
+// parseOutputFlags extracts --json and --format from args and returns the
+// selected format along with the remaining arguments.
+// Supported formats are "text" (default), "json" and "template=<go template>";
+// any other --format value is a usage error.
+func parseOutputFlags(args []string) (outputFormat, []string, error) {
+	format := outputFormat{kind: outputText}
+	rest := make([]string, 0, len(args))
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		var value string
+		switch {
+		case arg == "--json":
+			value = "json"
+		case arg == "--format":
+			if i+1 >= len(args) {
+				return format, nil, fmt.Errorf("--format requires a value (text, json or template=<Go template>)")
+			}
+			i++
+			value = args[i]
+		case strings.HasPrefix(arg, "--format="):
+			value = strings.TrimPrefix(arg, "--format=")
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+
+		switch {
+		case value == "text":
+			format = outputFormat{kind: outputText}
+		case value == "json":
+			format = outputFormat{kind: outputJSON}
+		case strings.HasPrefix(value, "template="):
+			tmpl, err := template.New("output").Parse(strings.TrimPrefix(value, "template="))
+			if err != nil {
+				return format, nil, fmt.Errorf("invalid --format template: %w", err)
+			}
+			format = outputFormat{kind: outputTemplate, tmpl: tmpl}
+		default:
+			return format, nil, fmt.Errorf("unknown --format %q (text, json or template=<Go template>)", value)
+		}
+	}
+
+	return format, rest, nil
+}
+
+// structured reports whether the format produces machine-readable output
+func (f outputFormat) structured() bool {
+	return f.kind != outputText
+}
+
+// write renders data in the selected structured format to w.
+// It must only be called when structured() is true.
+func (f outputFormat) write(w io.Writer, data interface{}) error {
+	switch f.kind {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		if !f.compact {
+			enc.SetIndent("", "  ")
+		}
+		return enc.Encode(data)
+	case outputTemplate:
+		if err := f.tmpl.Execute(w, data); err != nil {
+			return fmt.Errorf("failed to execute template: %w", err)
+		}
+		_, err := fmt.Fprintln(w)
+		return err
+	default:
+		return fmt.Errorf("text output must be rendered by the command")
+	}
+}
+
+// emit writes data to stdout in the selected structured format and
+// returns the exit code for the command.
+func (f outputFormat) emit(data interface{}) int {
+	if err := f.write(os.Stdout, data); err != nil {
+		printError("Failed to write output: %v", err)
+		return ExitError
+	}
+	return ExitSuccess
+}
+
+// fail reports an error in the selected format and returns exitCode.
+// In text mode the message goes to stderr; in structured modes a JSON
+// error document is written to stdout so callers can always parse it.
+func (f outputFormat) fail(exitCode int, format string, args ...interface{}) int {
+	if !f.structured() {
+		printError(format, args...)
+		return exitCode
+	}
+
+	doc := errorOutput{
+		SchemaVersion: schemaVersion,
+		Error: errorDetail{
+			Code:     exitCodeName(exitCode),
+			ExitCode: exitCode,
+			Message:  fmt.Sprintf(format, args...),
+		},
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		printError(format, args...)
+	}
+	return exitCode
+}
+
+// exitCodeName returns the stable identifier for an exit code
+func exitCodeName(code int) string {
+	switch code {
+	case ExitSuccess:
+		return "ok"
+	case ExitUsage:
+		return "usage"
+	case ExitDaemonNotRunning:
+		return "daemon_not_running"
+	case ExitDaemonRequest:
+		return "daemon_request_failed"
+	case ExitNotFound:
+		return "not_found"
+	case ExitCheckFailed:
+		return "check_failed"
+	default:
+		return "error"
+	}
+}
+
+// versionOutput is the machine-readable form of 'faa version'
+type versionOutput struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Version       string `json:"version"`
+	Commit        string `json:"commit"`
+	Date          string `json:"date"`
+	OS            string `json:"os"`
+	Arch          string `json:"arch"`
+}
+
+// statusOutput is the machine-readable form of 'faa status'
+type statusOutput struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	Daemon        daemonOutput      `json:"daemon"`
+	Routes        []daemon.Route    `json:"routes"`
+	Processes     []*daemon.Process `json:"processes"`
+	Ready         map[string]bool   `json:"ready"`
+}
+
+// daemonOutput describes the daemon state
+type daemonOutput struct {
+	Running bool `json:"running"`
+}
+
+// routesOutput is the machine-readable form of 'faa routes'
+type routesOutput struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Routes        []daemon.Route `json:"routes"`
+}
+
+// processesOutput is the machine-readable form of 'faa processes'
+type processesOutput struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	Processes     []*daemon.Process `json:"processes"`
+}
+
+// caPathOutput is the machine-readable form of 'faa ca-path'
+type caPathOutput struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Path          string `json:"path"`
+	Exists        bool   `json:"exists"`
+}
+
+// apiOutput is the machine-readable form of 'faa api'
+type apiOutput struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Enabled       bool   `json:"enabled"`
+	URL           string `json:"url"`
+	TokenPath     string `json:"tokenPath"`
+}
+
+// setupOutput is the machine-readable form of 'faa setup'
+type setupOutput struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	OS            string        `json:"os"`
+	Check         bool          `json:"check"`
+	Complete      bool          `json:"complete"`
+	Steps         []*setup.Step `json:"steps"`
+}
+
+// doctorOutput is the machine-readable form of 'faa doctor'
+type doctorOutput struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	Ok            bool          `json:"ok"`
+	Checks        []doctorCheck `json:"checks"`
+}
+
+// doctorCheck is the result of a single diagnostic check
+type doctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"` // "ok", "warn" or "fail"
+	Message string `json:"message"`
+}
+
+// sortRoutes sorts routes by host so output is stable across runs
+func sortRoutes(routes []daemon.Route) {
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Host < routes[j].Host
+	})
+}
+
+// sortProcesses sorts processes by project root so output is stable across runs
+func sortProcesses(processes []*daemon.Process) {
+	sort.Slice(processes, func(i, j int) bool {
+		return processes[i].ProjectRoot < processes[j].ProjectRoot
+	})
+}
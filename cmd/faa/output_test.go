@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseOutputFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantKind outputKind
+		wantRest []string
+		wantErr  bool
+	}{
+		{"no flags", []string{"--clear-routes"}, outputText, []string{"--clear-routes"}, false},
+		{"json flag", []string{"--json"}, outputJSON, []string{}, false},
+		{"format json", []string{"--format", "json"}, outputJSON, []string{}, false},
+		{"format equals", []string{"--format=json", "x"}, outputJSON, []string{"x"}, false},
+		{"format text", []string{"--json", "--format", "text"}, outputText, []string{}, false},
+		{"template", []string{"--format", "template={{.Version}}"}, outputTemplate, []string{}, false},
+		{"template equals", []string{"--format=template={{.Version}}"}, outputTemplate, []string{}, false},
+		{"missing value", []string{"--format"}, outputText, nil, true},
+		{"bad template", []string{"--format", "template={{.Version"}, outputText, nil, true},
+		{"unknown format", []string{"--format", "yaml"}, outputText, nil, true},
+		{"template without prefix", []string{"--format", "{{.Version}}"}, outputText, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, rest, err := parseOutputFlags(tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if format.kind != tt.wantKind {
+				t.Errorf("kind = %v, want %v", format.kind, tt.wantKind)
+			}
+			if strings.Join(rest, " ") != strings.Join(tt.wantRest, " ") {
+				t.Errorf("rest = %v, want %v", rest, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestOutputFormatWrite(t *testing.T) {
+	data := versionOutput{SchemaVersion: schemaVersion, Version: "1.2.3"}
+
+	format, _, err := parseOutputFlags([]string{"--json"})
+	if err != nil {
+		t.Fatalf("parseOutputFlags() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := format.write(&buf, data); err != nil {
+		t.Fatalf("write() failed: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if decoded["schemaVersion"] != float64(schemaVersion) {
+		t.Errorf("schemaVersion = %v, want %d", decoded["schemaVersion"], schemaVersion)
+	}
+	if decoded["version"] != "1.2.3" {
+		t.Errorf("version = %v, want 1.2.3", decoded["version"])
+	}
+
+	format, _, err = parseOutputFlags([]string{"--format", "template=v{{.Version}}"})
+	if err != nil {
+		t.Fatalf("parseOutputFlags() failed: %v", err)
+	}
+	buf.Reset()
+	if err := format.write(&buf, data); err != nil {
+		t.Fatalf("write() failed: %v", err)
+	}
+	if got := buf.String(); got != "v1.2.3\n" {
+		t.Errorf("template output = %q, want %q", got, "v1.2.3\n")
+	}
+}
+
+func TestExitCodeNamesAreDistinct(t *testing.T) {
+	codes := []int{ExitSuccess, ExitError, ExitUsage, ExitDaemonNotRunning, ExitDaemonRequest, ExitNotFound, ExitCheckFailed}
+	seen := make(map[string]int)
+	for _, code := range codes {
+		name := exitCodeName(code)
+		if other, ok := seen[name]; ok {
+			t.Errorf("exit codes %d and %d share name %q", other, code, name)
+		}
+		seen[name] = code
+	}
+}
+
+func TestStatusJSONWhenDaemonNotRunning(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Override HOME for testing
+	originalHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", originalHome)
+	os.Setenv("HOME", tmpDir)
+
+	// Capture stdout
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	exitCode := run([]string{"--json", "status"})
+
+	w.Close()
+	os.Stdout = oldStdout
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+
+	if exitCode != ExitDaemonNotRunning {
+		t.Errorf("exit code = %d, want %d", exitCode, ExitDaemonNotRunning)
+	}
+
+	var doc errorOutput
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if doc.Error.Code != "daemon_not_running" {
+		t.Errorf("error code = %q, want daemon_not_running", doc.Error.Code)
+	}
+}
+
+func TestRunRejectsOutputFlags(t *testing.T) {
+	// Commands without structured output must not pass the flags on, such
+	// as to the dev server's arguments
+	for _, args := range [][]string{
+		{"--json", "npm", "start"},
+		{"--json", "run", "--", "npm", "dev"},
+		{"--format", "json", "stop"},
+		{"--json", "clean", "-y"},
+		{"--json", "daemon"},
+	} {
+		if code := run(args); code != ExitUsage {
+			t.Errorf("run(%v) = %d, want %d", args, code, ExitUsage)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sahithyandev/faa/internal/daemon"
+	"github.com/sahithyandev/faa/internal/replay"
+)
+
+// replayDiffContext is the number of unchanged body lines shown around changes
+const replayDiffContext = 3
+
+// replayOptions holds the parsed arguments of 'faa replay'
+type replayOptions struct {
+	id    uint64
+	edits replay.Edits
+}
+
+// replayOutput is the machine-readable form of 'faa replay'
+type replayOutput struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	ID            uint64         `json:"id"`
+	Upstream      string         `json:"upstream"`
+	Response      *replay.Result `json:"response"`
+	Changed       bool           `json:"changed"`
+	Diff          *replay.Diff   `json:"diff"`
+}
+
+// parseReplayArgs parses the arguments of 'faa replay' after output flags
+// have been removed. Body arguments starting with @ are read from a file,
+// or from stdin for @-.
+func parseReplayArgs(args []string, stdin io.Reader) (replayOptions, error) {
+	var opts replayOptions
+	var positional []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-X", "--method", "-H", "--header", "-d", "--data":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("%s requires a value", arg)
+			}
+			i++
+			value := args[i]
+
+			switch arg {
+			case "-X", "--method":
+				opts.edits.Method = value
+			case "-H", "--header":
+				header, err := replay.ParseHeader(value)
+				if err != nil {
+					return opts, err
+				}
+				opts.edits.SetHeaders = append(opts.edits.SetHeaders, header)
+			default:
+				body, err := readReplayBody(value, stdin)
+				if err != nil {
+					return opts, err
+				}
+				opts.edits.Body = body
+			}
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return opts, fmt.Errorf("unknown option: %s", arg)
+			}
+			positional = append(positional, arg)
+		}
+	}
+
+	if len(positional) != 1 {
+		return opts, fmt.Errorf("a request id is required. Usage: faa replay <id> [options]")
+	}
+	id, err := strconv.ParseUint(positional[0], 10, 64)
+	if err != nil || id == 0 {
+		return opts, fmt.Errorf("invalid request id: %s", positional[0])
+	}
+	opts.id = id
+	return opts, nil
+}
+
+// readReplayBody returns the body given to -d, reading @file or @- (stdin)
+func readReplayBody(value string, stdin io.Reader) ([]byte, error) {
+	if !strings.HasPrefix(value, "@") {
+		return []byte(value), nil
+	}
+	if value == "@-" {
+		body, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read body from stdin: %w", err)
+		}
+		return body, nil
+	}
+	body, err := os.ReadFile(value[1:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	return body, nil
+}
+
+func handleReplay(args []string) int {
+	format, rest, err := parseOutputFlags(args)
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
+	}
+
+	opts, err := parseReplayArgs(rest, os.Stdin)
+	if err != nil {
+		printError("%v", err)
+		return ExitUsage
+	}
+
+	client, err := daemon.Connect()
+	if err != nil {
+		return format.fail(ExitDaemonNotRunning, "Daemon is not running. Start it with: faa daemon")
+	}
+	defer client.Close()
+
+	exchange, err := client.GetCapture(opts.id)
+	if err != nil {
+		return format.fail(ExitDaemonRequest, "Failed to get capture: %v", err)
+	}
+	if exchange == nil {
+		return format.fail(ExitNotFound, "No captured exchange with id %d", opts.id)
+	}
+
+	// Replay against wherever the route points now, which may be a new port
+	port, err := client.GetRoute(exchange.Host)
+	if err != nil {
+		return format.fail(ExitDaemonRequest, "Failed to get route: %v", err)
+	}
+	if port == 0 {
+		return format.fail(ExitNotFound, "No route for %s", exchange.Host)
+	}
+
+	req, err := replay.NewRequest(exchange, port, opts.edits)
+	if err != nil {
+		return format.fail(ExitError, "%v", err)
+	}
+	result, err := replay.Send(req, replay.DefaultTimeout)
+	if err != nil {
+		return format.fail(ExitError, "Replay failed: %v", err)
+	}
+	diff := replay.Compare(exchange, result)
+
+	upstream := fmt.Sprintf("localhost:%d", port)
+	if format.structured() {
+		return format.emit(replayOutput{
+			SchemaVersion: schemaVersion,
+			ID:            exchange.ID,
+			Upstream:      upstream,
+			Response:      result,
+			Changed:       diff.Changed(),
+			Diff:          diff,
+		})
+	}
+
+	fmt.Printf("Replayed #%d %s https://%s%s -> %s\n", exchange.ID, req.Method, exchange.Host, stripControl(exchange.URL), upstream)
+	fmt.Printf("%s in %s (was %s in %s)\n",
+		statusLine(result.Status), formatDuration(result.DurationMs),
+		statusLine(exchange.Status), formatDuration(exchange.DurationMs))
+	fmt.Println()
+	printReplayDiff(os.Stdout, diff)
+	return ExitSuccess
+}
+
+// statusLine formats a status code with its text
+func statusLine(status int) string {
+	if text := http.StatusText(status); text != "" {
+		return fmt.Sprintf("%d %s", status, text)
+	}
+	return strconv.Itoa(status)
+}
+
+// printReplayDiff prints how the replayed response differs from the original
+func printReplayDiff(w io.Writer, diff *replay.Diff) {
+	if !diff.Changed() {
+		fmt.Fprintln(w, "Response unchanged")
+		return
+	}
+
+	if diff.StatusBefore != diff.StatusAfter {
+		fmt.Fprintf(w, "Status:\n- %s\n+ %s\n\n", statusLine(diff.StatusBefore), statusLine(diff.StatusAfter))
+	}
+	if len(diff.Headers) > 0 {
+		fmt.Fprintln(w, "Headers:")
+		for _, line := range diff.Headers {
+			fmt.Fprintf(w, "%s %s\n", line.Op, stripControl(line.Text))
+		}
+		fmt.Fprintln(w)
+	}
+	if diff.BodyNote != "" {
+		fmt.Fprintf(w, "Body: %s\n", diff.BodyNote)
+	} else if diff.Body != nil {
+		fmt.Fprintln(w, "Body:")
+		printDiffLines(w, diff.Body, replayDiffContext)
+	}
+}
+
+// printDiffLines prints changed lines with up to context unchanged lines
+// around them, marking skipped runs with "..."
+func printDiffLines(w io.Writer, lines []replay.Line, context int) {
+	// show[i] is set for changed lines and those within context of one
+	show := make([]bool, len(lines))
+	for i, line := range lines {
+		if line.Op == replay.OpEqual {
+			continue
+		}
+		for j := max(0, i-context); j <= min(len(lines)-1, i+context); j++ {
+			show[j] = true
+		}
+	}
+
+	skipped := false
+	for i, line := range lines {
+		if !show[i] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			fmt.Fprintln(w, "  ...")
+			skipped = false
+		}
+		fmt.Fprintf(w, "%s %s\n", line.Op, stripControl(line.Text))
+	}
+	if skipped {
+		fmt.Fprintln(w, "  ...")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sahithyandev/faa/internal/replay"
+)
+
+func TestParseReplayArgs(t *testing.T) {
+	bodyFile := filepath.Join(t.TempDir(), "body.json")
+	if err := os.WriteFile(bodyFile, []byte(`{"from":"file"}`), 0644); err != nil {
+		t.Fatalf("Failed to write body file: %v", err)
+	}
+
+	opts, err := parseReplayArgs([]string{"42", "-X", "PUT", "-H", "X-Test: 1", "-H", "Cookie:", "-d", "@" + bodyFile}, nil)
+	if err != nil {
+		t.Fatalf("parseReplayArgs() failed: %v", err)
+	}
+	if opts.id != 42 || opts.edits.Method != "PUT" {
+		t.Errorf("opts = %+v", opts)
+	}
+	if len(opts.edits.SetHeaders) != 2 || opts.edits.SetHeaders[1] != (replay.Header{Name: "Cookie"}) {
+		t.Errorf("SetHeaders = %+v", opts.edits.SetHeaders)
+	}
+	if string(opts.edits.Body) != `{"from":"file"}` {
+		t.Errorf("Body = %s", opts.edits.Body)
+	}
+
+	opts, err = parseReplayArgs([]string{"7", "-d", "@-"}, strings.NewReader("from stdin"))
+	if err != nil || string(opts.edits.Body) != "from stdin" {
+		t.Errorf("parseReplayArgs() with @- = %q, %v", opts.edits.Body, err)
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"abc"},
+		{"1", "2"},
+		{"1", "-H"},
+		{"1", "-H", "invalid"},
+		{"1", "--bogus"},
+	} {
+		if _, err := parseReplayArgs(args, nil); err == nil {
+			t.Errorf("parseReplayArgs(%v) should fail", args)
+		}
+	}
+}
+
+func TestPrintReplayDiff(t *testing.T) {
+	var buf bytes.Buffer
+	printReplayDiff(&buf, &replay.Diff{StatusBefore: 200, StatusAfter: 200})
+	if !strings.Contains(buf.String(), "Response unchanged") {
+		t.Errorf("Unexpected output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	body := []replay.Line{{Op: replay.OpEqual, Text: "{"}}
+	for i := 0; i < 10; i++ {
+		body = append(body, replay.Line{Op: replay.OpEqual, Text: "  same"})
+	}
+	body = append(body,
+		replay.Line{Op: replay.OpRemove, Text: `  "ok": true`},
+		replay.Line{Op: replay.OpAdd, Text: `  "ok": false`},
+		replay.Line{Op: replay.OpEqual, Text: "}"},
+	)
+	printReplayDiff(&buf, &replay.Diff{
+		StatusBefore: 200,
+		StatusAfter:  500,
+		Headers:      []replay.Line{{Op: replay.OpAdd, Text: "X-Error: 1"}},
+		Body:         body,
+	})
+
+	output := buf.String()
+	for _, want := range []string{
+		"- 200 OK\n+ 500 Internal Server Error",
+		"+ X-Error: 1",
+		"  ...\n",
+		"-   \"ok\": true\n+   \"ok\": false\n  }",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Output does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Count(output, "  same") != replayDiffContext {
+		t.Errorf("Expected %d context lines:\n%s", replayDiffContext, output)
+	}
+}
@@ -0,0 +1,547 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/sahithyandev/faa/internal/daemon"
+	"github.com/sahithyandev/faa/internal/procstat"
+	"github.com/sahithyandev/faa/internal/proxy"
+)
+
+const (
+	// topRefreshInterval is how often 'faa top' polls the daemon
+	topRefreshInterval = 1 * time.Second
+
+	// topLogLines is the number of log lines fetched for the log view
+	topLogLines = 500
+)
+
+// topAction is what the TUI should do in response to a key press
+type topAction int
+
+const (
+	topNone topAction = iota
+	topQuit
+	topRestart
+	topStop
+	topOpen
+)
+
+// topRow is one project in the 'faa top' table
+type topRow struct {
+	proc  *daemon.Process
+	ready bool
+
+	// usage is only meaningful when usageOK is set
+	usage   procstat.Usage
+	usageOK bool
+
+	requestRate float64
+	lastError   *proxy.RequestRecord
+}
+
+// topModel holds the state of the 'faa top' screen.
+// It is independent of the terminal so it can be rendered in tests.
+type topModel struct {
+	rows     []topRow
+	selected int
+	showLog  bool
+	logLines []string
+	message  string
+	updated  time.Time
+
+	// Request totals from the previous refresh, for computing rates
+	previousTotals map[string]uint64
+	previousTime   time.Time
+}
+
+// newTopModel creates an empty model
+func newTopModel() *topModel {
+	return &topModel{previousTotals: make(map[string]uint64)}
+}
+
+// update replaces the rows with fresh data from the daemon
+func (m *topModel) update(status *daemon.StatusResponseData, stats map[string]proxy.HostStats, sampler *procstat.Sampler, now time.Time) {
+	var selectedRoot string
+	if row, ok := m.selectedRow(); ok {
+		selectedRoot = row.proc.ProjectRoot
+	}
+
+	sortProcesses(status.Processes)
+
+	elapsed := now.Sub(m.previousTime).Seconds()
+	totals := make(map[string]uint64, len(stats))
+	m.rows = make([]topRow, 0, len(status.Processes))
+	for _, proc := range status.Processes {
+		row := topRow{proc: proc, ready: status.Ready[proc.Host]}
+
+		if sampler != nil {
+			// Dev servers run in their own process group led by the registered PID
+			if usage, err := sampler.Sample(proc.PID); err == nil {
+				row.usage = usage
+				row.usageOK = true
+			}
+		}
+
+		if hostStats, ok := stats[proc.Host]; ok {
+			totals[proc.Host] = hostStats.Total
+			previous, seen := m.previousTotals[proc.Host]
+			if seen && elapsed > 0 && hostStats.Total >= previous {
+				row.requestRate = float64(hostStats.Total-previous) / elapsed
+			}
+			row.lastError = hostStats.LastError
+		}
+
+		m.rows = append(m.rows, row)
+	}
+	m.previousTotals = totals
+	m.previousTime = now
+	m.updated = now
+
+	// Keep the same project selected when rows move around
+	m.selected = 0
+	for i, row := range m.rows {
+		if row.proc.ProjectRoot == selectedRoot {
+			m.selected = i
+		}
+	}
+	if len(m.rows) == 0 {
+		m.showLog = false
+	}
+}
+
+// selectedRow returns the highlighted row, if any
+func (m *topModel) selectedRow() (topRow, bool) {
+	if m.selected < 0 || m.selected >= len(m.rows) {
+		return topRow{}, false
+	}
+	return m.rows[m.selected], true
+}
+
+// handleKey updates the model for a key press and returns the action to run
+func (m *topModel) handleKey(key string) topAction {
+	switch key {
+	case "q", "ctrl-c":
+		if key == "q" && m.showLog {
+			m.showLog = false
+			return topNone
+		}
+		return topQuit
+	case "esc":
+		m.showLog = false
+	case "up", "k":
+		if m.selected > 0 {
+			m.selected--
+		}
+	case "down", "j":
+		if m.selected < len(m.rows)-1 {
+			m.selected++
+		}
+	case "l", "enter":
+		if _, ok := m.selectedRow(); ok {
+			m.showLog = !m.showLog
+			m.logLines = nil
+		}
+	case "r":
+		return topRestart
+	case "s":
+		return topStop
+	case "o":
+		return topOpen
+	}
+	return topNone
+}
+
+// render returns the screen contents as lines no wider than width
+func (m *topModel) render(width, height int) []string {
+	lines := []string{
+		fmt.Sprintf("faa top - %d project(s) - %s", len(m.rows), m.updated.Format("15:04:05")),
+		"",
+	}
+
+	if m.showLog {
+		lines = append(lines, m.renderLog(height-4)...)
+	} else {
+		lines = append(lines, m.renderTable()...)
+	}
+
+	// Pad so the footer stays on the last lines of the screen
+	for len(lines) < height-2 {
+		lines = append(lines, "")
+	}
+	if len(lines) > height-2 && height > 2 {
+		lines = lines[:height-2]
+	}
+
+	lines = append(lines, m.message)
+	if m.showLog {
+		lines = append(lines, "q/esc back  r restart  s stop  o open")
+	} else {
+		lines = append(lines, "q quit  up/down select  l logs  r restart  s stop  o open")
+	}
+
+	for i, line := range lines {
+		lines[i] = truncate(line, width)
+	}
+
+	// Highlight the selected row after truncating so escape codes stay intact
+	if !m.showLog && len(m.rows) > 0 {
+		// Rows start after the title, blank line and column header
+		index := 3 + m.selected
+		if index < len(lines) {
+			lines[index] = "\033[7m" + padRight(lines[index], width) + "\033[0m"
+		}
+	}
+	return lines
+}
+
+// renderTable renders the project table
+func (m *topModel) renderTable() []string {
+	if len(m.rows) == 0 {
+		return []string{"No running projects. Start one with: faa run -- <command>"}
+	}
+
+	hostWidth := len("HOST")
+	for _, row := range m.rows {
+		if len(row.proc.Host) > hostWidth {
+			hostWidth = len(row.proc.Host)
+		}
+	}
+
+	format := fmt.Sprintf("%%-%ds  %%5s  %%7s  %%-8s  %%6s  %%7s  %%6s  %%s", hostWidth)
+	lines := []string{fmt.Sprintf(format, "HOST", "PORT", "PID", "STATE", "CPU%", "MEM", "REQ/S", "LAST ERROR")}
+
+	for _, row := range m.rows {
+		state := "starting"
+		if row.ready {
+			state = "ready"
+		}
+
+		cpu, mem := "-", "-"
+		if row.usageOK {
+			cpu = fmt.Sprintf("%.1f", row.usage.CPUPercent)
+			mem = formatBytes(row.usage.RSSBytes)
+		}
+
+		lastError := ""
+		if row.lastError != nil {
+			detail := row.lastError.Error
+			if detail == "" {
+				detail = row.lastError.Method + " " + row.lastError.Path
+			}
+			lastError = fmt.Sprintf("%d %s (%s ago)", row.lastError.Status, detail, formatAge(m.updated.Sub(row.lastError.Time)))
+		}
+
+		lines = append(lines, fmt.Sprintf(format,
+			row.proc.Host,
+			fmt.Sprint(row.proc.Port),
+			fmt.Sprint(row.proc.PID),
+			state,
+			cpu,
+			mem,
+			fmt.Sprintf("%.1f", row.requestRate),
+			lastError,
+		))
+	}
+	return lines
+}
+
+// renderLog renders the last lines of the selected project's log
+func (m *topModel) renderLog(height int) []string {
+	row, ok := m.selectedRow()
+	if !ok {
+		return nil
+	}
+
+	lines := []string{fmt.Sprintf("Log: %s", row.proc.Host)}
+	logLines := m.logLines
+	if height-1 >= 0 && len(logLines) > height-1 {
+		logLines = logLines[len(logLines)-(height-1):]
+	}
+	for _, line := range logLines {
+		// Control characters from the dev server would corrupt the screen
+		lines = append(lines, stripControl(line))
+	}
+	return lines
+}
+
+// formatBytes formats a byte count with a binary unit suffix
+func formatBytes(n uint64) string {
+	const unit = 1024
+	switch {
+	case n >= unit*unit*unit:
+		return fmt.Sprintf("%.1fG", float64(n)/(unit*unit*unit))
+	case n >= unit*unit:
+		return fmt.Sprintf("%.0fM", float64(n)/(unit*unit))
+	case n >= unit:
+		return fmt.Sprintf("%.0fK", float64(n)/unit)
+	default:
+		return fmt.Sprintf("%dB", n)
+	}
+}
+
+// formatAge formats a duration coarsely, e.g. "5s", "3m" or "2h"
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+// truncate shortens s to at most width bytes
+func truncate(s string, width int) string {
+	if width >= 0 && len(s) > width {
+		return s[:width]
+	}
+	return s
+}
+
+// padRight pads s with spaces to width bytes
+func padRight(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
+
+// stripControl expands tabs and removes control characters and ANSI escape
+// sequences, which would otherwise corrupt the screen
+func stripControl(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == 0x1b && i+1 < len(s) && s[i+1] == '[':
+			// Skip a CSI sequence up to and including its final byte
+			i += 2
+			for i < len(s) && (s[i] < 0x40 || s[i] > 0x7e) {
+				i++
+			}
+		case c == '\t':
+			b.WriteString("    ")
+		case c < 0x20 || c == 0x7f:
+			// dropped
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// parseKeys converts raw terminal input into key names
+func parseKeys(input []byte) []string {
+	var keys []string
+	for i := 0; i < len(input); i++ {
+		switch c := input[i]; {
+		case c == 0x1b:
+			// Arrow keys arrive as ESC [ A/B (or ESC O A/B in application mode)
+			if i+2 < len(input) && (input[i+1] == '[' || input[i+1] == 'O') {
+				switch input[i+2] {
+				case 'A':
+					keys = append(keys, "up")
+				case 'B':
+					keys = append(keys, "down")
+				}
+				i += 2
+				continue
+			}
+			keys = append(keys, "esc")
+		case c == 3:
+			keys = append(keys, "ctrl-c")
+		case c == '\r' || c == '\n':
+			keys = append(keys, "enter")
+		case c >= 0x20 && c < 0x7f:
+			keys = append(keys, string(c))
+		}
+	}
+	return keys
+}
+
+// readKeys sends key names read from r until it fails
+func readKeys(r io.Reader, keys chan<- string) {
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			keys <- key
+		}
+	}
+}
+
+func handleTop(args []string) int {
+	if len(args) > 0 {
+		printError("Unknown option: %s", args[0])
+		return ExitUsage
+	}
+
+	stdinFd := int(os.Stdin.Fd())
+	stdoutFd := int(os.Stdout.Fd())
+	if !term.IsTerminal(stdinFd) || !term.IsTerminal(stdoutFd) {
+		printError("faa top needs an interactive terminal; use 'faa status --watch' instead")
+		return ExitUsage
+	}
+
+	client, err := daemon.Connect()
+	if err != nil {
+		printError("Daemon is not running. Start it with: faa daemon")
+		return ExitDaemonNotRunning
+	}
+	defer client.Close()
+
+	// Redraw immediately on daemon events instead of waiting for the next tick
+	changes := make(chan struct{}, 1)
+	if sub, err := daemon.Subscribe(); err == nil {
+		defer sub.Close()
+		go func() {
+			for {
+				if _, err := sub.Next(); err != nil {
+					return
+				}
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+
+	oldState, err := term.MakeRaw(stdinFd)
+	if err != nil {
+		printError("Failed to configure terminal: %v", err)
+		return ExitError
+	}
+	defer func() { _ = term.Restore(stdinFd, oldState) }()
+
+	// Use the alternate screen and hide the cursor; undo both on exit
+	fmt.Print("\033[?1049h\033[?25l")
+	defer fmt.Print("\033[?25h\033[?1049l")
+
+	keys := make(chan string, 16)
+	go readKeys(os.Stdin, keys)
+
+	resize := make(chan os.Signal, 1)
+	signal.Notify(resize, syscall.SIGWINCH)
+	defer signal.Stop(resize)
+
+	ticker := time.NewTicker(topRefreshInterval)
+	defer ticker.Stop()
+
+	var sampler *procstat.Sampler
+	if procstat.Supported() {
+		sampler = procstat.NewSampler()
+	}
+
+	model := newTopModel()
+	refresh := func() error {
+		status, err := client.Status()
+		if err != nil {
+			return err
+		}
+		stats, err := client.RequestStats()
+		if err != nil {
+			return err
+		}
+		model.update(status, stats, sampler, time.Now())
+
+		if row, ok := model.selectedRow(); ok && model.showLog {
+			tail, err := client.TailLog(row.proc.Host, topLogLines)
+			if err != nil {
+				model.message = fmt.Sprintf("Failed to read log: %v", err)
+			} else {
+				model.logLines = tail.Lines
+			}
+		}
+		return nil
+	}
+	draw := func() {
+		width, height, err := term.GetSize(stdoutFd)
+		if err != nil {
+			width, height = 80, 24
+		}
+		var b strings.Builder
+		b.WriteString("\033[H")
+		for i, line := range model.render(width, height) {
+			if i > 0 {
+				b.WriteString("\r\n")
+			}
+			b.WriteString(line)
+			b.WriteString("\033[K")
+		}
+		b.WriteString("\033[J")
+		fmt.Print(b.String())
+	}
+
+	if err := refresh(); err != nil {
+		model.message = fmt.Sprintf("Failed to get status: %v", err)
+	}
+	draw()
+
+	for {
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				return ExitSuccess
+			}
+			model.message = ""
+			action := model.handleKey(key)
+			row, hasRow := model.selectedRow()
+			switch {
+			case action == topQuit:
+				return ExitSuccess
+			case action == topNone:
+			case !hasRow:
+				model.message = "No project selected"
+			case action == topRestart:
+				if err := client.RestartProcess(row.proc.ProjectRoot); err != nil {
+					model.message = fmt.Sprintf("Failed to restart: %v", err)
+				} else {
+					model.message = fmt.Sprintf("Restart requested for %s", row.proc.Host)
+				}
+			case action == topStop:
+				if err := client.StopProcess(row.proc.ProjectRoot); err != nil {
+					model.message = fmt.Sprintf("Failed to stop: %v", err)
+				} else {
+					model.message = fmt.Sprintf("Stopping %s", row.proc.Host)
+				}
+			case action == topOpen:
+				url := siteURL(row.proc.Host)
+				if err := openBrowser(url); err != nil {
+					model.message = fmt.Sprintf("Failed to open %s: %v", url, err)
+				} else {
+					model.message = fmt.Sprintf("Opened %s", url)
+				}
+			}
+			if err := refresh(); err != nil {
+				model.message = fmt.Sprintf("Lost connection to daemon: %v", err)
+			}
+		case <-changes:
+			if err := refresh(); err != nil {
+				model.message = fmt.Sprintf("Lost connection to daemon: %v", err)
+			}
+		case <-ticker.C:
+			if err := refresh(); err != nil {
+				model.message = fmt.Sprintf("Lost connection to daemon: %v", err)
+			}
+		case <-resize:
+		}
+		draw()
+	}
+}
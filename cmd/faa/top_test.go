@@ -0,0 +1,219 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sahithyandev/faa/internal/daemon"
+	"github.com/sahithyandev/faa/internal/proxy"
+)
+
+// testTopStatus returns a status with two processes, one of them ready
+func testTopStatus() *daemon.StatusResponseData {
+	return &daemon.StatusResponseData{
+		Processes: []*daemon.Process{
+			{ProjectRoot: "/work/web", PID: 200, Host: "web.localhost", Port: 20000},
+			{ProjectRoot: "/work/api", PID: 100, Host: "api.localhost", Port: 10000},
+		},
+		Ready: map[string]bool{"api.localhost": true},
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"q", []string{"q"}},
+		{"\x1b[A\x1b[B", []string{"up", "down"}},
+		{"\x1bOA", []string{"up"}},
+		{"\x1b", []string{"esc"}},
+		{"\r", []string{"enter"}},
+		{"\x03", []string{"ctrl-c"}},
+		{"jk", []string{"j", "k"}},
+	}
+
+	for _, tt := range tests {
+		got := parseKeys([]byte(tt.input))
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("parseKeys(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestTopModelUpdate(t *testing.T) {
+	model := newTopModel()
+	start := time.Now()
+
+	model.update(testTopStatus(), map[string]proxy.HostStats{
+		"api.localhost": {Total: 10},
+	}, nil, start)
+
+	if len(model.rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(model.rows))
+	}
+	// Rows are sorted by project root
+	if model.rows[0].proc.Host != "api.localhost" {
+		t.Errorf("First row = %s, want api.localhost", model.rows[0].proc.Host)
+	}
+	if !model.rows[0].ready || model.rows[1].ready {
+		t.Error("Readiness not taken from status")
+	}
+	if model.rows[0].requestRate != 0 {
+		t.Errorf("First refresh should have no rate, got %f", model.rows[0].requestRate)
+	}
+
+	lastError := &proxy.RequestRecord{Status: 502, Error: "connection refused", Time: start}
+	model.update(testTopStatus(), map[string]proxy.HostStats{
+		"api.localhost": {Total: 30, Errors: 1, LastError: lastError},
+	}, nil, start.Add(2*time.Second))
+
+	if model.rows[0].requestRate != 10 {
+		t.Errorf("requestRate = %f, want 10", model.rows[0].requestRate)
+	}
+	if model.rows[0].lastError != lastError {
+		t.Error("lastError not taken from stats")
+	}
+}
+
+func TestTopModelKeepsSelection(t *testing.T) {
+	model := newTopModel()
+	model.update(testTopStatus(), nil, nil, time.Now())
+
+	model.handleKey("down")
+	if row, _ := model.selectedRow(); row.proc.Host != "web.localhost" {
+		t.Fatalf("Selected %s, want web.localhost", row.proc.Host)
+	}
+
+	// A new project sorting before the selected one must not move the selection
+	status := testTopStatus()
+	status.Processes = append(status.Processes, &daemon.Process{ProjectRoot: "/work/aaa", PID: 300, Host: "aaa.localhost", Port: 30000})
+	model.update(status, nil, nil, time.Now())
+
+	if row, _ := model.selectedRow(); row.proc.Host != "web.localhost" {
+		t.Errorf("Selected %s after update, want web.localhost", row.proc.Host)
+	}
+
+	// Selection is clamped at the ends
+	for i := 0; i < 5; i++ {
+		model.handleKey("down")
+	}
+	if model.selected != 2 {
+		t.Errorf("selected = %d, want 2", model.selected)
+	}
+}
+
+func TestTopModelHandleKey(t *testing.T) {
+	model := newTopModel()
+	model.update(testTopStatus(), nil, nil, time.Now())
+
+	if action := model.handleKey("r"); action != topRestart {
+		t.Errorf("r = %v, want topRestart", action)
+	}
+	if action := model.handleKey("s"); action != topStop {
+		t.Errorf("s = %v, want topStop", action)
+	}
+	if action := model.handleKey("o"); action != topOpen {
+		t.Errorf("o = %v, want topOpen", action)
+	}
+
+	model.handleKey("l")
+	if !model.showLog {
+		t.Fatal("l should open the log view")
+	}
+	// q leaves the log view before quitting
+	if action := model.handleKey("q"); action != topNone || model.showLog {
+		t.Error("q in the log view should return to the table")
+	}
+	if action := model.handleKey("q"); action != topQuit {
+		t.Errorf("q = %v, want topQuit", action)
+	}
+	if action := model.handleKey("ctrl-c"); action != topQuit {
+		t.Errorf("ctrl-c = %v, want topQuit", action)
+	}
+}
+
+func TestTopModelRender(t *testing.T) {
+	model := newTopModel()
+	now := time.Now()
+	model.update(testTopStatus(), map[string]proxy.HostStats{
+		"web.localhost": {Total: 1, Errors: 1, LastError: &proxy.RequestRecord{
+			Status: 502,
+			Error:  "connection refused",
+			Time:   now.Add(-3 * time.Minute),
+		}},
+	}, nil, now)
+
+	lines := model.render(120, 10)
+	if len(lines) != 10 {
+		t.Fatalf("Expected 10 lines, got %d", len(lines))
+	}
+
+	screen := strings.Join(lines, "\n")
+	for _, want := range []string{"HOST", "api.localhost", "ready", "starting", "502 connection refused (3m ago)", "q quit"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("Screen does not contain %q:\n%s", want, screen)
+		}
+	}
+
+	// The selected row is highlighted
+	if !strings.HasPrefix(lines[3], "\033[7m") || !strings.Contains(lines[3], "api.localhost") {
+		t.Errorf("Selected row not highlighted: %q", lines[3])
+	}
+
+	// Lines are cut to the terminal width
+	for _, line := range model.render(20, 10) {
+		visible := strings.TrimSuffix(strings.TrimPrefix(line, "\033[7m"), "\033[0m")
+		if len(visible) > 20 {
+			t.Errorf("Line wider than terminal: %q", line)
+		}
+	}
+}
+
+func TestTopModelRenderLog(t *testing.T) {
+	model := newTopModel()
+	model.update(testTopStatus(), nil, nil, time.Now())
+	model.handleKey("l")
+	model.logLines = []string{"one", "two", "\x1b[31mthree\x1b[0m"}
+
+	lines := model.render(80, 6)
+	screen := strings.Join(lines, "\n")
+	if !strings.Contains(screen, "Log: api.localhost") {
+		t.Errorf("Log view missing title:\n%s", screen)
+	}
+	if strings.Contains(screen, "one") {
+		t.Errorf("Log view should only show lines that fit:\n%s", screen)
+	}
+	if strings.Contains(screen, "[31m") || !strings.Contains(screen, "three") {
+		t.Errorf("Escape sequences from the log must be stripped:\n%q", screen)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[uint64]string{
+		512:                    "512B",
+		2048:                   "2K",
+		150 * 1024 * 1024:      "150M",
+		3 * 1024 * 1024 * 1024: "3.0G",
+	}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	tests := map[time.Duration]string{
+		5 * time.Second: "5s",
+		3 * time.Minute: "3m",
+		2 * time.Hour:   "2h",
+		50 * time.Hour:  "2d",
+	}
+	for d, want := range tests {
+		if got := formatAge(d); got != want {
+			t.Errorf("formatAge(%v) = %s, want %s", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package certexport encodes certificates and private keys in the formats
+// that devices and runtimes other than browsers import: DER, PKCS #12,
+// Java KeyStore and Apple configuration profiles.
+//
+// The encodings favour compatibility over strength, since their password
+// only guards files on a developer's machine: PKCS #12 files use the
+// SHA-1 and 3DES algorithms every version of iOS, Android, macOS, Windows
+// and Java reads.
+package certexport
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// DefaultPassword protects exported key stores unless another is given.
+// It is the password Java's own trust store uses.
+const DefaultPassword = "changeit"
+
+// ParseCertificates returns the certificates in PEM data, in order
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM certificate found")
+	}
+	return certs, nil
+}
+
+// ParsePrivateKey returns the first private key in PEM data, which may be
+// PKCS #8, PKCS #1 or SEC 1 encoded
+func ParsePrivateKey(data []byte) (crypto.PrivateKey, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no PEM private key found")
+		}
+
+		switch block.Type {
+		case "PRIVATE KEY":
+			return x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			return x509.ParseECPrivateKey(block.Bytes)
+		}
+	}
+}
+
+// encodeBMPString encodes a password as PKCS #12 does (RFC 7292,
+// appendix B.1): UTF-16 big-endian with two trailing zero bytes
+func encodeBMPString(s string) ([]byte, error) {
+	for _, r := range s {
+		if r > 0xFFFF {
+			return nil, fmt.Errorf("password character %q is outside the Basic Multilingual Plane", r)
+		}
+	}
+	return append(utf16BE(s), 0, 0), nil
+}
+
+// utf16BE encodes s as UTF-16 big-endian, the byte order Java and ASN.1
+// BMPStrings use
+func utf16BE(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	encoded := make([]byte, 0, 2*len(units))
+	for _, unit := range units {
+		encoded = append(encoded, byte(unit>>8), byte(unit))
+	}
+	return encoded
+}
@@ -0,0 +1,136 @@
+package certexport
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// samePublicKey reports whether key is the private key of signer
+func samePublicKey(signer crypto.Signer, key crypto.PrivateKey) bool {
+	other, ok := key.(crypto.Signer)
+	if !ok {
+		return false
+	}
+	return signer.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(other.Public())
+}
+
+// testChain returns a leaf key and its chain, leaf first, issued by a
+// self-signed CA
+func testChain(t *testing.T) (crypto.Signer, []*x509.Certificate) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "faa test root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "app.localhost"},
+		DNSNames:     []string{"app.localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Failed to create leaf certificate: %v", err)
+	}
+	leaf, _ := x509.ParseCertificate(leafDER)
+	return key, []*x509.Certificate{leaf, ca}
+}
+
+func TestParseCertificates(t *testing.T) {
+	_, chain := testChain(t)
+	var data []byte
+	for _, cert := range chain {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("skipped")})...)
+
+	certs, err := ParseCertificates(data)
+	if err != nil {
+		t.Fatalf("ParseCertificates() failed: %v", err)
+	}
+	if len(certs) != 2 || !certs[0].Equal(chain[0]) || !certs[1].Equal(chain[1]) {
+		t.Errorf("ParseCertificates() = %d certificates, want the chain in order", len(certs))
+	}
+
+	if _, err := ParseCertificates([]byte("not PEM")); err == nil {
+		t.Error("Expected an error without certificates")
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+	sec1, _ := x509.MarshalECPrivateKey(ecKey)
+
+	tests := []struct {
+		name  string
+		block *pem.Block
+		key   crypto.Signer
+	}{
+		{"PKCS #8", &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}, ecKey},
+		{"SEC 1", &pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}, ecKey},
+		{"PKCS #1", &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}, rsaKey},
+	}
+	for _, tt := range tests {
+		// Certificates before the key are skipped
+		data := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("x")}), pem.EncodeToMemory(tt.block)...)
+		key, err := ParsePrivateKey(data)
+		if err != nil {
+			t.Errorf("ParsePrivateKey() for %s failed: %v", tt.name, err)
+			continue
+		}
+		if !samePublicKey(tt.key, key) {
+			t.Errorf("ParsePrivateKey() for %s returned another key", tt.name)
+		}
+	}
+
+	if _, err := ParsePrivateKey([]byte("not PEM")); err == nil {
+		t.Error("Expected an error without a key")
+	}
+}
+
+func TestEncodeBMPString(t *testing.T) {
+	got, err := encodeBMPString("Beavis")
+	if err != nil {
+		t.Fatalf("encodeBMPString() failed: %v", err)
+	}
+	want := []byte{0, 'B', 0, 'e', 0, 'a', 0, 'v', 0, 'i', 0, 's', 0, 0}
+	if !bytes.Equal(got, want) {
+		t.Errorf("encodeBMPString() = %x, want %x", got, want)
+	}
+
+	if _, err := encodeBMPString("🔑"); err == nil {
+		t.Error("Expected an error for a character outside the BMP")
+	}
+}
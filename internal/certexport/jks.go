@@ -0,0 +1,168 @@
+package certexport
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Java KeyStore layout, as written by the JDK's sun.security.provider.JavaKeyStore
+const (
+	jksMagic             = 0xFEEDFEED
+	jksVersion           = 2
+	jksPrivateKeyEntry   = 1
+	jksTrustedCertEntry  = 2
+	jksCertificateType   = "X.509"
+	jksIntegritySaltWord = "Mighty Aphrodite"
+)
+
+// oidJKSKeyProtector identifies the JDK's proprietary key protection
+var oidJKSKeyProtector = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 42, 2, 17, 1, 1}
+
+// JKS encodes a private key and its certificate chain, leaf first, as a
+// Java KeyStore entry named alias, protected by password. The key has the
+// same password as the store, as keytool expects.
+func JKS(key crypto.PrivateKey, chain []*x509.Certificate, alias, password string, created time.Time) ([]byte, error) {
+	if len(chain) == 0 {
+		return nil, errors.New("no certificate for the key")
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	protected, err := protectJKSKey(pkcs8, password)
+	if err != nil {
+		return nil, err
+	}
+
+	w := newJKSWriter(1)
+	w.writeInt(jksPrivateKeyEntry)
+	if err := w.writeEntryHeader(alias, created); err != nil {
+		return nil, err
+	}
+	w.writeBytes(protected)
+	w.writeInt(uint32(len(chain)))
+	for _, cert := range chain {
+		w.writeCertificate(cert)
+	}
+	return w.finish(password), nil
+}
+
+// JKSTrustStore encodes cert as a trusted certificate entry named alias in
+// a Java KeyStore protected by password
+func JKSTrustStore(cert *x509.Certificate, alias, password string, created time.Time) ([]byte, error) {
+	w := newJKSWriter(1)
+	w.writeInt(jksTrustedCertEntry)
+	if err := w.writeEntryHeader(alias, created); err != nil {
+		return nil, err
+	}
+	w.writeCertificate(cert)
+	return w.finish(password), nil
+}
+
+// jksWriter writes the big-endian layout of java.io.DataOutputStream
+type jksWriter struct {
+	buf bytes.Buffer
+}
+
+func newJKSWriter(entries int) *jksWriter {
+	w := &jksWriter{}
+	w.writeInt(jksMagic)
+	w.writeInt(jksVersion)
+	w.writeInt(uint32(entries))
+	return w
+}
+
+func (w *jksWriter) writeInt(v uint32) {
+	w.buf.Write(binary.BigEndian.AppendUint32(nil, v))
+}
+
+// writeBytes writes b preceded by its length
+func (w *jksWriter) writeBytes(b []byte) {
+	w.writeInt(uint32(len(b)))
+	w.buf.Write(b)
+}
+
+// writeUTF writes s like DataOutputStream.writeUTF, which for ASCII is a
+// 16-bit length and the bytes
+func (w *jksWriter) writeUTF(s string) {
+	w.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(len(s))))
+	w.buf.WriteString(s)
+}
+
+// writeEntryHeader writes an entry's alias and creation time. The JDK
+// looks aliases up in lower case.
+func (w *jksWriter) writeEntryHeader(alias string, created time.Time) error {
+	for _, r := range alias {
+		if r < 0x20 || r > 0x7E {
+			return fmt.Errorf("alias %q must be printable ASCII", alias)
+		}
+	}
+	w.writeUTF(strings.ToLower(alias))
+	w.buf.Write(binary.BigEndian.AppendUint64(nil, uint64(created.UnixMilli())))
+	return nil
+}
+
+func (w *jksWriter) writeCertificate(cert *x509.Certificate) {
+	w.writeUTF(jksCertificateType)
+	w.writeBytes(cert.Raw)
+}
+
+// finish appends the store's integrity check and returns its bytes
+func (w *jksWriter) finish(password string) []byte {
+	h := sha1.New()
+	h.Write(utf16BE(password))
+	h.Write([]byte(jksIntegritySaltWord))
+	h.Write(w.buf.Bytes())
+	return h.Sum(w.buf.Bytes())
+}
+
+// protectJKSKey encrypts a PKCS #8 key the way the JDK's KeyProtector does:
+// XORed with a SHA-1 keystream seeded by a random salt, followed by a
+// SHA-1 check of the password and key
+func protectJKSKey(pkcs8 []byte, password string) ([]byte, error) {
+	passwordBytes := utf16BE(password)
+
+	salt := make([]byte, sha1.Size)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	protected := append([]byte{}, salt...)
+	digest := salt
+	for offset := 0; offset < len(pkcs8); offset += sha1.Size {
+		h := sha1.New()
+		h.Write(passwordBytes)
+		h.Write(digest)
+		digest = h.Sum(nil)
+		for i := 0; i < sha1.Size && offset+i < len(pkcs8); i++ {
+			protected = append(protected, pkcs8[offset+i]^digest[i])
+		}
+	}
+
+	check := sha1.New()
+	check.Write(passwordBytes)
+	check.Write(pkcs8)
+	protected = check.Sum(protected)
+
+	encoded, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidJKSKeyProtector,
+			Parameters: asn1.NullRawValue,
+		},
+		EncryptedData: protected,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode protected key: %w", err)
+	}
+	return encoded, nil
+}
@@ -0,0 +1,175 @@
+package certexport
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// jksReader reads the fields jksWriter writes
+type jksReader struct {
+	t    *testing.T
+	data []byte
+}
+
+func (r *jksReader) int() uint32 {
+	r.t.Helper()
+	if len(r.data) < 4 {
+		r.t.Fatal("key store is truncated")
+	}
+	v := binary.BigEndian.Uint32(r.data)
+	r.data = r.data[4:]
+	return v
+}
+
+func (r *jksReader) utf() string {
+	r.t.Helper()
+	n := int(binary.BigEndian.Uint16(r.data))
+	s := string(r.data[2 : 2+n])
+	r.data = r.data[2+n:]
+	return s
+}
+
+func (r *jksReader) bytes() []byte {
+	r.t.Helper()
+	n := int(r.int())
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *jksReader) time() time.Time {
+	r.t.Helper()
+	ms := binary.BigEndian.Uint64(r.data)
+	r.data = r.data[8:]
+	return time.UnixMilli(int64(ms))
+}
+
+func (r *jksReader) certificate() *x509.Certificate {
+	r.t.Helper()
+	if certType := r.utf(); certType != "X.509" {
+		r.t.Fatalf("certificate type = %q, want X.509", certType)
+	}
+	cert, err := x509.ParseCertificate(r.bytes())
+	if err != nil {
+		r.t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return cert
+}
+
+// readJKS checks a key store's header and integrity and returns a reader
+// for its single entry
+func readJKS(t *testing.T, data []byte, password string) *jksReader {
+	t.Helper()
+	body, digest := data[:len(data)-sha1.Size], data[len(data)-sha1.Size:]
+	h := sha1.New()
+	h.Write(utf16BE(password))
+	h.Write([]byte("Mighty Aphrodite"))
+	h.Write(body)
+	if !bytes.Equal(h.Sum(nil), digest) {
+		t.Error("integrity check doesn't match")
+	}
+
+	r := &jksReader{t: t, data: body}
+	if magic, version, count := r.int(), r.int(), r.int(); magic != 0xFEEDFEED || version != 2 || count != 1 {
+		t.Fatalf("header = %x, %d, %d entries, want a version 2 JKS with 1 entry", magic, version, count)
+	}
+	return r
+}
+
+func TestJKSTrustStore(t *testing.T) {
+	_, chain := testChain(t)
+	created := time.UnixMilli(1767225600123)
+	data, err := JKSTrustStore(chain[1], "FAA-Root-CA", DefaultPassword, created)
+	if err != nil {
+		t.Fatalf("JKSTrustStore() failed: %v", err)
+	}
+
+	r := readJKS(t, data, DefaultPassword)
+	if tag := r.int(); tag != 2 {
+		t.Errorf("entry tag = %d, want a trusted certificate", tag)
+	}
+	if alias := r.utf(); alias != "faa-root-ca" {
+		t.Errorf("alias = %q, want it in lower case", alias)
+	}
+	if got := r.time(); !got.Equal(created) {
+		t.Errorf("created = %v, want %v", got, created)
+	}
+	if cert := r.certificate(); !cert.Equal(chain[1]) {
+		t.Error("entry holds another certificate")
+	}
+	if len(r.data) != 0 {
+		t.Errorf("%d bytes left after the entry", len(r.data))
+	}
+
+	if _, err := JKSTrustStore(chain[1], "faa\nroot", DefaultPassword, created); err == nil {
+		t.Error("Expected an error for an alias with control characters")
+	}
+}
+
+func TestJKS(t *testing.T) {
+	key, chain := testChain(t)
+	data, err := JKS(key, chain, "app.localhost", "s3cret", time.Now())
+	if err != nil {
+		t.Fatalf("JKS() failed: %v", err)
+	}
+
+	r := readJKS(t, data, "s3cret")
+	if tag := r.int(); tag != 1 {
+		t.Errorf("entry tag = %d, want a private key", tag)
+	}
+	r.utf()
+	r.time()
+
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(r.bytes(), &info); err != nil {
+		t.Fatalf("Failed to parse protected key: %v", err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidJKSKeyProtector) {
+		t.Errorf("key algorithm = %v, want the JDK key protector", info.Algorithm.Algorithm)
+	}
+
+	// Undo the key protector: salt, XORed key, check digest
+	protected := info.EncryptedData
+	salt := protected[:sha1.Size]
+	encrypted := protected[sha1.Size : len(protected)-sha1.Size]
+	check := protected[len(protected)-sha1.Size:]
+	password := utf16BE("s3cret")
+	plain := make([]byte, len(encrypted))
+	digest := salt
+	for offset := 0; offset < len(encrypted); offset += sha1.Size {
+		h := sha1.New()
+		h.Write(password)
+		h.Write(digest)
+		digest = h.Sum(nil)
+		for i := 0; i < sha1.Size && offset+i < len(encrypted); i++ {
+			plain[offset+i] = encrypted[offset+i] ^ digest[i]
+		}
+	}
+	h := sha1.New()
+	h.Write(password)
+	h.Write(plain)
+	if !bytes.Equal(h.Sum(nil), check) {
+		t.Error("key check digest doesn't match")
+	}
+	decoded, err := x509.ParsePKCS8PrivateKey(plain)
+	if err != nil {
+		t.Fatalf("Failed to parse unprotected key: %v", err)
+	}
+	if !samePublicKey(key, decoded) {
+		t.Error("entry holds another key")
+	}
+
+	if count := r.int(); count != uint32(len(chain)) {
+		t.Fatalf("chain length = %d, want %d", count, len(chain))
+	}
+	for i := range chain {
+		if cert := r.certificate(); !cert.Equal(chain[i]) {
+			t.Errorf("chain certificate %d differs", i)
+		}
+	}
+}
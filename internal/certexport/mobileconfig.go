@@ -0,0 +1,78 @@
+package certexport
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// profileIdentifier prefixes the identifiers of profiles faa creates
+const profileIdentifier = "com.github.sahithyandev.faa"
+
+// MobileConfig returns an Apple configuration profile that installs cert
+// as a trusted root on iOS, iPadOS and macOS. The profile's identifiers
+// are derived from the certificate, so installing it again replaces the
+// previous copy rather than adding another.
+//
+// iOS only trusts installed roots for TLS once full trust is enabled under
+// Settings > General > About > Certificate Trust Settings.
+func MobileConfig(cert *x509.Certificate, name string) ([]byte, error) {
+	if !cert.IsCA {
+		return nil, fmt.Errorf("%s is not a CA certificate", cert.Subject.CommonName)
+	}
+	sum := sha256.Sum256(cert.Raw)
+	fingerprint := fmt.Sprintf("%x", sum[:8])
+	body := base64.StdEncoding.EncodeToString(cert.Raw)
+
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	b.WriteString(`<plist version="1.0">` + "\n<dict>\n")
+	b.WriteString("\t<key>PayloadContent</key>\n\t<array>\n\t\t<dict>\n")
+	writePlistString(&b, 3, "PayloadCertificateFileName", "faa-root-ca.cer")
+	b.WriteString("\t\t\t<key>PayloadContent</key>\n\t\t\t<data>\n")
+	for len(body) > 0 {
+		n := min(len(body), 64)
+		fmt.Fprintf(&b, "\t\t\t%s\n", body[:n])
+		body = body[n:]
+	}
+	b.WriteString("\t\t\t</data>\n")
+	writePlistString(&b, 3, "PayloadDescription", "Adds a CA root certificate")
+	writePlistString(&b, 3, "PayloadDisplayName", cert.Subject.CommonName)
+	writePlistString(&b, 3, "PayloadIdentifier", profileIdentifier+".root."+fingerprint)
+	writePlistString(&b, 3, "PayloadType", "com.apple.security.root")
+	writePlistString(&b, 3, "PayloadUUID", profileUUID(sum[:], "root"))
+	b.WriteString("\t\t\t<key>PayloadVersion</key>\n\t\t\t<integer>1</integer>\n")
+	b.WriteString("\t\t</dict>\n\t</array>\n")
+	writePlistString(&b, 1, "PayloadDescription", "Trusts "+cert.Subject.CommonName+" for HTTPS to faa's development sites")
+	writePlistString(&b, 1, "PayloadDisplayName", name)
+	writePlistString(&b, 1, "PayloadIdentifier", profileIdentifier+".ca."+fingerprint)
+	b.WriteString("\t<key>PayloadRemovalDisallowed</key>\n\t<false/>\n")
+	writePlistString(&b, 1, "PayloadType", "Configuration")
+	writePlistString(&b, 1, "PayloadUUID", profileUUID(sum[:], "profile"))
+	b.WriteString("\t<key>PayloadVersion</key>\n\t<integer>1</integer>\n")
+	b.WriteString("</dict>\n</plist>\n")
+	return b.Bytes(), nil
+}
+
+// writePlistString writes a key and string value at the given depth
+func writePlistString(b *bytes.Buffer, depth int, key, value string) {
+	indent := strings.Repeat("\t", depth)
+	fmt.Fprintf(b, "%s<key>%s</key>\n%s<string>", indent, key, indent)
+	xml.EscapeText(b, []byte(value))
+	b.WriteString("</string>\n")
+}
+
+// profileUUID returns a name-based UUID for a payload of the profile for
+// the certificate with the given SHA-256 sum
+func profileUUID(certSum []byte, payload string) string {
+	sum := sha256.Sum256(append(append([]byte{}, certSum...), payload...))
+	u := sum[:16]
+	u[6] = u[6]&0x0F | 0x50 // version 5 layout, with SHA-256 in place of SHA-1
+	u[8] = u[8]&0x3F | 0x80 // RFC 4122 variant
+	return strings.ToUpper(fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16]))
+}
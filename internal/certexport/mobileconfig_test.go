@@ -0,0 +1,64 @@
+package certexport
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMobileConfig(t *testing.T) {
+	_, chain := testChain(t)
+	ca := chain[1]
+
+	data, err := MobileConfig(ca, "faa <dev> CA")
+	if err != nil {
+		t.Fatalf("MobileConfig() failed: %v", err)
+	}
+
+	// The profile must be well-formed XML
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+	var plist struct {
+		Dict struct {
+			Keys    []string `xml:"key"`
+			Strings []string `xml:"string"`
+			Array   struct {
+				Dict struct {
+					Data string `xml:"data"`
+				} `xml:"dict"`
+			} `xml:"array"`
+		} `xml:"dict"`
+	}
+	if err := decoder.Decode(&plist); err != nil {
+		t.Fatalf("profile isn't valid XML: %v\n%s", err, data)
+	}
+
+	certData, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(plist.Dict.Array.Dict.Data), ""))
+	if err != nil || !bytes.Equal(certData, ca.Raw) {
+		t.Errorf("payload holds another certificate, err %v", err)
+	}
+	if !strings.Contains(string(data), "<string>com.apple.security.root</string>") {
+		t.Error("Expected a root certificate payload")
+	}
+	if !strings.Contains(strings.Join(plist.Dict.Strings, "\n"), "faa <dev> CA") {
+		t.Errorf("Expected the escaped display name, got %q", plist.Dict.Strings)
+	}
+
+	// The same certificate gives the same identifiers, so reinstalling
+	// replaces the profile
+	again, _ := MobileConfig(ca, "faa <dev> CA")
+	if !bytes.Equal(data, again) {
+		t.Error("Expected the same profile for the same certificate")
+	}
+	uuid := regexp.MustCompile(`[0-9A-F]{8}-[0-9A-F]{4}-5[0-9A-F]{3}-[89AB][0-9A-F]{3}-[0-9A-F]{12}`)
+	if uuids := uuid.FindAllString(string(data), -1); len(uuids) != 2 || uuids[0] == uuids[1] {
+		t.Errorf("Expected two distinct UUIDs, got %q", uuids)
+	}
+
+	if _, err := MobileConfig(chain[0], "leaf"); err == nil {
+		t.Error("Expected an error for a leaf certificate")
+	}
+}
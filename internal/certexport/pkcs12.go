@@ -0,0 +1,356 @@
+package certexport
+
+import (
+	"crypto"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	oidDataContentType     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidPKCS8ShroudedKeyBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidCertTypeX509        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidFriendlyName        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidLocalKeyID          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	oidPBEWithSHA1And3DES  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidSHA1                = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+
+	// Java treats certificates with this attribute as trusted, for the
+	// extended key usages it lists
+	oidJavaTrustedKeyUsage = asn1.ObjectIdentifier{2, 16, 840, 1, 113894, 746875, 1, 1}
+	oidAnyExtendedKeyUsage = asn1.ObjectIdentifier{2, 5, 29, 37, 0}
+)
+
+// pkcs12Iterations is the iteration count for key derivation, OpenSSL's
+// default
+const pkcs12Iterations = 2048
+
+// PFX and its parts as defined in RFC 7292
+type (
+	pfxPDU struct {
+		Version  int
+		AuthSafe contentInfo
+		MacData  macData
+	}
+
+	contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}
+
+	macData struct {
+		Mac        digestInfo
+		MacSalt    []byte
+		Iterations int
+	}
+
+	digestInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		Digest    []byte
+	}
+
+	safeBag struct {
+		ID         asn1.ObjectIdentifier
+		Value      asn1.RawValue
+		Attributes []pkcs12Attribute `asn1:"set,optional"`
+	}
+
+	pkcs12Attribute struct {
+		ID    asn1.ObjectIdentifier
+		Value asn1.RawValue
+	}
+
+	certBag struct {
+		ID   asn1.ObjectIdentifier
+		Data []byte `asn1:"tag:0,explicit"`
+	}
+
+	encryptedPrivateKeyInfo struct {
+		Algorithm     pkix.AlgorithmIdentifier
+		EncryptedData []byte
+	}
+
+	pbeParams struct {
+		Salt       []byte
+		Iterations int
+	}
+)
+
+// PKCS12 encodes a private key and its certificate chain, leaf first, as
+// a PKCS #12 file protected by password. The key and the leaf are named
+// alias.
+func PKCS12(key crypto.PrivateKey, chain []*x509.Certificate, alias, password string) ([]byte, error) {
+	if len(chain) == 0 {
+		return nil, errors.New("no certificate for the key")
+	}
+	encodedPassword, err := encodeBMPString(password)
+	if err != nil {
+		return nil, err
+	}
+
+	// The key and the leaf share a local key ID, which is how importers
+	// pair them up
+	keyID := sha1.Sum(chain[0].Raw)
+	leafAttributes, err := bagAttributes(alias, keyID[:])
+	if err != nil {
+		return nil, err
+	}
+
+	certBags := make([]safeBag, 0, len(chain))
+	for i, cert := range chain {
+		var attributes []pkcs12Attribute
+		if i == 0 {
+			attributes = leafAttributes
+		}
+		bag, err := newCertBag(cert, attributes)
+		if err != nil {
+			return nil, err
+		}
+		certBags = append(certBags, bag)
+	}
+
+	keyBag, err := newShroudedKeyBag(key, encodedPassword, leafAttributes)
+	if err != nil {
+		return nil, err
+	}
+	return encodePFX(encodedPassword, certBags, []safeBag{keyBag})
+}
+
+// PKCS12TrustStore encodes cert as a PKCS #12 trust store protected by
+// password, in which Java trusts it under alias
+func PKCS12TrustStore(cert *x509.Certificate, alias, password string) ([]byte, error) {
+	encodedPassword, err := encodeBMPString(password)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes, err := bagAttributes(alias, nil)
+	if err != nil {
+		return nil, err
+	}
+	trusted, err := newAttribute(oidJavaTrustedKeyUsage, oidAnyExtendedKeyUsage)
+	if err != nil {
+		return nil, err
+	}
+	bag, err := newCertBag(cert, append(attributes, trusted))
+	if err != nil {
+		return nil, err
+	}
+	return encodePFX(encodedPassword, []safeBag{bag})
+}
+
+// encodePFX encodes each list of bags as an unencrypted SafeContents, and
+// MACs the result. Keys are encrypted in their own bags.
+func encodePFX(password []byte, contents ...[]safeBag) ([]byte, error) {
+	authenticatedSafe := make([]contentInfo, 0, len(contents))
+	for _, bags := range contents {
+		safeContents, err := asn1.Marshal(bags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode PKCS #12 bags: %w", err)
+		}
+		info, err := newDataContentInfo(safeContents)
+		if err != nil {
+			return nil, err
+		}
+		authenticatedSafe = append(authenticatedSafe, info)
+	}
+	authSafeBytes, err := asn1.Marshal(authenticatedSafe)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode PKCS #12 contents: %w", err)
+	}
+
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	macKey := pbkdf(password, salt, pkcs12Iterations, 3, sha1.Size)
+	mac := hmac.New(sha1.New, macKey)
+	mac.Write(authSafeBytes)
+
+	authSafe, err := newDataContentInfo(authSafeBytes)
+	if err != nil {
+		return nil, err
+	}
+	pfx := pfxPDU{
+		Version:  3,
+		AuthSafe: authSafe,
+		MacData: macData{
+			Mac: digestInfo{
+				Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA1, Parameters: asn1.NullRawValue},
+				Digest:    mac.Sum(nil),
+			},
+			MacSalt:    salt,
+			Iterations: pkcs12Iterations,
+		},
+	}
+	return asn1.Marshal(pfx)
+}
+
+// newDataContentInfo wraps data in a ContentInfo of type data
+func newDataContentInfo(data []byte) (contentInfo, error) {
+	octets, err := asn1.Marshal(data)
+	if err != nil {
+		return contentInfo{}, err
+	}
+	return contentInfo{ContentType: oidDataContentType, Content: explicitTag(octets)}, nil
+}
+
+// newCertBag returns a SafeBag holding cert
+func newCertBag(cert *x509.Certificate, attributes []pkcs12Attribute) (safeBag, error) {
+	bag, err := asn1.Marshal(certBag{ID: oidCertTypeX509, Data: cert.Raw})
+	if err != nil {
+		return safeBag{}, fmt.Errorf("failed to encode certificate bag: %w", err)
+	}
+	return safeBag{ID: oidCertBag, Value: explicitTag(bag), Attributes: attributes}, nil
+}
+
+// newShroudedKeyBag returns a SafeBag holding key encrypted with password
+func newShroudedKeyBag(key crypto.PrivateKey, password []byte, attributes []pkcs12Attribute) (safeBag, error) {
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return safeBag{}, fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return safeBag{}, err
+	}
+	params, err := asn1.Marshal(pbeParams{Salt: salt, Iterations: pkcs12Iterations})
+	if err != nil {
+		return safeBag{}, err
+	}
+
+	encrypted, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidPBEWithSHA1And3DES,
+			Parameters: asn1.RawValue{FullBytes: params},
+		},
+		EncryptedData: encryptPBE(password, salt, pkcs12Iterations, pkcs8),
+	})
+	if err != nil {
+		return safeBag{}, fmt.Errorf("failed to encode encrypted private key: %w", err)
+	}
+	return safeBag{ID: oidPKCS8ShroudedKeyBag, Value: explicitTag(encrypted), Attributes: attributes}, nil
+}
+
+// bagAttributes returns the friendly name and, if set, local key ID
+// attributes of a bag
+func bagAttributes(alias string, keyID []byte) ([]pkcs12Attribute, error) {
+	// friendlyName is a BMPString, which encoding/asn1 can't marshal
+	friendlyName, err := newAttribute(oidFriendlyName, asn1.RawValue{Tag: asn1.TagBMPString, Bytes: utf16BE(alias)})
+	if err != nil {
+		return nil, err
+	}
+	attributes := []pkcs12Attribute{friendlyName}
+
+	if keyID != nil {
+		localKeyID, err := newAttribute(oidLocalKeyID, keyID)
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, localKeyID)
+	}
+	return attributes, nil
+}
+
+// newAttribute returns an attribute with a single value
+func newAttribute(id asn1.ObjectIdentifier, value interface{}) (pkcs12Attribute, error) {
+	encoded, err := asn1.Marshal(value)
+	if err != nil {
+		return pkcs12Attribute{}, fmt.Errorf("failed to encode attribute %v: %w", id, err)
+	}
+	return pkcs12Attribute{
+		ID:    id,
+		Value: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: encoded},
+	}, nil
+}
+
+// explicitTag wraps DER in the [0] EXPLICIT tag PKCS #12 puts contents in
+func explicitTag(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+}
+
+// encryptPBE encrypts data with pbeWithSHAAnd3-KeyTripleDES-CBC
+func encryptPBE(password, salt []byte, iterations int, data []byte) []byte {
+	key := pbkdf(password, salt, iterations, 1, 24)
+	iv := pbkdf(password, salt, iterations, 2, des.BlockSize)
+	block, err := des.NewTripleDESCipher(key)
+	if err != nil {
+		// The key is always 24 bytes
+		panic(err)
+	}
+
+	padding := des.BlockSize - len(data)%des.BlockSize
+	padded := make([]byte, len(data), len(data)+padding)
+	copy(padded, data)
+	for i := 0; i < padding; i++ {
+		padded = append(padded, byte(padding))
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(padded, padded)
+	return padded
+}
+
+// pbkdf derives size bytes from password and salt with the PKCS #12 key
+// derivation function over SHA-1 (RFC 7292, appendix B.2). id is 1 for
+// encryption keys, 2 for IVs and 3 for MAC keys.
+func pbkdf(password, salt []byte, iterations int, id byte, size int) []byte {
+	const v = 64 // SHA-1's block size
+
+	// fill repeats b to a multiple of v bytes
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		filled := make([]byte, v*((len(b)+v-1)/v))
+		for i := range filled {
+			filled[i] = b[i%len(b)]
+		}
+		return filled
+	}
+
+	diversifier := make([]byte, v)
+	for i := range diversifier {
+		diversifier[i] = id
+	}
+	input := append(fill(salt), fill(password)...)
+
+	var derived []byte
+	for {
+		h := sha1.New()
+		h.Write(diversifier)
+		h.Write(input)
+		a := h.Sum(nil)
+		for i := 1; i < iterations; i++ {
+			sum := sha1.Sum(a)
+			a = sum[:]
+		}
+		derived = append(derived, a...)
+		if len(derived) >= size {
+			return derived[:size]
+		}
+
+		// Each v-byte block of the input becomes (block + B + 1) mod 2^(8v),
+		// where B is a repeated to v bytes
+		b := new(big.Int).SetBytes(fill(a))
+		b.Add(b, big.NewInt(1))
+		for j := 0; j < len(input); j += v {
+			block := new(big.Int).SetBytes(input[j : j+v])
+			sum := block.Add(block, b).Bytes()
+			if len(sum) > v {
+				sum = sum[len(sum)-v:]
+			}
+			clear(input[j : j+v])
+			copy(input[j+v-len(sum):j+v], sum)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package certexport
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+func TestPBKDF(t *testing.T) {
+	// Expected values from OpenSSL's PKCS12KDF, given the BMP-encoded
+	// password
+	tests := []struct {
+		password   string
+		salt       string
+		iterations int
+		id         byte
+		expected   string
+	}{
+		{"sesame", "05dec959acff72f7", 2048, 1, "f78a3db17a16de258cb9233c07accf4585863af41030cbc6"},
+		{"sesame", "05dec959acff72f7", 2048, 2, "458c3538bc3e4d61"},
+		{"changeit", "0001020304050607", 1, 3, "0fbbd7e15d82e2a8841885961d18e0e0e6c7835d"},
+		// Longer than one SHA-1 output, which mixes the input between rounds
+		{"changeit", "0001020304050607", 3, 1, "7b59dd5d3fe2515a964debc0d4676662cccf91571f90547f437d8161df9d8e8ff9225e6a87187fdc0dfe566c62b0918248e891adcbbaacb0d0a2e14922869d7203598c60f573"},
+	}
+	for _, tt := range tests {
+		password, _ := encodeBMPString(tt.password)
+		salt, _ := hex.DecodeString(tt.salt)
+		size := len(tt.expected) / 2
+		if got := hex.EncodeToString(pbkdf(password, salt, tt.iterations, tt.id, size)); got != tt.expected {
+			t.Errorf("pbkdf(%q, id %d, %d bytes) = %s, want %s", tt.password, tt.id, size, got, tt.expected)
+		}
+	}
+}
+
+func TestPKCS12(t *testing.T) {
+	key, chain := testChain(t)
+	data, err := PKCS12(key, chain, "app.localhost", "s3cret")
+	if err != nil {
+		t.Fatalf("PKCS12() failed: %v", err)
+	}
+
+	blocks, err := pkcs12.ToPEM(data, "s3cret")
+	if err != nil {
+		t.Fatalf("Failed to decode PKCS #12: %v", err)
+	}
+	var certs int
+	var keyID string
+	for _, block := range blocks {
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil || !cert.Equal(chain[certs]) {
+				t.Errorf("certificate %d isn't the chain's, err %v", certs, err)
+			}
+			if certs == 0 {
+				keyID = block.Headers["localKeyId"]
+				if block.Headers["friendlyName"] != "app.localhost" {
+					t.Errorf("leaf friendlyName = %q, want app.localhost", block.Headers["friendlyName"])
+				}
+			}
+			certs++
+		case "PRIVATE KEY":
+			// ToPEM re-encodes EC keys in SEC 1
+			decoded, err := x509.ParseECPrivateKey(block.Bytes)
+			if err != nil || !samePublicKey(key, decoded) {
+				t.Errorf("Decoded another key, err %v", err)
+			}
+			if block.Headers["localKeyId"] == "" || block.Headers["localKeyId"] != keyID {
+				t.Errorf("key localKeyId = %q, want the leaf's %q", block.Headers["localKeyId"], keyID)
+			}
+		}
+	}
+	if certs != len(chain) {
+		t.Errorf("Decoded %d certificates, want %d", certs, len(chain))
+	}
+
+	// Decode only accepts a single certificate
+	single, err := PKCS12(key, chain[:1], "app.localhost", "s3cret")
+	if err != nil {
+		t.Fatalf("PKCS12() failed: %v", err)
+	}
+	decodedKey, cert, err := pkcs12.Decode(single, "s3cret")
+	if err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if !cert.Equal(chain[0]) || !samePublicKey(key, decodedKey) {
+		t.Error("Decode() returned another key or certificate")
+	}
+
+	if _, err := pkcs12.ToPEM(data, "wrong"); err == nil {
+		t.Error("Expected decoding with the wrong password to fail")
+	}
+	if _, err := PKCS12(key, nil, "app.localhost", "s3cret"); err == nil {
+		t.Error("Expected an error without certificates")
+	}
+}
+
+func TestPKCS12TrustStore(t *testing.T) {
+	_, chain := testChain(t)
+	ca := chain[1]
+	data, err := PKCS12TrustStore(ca, "faa-root-ca", DefaultPassword)
+	if err != nil {
+		t.Fatalf("PKCS12TrustStore() failed: %v", err)
+	}
+
+	var pfx pfxPDU
+	if _, err := asn1.Unmarshal(data, &pfx); err != nil {
+		t.Fatalf("Failed to parse PFX: %v", err)
+	}
+	var authSafe []byte
+	if _, err := asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		t.Fatalf("Failed to parse authenticated safe: %v", err)
+	}
+
+	password, _ := encodeBMPString(DefaultPassword)
+	mac := hmac.New(sha1.New, pbkdf(password, pfx.MacData.MacSalt, pfx.MacData.Iterations, 3, sha1.Size))
+	mac.Write(authSafe)
+	if !hmac.Equal(mac.Sum(nil), pfx.MacData.Mac.Digest) {
+		t.Error("MAC doesn't match the contents")
+	}
+
+	var contents []contentInfo
+	if _, err := asn1.Unmarshal(authSafe, &contents); err != nil || len(contents) != 1 {
+		t.Fatalf("Expected one content info, got %d, err %v", len(contents), err)
+	}
+	var safeContents []byte
+	if _, err := asn1.Unmarshal(contents[0].Content.Bytes, &safeContents); err != nil {
+		t.Fatalf("Failed to parse safe contents: %v", err)
+	}
+	var bags []safeBag
+	if _, err := asn1.Unmarshal(safeContents, &bags); err != nil || len(bags) != 1 {
+		t.Fatalf("Expected one bag, got %d, err %v", len(bags), err)
+	}
+
+	var bag certBag
+	if _, err := asn1.Unmarshal(bags[0].Value.Bytes, &bag); err != nil {
+		t.Fatalf("Failed to parse certificate bag: %v", err)
+	}
+	if cert, err := x509.ParseCertificate(bag.Data); err != nil || !cert.Equal(ca) {
+		t.Errorf("bag holds another certificate, err %v", err)
+	}
+
+	var trusted bool
+	for _, attribute := range bags[0].Attributes {
+		if !attribute.ID.Equal(oidJavaTrustedKeyUsage) {
+			continue
+		}
+		var usage asn1.ObjectIdentifier
+		if _, err := asn1.Unmarshal(attribute.Value.Bytes, &usage); err != nil || !usage.Equal(oidAnyExtendedKeyUsage) {
+			t.Errorf("trusted key usage = %v, err %v, want any", usage, err)
+		}
+		trusted = true
+	}
+	if !trusted {
+		t.Error("Expected the certificate to be marked trusted for Java")
+	}
+
+	if _, err := PKCS12TrustStore(ca, "faa-root-ca", strings.Repeat("🔑", 2)); err == nil {
+		t.Error("Expected an error for a password outside the BMP")
+	}
+}
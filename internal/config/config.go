@@ -0,0 +1,477 @@
+// Package config loads optional user settings from ~/.config/faa/config.json.
+//
+// Every setting has a default, so a missing file is equivalent to an empty
+// one. The file is only read; faa never rewrites it.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultAPIHost is the hostname the management API is served on
+	DefaultAPIHost = "faa.localhost"
+
+	// DefaultAPIListen is the local address the management API listens on.
+	// Port 0 picks a free port; the proxy routes DefaultAPIHost to it.
+	DefaultAPIListen = "127.0.0.1:0"
+
+	// DefaultHTTPPort and DefaultHTTPSPort are the unprivileged ports the
+	// proxy listens on when it doesn't use ports 80 and 443. They are below
+	// the range dev servers are assigned.
+	DefaultHTTPPort  = 9080
+	DefaultHTTPSPort = 9443
+
+	// DefaultDNSListen is where the DNS server for dns.domains listens.
+	// The system resolver is pointed at it, so the port is fixed.
+	DefaultDNSListen = "127.0.0.1:9053"
+)
+
+// Port modes select how the proxy reaches ports 80 and 443
+const (
+	// PortModeStandard listens on ports 80 and 443, which needs
+	// cap_net_bind_service or a lowered ip_unprivileged_port_start on Linux
+	PortModeStandard = "standard"
+
+	// PortModeForward listens on the unprivileged ports, with ports 80 and
+	// 443 forwarded to them by firewall rules that faa setup installs
+	PortModeForward = "forward"
+
+	// PortModePort listens on the unprivileged ports and includes the HTTPS
+	// port in URLs, so it needs no privileges at all
+	PortModePort = "port"
+)
+
+// mDNS modes of MDNSConfig.Mode
+const (
+	// MDNSModeLocal answers mDNS queries from this machine only, with the
+	// loopback addresses
+	MDNSModeLocal = "local"
+
+	// MDNSModeLAN also answers other hosts on the LAN, with the address of
+	// the interface they are on
+	MDNSModeLAN = "lan"
+
+	// MDNSModeOff doesn't publish the .local names
+	MDNSModeOff = "off"
+)
+
+// Config holds all user settings
+type Config struct {
+	API   APIConfig   `json:"api"`
+	Proxy ProxyConfig `json:"proxy"`
+	TLS   TLSConfig   `json:"tls"`
+	DNS   DNSConfig   `json:"dns"`
+	Hosts HostsConfig `json:"hosts"`
+	MDNS  MDNSConfig  `json:"mdns"`
+	Log   LogConfig   `json:"log"`
+}
+
+// MDNSConfig publishes the routes' .local names over multicast DNS
+type MDNSConfig struct {
+	// Mode is one of the MDNSMode constants; unset means MDNSModeLocal
+	Mode string `json:"mode,omitempty"`
+}
+
+// HostsConfig keeps a block of entries for the routed hosts in /etc/hosts,
+// for tools that don't resolve *.localhost themselves
+type HostsConfig struct {
+	// Enabled makes the daemon update the block when routes change, which
+	// needs root or the helper 'faa setup' allows through sudo
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+// DNSConfig runs a DNS server answering for development domains other
+// than .localhost, which every route is also served under
+type DNSConfig struct {
+	// Domains are the domains answered for, such as "test" or
+	// "dev.internal"; the server is off when there are none
+	Domains []string `json:"domains,omitempty"`
+
+	// Listen is the IP address and port the server listens on, over UDP
+	// and TCP
+	Listen string `json:"listen,omitempty"`
+}
+
+// TLSConfig selects where the proxy's certificates come from. By default
+// they are issued by a local CA that Caddy creates.
+type TLSConfig struct {
+	// CA is an existing CA, such as mkcert's root or a corporate
+	// development CA, to issue certificates from instead
+	CA *KeyPair `json:"ca,omitempty"`
+
+	// Certificates are served for the hostnames they are valid for
+	// instead of issuing certificates for them
+	Certificates []KeyPair `json:"certificates,omitempty"`
+
+	// ClientAuth requires or accepts client certificates for some hosts
+	ClientAuth *ClientAuthConfig `json:"clientAuth,omitempty"`
+}
+
+// Client certificate modes of ClientAuthConfig.Hosts
+const (
+	// ClientAuthRequire rejects clients without a certificate from the CA
+	ClientAuthRequire = "require"
+
+	// ClientAuthOptional verifies a certificate if the client has one
+	ClientAuthOptional = "optional"
+)
+
+// ClientAuthConfig sets up mutual TLS for hosts, with client certificates
+// issued by 'faa cert client'
+type ClientAuthConfig struct {
+	// Hosts maps hostnames to a ClientAuth mode. Names without a .local
+	// or .localhost suffix get .localhost, as routes do.
+	Hosts map[string]string `json:"hosts"`
+
+	// Header is the request header the subject of a verified client
+	// certificate is passed to the dev server in; unset means
+	// X-Client-Cert-Subject
+	Header string `json:"header,omitempty"`
+}
+
+// KeyPair names a PEM certificate file and its private key file. Paths
+// must be absolute or start with ~/ for the home directory.
+type KeyPair struct {
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+}
+
+// LogConfig controls the daemon log
+type LogConfig struct {
+	// Level is the lowest level logged: "debug", "info" (the default),
+	// "warn" or "error"
+	Level string `json:"level,omitempty"`
+}
+
+// APIConfig controls the HTTP management API
+type APIConfig struct {
+	// Enabled turns the API on; it is off by default
+	Enabled bool `json:"enabled"`
+
+	// Host is the hostname the proxy serves the API on
+	Host string `json:"host,omitempty"`
+
+	// Listen is the loopback address the API server binds to
+	Listen string `json:"listen,omitempty"`
+}
+
+// ProxyConfig tunes the reverse proxy
+type ProxyConfig struct {
+	// StreamTimeout closes websocket connections after this long; unset
+	// means never
+	StreamTimeout Duration `json:"streamTimeout,omitempty"`
+
+	// FlushInterval is how often buffered responses are flushed to the
+	// client; a negative value flushes after every write. Server-sent
+	// events are always flushed immediately.
+	FlushInterval Duration `json:"flushInterval,omitempty"`
+
+	// Admin is where Caddy's admin endpoint listens, such as
+	// "localhost:2019" or "unix//path/to/admin.sock", or "off" to disable
+	// it. Unset means a Unix socket in the faa config directory.
+	Admin string `json:"admin,omitempty"`
+
+	// PortMode is one of the PortMode constants
+	PortMode string `json:"portMode,omitempty"`
+
+	// HTTPPort and HTTPSPort are where the proxy listens outside the
+	// standard port mode
+	HTTPPort  int `json:"httpPort,omitempty"`
+	HTTPSPort int `json:"httpsPort,omitempty"`
+}
+
+// ListenPorts returns the ports the proxy listens on
+func (p ProxyConfig) ListenPorts() (httpPort, httpsPort int) {
+	if p.PortMode == PortModeStandard {
+		return 80, 443
+	}
+	return p.HTTPPort, p.HTTPSPort
+}
+
+// URLPort returns the HTTPS port clients connect to
+func (p ProxyConfig) URLPort() int {
+	if p.PortMode == PortModePort {
+		return p.HTTPSPort
+	}
+	return 443
+}
+
+// URL returns the URL host is served at
+func (p ProxyConfig) URL(host string) string {
+	if port := p.URLPort(); port != 443 {
+		return "https://" + host + ":" + strconv.Itoa(port)
+	}
+	return "https://" + host
+}
+
+// Duration is a time.Duration written as a string such as "30s" or "1h"
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\"")
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON formats the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Dir returns the faa configuration directory (~/.config/faa)
+func Dir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "faa"), nil
+}
+
+// Path returns the path to config.json
+func Path() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// Default returns a Config with every setting at its default value
+func Default() *Config {
+	cfg := &Config{}
+	cfg.applyDefaults()
+	return cfg
+}
+
+// Load reads config.json, returning defaults if the file does not exist
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return LoadFile(path)
+}
+
+// LoadFile reads the configuration from path, returning defaults if the
+// file does not exist or is empty
+func LoadFile(path string) (*Config, error) {
+	cfg := &Config{}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	if cfg.Proxy.StreamTimeout < 0 {
+		return nil, fmt.Errorf("invalid %s: proxy.streamTimeout must not be negative", path)
+	}
+
+	if cfg.Log.Level != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(cfg.Log.Level)); err != nil {
+			return nil, fmt.Errorf("invalid %s: unknown log.level %q", path, cfg.Log.Level)
+		}
+	}
+
+	if cfg.TLS.CA != nil {
+		if err := cfg.TLS.CA.resolve(); err != nil {
+			return nil, fmt.Errorf("invalid %s: tls.ca: %w", path, err)
+		}
+	}
+	for i := range cfg.TLS.Certificates {
+		if err := cfg.TLS.Certificates[i].resolve(); err != nil {
+			return nil, fmt.Errorf("invalid %s: tls.certificates[%d]: %w", path, i, err)
+		}
+	}
+
+	if cfg.TLS.ClientAuth != nil {
+		if err := cfg.TLS.ClientAuth.resolve(); err != nil {
+			return nil, fmt.Errorf("invalid %s: tls.clientAuth: %w", path, err)
+		}
+	}
+
+	if err := cfg.DNS.resolve(); err != nil {
+		return nil, fmt.Errorf("invalid %s: dns: %w", path, err)
+	}
+
+	cfg.applyDefaults()
+
+	switch cfg.Proxy.PortMode {
+	case PortModeStandard, PortModeForward, PortModePort:
+	default:
+		return nil, fmt.Errorf("invalid %s: proxy.portMode must be %q, %q or %q", path, PortModeStandard, PortModeForward, PortModePort)
+	}
+	for _, port := range []int{cfg.Proxy.HTTPPort, cfg.Proxy.HTTPSPort} {
+		if port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid %s: proxy port %d out of range", path, port)
+		}
+	}
+	if cfg.Proxy.HTTPPort == cfg.Proxy.HTTPSPort {
+		return nil, fmt.Errorf("invalid %s: proxy.httpPort and proxy.httpsPort must differ", path)
+	}
+
+	switch cfg.MDNS.Mode {
+	case MDNSModeLocal, MDNSModeLAN, MDNSModeOff:
+	default:
+		return nil, fmt.Errorf("invalid %s: mdns.mode must be %q, %q or %q", path, MDNSModeLocal, MDNSModeLAN, MDNSModeOff)
+	}
+
+	return cfg, nil
+}
+
+// resolve checks that both paths are set and expands a leading ~/
+func (k *KeyPair) resolve() error {
+	if k.Cert == "" || k.Key == "" {
+		return fmt.Errorf("cert and key must both be set")
+	}
+	for _, path := range []*string{&k.Cert, &k.Key} {
+		if strings.HasPrefix(*path, "~/") {
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				return fmt.Errorf("failed to get home directory: %w", err)
+			}
+			*path = filepath.Join(homeDir, (*path)[2:])
+		}
+		if !filepath.IsAbs(*path) {
+			return fmt.Errorf("%s must be an absolute path", *path)
+		}
+	}
+	return nil
+}
+
+// resolve checks the modes and normalizes the hostnames
+func (c *ClientAuthConfig) resolve() error {
+	hosts := make(map[string]string, len(c.Hosts))
+	for host, mode := range c.Hosts {
+		if mode != ClientAuthRequire && mode != ClientAuthOptional {
+			return fmt.Errorf("%s: mode must be %q or %q", host, ClientAuthRequire, ClientAuthOptional)
+		}
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host == "" {
+			return fmt.Errorf("hostnames must not be empty")
+		}
+		if !strings.HasSuffix(host, ".local") && !strings.HasSuffix(host, ".localhost") {
+			host += ".localhost"
+		}
+		hosts[host] = mode
+	}
+	c.Hosts = hosts
+	if c.Header != "" && !validHeaderName(c.Header) {
+		return fmt.Errorf("invalid header name %q", c.Header)
+	}
+	return nil
+}
+
+// resolve normalizes the domains and checks them and the listen address
+func (c *DNSConfig) resolve() error {
+	seen := make(map[string]bool, len(c.Domains))
+	domains := make([]string, 0, len(c.Domains))
+	for _, domain := range c.Domains {
+		domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if !validDomain(domain) {
+			return fmt.Errorf("invalid domain %q", domain)
+		}
+		// .localhost resolves without help, and .local belongs to mDNS
+		if domain == "localhost" || domain == "local" {
+			return fmt.Errorf("domain %q is always served; list other domains", domain)
+		}
+		if !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+	c.Domains = domains
+
+	if c.Listen == "" {
+		return nil
+	}
+	host, port, err := net.SplitHostPort(c.Listen)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q: %w", c.Listen, err)
+	}
+	if net.ParseIP(host) == nil {
+		return fmt.Errorf("listen address %q must be an IP address", c.Listen)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("listen address %q needs a port from 1 to 65535", c.Listen)
+	}
+	return nil
+}
+
+// validDomain reports whether domain is a valid DNS name of letters,
+// digits and hyphens
+func validDomain(domain string) bool {
+	if domain == "" || len(domain) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// validHeaderName reports whether name is a valid HTTP header field name
+func validHeaderName(name string) bool {
+	for _, r := range name {
+		alphanumeric := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+		if !alphanumeric && !strings.ContainsRune("!#$%&'*+-.^_`|~", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyDefaults fills in unset fields
+func (c *Config) applyDefaults() {
+	if c.API.Host == "" {
+		c.API.Host = DefaultAPIHost
+	}
+	if c.API.Listen == "" {
+		c.API.Listen = DefaultAPIListen
+	}
+	if c.DNS.Listen == "" {
+		c.DNS.Listen = DefaultDNSListen
+	}
+	if c.Proxy.PortMode == "" {
+		c.Proxy.PortMode = PortModeStandard
+	}
+	if c.MDNS.Mode == "" {
+		c.MDNS.Mode = MDNSModeLocal
+	}
+	if c.Proxy.HTTPPort == 0 {
+		c.Proxy.HTTPPort = DefaultHTTPPort
+	}
+	if c.Proxy.HTTPSPort == 0 {
+		c.Proxy.HTTPSPort = DefaultHTTPSPort
+	}
+}
@@ -0,0 +1,347 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadFileMissing(t *testing.T) {
+	cfg, err := LoadFile(filepath.Join(t.TempDir(), "config.json"))
+	if err != nil {
+		t.Fatalf("LoadFile() failed: %v", err)
+	}
+
+	if cfg.API.Enabled {
+		t.Error("API should be disabled by default")
+	}
+	if cfg.API.Host != DefaultAPIHost {
+		t.Errorf("API.Host = %q, want %q", cfg.API.Host, DefaultAPIHost)
+	}
+	if cfg.API.Listen != DefaultAPIListen {
+		t.Errorf("API.Listen = %q, want %q", cfg.API.Listen, DefaultAPIListen)
+	}
+	if cfg.DNS.Listen != DefaultDNSListen || len(cfg.DNS.Domains) != 0 {
+		t.Errorf("DNS = %+v, want no domains on %q", cfg.DNS, DefaultDNSListen)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"api": {"enabled": true, "listen": "127.0.0.1:7070"}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() failed: %v", err)
+	}
+
+	if !cfg.API.Enabled {
+		t.Error("API should be enabled")
+	}
+	if cfg.API.Listen != "127.0.0.1:7070" {
+		t.Errorf("API.Listen = %q, want 127.0.0.1:7070", cfg.API.Listen)
+	}
+	if cfg.API.Host != DefaultAPIHost {
+		t.Errorf("API.Host = %q, want default %q", cfg.API.Host, DefaultAPIHost)
+	}
+}
+
+func TestLoadFileInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{not json`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	if _, err := LoadFile(path); err == nil {
+		t.Error("LoadFile() should fail for invalid JSON")
+	}
+}
+
+func TestLoadFileProxy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"proxy": {"streamTimeout": "2h", "flushInterval": "-1s", "admin": "off"}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() failed: %v", err)
+	}
+	if time.Duration(cfg.Proxy.StreamTimeout) != 2*time.Hour {
+		t.Errorf("Proxy.StreamTimeout = %v, want 2h", time.Duration(cfg.Proxy.StreamTimeout))
+	}
+	if time.Duration(cfg.Proxy.FlushInterval) != -time.Second {
+		t.Errorf("Proxy.FlushInterval = %v, want -1s", time.Duration(cfg.Proxy.FlushInterval))
+	}
+	if cfg.Proxy.Admin != "off" {
+		t.Errorf("Proxy.Admin = %q, want off", cfg.Proxy.Admin)
+	}
+
+	for _, content := range []string{
+		`{"proxy": {"streamTimeout": 30}}`,
+		`{"proxy": {"streamTimeout": "soon"}}`,
+		`{"proxy": {"streamTimeout": "-5s"}}`,
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := LoadFile(path); err == nil {
+			t.Errorf("LoadFile() should fail for %s", content)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	path, err := Path()
+	if err != nil {
+		t.Fatalf("Path() failed: %v", err)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatalf("Failed to get home directory: %v", err)
+	}
+
+	expected := filepath.Join(homeDir, ".config", "faa", "config.json")
+	if path != expected {
+		t.Errorf("Path() = %s, want %s", path, expected)
+	}
+}
+
+func TestLoadFilePortMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() failed: %v", err)
+	}
+	if cfg.Proxy.PortMode != PortModeStandard {
+		t.Errorf("Proxy.PortMode = %q, want %q", cfg.Proxy.PortMode, PortModeStandard)
+	}
+	if httpPort, httpsPort := cfg.Proxy.ListenPorts(); httpPort != 80 || httpsPort != 443 {
+		t.Errorf("ListenPorts() = %d, %d, want 80, 443", httpPort, httpsPort)
+	}
+	if url := cfg.Proxy.URL("app.localhost"); url != "https://app.localhost" {
+		t.Errorf("URL() = %q, want https://app.localhost", url)
+	}
+
+	tests := []struct {
+		content   string
+		httpPort  int
+		httpsPort int
+		url       string
+	}{
+		{`{"proxy": {"portMode": "forward"}}`, DefaultHTTPPort, DefaultHTTPSPort, "https://app.localhost"},
+		{`{"proxy": {"portMode": "port"}}`, DefaultHTTPPort, DefaultHTTPSPort, "https://app.localhost:9443"},
+		{`{"proxy": {"portMode": "port", "httpPort": 7080, "httpsPort": 7443}}`, 7080, 7443, "https://app.localhost:7443"},
+	}
+	for _, tt := range tests {
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		cfg, err := LoadFile(path)
+		if err != nil {
+			t.Fatalf("LoadFile() failed for %s: %v", tt.content, err)
+		}
+		if httpPort, httpsPort := cfg.Proxy.ListenPorts(); httpPort != tt.httpPort || httpsPort != tt.httpsPort {
+			t.Errorf("ListenPorts() = %d, %d for %s, want %d, %d", httpPort, httpsPort, tt.content, tt.httpPort, tt.httpsPort)
+		}
+		if url := cfg.Proxy.URL("app.localhost"); url != tt.url {
+			t.Errorf("URL() = %q for %s, want %q", url, tt.content, tt.url)
+		}
+	}
+
+	for _, content := range []string{
+		`{"proxy": {"portMode": "high"}}`,
+		`{"proxy": {"httpPort": 70000}}`,
+		`{"proxy": {"httpsPort": -1}}`,
+		`{"proxy": {"httpPort": 9443}}`,
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := LoadFile(path); err == nil {
+			t.Errorf("LoadFile() should fail for %s", content)
+		}
+	}
+}
+
+func TestLoadFileLogLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"log": {"level": "debug"}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() failed: %v", err)
+	}
+	if cfg.Log.Level != "debug" {
+		t.Errorf("Log.Level = %q, want debug", cfg.Log.Level)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"log": {"level": "loud"}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Error("LoadFile() should fail for an unknown log level")
+	}
+}
+
+func TestLoadFileTLS(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	content := `{"tls": {
+		"ca": {"cert": "~/mkcert/rootCA.pem", "key": "~/mkcert/rootCA-key.pem"},
+		"certificates": [{"cert": "/etc/dev/app.pem", "key": "/etc/dev/app-key.pem"}]
+	}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() failed: %v", err)
+	}
+	if want := filepath.Join(home, "mkcert", "rootCA.pem"); cfg.TLS.CA == nil || cfg.TLS.CA.Cert != want {
+		t.Errorf("TLS.CA = %+v, want cert %s", cfg.TLS.CA, want)
+	}
+	if len(cfg.TLS.Certificates) != 1 || cfg.TLS.Certificates[0].Key != "/etc/dev/app-key.pem" {
+		t.Errorf("TLS.Certificates = %+v", cfg.TLS.Certificates)
+	}
+
+	for _, content := range []string{
+		`{"tls": {"ca": {"cert": "/etc/dev/ca.pem"}}}`,
+		`{"tls": {"ca": {"cert": "ca.pem", "key": "ca-key.pem"}}}`,
+		`{"tls": {"certificates": [{"key": "/etc/dev/app-key.pem"}]}}`,
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := LoadFile(path); err == nil {
+			t.Errorf("LoadFile() should fail for %s", content)
+		}
+	}
+}
+
+func TestLoadFileClientAuth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	content := `{"tls": {"clientAuth": {
+		"hosts": {"Admin": "require", "api.local": "optional"},
+		"header": "X-Client"
+	}}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() failed: %v", err)
+	}
+	want := map[string]string{"admin.localhost": ClientAuthRequire, "api.local": ClientAuthOptional}
+	if cfg.TLS.ClientAuth == nil || !reflect.DeepEqual(cfg.TLS.ClientAuth.Hosts, want) {
+		t.Errorf("TLS.ClientAuth = %+v, want hosts %v", cfg.TLS.ClientAuth, want)
+	}
+	if cfg.TLS.ClientAuth.Header != "X-Client" {
+		t.Errorf("TLS.ClientAuth.Header = %q, want X-Client", cfg.TLS.ClientAuth.Header)
+	}
+
+	for _, content := range []string{
+		`{"tls": {"clientAuth": {"hosts": {"admin": "always"}}}}`,
+		`{"tls": {"clientAuth": {"hosts": {"": "require"}}}}`,
+		`{"tls": {"clientAuth": {"hosts": {"admin": "require"}, "header": "X Client"}}}`,
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := LoadFile(path); err == nil {
+			t.Errorf("LoadFile() should fail for %s", content)
+		}
+	}
+}
+
+func TestLoadFileHosts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"hosts": {"enabled": true}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() failed: %v", err)
+	}
+	if !cfg.Hosts.Enabled {
+		t.Error("Expected hosts.enabled to be loaded")
+	}
+	if Default().Hosts.Enabled {
+		t.Error("Expected the hosts block to be off by default")
+	}
+}
+
+func TestLoadFileMDNS(t *testing.T) {
+	if mode := Default().MDNS.Mode; mode != MDNSModeLocal {
+		t.Errorf("default MDNS.Mode = %q, want %q", mode, MDNSModeLocal)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	for _, mode := range []string{MDNSModeLocal, MDNSModeLAN, MDNSModeOff} {
+		if err := os.WriteFile(path, []byte(`{"mdns": {"mode": "`+mode+`"}}`), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		cfg, err := LoadFile(path)
+		if err != nil {
+			t.Fatalf("LoadFile() failed for mode %q: %v", mode, err)
+		}
+		if cfg.MDNS.Mode != mode {
+			t.Errorf("MDNS.Mode = %q, want %q", cfg.MDNS.Mode, mode)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(`{"mdns": {"mode": "everywhere"}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Error("LoadFile() should fail for an unknown mdns.mode")
+	}
+}
+
+func TestLoadFileDNS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	content := `{"dns": {"domains": [".Test", "dev.internal.", "test"], "listen": "127.0.0.1:5300"}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() failed: %v", err)
+	}
+	if want := []string{"test", "dev.internal"}; !reflect.DeepEqual(cfg.DNS.Domains, want) {
+		t.Errorf("DNS.Domains = %v, want %v", cfg.DNS.Domains, want)
+	}
+	if cfg.DNS.Listen != "127.0.0.1:5300" {
+		t.Errorf("DNS.Listen = %q, want 127.0.0.1:5300", cfg.DNS.Listen)
+	}
+
+	for _, content := range []string{
+		`{"dns": {"domains": ["localhost"]}}`,
+		`{"dns": {"domains": ["local"]}}`,
+		`{"dns": {"domains": [""]}}`,
+		`{"dns": {"domains": ["my_app.test"]}}`,
+		`{"dns": {"domains": ["-bad.test"]}}`,
+		`{"dns": {"domains": ["test"], "listen": "localhost:5300"}}`,
+		`{"dns": {"domains": ["test"], "listen": "127.0.0.1:0"}}`,
+		`{"dns": {"domains": ["test"], "listen": "127.0.0.1"}}`,
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := LoadFile(path); err == nil {
+			t.Errorf("LoadFile() should fail for %s", content)
+		}
+	}
+}
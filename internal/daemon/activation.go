@@ -0,0 +1,59 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFDsStart is the first file descriptor systemd passes to a
+// socket-activated service
+const listenFDsStart = 3
+
+// ActivationListener returns the control socket listener passed in by
+// systemd socket activation, or nil if the daemon was started directly.
+// The LISTEN_* variables are cleared so dev servers don't inherit them.
+func ActivationListener() (net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	return activationListener(os.Getenv, os.Getpid(), listenFDsStart)
+}
+
+// activationListener implements ActivationListener, reading the
+// environment through getenv and the listener from fd
+func activationListener(getenv func(string) string, pid int, fd uintptr) (net.Listener, error) {
+	if getenv("LISTEN_PID") == "" {
+		return nil, nil
+	}
+
+	// The variables are meant for this process, not for a parent that
+	// passed its environment on
+	listenPID, err := strconv.Atoi(getenv("LISTEN_PID"))
+	if err != nil || listenPID != pid {
+		return nil, nil
+	}
+
+	count, err := strconv.Atoi(getenv("LISTEN_FDS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LISTEN_FDS: %q", getenv("LISTEN_FDS"))
+	}
+	if count != 1 {
+		return nil, fmt.Errorf("expected one socket from systemd, got %d", count)
+	}
+
+	file := os.NewFile(fd, "ctl.sock")
+	defer file.Close()
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use socket from systemd: %w", err)
+	}
+	if listener.Addr().Network() != "unix" {
+		listener.Close()
+		return nil, fmt.Errorf("socket from systemd is not a Unix socket")
+	}
+	return listener, nil
+}
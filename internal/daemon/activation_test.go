@@ -0,0 +1,101 @@
+package daemon
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestActivationListener(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "ctl.sock")
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	file, err := ln.(*net.UnixListener).File()
+	if err != nil {
+		t.Fatalf("Failed to get listener file: %v", err)
+	}
+	defer file.Close()
+
+	pid := 4242
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+
+	// Not socket activated
+	listener, err := activationListener(env(nil), pid, file.Fd())
+	if listener != nil || err != nil {
+		t.Errorf("activationListener() = %v, %v without LISTEN_PID, want nil, nil", listener, err)
+	}
+
+	// Variables meant for another process
+	listener, err = activationListener(env(map[string]string{"LISTEN_PID": "1", "LISTEN_FDS": "1"}), pid, file.Fd())
+	if listener != nil || err != nil {
+		t.Errorf("activationListener() = %v, %v for another PID, want nil, nil", listener, err)
+	}
+
+	for _, fds := range []string{"", "two", "2"} {
+		_, err := activationListener(env(map[string]string{"LISTEN_PID": strconv.Itoa(pid), "LISTEN_FDS": fds}), pid, file.Fd())
+		if err == nil {
+			t.Errorf("activationListener() should fail for LISTEN_FDS=%q", fds)
+		}
+	}
+
+	// activationListener takes ownership of the descriptor, as it does of
+	// the one systemd passes
+	listener, err = activationListener(env(map[string]string{"LISTEN_PID": strconv.Itoa(pid), "LISTEN_FDS": "1"}), pid, dupFD(t, file))
+	if err != nil {
+		t.Fatalf("activationListener() failed: %v", err)
+	}
+	defer listener.Close()
+	if listener.Addr().String() != sockPath {
+		t.Errorf("Listener address = %s, want %s", listener.Addr(), sockPath)
+	}
+
+	// The inherited listener accepts connections to the socket
+	go func() {
+		if conn, err := net.Dial("unix", sockPath); err == nil {
+			conn.Close()
+		}
+	}()
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("Accept() failed: %v", err)
+	}
+	conn.Close()
+}
+
+func TestActivationListenerNotSocket(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	file, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("Failed to get listener file: %v", err)
+	}
+	defer file.Close()
+
+	env := map[string]string{"LISTEN_PID": "7", "LISTEN_FDS": "1"}
+	if _, err := activationListener(func(key string) string { return env[key] }, 7, dupFD(t, file)); err == nil {
+		t.Error("activationListener() should reject a TCP socket")
+	}
+}
+
+// dupFD returns a new descriptor for file that isn't owned by an *os.File
+func dupFD(t *testing.T, file *os.File) uintptr {
+	t.Helper()
+	fd, err := syscall.Dup(int(file.Fd()))
+	if err != nil {
+		t.Fatalf("Failed to duplicate descriptor: %v", err)
+	}
+	return uintptr(fd)
+}
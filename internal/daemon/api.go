@@ -0,0 +1,446 @@
+package daemon
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// apiTokenBytes is the number of random bytes in a generated API token
+	apiTokenBytes = 32
+
+	// apiMaxBodyBytes limits the size of API request bodies
+	apiMaxBodyBytes = 1 << 20
+)
+
+// APITokenPath returns the path to the management API token file
+func APITokenPath() (string, error) {
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "api.token"), nil
+}
+
+// ReadAPIToken returns the management API token, creating it if needed.
+// The token file is only readable by the current user.
+func ReadAPIToken() (string, error) {
+	tokenPath, err := APITokenPath()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(tokenPath)
+	if err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read API token: %w", err)
+	}
+
+	buf := make([]byte, apiTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate API token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := os.MkdirAll(filepath.Dir(tokenPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(tokenPath, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write API token: %w", err)
+	}
+
+	return token, nil
+}
+
+// startAPI starts the HTTP management API if it is enabled in the config.
+// The API listens on loopback only and is exposed through the proxy under
+// the configured host (faa.localhost by default).
+func (d *Daemon) startAPI() error {
+	if d.config == nil || !d.config.API.Enabled {
+		return nil
+	}
+
+	if err := requireLoopback(d.config.API.Listen); err != nil {
+		return err
+	}
+
+	token, err := ReadAPIToken()
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", d.config.API.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", d.config.API.Listen, err)
+	}
+
+	d.apiServer = &http.Server{
+		Handler:           d.apiHandler(token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := d.apiServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			slog.Warn("management API stopped", "error", err)
+		}
+	}()
+
+	d.internalRoutes[d.config.API.Host] = listener.Addr().(*net.TCPAddr).Port
+	return nil
+}
+
+// stopAPI shuts down the management API server if it is running
+func (d *Daemon) stopAPI() {
+	if d.apiServer != nil {
+		_ = d.apiServer.Close()
+	}
+}
+
+// requireLoopback returns an error unless addr is a loopback address
+func requireLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid API listen address %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("API listen address %q must be a loopback address", addr)
+}
+
+// apiHandler returns the HTTP handler for the management API and dashboard.
+// Every API endpoint except the OpenAPI description requires the bearer token.
+func (d *Daemon) apiHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(openAPISpec))
+	})
+
+	authed := http.NewServeMux()
+	authed.HandleFunc("GET /api/ping", func(w http.ResponseWriter, r *http.Request) {
+		d.serveAPIRequest(w, MessageTypePing, nil)
+	})
+	authed.HandleFunc("GET /api/status", func(w http.ResponseWriter, r *http.Request) {
+		d.serveAPIRequest(w, MessageTypeStatus, nil)
+	})
+	authed.HandleFunc("GET /api/routes", func(w http.ResponseWriter, r *http.Request) {
+		d.serveAPIRequest(w, MessageTypeListRoutes, nil)
+	})
+	authed.HandleFunc("GET /api/routes/{host}", d.apiGetRoute)
+	authed.HandleFunc("PUT /api/routes/{host}", d.apiPutRoute)
+	authed.HandleFunc("GET /api/processes", d.apiGetProcesses)
+	authed.HandleFunc("PUT /api/processes", d.apiPutProcess)
+	authed.HandleFunc("DELETE /api/processes", d.apiDeleteProcess)
+	authed.HandleFunc("POST /api/processes/stop", d.apiControlProcess(MessageTypeStopProcess))
+	authed.HandleFunc("POST /api/processes/restart", d.apiControlProcess(MessageTypeRestartProcess))
+	authed.HandleFunc("GET /api/requests", d.apiGetRequests)
+	authed.HandleFunc("GET /api/logs", d.apiGetLogs)
+	authed.HandleFunc("GET /api/captures", d.apiGetCaptures)
+	authed.HandleFunc("GET /api/captures/{id}", d.apiGetCapture)
+	authed.HandleFunc("GET /api/chaos", d.apiGetChaos)
+	authed.HandleFunc("PUT /api/chaos", d.apiPutChaos)
+	authed.HandleFunc("POST /api/stop", d.apiStop)
+	mux.Handle("/api/", requireToken(token, authed))
+
+	// The dashboard is static; it calls the API above with the token the
+	// user provides, so serving it needs no authentication
+	mux.Handle("/", dashboardHandler())
+
+	return withCORS(mux)
+}
+
+// withCORS allows browser-based tools on other origins to call the API.
+// Authentication uses a bearer token rather than cookies, so allowing any
+// origin does not expose the API to ambient credentials.
+func withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, POST, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireToken rejects requests without a valid bearer token
+func requireToken(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="faa"`)
+			writeAPIError(w, http.StatusUnauthorized, "missing or invalid API token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serveAPIRequest runs an IPC request through handleRequest and writes the
+// result. Failures caused by the request get the status of their
+// requestError, and others are internal errors.
+func (d *Daemon) serveAPIRequest(w http.ResponseWriter, msgType MessageType, data interface{}) {
+	req, err := NewRequest(msgType, data)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := d.handleRequest(req)
+	if !resp.Ok {
+		status := resp.Status
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		writeAPIError(w, status, resp.Error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(resp.Data) == 0 {
+		_, _ = w.Write([]byte("{}\n"))
+		return
+	}
+	_, _ = w.Write(append(resp.Data, '\n'))
+}
+
+// apiGetRoute handles GET /api/routes/{host}
+func (d *Daemon) apiGetRoute(w http.ResponseWriter, r *http.Request) {
+	host := r.PathValue("host")
+	port, err := d.registry.GetRoute(host)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if port == 0 {
+		writeAPIError(w, http.StatusNotFound, fmt.Sprintf("no route for %s", host))
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, Route{Host: normalizeHost(host), Port: port})
+}
+
+// apiPutRoute handles PUT /api/routes/{host} with a {"port": N} body
+func (d *Daemon) apiPutRoute(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Port int `json:"port"`
+	}
+	if !decodeAPIBody(w, r, &body) {
+		return
+	}
+	if body.Port <= 0 || body.Port > 65535 {
+		writeAPIError(w, http.StatusBadRequest, "port must be between 1 and 65535")
+		return
+	}
+
+	d.serveAPIRequest(w, MessageTypeUpsertRoute, &UpsertRouteData{
+		Host: r.PathValue("host"),
+		Port: body.Port,
+	})
+}
+
+// apiGetProcesses handles GET /api/processes, optionally filtered by
+// the projectRoot query parameter
+func (d *Daemon) apiGetProcesses(w http.ResponseWriter, r *http.Request) {
+	projectRoot := r.URL.Query().Get("projectRoot")
+	if projectRoot == "" {
+		d.cleanupStaleProcesses()
+		processes, err := d.registry.ListProcesses()
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, processes)
+		return
+	}
+
+	d.cleanupStaleProcesses()
+	proc, err := d.registry.GetProcess(projectRoot)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if proc == nil {
+		writeAPIError(w, http.StatusNotFound, fmt.Sprintf("no process for %s", projectRoot))
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, proc)
+}
+
+// apiPutProcess handles PUT /api/processes with a SetProcessData body
+func (d *Daemon) apiPutProcess(w http.ResponseWriter, r *http.Request) {
+	var data SetProcessData
+	if !decodeAPIBody(w, r, &data) {
+		return
+	}
+	if data.ProjectRoot == "" {
+		writeAPIError(w, http.StatusBadRequest, "projectRoot is required")
+		return
+	}
+	d.serveAPIRequest(w, MessageTypeSetProcess, &data)
+}
+
+// apiDeleteProcess handles DELETE /api/processes?projectRoot=...
+func (d *Daemon) apiDeleteProcess(w http.ResponseWriter, r *http.Request) {
+	projectRoot := r.URL.Query().Get("projectRoot")
+	if projectRoot == "" {
+		writeAPIError(w, http.StatusBadRequest, "projectRoot query parameter is required")
+		return
+	}
+	d.serveAPIRequest(w, MessageTypeClearProcess, &ClearProcessData{ProjectRoot: projectRoot})
+}
+
+// apiControlProcess returns a handler for POST /api/processes/stop and
+// /api/processes/restart, which take the projectRoot query parameter
+func (d *Daemon) apiControlProcess(msgType MessageType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectRoot := r.URL.Query().Get("projectRoot")
+		if projectRoot == "" {
+			writeAPIError(w, http.StatusBadRequest, "projectRoot query parameter is required")
+			return
+		}
+		d.serveAPIRequest(w, msgType, &ProcessControlData{ProjectRoot: projectRoot})
+	}
+}
+
+// apiGetRequests handles GET /api/requests?host=...&limit=...
+func (d *Daemon) apiGetRequests(w http.ResponseWriter, r *http.Request) {
+	host := r.URL.Query().Get("host")
+	if host == "" {
+		writeAPIError(w, http.StatusBadRequest, "host query parameter is required")
+		return
+	}
+	limit, ok := queryInt(w, r, "limit")
+	if !ok {
+		return
+	}
+	d.serveAPIRequest(w, MessageTypeRecentRequests, &RecentRequestsData{Host: host, Limit: limit})
+}
+
+// apiGetLogs handles GET /api/logs?host=...&lines=...
+func (d *Daemon) apiGetLogs(w http.ResponseWriter, r *http.Request) {
+	host := r.URL.Query().Get("host")
+	if host == "" {
+		writeAPIError(w, http.StatusBadRequest, "host query parameter is required")
+		return
+	}
+	lines, ok := queryInt(w, r, "lines")
+	if !ok {
+		return
+	}
+	d.serveAPIRequest(w, MessageTypeTailLog, &TailLogData{Host: host, Lines: lines})
+}
+
+// apiGetCaptures handles GET /api/captures?host=...&limit=...
+func (d *Daemon) apiGetCaptures(w http.ResponseWriter, r *http.Request) {
+	host := r.URL.Query().Get("host")
+	if host == "" {
+		writeAPIError(w, http.StatusBadRequest, "host query parameter is required")
+		return
+	}
+	limit, ok := queryInt(w, r, "limit")
+	if !ok {
+		return
+	}
+	d.serveAPIRequest(w, MessageTypeListCaptures, &ListCapturesData{Host: host, Limit: limit})
+}
+
+// apiGetCapture handles GET /api/captures/{id}
+func (d *Daemon) apiGetCapture(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "id must be a positive integer")
+		return
+	}
+	d.serveAPIRequest(w, MessageTypeGetCapture, &GetCaptureData{ID: id})
+}
+
+// apiGetChaos handles GET /api/chaos?host=...
+func (d *Daemon) apiGetChaos(w http.ResponseWriter, r *http.Request) {
+	host := r.URL.Query().Get("host")
+	if host == "" {
+		writeAPIError(w, http.StatusBadRequest, "host query parameter is required")
+		return
+	}
+	d.serveAPIRequest(w, MessageTypeGetChaos, &GetChaosData{Host: host})
+}
+
+// apiPutChaos handles PUT /api/chaos with a SetChaosData body
+func (d *Daemon) apiPutChaos(w http.ResponseWriter, r *http.Request) {
+	var data SetChaosData
+	if !decodeAPIBody(w, r, &data) {
+		return
+	}
+	if data.Host == "" {
+		writeAPIError(w, http.StatusBadRequest, "host is required")
+		return
+	}
+	d.serveAPIRequest(w, MessageTypeSetChaos, &data)
+}
+
+// apiStop handles POST /api/stop with an optional StopData body
+func (d *Daemon) apiStop(w http.ResponseWriter, r *http.Request) {
+	var data StopData
+	if r.ContentLength != 0 && !decodeAPIBody(w, r, &data) {
+		return
+	}
+	d.serveAPIRequest(w, MessageTypeStop, &data)
+}
+
+// decodeAPIBody decodes a JSON request body into v, writing a 400
+// response and returning false on failure
+func decodeAPIBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return false
+	}
+	return true
+}
+
+// queryInt parses an optional non-negative integer query parameter, writing a
+// 400 response and returning false if it is invalid
+func queryInt(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("%s must be a non-negative integer", name))
+		return 0, false
+	}
+	return n, true
+}
+
+// writeAPIJSON writes v as a JSON response with the given status code
+func writeAPIJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError writes a JSON error response
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, map[string]string{"error": message})
+}
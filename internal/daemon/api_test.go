@@ -0,0 +1,481 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sahithyandev/faa/internal/config"
+)
+
+// newTestAPI creates a daemon with a temporary registry and returns an
+// httptest server for its management API
+func newTestAPI(t *testing.T) (*Daemon, *httptest.Server) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	d := New(&Registry{configDir: tmpDir}, nil)
+	server := httptest.NewServer(d.apiHandler("secret"))
+	t.Cleanup(server.Close)
+
+	return d, server
+}
+
+// apiRequest sends an authenticated request to the test API
+func apiRequest(t *testing.T, server *httptest.Server, method, path, body string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest() failed: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestAPIRequiresToken(t *testing.T) {
+	_, server := newTestAPI(t)
+
+	resp, err := http.Get(server.URL + "/api/status")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/status", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	resp2, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp2.Body.Close()
+	if resp2.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status with wrong token = %d, want %d", resp2.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestAPIOpenAPISpec(t *testing.T) {
+	_, server := newTestAPI(t)
+
+	// The spec is public so tools can discover the API before authenticating
+	resp, err := http.Get(server.URL + "/api/openapi.json")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	var spec map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&spec); err != nil {
+		t.Fatalf("spec is not valid JSON: %v", err)
+	}
+	if spec["openapi"] != "3.0.3" {
+		t.Errorf("openapi = %v, want 3.0.3", spec["openapi"])
+	}
+}
+
+func TestAPIRoutes(t *testing.T) {
+	_, server := newTestAPI(t)
+
+	resp := apiRequest(t, server, http.MethodPut, "/api/routes/my-app", `{"port": 3000}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	resp = apiRequest(t, server, http.MethodGet, "/api/routes/my-app", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var route Route
+	if err := json.NewDecoder(resp.Body).Decode(&route); err != nil {
+		t.Fatalf("Failed to decode route: %v", err)
+	}
+	if route.Host != "my-app.localhost" || route.Port != 3000 {
+		t.Errorf("route = %+v, want my-app.localhost:3000", route)
+	}
+
+	resp = apiRequest(t, server, http.MethodGet, "/api/routes", "")
+	var routes []Route
+	if err := json.NewDecoder(resp.Body).Decode(&routes); err != nil {
+		t.Fatalf("Failed to decode routes: %v", err)
+	}
+	if len(routes) != 1 {
+		t.Errorf("Expected 1 route, got %d", len(routes))
+	}
+
+	resp = apiRequest(t, server, http.MethodGet, "/api/routes/missing", "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET missing status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+
+	resp = apiRequest(t, server, http.MethodPut, "/api/routes/my-app", `{"port": 0}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("PUT invalid port status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestAPIProcesses(t *testing.T) {
+	_, server := newTestAPI(t)
+
+	body, _ := json.Marshal(&SetProcessData{
+		ProjectRoot: "/tmp/api-app",
+		PID:         os.Getpid(),
+		Host:        "api-app.localhost",
+		Port:        3000,
+		StartedAt:   time.Now(),
+	})
+	resp := apiRequest(t, server, http.MethodPut, "/api/processes", string(body))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	resp = apiRequest(t, server, http.MethodGet, "/api/processes?projectRoot=/tmp/api-app", "")
+	var proc Process
+	if err := json.NewDecoder(resp.Body).Decode(&proc); err != nil {
+		t.Fatalf("Failed to decode process: %v", err)
+	}
+	if proc.PID != os.Getpid() {
+		t.Errorf("PID = %d, want %d", proc.PID, os.Getpid())
+	}
+
+	resp = apiRequest(t, server, http.MethodDelete, "/api/processes", "")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("DELETE without projectRoot status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	resp = apiRequest(t, server, http.MethodDelete, "/api/processes?projectRoot=/tmp/api-app", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("DELETE status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	resp = apiRequest(t, server, http.MethodGet, "/api/processes?projectRoot=/tmp/api-app", "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET after delete status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestRequireLoopback(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{"127.0.0.1:0", false},
+		{"[::1]:8080", false},
+		{"localhost:8080", false},
+		{"0.0.0.0:8080", true},
+		{"192.168.1.10:8080", true},
+		{"not-an-address", true},
+	}
+
+	for _, tt := range tests {
+		err := requireLoopback(tt.addr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("requireLoopback(%q) error = %v, wantErr %v", tt.addr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDaemonStartsAPI(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Override HOME for testing
+	originalHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", originalHome)
+	os.Setenv("HOME", tmpDir)
+
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+
+	cfg := config.Default()
+	cfg.API.Enabled = true
+
+	d := New(registry, nil)
+	d.SetConfig(cfg)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- d.Start()
+	}()
+
+	// Wait for daemon to start
+	time.Sleep(100 * time.Millisecond)
+
+	port, ok := d.internalRoutes[config.DefaultAPIHost]
+	if !ok || port == 0 {
+		t.Fatalf("API route not registered: %v", d.internalRoutes)
+	}
+
+	token, err := ReadAPIToken()
+	if err != nil {
+		t.Fatalf("ReadAPIToken() failed: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1:"+strconv.Itoa(port)+"/api/ping", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/ping failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	// Shutdown daemon
+	d.Shutdown()
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Errorf("Daemon returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Daemon didn't shutdown in time")
+	}
+}
+
+func TestReadAPIToken(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Override HOME for testing
+	originalHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", originalHome)
+	os.Setenv("HOME", tmpDir)
+
+	token, err := ReadAPIToken()
+	if err != nil {
+		t.Fatalf("ReadAPIToken() failed: %v", err)
+	}
+	if len(token) != apiTokenBytes*2 {
+		t.Errorf("token length = %d, want %d", len(token), apiTokenBytes*2)
+	}
+
+	tokenPath, err := APITokenPath()
+	if err != nil {
+		t.Fatalf("APITokenPath() failed: %v", err)
+	}
+	info, err := os.Stat(tokenPath)
+	if err != nil {
+		t.Fatalf("Failed to stat token file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("token file permissions = %o, want 600", info.Mode().Perm())
+	}
+
+	// A second call returns the same token
+	again, err := ReadAPIToken()
+	if err != nil {
+		t.Fatalf("ReadAPIToken() failed: %v", err)
+	}
+	if again != token {
+		t.Error("ReadAPIToken() should return the stored token")
+	}
+}
+
+func TestAPIDashboard(t *testing.T) {
+	_, server := newTestAPI(t)
+
+	// The dashboard page itself is public; its data comes from the API
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("Content-Type = %q, want text/html", resp.Header.Get("Content-Type"))
+	}
+	if resp.Header.Get("Content-Security-Policy") == "" {
+		t.Error("dashboard should set a Content-Security-Policy")
+	}
+
+	for _, asset := range []string{"/app.js", "/style.css"} {
+		resp, err := http.Get(server.URL + asset)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", asset, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s status = %d, want %d", asset, resp.StatusCode, http.StatusOK)
+		}
+	}
+}
+
+func TestAPIProcessControl(t *testing.T) {
+	d, server := newTestAPI(t)
+
+	resp := apiRequest(t, server, http.MethodPost, "/api/processes/stop", "")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("stop without projectRoot status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	for _, action := range []string{"stop", "restart"} {
+		resp = apiRequest(t, server, http.MethodPost, "/api/processes/"+action+"?projectRoot=/missing", "")
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s of unknown project status = %d, want %d", action, resp.StatusCode, http.StatusNotFound)
+		}
+	}
+
+	// Start a process in its own group, like devproc does
+	cmd := exec.Command("sleep", "60")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+	defer func() { _ = cmd.Process.Kill() }()
+
+	if err := d.registry.SetProcess("/project", cmd.Process.Pid, "project.localhost", 3000, time.Now()); err != nil {
+		t.Fatalf("SetProcess() failed: %v", err)
+	}
+
+	sub := d.events.subscribe([]EventType{EventRestartRequested, EventStopRequested})
+	defer d.events.unsubscribe(sub)
+
+	resp = apiRequest(t, server, http.MethodPost, "/api/processes/restart?projectRoot=/project", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("restart status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	expectEvent := func(want EventType) {
+		t.Helper()
+		select {
+		case event := <-sub.events:
+			var data ProcessEventData
+			if err := json.Unmarshal(event.Data, &data); err != nil {
+				t.Fatalf("Failed to decode event data: %v", err)
+			}
+			if event.Type != want || data.ProjectRoot != "/project" || data.PID != cmd.Process.Pid {
+				t.Errorf("event = %s %+v, want %s for /project", event.Type, data, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected a %s event", want)
+		}
+	}
+	expectEvent(EventRestartRequested)
+
+	// Stopping is left to the 'faa run' owning the process; the daemon
+	// doesn't signal it itself
+	resp = apiRequest(t, server, http.MethodPost, "/api/processes/stop?projectRoot=/project", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("stop status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	expectEvent(EventStopRequested)
+	select {
+	case <-exited:
+		t.Error("Process was signalled by the daemon")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestAPILogsAndRequests(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Override HOME for testing
+	originalHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", originalHome)
+	os.Setenv("HOME", tmpDir)
+
+	_, server := newTestAPI(t)
+
+	logFile, err := OpenLog("my-app")
+	if err != nil {
+		t.Fatalf("OpenLog() failed: %v", err)
+	}
+	_, _ = logFile.WriteString("ready on port 3000\nGET / 200\n")
+	logFile.Close()
+
+	resp := apiRequest(t, server, http.MethodGet, "/api/logs?host=my-app&lines=1", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("logs status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var tail TailLogResponseData
+	if err := json.NewDecoder(resp.Body).Decode(&tail); err != nil {
+		t.Fatalf("Failed to decode log tail: %v", err)
+	}
+	if len(tail.Lines) != 1 || tail.Lines[0] != "GET / 200" {
+		t.Errorf("log lines = %v, want [GET / 200]", tail.Lines)
+	}
+
+	resp = apiRequest(t, server, http.MethodGet, "/api/logs?host=my-app&lines=-1", "")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("negative lines status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	resp = apiRequest(t, server, http.MethodGet, "/api/logs?host=../daemon", "")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("logs outside the log directory status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	resp = apiRequest(t, server, http.MethodGet, "/api/requests?host=my-app", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("requests status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var records []json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&records); err != nil {
+		t.Fatalf("Failed to decode requests: %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("Expected no recorded requests, got %d", len(records))
+	}
+
+	resp = apiRequest(t, server, http.MethodGet, "/api/requests", "")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("requests without host status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestAPIErrorStatuses(t *testing.T) {
+	_, server := newTestAPI(t)
+
+	resp := apiRequest(t, server, http.MethodPut, "/api/routes/my-app", `{"port": 3000}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT route status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	tests := []struct {
+		method, path, body string
+		expected           int
+	}{
+		{http.MethodGet, "/api/captures/42", "", http.StatusNotFound},
+		{http.MethodGet, "/api/captures?host=missing", "", http.StatusNotFound},
+		{http.MethodGet, "/api/chaos?host=missing", "", http.StatusNotFound},
+		{http.MethodPut, "/api/chaos", `{"host": "missing", "rules": []}`, http.StatusNotFound},
+		{http.MethodPut, "/api/chaos", `{"host": "my-app", "rules": [{"error_rate": 2}]}`, http.StatusBadRequest},
+		{http.MethodPut, "/api/chaos", `{"host": "my-app", "rules": [{"error_rate": 0.5}]}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		resp := apiRequest(t, server, tt.method, tt.path, tt.body)
+		if resp.StatusCode != tt.expected {
+			t.Errorf("%s %s %s status = %d, want %d", tt.method, tt.path, tt.body, resp.StatusCode, tt.expected)
+		}
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"net/http"
+
+	"github.com/sahithyandev/faa/internal/proxy"
 )
 
 // Client represents a client connection to the daemon
@@ -215,6 +218,80 @@ func (c *Client) Status() (*StatusResponseData, error) {
 	return &status, nil
 }
 
+// RotateCA asks the daemon to replace the local CA, returning the new root
+func (c *Client) RotateCA() (*proxy.CertificateInfo, error) {
+	req, err := NewRequest(MessageTypeRotateCA, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.Ok {
+		return nil, fmt.Errorf("rotate_ca failed: %s", resp.Error)
+	}
+
+	var root proxy.CertificateInfo
+	if err := json.Unmarshal(resp.Data, &root); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal CA certificate: %w", err)
+	}
+
+	return &root, nil
+}
+
+// GetCertificate returns the certificate and private key the proxy serves
+// for a routed host, having it issued if needed
+func (c *Client) GetCertificate(host string) (*CertificateResponseData, error) {
+	req, err := NewRequest(MessageTypeGetCertificate, &GetCertificateData{Host: host})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.Ok {
+		return nil, fmt.Errorf("get_certificate failed: %s", resp.Error)
+	}
+
+	var cert CertificateResponseData
+	if err := json.Unmarshal(resp.Data, &cert); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal certificate: %w", err)
+	}
+
+	return &cert, nil
+}
+
+// IssueClientCertificate has the daemon issue a client certificate for
+// name from the proxy's CA, valid for days
+func (c *Client) IssueClientCertificate(name string, days int) (*ClientCertificateResponseData, error) {
+	req, err := NewRequest(MessageTypeIssueClientCertificate, &IssueClientCertificateData{Name: name, Days: days})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.Ok {
+		return nil, fmt.Errorf("issue_client_certificate failed: %s", resp.Error)
+	}
+
+	var cert ClientCertificateResponseData
+	if err := json.Unmarshal(resp.Data, &cert); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal certificate: %w", err)
+	}
+
+	return &cert, nil
+}
+
 // Stop sends a stop request to the daemon
 func (c *Client) Stop(clearRoutes bool) error {
 	req, err := NewRequest(MessageTypeStop, &StopData{
@@ -259,3 +336,319 @@ func (c *Client) ListRoutes() ([]Route, error) {
 
 	return routes, nil
 }
+
+// StopProcess asks the daemon to stop the dev server of a project
+func (c *Client) StopProcess(projectRoot string) error {
+	req, err := NewRequest(MessageTypeStopProcess, &ProcessControlData{
+		ProjectRoot: projectRoot,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return err
+	}
+
+	if !resp.Ok {
+		return fmt.Errorf("stop_process failed: %s", resp.Error)
+	}
+
+	return nil
+}
+
+// RestartProcess asks the 'faa run' owning a project's dev server to restart it
+func (c *Client) RestartProcess(projectRoot string) error {
+	req, err := NewRequest(MessageTypeRestartProcess, &ProcessControlData{
+		ProjectRoot: projectRoot,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return err
+	}
+
+	if !resp.Ok {
+		return fmt.Errorf("restart_process failed: %s", resp.Error)
+	}
+
+	return nil
+}
+
+// RecentRequests retrieves the latest requests proxied for host, newest first
+func (c *Client) RecentRequests(host string, limit int) ([]proxy.RequestRecord, error) {
+	req, err := NewRequest(MessageTypeRecentRequests, &RecentRequestsData{
+		Host:  host,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.Ok {
+		return nil, fmt.Errorf("recent_requests failed: %s", resp.Error)
+	}
+
+	var records []proxy.RequestRecord
+	if err := json.Unmarshal(resp.Data, &records); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal requests: %w", err)
+	}
+
+	return records, nil
+}
+
+// RequestStats retrieves request totals for every host, keyed by host
+func (c *Client) RequestStats() (map[string]proxy.HostStats, error) {
+	req, err := NewRequest(MessageTypeRequestStats, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.Ok {
+		return nil, fmt.Errorf("request_stats failed: %s", resp.Error)
+	}
+
+	var stats map[string]proxy.HostStats
+	if err := json.Unmarshal(resp.Data, &stats); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal request stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// TailLog retrieves the last lines of the output log for host
+func (c *Client) TailLog(host string, lines int) (*TailLogResponseData, error) {
+	req, err := NewRequest(MessageTypeTailLog, &TailLogData{
+		Host:  host,
+		Lines: lines,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.Ok {
+		return nil, fmt.Errorf("tail_log failed: %s", resp.Error)
+	}
+
+	var tail TailLogResponseData
+	if err := json.Unmarshal(resp.Data, &tail); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal log tail: %w", err)
+	}
+
+	return &tail, nil
+}
+
+// SetCapture enables or disables traffic capture for host
+func (c *Client) SetCapture(host string, enabled bool) error {
+	req, err := NewRequest(MessageTypeSetCapture, &SetCaptureData{
+		Host:    host,
+		Enabled: enabled,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return err
+	}
+
+	if !resp.Ok {
+		return fmt.Errorf("set_capture failed: %s", resp.Error)
+	}
+
+	return nil
+}
+
+// ListCaptures retrieves the latest exchanges captured for host, oldest first
+func (c *Client) ListCaptures(host string, limit int) (*ListCapturesResponseData, error) {
+	req, err := NewRequest(MessageTypeListCaptures, &ListCapturesData{
+		Host:  host,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.Ok {
+		return nil, fmt.Errorf("list_captures failed: %s", resp.Error)
+	}
+
+	var captures ListCapturesResponseData
+	if err := json.Unmarshal(resp.Data, &captures); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal captures: %w", err)
+	}
+
+	return &captures, nil
+}
+
+// GetCapture retrieves a single captured exchange by ID
+// Returns nil if no exchange has that ID
+func (c *Client) GetCapture(id uint64) (*proxy.Exchange, error) {
+	req, err := NewRequest(MessageTypeGetCapture, &GetCaptureData{ID: id})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Status == http.StatusNotFound {
+		return nil, nil
+	}
+	if !resp.Ok {
+		return nil, fmt.Errorf("get_capture failed: %s", resp.Error)
+	}
+
+	var exchange proxy.Exchange
+	if err := json.Unmarshal(resp.Data, &exchange); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal capture: %w", err)
+	}
+
+	return &exchange, nil
+}
+
+// ClearCaptures removes every exchange captured for host
+func (c *Client) ClearCaptures(host string) error {
+	req, err := NewRequest(MessageTypeClearCaptures, &ClearCapturesData{Host: host})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return err
+	}
+
+	if !resp.Ok {
+		return fmt.Errorf("clear_captures failed: %s", resp.Error)
+	}
+
+	return nil
+}
+
+// SetChaos replaces the fault injection rules for host; no rules removes them
+func (c *Client) SetChaos(host string, rules []proxy.ChaosRule) error {
+	req, err := NewRequest(MessageTypeSetChaos, &SetChaosData{
+		Host:  host,
+		Rules: rules,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return err
+	}
+
+	if !resp.Ok {
+		return fmt.Errorf("set_chaos failed: %s", resp.Error)
+	}
+
+	return nil
+}
+
+// GetChaos retrieves the fault injection rules for host
+func (c *Client) GetChaos(host string) (*ChaosResponseData, error) {
+	req, err := NewRequest(MessageTypeGetChaos, &GetChaosData{Host: host})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.Ok {
+		return nil, fmt.Errorf("get_chaos failed: %s", resp.Error)
+	}
+
+	var chaos ChaosResponseData
+	if err := json.Unmarshal(resp.Data, &chaos); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chaos rules: %w", err)
+	}
+
+	return &chaos, nil
+}
+
+// Subscription is a stream of events from the daemon
+type Subscription struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Subscribe opens a dedicated connection and subscribes to daemon events.
+// An empty types list subscribes to all events. The returned Subscription
+// must be closed when no longer needed.
+func Subscribe(types ...EventType) (*Subscription, error) {
+	client, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := NewRequest(MessageTypeSubscribe, &SubscribeData{Types: types})
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	if err := EncodeRequest(client.conn, req); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	// The same reader must be used for the response and the events that
+	// follow it, since both may arrive in a single read
+	reader := bufio.NewReader(client.conn)
+	resp, err := DecodeResponse(reader)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if !resp.Ok {
+		client.Close()
+		return nil, fmt.Errorf("subscribe failed: %s", resp.Error)
+	}
+
+	return &Subscription{conn: client.conn, reader: reader}, nil
+}
+
+// Next blocks until the next event arrives.
+// It returns an error when the connection is closed.
+func (s *Subscription) Next() (*Event, error) {
+	return DecodeEvent(s.reader)
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() error {
+	return s.conn.Close()
+}
@@ -4,6 +4,10 @@ import (
 	"os"
 	"testing"
 	"time"
+
+	"github.com/caddyserver/caddy/v2"
+
+	"github.com/sahithyandev/faa/internal/proxy"
 )
 
 func TestClientPing(t *testing.T) {
@@ -520,3 +524,256 @@ func TestClientStop(t *testing.T) {
 		t.Fatal("Daemon didn't shutdown in time after Stop()")
 	}
 }
+
+func TestClientTailLogAndRequests(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Override HOME for testing
+	originalHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", originalHome)
+	os.Setenv("HOME", tmpDir)
+
+	// Create registry
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+
+	// Start daemon in a goroutine
+	d := New(registry, nil)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- d.Start()
+	}()
+
+	// Wait for daemon to start
+	time.Sleep(100 * time.Millisecond)
+
+	// Connect to daemon
+	client, err := Connect()
+	if err != nil {
+		t.Fatalf("Connect() failed: %v", err)
+	}
+	defer client.Close()
+
+	logFile, err := OpenLog("my-app")
+	if err != nil {
+		t.Fatalf("OpenLog() failed: %v", err)
+	}
+	_, _ = logFile.WriteString("one\ntwo\nthree\n")
+	logFile.Close()
+
+	tail, err := client.TailLog("my-app", 2)
+	if err != nil {
+		t.Fatalf("TailLog() failed: %v", err)
+	}
+	if len(tail.Lines) != 2 || tail.Lines[0] != "two" || tail.Lines[1] != "three" {
+		t.Errorf("TailLog() lines = %v, want [two three]", tail.Lines)
+	}
+
+	records, err := client.RecentRequests("my-app", 10)
+	if err != nil {
+		t.Fatalf("RecentRequests() failed: %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("Expected no recorded requests, got %d", len(records))
+	}
+
+	stats, err := client.RequestStats()
+	if err != nil {
+		t.Fatalf("RequestStats() failed: %v", err)
+	}
+	if _, ok := stats["my-app.localhost"]; ok {
+		t.Error("Expected no request stats for a host without traffic")
+	}
+
+	// Controlling a project without a process fails
+	if err := client.StopProcess("/missing"); err == nil {
+		t.Error("StopProcess() should fail for a project without a process")
+	}
+	if err := client.RestartProcess("/missing"); err == nil {
+		t.Error("RestartProcess() should fail for a project without a process")
+	}
+
+	// Shutdown daemon
+	d.Shutdown()
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Errorf("Daemon returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Daemon didn't shutdown in time")
+	}
+}
+
+func TestClientCaptures(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Override HOME for testing
+	originalHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", originalHome)
+	os.Setenv("HOME", tmpDir)
+
+	// Create registry
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+	if err := registry.UpsertRoute("hooks.local", 3000); err != nil {
+		t.Fatalf("UpsertRoute() failed: %v", err)
+	}
+
+	// Start daemon in a goroutine
+	d := New(registry, nil)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- d.Start()
+	}()
+
+	// Wait for daemon to start
+	time.Sleep(100 * time.Millisecond)
+
+	// Connect to daemon
+	client, err := Connect()
+	if err != nil {
+		t.Fatalf("Connect() failed: %v", err)
+	}
+	defer client.Close()
+
+	// Capture requires an existing route
+	if err := client.SetCapture("missing", true); err == nil {
+		t.Error("SetCapture() should fail for a host without a route")
+	}
+
+	// The other local suffix resolves to the registered route
+	if err := client.SetCapture("hooks.localhost", true); err != nil {
+		t.Fatalf("SetCapture() failed: %v", err)
+	}
+	captures, err := client.ListCaptures("hooks", 0)
+	if err != nil {
+		t.Fatalf("ListCaptures() failed: %v", err)
+	}
+	if captures.Host != "hooks.local" || !captures.Enabled {
+		t.Errorf("ListCaptures() = %s enabled=%v, want hooks.local enabled", captures.Host, captures.Enabled)
+	}
+	if len(captures.Exchanges) != 0 {
+		t.Errorf("Expected no captured exchanges, got %d", len(captures.Exchanges))
+	}
+
+	if err := client.SetCapture("hooks.local", false); err != nil {
+		t.Fatalf("SetCapture() failed: %v", err)
+	}
+	captures, err = client.ListCaptures("hooks.local", 0)
+	if err != nil {
+		t.Fatalf("ListCaptures() failed: %v", err)
+	}
+	if captures.Enabled {
+		t.Error("Capture should be disabled")
+	}
+
+	exchange, err := client.GetCapture(999999)
+	if err != nil {
+		t.Errorf("GetCapture() failed: %v", err)
+	}
+	if exchange != nil {
+		t.Error("GetCapture() should return nil for an unknown id")
+	}
+	if err := client.ClearCaptures("hooks.local"); err != nil {
+		t.Errorf("ClearCaptures() failed: %v", err)
+	}
+
+	// Shutdown daemon
+	d.Shutdown()
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Errorf("Daemon returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Daemon didn't shutdown in time")
+	}
+}
+
+func TestClientChaos(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Override HOME for testing
+	originalHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", originalHome)
+	os.Setenv("HOME", tmpDir)
+
+	// Create registry
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+	if err := registry.UpsertRoute("flaky.local", 3000); err != nil {
+		t.Fatalf("UpsertRoute() failed: %v", err)
+	}
+
+	// Start daemon in a goroutine
+	d := New(registry, nil)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- d.Start()
+	}()
+
+	// Wait for daemon to start
+	time.Sleep(100 * time.Millisecond)
+
+	// Connect to daemon
+	client, err := Connect()
+	if err != nil {
+		t.Fatalf("Connect() failed: %v", err)
+	}
+	defer client.Close()
+
+	// Chaos requires an existing route
+	if err := client.SetChaos("missing", []proxy.ChaosRule{{ErrorRate: 0.5}}); err == nil {
+		t.Error("SetChaos() should fail for a host without a route")
+	}
+	if err := client.SetChaos("flaky.local", []proxy.ChaosRule{{ErrorRate: 2}}); err == nil {
+		t.Error("SetChaos() should reject an invalid rule")
+	}
+
+	rules := []proxy.ChaosRule{
+		{Latency: caddy.Duration(200 * time.Millisecond)},
+		{Path: "/api/*", ErrorRate: 0.1, ErrorStatus: 502},
+	}
+	if err := client.SetChaos("flaky", rules); err != nil {
+		t.Fatalf("SetChaos() failed: %v", err)
+	}
+	chaos, err := client.GetChaos("flaky.localhost")
+	if err != nil {
+		t.Fatalf("GetChaos() failed: %v", err)
+	}
+	if chaos.Host != "flaky.local" || len(chaos.Rules) != 2 {
+		t.Fatalf("GetChaos() = %+v", chaos)
+	}
+	if chaos.Rules[0].Path != "/api/*" {
+		t.Errorf("Path rules should come before host-wide ones: %+v", chaos.Rules)
+	}
+
+	if err := client.SetChaos("flaky.local", nil); err != nil {
+		t.Fatalf("SetChaos() failed: %v", err)
+	}
+	chaos, err = client.GetChaos("flaky.local")
+	if err != nil {
+		t.Fatalf("GetChaos() failed: %v", err)
+	}
+	if len(chaos.Rules) != 0 {
+		t.Errorf("Expected no rules, got %+v", chaos.Rules)
+	}
+
+	// Shutdown daemon
+	d.Shutdown()
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Errorf("Daemon returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Daemon didn't shutdown in time")
+	}
+}
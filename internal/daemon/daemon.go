@@ -5,15 +5,23 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/sahithyandev/faa/internal/config"
 	"github.com/sahithyandev/faa/internal/lock"
+	"github.com/sahithyandev/faa/internal/mdns"
 	"github.com/sahithyandev/faa/internal/proxy"
+	"github.com/sahithyandev/faa/internal/resolver"
 )
 
 const (
@@ -24,6 +32,16 @@ const (
 	// caExportRetryDelay is the delay between CA certificate export retry attempts
 	// Increased to give Caddy more time to generate certificates
 	caExportRetryDelay = 500 * time.Millisecond
+
+	// readinessInterval is how often process ports are probed for readiness
+	readinessInterval = 1 * time.Second
+
+	// readinessDialTimeout is the maximum time to wait when probing a port
+	readinessDialTimeout = 200 * time.Millisecond
+
+	// caExpiryInterval is how often the CA certificates are checked for
+	// nearing expiry
+	caExpiryInterval = 12 * time.Hour
 )
 
 // Daemon represents the daemon process that manages routes and processes
@@ -33,17 +51,54 @@ type Daemon struct {
 	lock     *lock.Lock
 	listener net.Listener
 	shutdown chan struct{}
+	events   *eventBus
+	config   *config.Config
+
+	// apiServer serves the optional HTTP management API
+	apiServer *http.Server
+
+	// dnsServer answers for the domains in the config's dns.domains
+	dnsServer *resolver.Server
+
+	// mdnsResponder publishes the routes' .local names over mDNS
+	mdnsResponder *mdns.Responder
+
+	// internalRoutes are proxy routes owned by the daemon itself (such as the
+	// management API); they are applied alongside registry routes but never
+	// persisted to routes.json
+	internalRoutes map[string]int
+
+	readyMu sync.Mutex
+	ready   map[string]bool // project root -> port accepting connections
+
+	captureMu sync.Mutex
+	capture   map[string]bool // hosts with traffic capture enabled
+
+	chaosMu sync.Mutex
+	chaos   map[string][]proxy.ChaosRule // host -> injected faults
 }
 
 // New creates a new Daemon instance with the given registry and proxy
-func New(registry *Registry, proxy *proxy.Proxy) *Daemon {
+func New(registry *Registry, p *proxy.Proxy) *Daemon {
 	return &Daemon{
 		registry: registry,
-		proxy:    proxy,
+		proxy:    p,
 		shutdown: make(chan struct{}),
+		events:   newEventBus(),
+		ready:    make(map[string]bool),
+		capture:  make(map[string]bool),
+		chaos:    make(map[string][]proxy.ChaosRule),
+
+		internalRoutes: make(map[string]int),
 	}
 }
 
+// SetConfig sets the user configuration. It must be called before Start;
+// without it every setting uses its default.
+func (d *Daemon) SetConfig(cfg *config.Config) {
+	d.config = cfg
+}
+
 // SocketPath returns the path to the Unix socket
 func SocketPath() (string, error) {
 	// On macOS with LaunchDaemon, use /var/run/faa if FAA_SOCKET_DIR is set
@@ -59,6 +114,16 @@ func SocketPath() (string, error) {
 	return filepath.Join(configDir, "ctl.sock"), nil
 }
 
+// AdminSocketPath returns the path to the Caddy admin socket, next to the
+// control socket
+func AdminSocketPath() (string, error) {
+	socketPath, err := SocketPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(socketPath), "caddy-admin.sock"), nil
+}
+
 // LockPath returns the path to the daemon lock file
 func LockPath() (string, error) {
 	configDir, err := ConfigDir()
@@ -129,7 +194,7 @@ func (d *Daemon) loadAndApplyRoutes() error {
 
 	// Apply routes to proxy
 	if d.proxy != nil {
-		if err := d.proxy.ApplyRoutes(routes); err != nil {
+		if err := d.applyRoutes(routes); err != nil {
 			return fmt.Errorf("failed to apply routes to proxy: %w", err)
 		}
 
@@ -141,50 +206,176 @@ func (d *Daemon) loadAndApplyRoutes() error {
 	return nil
 }
 
+// applyRoutes applies registry routes plus the daemon's internal routes to the proxy
+func (d *Daemon) applyRoutes(routes map[string]int) error {
+	merged := make(map[string]int, len(routes)+len(d.internalRoutes))
+	for host, port := range routes {
+		merged[host] = port
+	}
+	for host, port := range d.internalRoutes {
+		merged[host] = port
+	}
+	return d.proxy.ApplyRoutes(merged)
+}
+
 // tryExportCA attempts to export the CA certificate after routes are applied
 func (d *Daemon) tryExportCA() {
-	// Try to export with retry logic
-	// Quick retries since Caddy generates certificates almost immediately after routes are applied
-	if err := proxy.ExportCAWithRetry(maxCAExportAttempts, caExportRetryDelay); err != nil {
+	if err := d.exportCA(); err != nil {
 		// Log warning but don't fail - CA export is not critical for daemon operation
-		// Failures are logged to stderr and not surfaced to caller as this is a
+		// Failures are logged and not surfaced to caller as this is a
 		// best-effort operation that can be retried by running 'faa ca-path' later
-		fmt.Fprintf(os.Stderr, "Warning: Failed to export CA certificate: %v\n", err)
+		slog.Warn("failed to export CA certificate", "error", err)
 	}
 }
 
-// removeSocket removes the socket file if it exists
-func (d *Daemon) removeSocket() {
-	sockPath, err := SocketPath()
+// exportCA exports the CA certificate, publishing a ca_rotated event if it
+// changed
+func (d *Daemon) exportCA() error {
+	// Remember the previously exported certificate so a rotation can be detected
+	caPath, _ := proxy.GetCAPath()
+	previous, _ := os.ReadFile(caPath)
+
+	srcPath, err := d.proxy.RootCAPath()
 	if err != nil {
-		return
+		return err
 	}
-	_ = os.Remove(sockPath)
-}
 
-// Start starts the daemon server
-func (d *Daemon) Start() error {
-	// Acquire lock to ensure single instance
-	if err := d.acquireLock(); err != nil {
+	// Try to export with retry logic
+	// Quick retries since Caddy generates certificates almost immediately after routes are applied
+	if err := proxy.ExportCAWithRetry(srcPath, maxCAExportAttempts, caExportRetryDelay); err != nil {
 		return err
 	}
-	defer d.lock.Release()
 
-	// Write PID file
-	if err := d.writePidFile(); err != nil {
-		return err
+	current, err := os.ReadFile(caPath)
+	if err == nil && len(previous) > 0 && string(previous) != string(current) {
+		d.events.publish(EventCARotated, &CARotatedEventData{Path: caPath})
 	}
-	defer d.removePidFile()
+	return nil
+}
 
-	// Load existing routes from routes.json and apply to proxy
-	if err := d.loadAndApplyRoutes(); err != nil {
-		return fmt.Errorf("failed to load and apply routes: %w", err)
+// handleRotateCA handles rotate_ca requests, replacing the local CA and
+// returning the new root
+func (d *Daemon) handleRotateCA(req *Request) *Response {
+	if d.proxy == nil {
+		return NewErrorResponse(fmt.Errorf("proxy is not running"))
+	}
+
+	if err := d.proxy.RotateCA(); err != nil {
+		slog.Error("failed to rotate CA", "error", err)
+		return NewErrorResponse(fmt.Errorf("failed to rotate CA: %w", err))
+	}
+	if err := d.exportCA(); err != nil {
+		return NewErrorResponse(fmt.Errorf("failed to export the new CA: %w", err))
+	}
+
+	caPath, err := proxy.GetCAPath()
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+	root, err := proxy.ReadCertificateInfo(caPath)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+	slog.Info("rotated CA", "subject", root.Subject, "sha256", root.SHA256, "not_after", root.NotAfter)
+
+	resp, _ := NewSuccessResponse(root)
+	return resp
+}
+
+// handleGetCertificate handles get_certificate requests, returning the
+// certificate and key served for a route's host, which is issued first if
+// needed
+func (d *Daemon) handleGetCertificate(req *Request) *Response {
+	var data GetCertificateData
+	if err := json.Unmarshal(req.Data, &data); err != nil {
+		return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
+	}
+
+	host, err := d.routeHost(data.Host)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+	if d.proxy == nil {
+		return NewErrorResponse(fmt.Errorf("proxy is not running"))
+	}
+	// Aliases in the DNS domains have certificates of their own
+	if alias := strings.ToLower(data.Host); d.canonicalHost(alias) != alias {
+		host = alias
+	}
+
+	chain, key, err := d.proxy.Certificate(host)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+	resp, _ := NewSuccessResponse(&CertificateResponseData{
+		Host:        host,
+		Certificate: string(chain),
+		Key:         string(key),
+	})
+	return resp
+}
+
+// handleIssueClientCertificate handles issue_client_certificate requests,
+// signing a client certificate with the proxy's CA
+func (d *Daemon) handleIssueClientCertificate(req *Request) *Response {
+	var data IssueClientCertificateData
+	if err := json.Unmarshal(req.Data, &data); err != nil {
+		return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
+	}
+	if data.Days <= 0 {
+		return NewErrorResponse(invalidRequestError("days must be positive, got %d", data.Days))
+	}
+	if d.proxy == nil {
+		return NewErrorResponse(fmt.Errorf("proxy is not running"))
+	}
+
+	cert, key, err := d.proxy.IssueClientCertificate(data.Name, time.Duration(data.Days)*24*time.Hour)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+	resp, _ := NewSuccessResponse(&ClientCertificateResponseData{
+		Name:        data.Name,
+		Certificate: string(cert),
+		Key:         string(key),
+	})
+	return resp
+}
+
+// watchCAExpiry logs a warning for each CA certificate nearing expiry, at
+// startup and then every caExpiryInterval until ctx is cancelled
+func (d *Daemon) watchCAExpiry(ctx context.Context) {
+	ticker := time.NewTicker(caExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		configured := d.config != nil && d.config.TLS.CA != nil
+		for _, warning := range proxy.CAExpiryWarnings(configured, time.Now()) {
+			slog.Warn(warning)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
+}
 
+// removeSocket removes the socket file if it exists
+func (d *Daemon) removeSocket() {
+	sockPath, err := SocketPath()
+	if err != nil {
+		return
+	}
+	_ = os.Remove(sockPath)
+}
+
+// listen creates the control socket
+func (d *Daemon) listen() (net.Listener, error) {
 	// Get socket path
 	sockPath, err := SocketPath()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Remove existing socket file if it exists (from previous unclean shutdown)
@@ -193,17 +384,14 @@ func (d *Daemon) Start() error {
 	// Ensure socket directory exists with proper permissions
 	sockDir := filepath.Dir(sockPath)
 	if err := os.MkdirAll(sockDir, 0755); err != nil {
-		return fmt.Errorf("failed to create socket directory: %w", err)
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
 	}
 
 	// Create Unix socket listener
 	listener, err := net.Listen("unix", sockPath)
 	if err != nil {
-		return fmt.Errorf("failed to create Unix socket: %w", err)
+		return nil, fmt.Errorf("failed to create Unix socket: %w", err)
 	}
-	d.listener = listener
-	defer listener.Close()
-	defer d.removeSocket()
 
 	// Set socket permissions to 0666 to allow all users to connect
 	// This is needed when daemon runs as root but users need to connect
@@ -213,9 +401,65 @@ func (d *Daemon) Start() error {
 		socketPerms = 0666
 	}
 	if err := os.Chmod(sockPath, socketPerms); err != nil {
-		return fmt.Errorf("failed to set socket permissions: %w", err)
+		listener.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
 	}
 
+	return listener, nil
+}
+
+// Start starts the daemon server
+func (d *Daemon) Start() error {
+	// Acquire lock to ensure single instance
+	if err := d.acquireLock(); err != nil {
+		return err
+	}
+	defer d.lock.Release()
+
+	// Write PID file
+	if err := d.writePidFile(); err != nil {
+		return err
+	}
+	defer d.removePidFile()
+
+	// Start the management API first so its route is applied with the others
+	if err := d.startAPI(); err != nil {
+		return fmt.Errorf("failed to start management API: %w", err)
+	}
+	defer d.stopAPI()
+
+	if err := d.startDNS(); err != nil {
+		return fmt.Errorf("failed to start DNS server: %w", err)
+	}
+	defer d.stopDNS()
+
+	d.startMDNS()
+	defer d.stopMDNS()
+
+	// Load existing routes from routes.json and apply to proxy
+	if err := d.loadAndApplyRoutes(); err != nil {
+		return fmt.Errorf("failed to load and apply routes: %w", err)
+	}
+
+	// Use the control socket from systemd when socket activated. systemd
+	// owns the socket file, so it is left in place on shutdown.
+	listener, err := ActivationListener()
+	if err != nil {
+		return err
+	}
+	activated := listener != nil
+	if activated {
+		slog.Info("using control socket from systemd", "socket", listener.Addr().String())
+	} else if listener, err = d.listen(); err != nil {
+		return err
+	}
+	d.listener = listener
+	defer listener.Close()
+	if !activated {
+		defer d.removeSocket()
+	}
+	slog.Info("daemon started", "pid", os.Getpid(), "socket", listener.Addr().String())
+
 	// Setup signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
@@ -229,16 +473,28 @@ func (d *Daemon) Start() error {
 		errChan <- d.acceptLoop(ctx)
 	}()
 
+	// Probe process ports in the background to publish readiness changes
+	go d.watchReadiness(ctx)
+
+	// Warn about CA certificates nearing expiry
+	go d.watchCAExpiry(ctx)
+
+	// Keep the hosts file's block in sync with the routes, if enabled
+	go d.watchHosts(ctx)
+
+	// Publish and withdraw the routes' .local names over mDNS
+	go d.watchMDNS(ctx)
+
 	// Wait for shutdown signal or error
 	select {
 	case sig := <-sigChan:
-		fmt.Printf("Received signal %v, shutting down gracefully...\n", sig)
+		slog.Info("received signal, shutting down", "signal", sig.String())
 	case err := <-errChan:
 		if err != nil {
 			return err
 		}
 	case <-d.shutdown:
-		fmt.Println("Shutdown requested, stopping daemon...")
+		slog.Info("shutdown requested, stopping daemon")
 	}
 
 	// Cancel context to stop accepting new connections
@@ -246,7 +502,7 @@ func (d *Daemon) Start() error {
 
 	// Close listener to stop accepting connections
 	if err := d.listener.Close(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error closing listener: %v\n", err)
+		slog.Error("failed to close listener", "error", err)
 	}
 
 	return nil
@@ -296,17 +552,60 @@ func (d *Daemon) handleConnection(conn net.Conn) {
 			return
 		}
 
+		// A subscription takes over the connection until the client disconnects
+		if req.Type == MessageTypeSubscribe {
+			d.handleSubscribe(conn, reader, req)
+			return
+		}
+
 		// Handle request and generate response
-		resp := d.handleRequest(req)
+		var resp *Response
+		if err := checkPeer(conn, req.Type); err != nil {
+			resp = NewErrorResponse(err)
+		} else {
+			resp = d.handleRequest(req)
+		}
 
 		// Encode and send response
 		if err := EncodeResponse(conn, resp); err != nil {
-			fmt.Fprintf(os.Stderr, "Error encoding response: %v\n", err)
+			slog.Error("failed to encode response", "type", req.Type, "error", err)
 			return
 		}
 	}
 }
 
+// ownerOnlyRequests return secrets such as private keys and captured
+// headers, mint credentials or replace the CA, so on the control socket,
+// which any user can connect to when it is shared (see listen), they are
+// only served to the user running the daemon and root. The management API
+// is already limited to holders of its token.
+var ownerOnlyRequests = map[MessageType]bool{
+	MessageTypeGetCertificate:         true,
+	MessageTypeIssueClientCertificate: true,
+	MessageTypeRotateCA:               true,
+	MessageTypeGetCapture:             true,
+}
+
+// checkPeer returns an error if the client at the other end of conn may not
+// make requests of type msgType
+func checkPeer(conn net.Conn, msgType MessageType) error {
+	if !ownerOnlyRequests[msgType] {
+		return nil
+	}
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return fmt.Errorf("%s is only allowed on the control socket", msgType)
+	}
+	uid, err := peerUID(unixConn)
+	if err != nil {
+		return fmt.Errorf("failed to check the client's user for %s: %w", msgType, err)
+	}
+	if uid != 0 && uid != uint32(os.Getuid()) {
+		return fmt.Errorf("%s is only allowed for the user running the daemon", msgType)
+	}
+	return nil
+}
+
 // handleRequest processes a request and returns a response
 func (d *Daemon) handleRequest(req *Request) *Response {
 	switch req.Type {
@@ -328,8 +627,36 @@ func (d *Daemon) handleRequest(req *Request) *Response {
 		return d.handleStatus(req)
 	case MessageTypeStop:
 		return d.handleStop(req)
+	case MessageTypeStopProcess:
+		return d.handleStopProcess(req)
+	case MessageTypeRestartProcess:
+		return d.handleRestartProcess(req)
+	case MessageTypeRecentRequests:
+		return d.handleRecentRequests(req)
+	case MessageTypeRequestStats:
+		return d.handleRequestStats(req)
+	case MessageTypeTailLog:
+		return d.handleTailLog(req)
+	case MessageTypeSetCapture:
+		return d.handleSetCapture(req)
+	case MessageTypeListCaptures:
+		return d.handleListCaptures(req)
+	case MessageTypeGetCapture:
+		return d.handleGetCapture(req)
+	case MessageTypeClearCaptures:
+		return d.handleClearCaptures(req)
+	case MessageTypeSetChaos:
+		return d.handleSetChaos(req)
+	case MessageTypeGetChaos:
+		return d.handleGetChaos(req)
+	case MessageTypeRotateCA:
+		return d.handleRotateCA(req)
+	case MessageTypeGetCertificate:
+		return d.handleGetCertificate(req)
+	case MessageTypeIssueClientCertificate:
+		return d.handleIssueClientCertificate(req)
 	default:
-		return NewErrorResponse(fmt.Errorf("unknown message type: %s", req.Type))
+		return NewErrorResponse(invalidRequestError("unknown message type: %s", req.Type))
 	}
 }
 
@@ -343,23 +670,32 @@ func (d *Daemon) handlePing(req *Request) *Response {
 func (d *Daemon) handleUpsertRoute(req *Request) *Response {
 	var data UpsertRouteData
 	if err := json.Unmarshal(req.Data, &data); err != nil {
-		return NewErrorResponse(fmt.Errorf("invalid request data: %w", err))
+		return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
+	}
+
+	previousPort, err := d.registry.GetRoute(data.Host)
+	if err != nil {
+		return NewErrorResponse(err)
 	}
 
 	if err := d.registry.UpsertRoute(data.Host, data.Port); err != nil {
 		return NewErrorResponse(err)
 	}
 
-	// Load all routes and apply to proxy
-	routes, err := d.registry.loadRoutes()
-	if err != nil {
-		return NewErrorResponse(fmt.Errorf("failed to load routes: %w", err))
+	if previousPort != data.Port {
+		slog.Info("route updated", "host", normalizeHost(data.Host), "port", data.Port, "previous_port", previousPort)
+		d.events.publish(EventRouteAdded, &RouteEventData{
+			Host: normalizeHost(data.Host),
+			Port: data.Port,
+		})
 	}
 
-	// Apply routes to proxy
-	if d.proxy != nil {
-		if err := d.proxy.ApplyRoutes(routes); err != nil {
-			return NewErrorResponse(fmt.Errorf("failed to apply routes to proxy: %w", err))
+	// Update only this host in the proxy; the daemon's own routes win
+	host := normalizeHost(data.Host)
+	if _, internal := d.internalRoutes[host]; d.proxy != nil && !internal {
+		if err := d.proxy.SetRoute(host, data.Port); err != nil {
+			slog.Error("failed to apply route to proxy", "host", host, "error", err)
+			return NewErrorResponse(fmt.Errorf("failed to apply route to proxy: %w", err))
 		}
 
 		// Export CA after applying routes (in background to not block the response)
@@ -374,7 +710,7 @@ func (d *Daemon) handleUpsertRoute(req *Request) *Response {
 func (d *Daemon) handleGetRoute(req *Request) *Response {
 	var data GetRouteData
 	if err := json.Unmarshal(req.Data, &data); err != nil {
-		return NewErrorResponse(fmt.Errorf("invalid request data: %w", err))
+		return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
 	}
 
 	port, err := d.registry.GetRoute(data.Host)
@@ -401,7 +737,7 @@ func (d *Daemon) handleListRoutes(req *Request) *Response {
 func (d *Daemon) handleSetProcess(req *Request) *Response {
 	var data SetProcessData
 	if err := json.Unmarshal(req.Data, &data); err != nil {
-		return NewErrorResponse(fmt.Errorf("invalid request data: %w", err))
+		return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
 	}
 
 	startedAt := data.StartedAt
@@ -409,10 +745,28 @@ func (d *Daemon) handleSetProcess(req *Request) *Response {
 		startedAt = time.Now()
 	}
 
+	previous, err := d.registry.GetProcess(data.ProjectRoot)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+
 	if err := d.registry.SetProcess(data.ProjectRoot, data.PID, data.Host, data.Port, startedAt); err != nil {
 		return NewErrorResponse(err)
 	}
 
+	eventData := &ProcessEventData{
+		ProjectRoot: data.ProjectRoot,
+		PID:         data.PID,
+		Host:        data.Host,
+		Port:        data.Port,
+	}
+	switch {
+	case previous == nil:
+		d.events.publish(EventProcessStarted, eventData)
+	case previous.PID != data.PID:
+		d.events.publish(EventProcessRestarted, eventData)
+	}
+
 	resp, _ := NewSuccessResponse(nil)
 	return resp
 }
@@ -421,14 +775,11 @@ func (d *Daemon) handleSetProcess(req *Request) *Response {
 func (d *Daemon) handleGetProcess(req *Request) *Response {
 	var data GetProcessData
 	if err := json.Unmarshal(req.Data, &data); err != nil {
-		return NewErrorResponse(fmt.Errorf("invalid request data: %w", err))
+		return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
 	}
 
 	// Clean up stale processes before checking
-	if _, err := d.registry.CleanupStaleProcesses(); err != nil {
-		// Log the error but continue - this shouldn't fail the request
-		fmt.Fprintf(os.Stderr, "Warning: Failed to cleanup stale processes: %v\n", err)
-	}
+	d.cleanupStaleProcesses()
 
 	proc, err := d.registry.GetProcess(data.ProjectRoot)
 	if err != nil {
@@ -443,13 +794,22 @@ func (d *Daemon) handleGetProcess(req *Request) *Response {
 func (d *Daemon) handleClearProcess(req *Request) *Response {
 	var data ClearProcessData
 	if err := json.Unmarshal(req.Data, &data); err != nil {
-		return NewErrorResponse(fmt.Errorf("invalid request data: %w", err))
+		return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
+	}
+
+	previous, err := d.registry.GetProcess(data.ProjectRoot)
+	if err != nil {
+		return NewErrorResponse(err)
 	}
 
 	if err := d.registry.ClearProcess(data.ProjectRoot); err != nil {
 		return NewErrorResponse(err)
 	}
 
+	if previous != nil {
+		d.publishProcessExited(previous)
+	}
+
 	resp, _ := NewSuccessResponse(nil)
 	return resp
 }
@@ -457,10 +817,7 @@ func (d *Daemon) handleClearProcess(req *Request) *Response {
 // handleStatus handles status requests
 func (d *Daemon) handleStatus(req *Request) *Response {
 	// Clean up stale processes before returning status
-	if _, err := d.registry.CleanupStaleProcesses(); err != nil {
-		// Log the error but continue - this shouldn't fail the request
-		fmt.Fprintf(os.Stderr, "Warning: Failed to cleanup stale processes: %v\n", err)
-	}
+	d.cleanupStaleProcesses()
 
 	routes, err := d.registry.ListRoutes()
 	if err != nil {
@@ -475,8 +832,15 @@ func (d *Daemon) handleStatus(req *Request) *Response {
 	statusData := StatusResponseData{
 		Routes:    routes,
 		Processes: processes,
+		Ready:     make(map[string]bool, len(processes)),
 	}
 
+	d.readyMu.Lock()
+	for _, proc := range processes {
+		statusData.Ready[proc.Host] = d.ready[proc.ProjectRoot]
+	}
+	d.readyMu.Unlock()
+
 	resp, _ := NewSuccessResponse(statusData)
 	return resp
 }
@@ -486,7 +850,7 @@ func (d *Daemon) handleStop(req *Request) *Response {
 	var data StopData
 	if req.Data != nil {
 		if err := json.Unmarshal(req.Data, &data); err != nil {
-			return NewErrorResponse(fmt.Errorf("invalid request data: %w", err))
+			return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
 		}
 	}
 
@@ -504,3 +868,386 @@ func (d *Daemon) handleStop(req *Request) *Response {
 	resp, _ := NewSuccessResponse(nil)
 	return resp
 }
+
+// handleStopProcess handles stop_process requests by publishing a
+// stop_requested event for the 'faa run' that owns the process, which stops
+// its own dev server. The daemon never signals a PID a client recorded.
+func (d *Daemon) handleStopProcess(req *Request) *Response {
+	var data ProcessControlData
+	if err := json.Unmarshal(req.Data, &data); err != nil {
+		return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
+	}
+
+	d.cleanupStaleProcesses()
+
+	proc, err := d.registry.GetProcess(data.ProjectRoot)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+	if proc == nil {
+		return NewErrorResponse(notFoundError("no process running for %s", data.ProjectRoot))
+	}
+
+	d.events.publish(EventStopRequested, &ProcessEventData{
+		ProjectRoot: proc.ProjectRoot,
+		PID:         proc.PID,
+		Host:        proc.Host,
+		Port:        proc.Port,
+	})
+
+	resp, _ := NewSuccessResponse(nil)
+	return resp
+}
+
+// handleRestartProcess handles restart_process requests by publishing a
+// restart_requested event for the 'faa run' that owns the process
+func (d *Daemon) handleRestartProcess(req *Request) *Response {
+	var data ProcessControlData
+	if err := json.Unmarshal(req.Data, &data); err != nil {
+		return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
+	}
+
+	d.cleanupStaleProcesses()
+
+	proc, err := d.registry.GetProcess(data.ProjectRoot)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+	if proc == nil {
+		return NewErrorResponse(notFoundError("no process running for %s", data.ProjectRoot))
+	}
+
+	d.events.publish(EventRestartRequested, &ProcessEventData{
+		ProjectRoot: proc.ProjectRoot,
+		PID:         proc.PID,
+		Host:        proc.Host,
+		Port:        proc.Port,
+	})
+
+	resp, _ := NewSuccessResponse(nil)
+	return resp
+}
+
+// handleRecentRequests handles recent_requests requests
+func (d *Daemon) handleRecentRequests(req *Request) *Response {
+	var data RecentRequestsData
+	if err := json.Unmarshal(req.Data, &data); err != nil {
+		return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
+	}
+
+	resp, _ := NewSuccessResponse(proxy.RecentRequests(normalizeHost(data.Host), data.Limit))
+	return resp
+}
+
+// handleRequestStats handles request_stats requests
+func (d *Daemon) handleRequestStats(req *Request) *Response {
+	resp, _ := NewSuccessResponse(proxy.RequestStats())
+	return resp
+}
+
+// handleTailLog handles tail_log requests
+func (d *Daemon) handleTailLog(req *Request) *Response {
+	var data TailLogData
+	if err := json.Unmarshal(req.Data, &data); err != nil {
+		return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
+	}
+
+	logPath, err := LogPath(data.Host)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+
+	lines, err := tailLog(logPath, data.Lines)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+
+	resp, _ := NewSuccessResponse(&TailLogResponseData{Path: logPath, Lines: lines})
+	return resp
+}
+
+// handleSetCapture handles set_capture requests
+func (d *Daemon) handleSetCapture(req *Request) *Response {
+	var data SetCaptureData
+	if err := json.Unmarshal(req.Data, &data); err != nil {
+		return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
+	}
+
+	host, err := d.routeHost(data.Host)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+
+	d.captureMu.Lock()
+	defer d.captureMu.Unlock()
+
+	if d.proxy != nil {
+		if err := d.proxy.SetCapture(host, data.Enabled); err != nil {
+			return NewErrorResponse(fmt.Errorf("failed to update proxy: %w", err))
+		}
+	}
+	if data.Enabled {
+		d.capture[host] = true
+	} else {
+		delete(d.capture, host)
+	}
+
+	resp, _ := NewSuccessResponse(nil)
+	return resp
+}
+
+// handleListCaptures handles list_captures requests
+func (d *Daemon) handleListCaptures(req *Request) *Response {
+	var data ListCapturesData
+	if err := json.Unmarshal(req.Data, &data); err != nil {
+		return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
+	}
+
+	host, err := d.routeHost(data.Host)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+
+	d.captureMu.Lock()
+	enabled := d.capture[host]
+	d.captureMu.Unlock()
+
+	resp, _ := NewSuccessResponse(&ListCapturesResponseData{
+		Host:      host,
+		Enabled:   enabled,
+		Exchanges: proxy.CapturedExchanges(host, data.Limit),
+	})
+	return resp
+}
+
+// handleGetCapture handles get_capture requests
+func (d *Daemon) handleGetCapture(req *Request) *Response {
+	var data GetCaptureData
+	if err := json.Unmarshal(req.Data, &data); err != nil {
+		return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
+	}
+
+	exchange, ok := proxy.CapturedExchange(data.ID)
+	if !ok {
+		return NewErrorResponse(notFoundError("no captured exchange with id %d", data.ID))
+	}
+
+	resp, _ := NewSuccessResponse(&exchange)
+	return resp
+}
+
+// handleClearCaptures handles clear_captures requests
+func (d *Daemon) handleClearCaptures(req *Request) *Response {
+	var data ClearCapturesData
+	if err := json.Unmarshal(req.Data, &data); err != nil {
+		return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
+	}
+
+	host, err := d.routeHost(data.Host)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+
+	proxy.ClearCaptured(host)
+
+	resp, _ := NewSuccessResponse(nil)
+	return resp
+}
+
+// handleSetChaos handles set_chaos requests
+func (d *Daemon) handleSetChaos(req *Request) *Response {
+	var data SetChaosData
+	if err := json.Unmarshal(req.Data, &data); err != nil {
+		return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
+	}
+
+	host, err := d.routeHost(data.Host)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+	for _, rule := range data.Rules {
+		if err := rule.Validate(); err != nil {
+			return NewErrorResponse(invalidRequestError("invalid chaos rule: %w", err))
+		}
+	}
+
+	d.chaosMu.Lock()
+	defer d.chaosMu.Unlock()
+
+	if d.proxy != nil {
+		if err := d.proxy.SetChaos(host, data.Rules); err != nil {
+			return NewErrorResponse(fmt.Errorf("failed to update proxy: %w", err))
+		}
+	}
+	if len(data.Rules) == 0 {
+		delete(d.chaos, host)
+	} else {
+		d.chaos[host] = proxy.SortChaosRules(data.Rules)
+	}
+
+	resp, _ := NewSuccessResponse(nil)
+	return resp
+}
+
+// handleGetChaos handles get_chaos requests
+func (d *Daemon) handleGetChaos(req *Request) *Response {
+	var data GetChaosData
+	if err := json.Unmarshal(req.Data, &data); err != nil {
+		return NewErrorResponse(invalidRequestError("invalid request data: %w", err))
+	}
+
+	host, err := d.routeHost(data.Host)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+
+	d.chaosMu.Lock()
+	rules := append([]proxy.ChaosRule{}, d.chaos[host]...)
+	d.chaosMu.Unlock()
+
+	resp, _ := NewSuccessResponse(&ChaosResponseData{Host: host, Rules: rules})
+	return resp
+}
+
+// routeHost returns the host a route is registered under, accepting either
+// local suffix like GetRoute does
+func (d *Daemon) routeHost(host string) (string, error) {
+	routes, err := d.registry.loadRoutes()
+	if err != nil {
+		return "", fmt.Errorf("failed to load routes: %w", err)
+	}
+
+	normalizedHost := normalizeHost(d.canonicalHost(host))
+	if _, ok := routes[normalizedHost]; ok {
+		return normalizedHost, nil
+	}
+	if alternateHost := alternateLocalHost(normalizedHost); alternateHost != "" {
+		if _, ok := routes[alternateHost]; ok {
+			return alternateHost, nil
+		}
+	}
+	return "", notFoundError("no route for %s", normalizedHost)
+}
+
+// handleSubscribe streams events to the client until it disconnects or the
+// daemon shuts down
+func (d *Daemon) handleSubscribe(conn net.Conn, reader *bufio.Reader, req *Request) {
+	var data SubscribeData
+	if req.Data != nil {
+		if err := json.Unmarshal(req.Data, &data); err != nil {
+			_ = EncodeResponse(conn, NewErrorResponse(invalidRequestError("invalid request data: %w", err)))
+			return
+		}
+	}
+
+	sub := d.events.subscribe(data.Types)
+	defer d.events.unsubscribe(sub)
+
+	resp, _ := NewSuccessResponse(nil)
+	if err := EncodeResponse(conn, resp); err != nil {
+		return
+	}
+
+	// Detect client disconnects; subscribers send nothing after subscribing
+	closed := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, reader)
+		close(closed)
+	}()
+
+	for {
+		select {
+		case event, ok := <-sub.events:
+			if !ok {
+				// Dropped for falling behind
+				return
+			}
+			if err := EncodeEvent(conn, event); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-d.shutdown:
+			return
+		}
+	}
+}
+
+// cleanupStaleProcesses removes dead processes and publishes exit events
+func (d *Daemon) cleanupStaleProcesses() {
+	stale, err := d.registry.RemoveStaleProcesses()
+	if err != nil {
+		// Log the error but continue - this shouldn't fail the request
+		slog.Warn("failed to clean up stale processes", "error", err)
+	}
+	for _, proc := range stale {
+		d.publishProcessExited(proc)
+	}
+}
+
+// publishProcessExited publishes a process_exited event for proc
+func (d *Daemon) publishProcessExited(proc *Process) {
+	d.events.publish(EventProcessExited, &ProcessEventData{
+		ProjectRoot: proc.ProjectRoot,
+		PID:         proc.PID,
+		Host:        proc.Host,
+		Port:        proc.Port,
+	})
+}
+
+// watchReadiness periodically probes process ports until ctx is cancelled
+func (d *Daemon) watchReadiness(ctx context.Context) {
+	ticker := time.NewTicker(readinessInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.checkReadiness()
+		}
+	}
+}
+
+// checkReadiness probes each registered process port once and publishes a
+// readiness_changed event for every process whose state changed
+func (d *Daemon) checkReadiness() {
+	processes, err := d.registry.ListProcesses()
+	if err != nil {
+		return
+	}
+
+	current := make(map[string]bool, len(processes))
+	for _, proc := range processes {
+		current[proc.ProjectRoot] = isPortAccepting(proc.Port)
+	}
+
+	d.readyMu.Lock()
+	previous := d.ready
+	d.ready = current
+	d.readyMu.Unlock()
+
+	for _, proc := range processes {
+		ready := current[proc.ProjectRoot]
+		if ready == previous[proc.ProjectRoot] {
+			continue
+		}
+		d.events.publish(EventReadinessChanged, &ReadinessEventData{
+			ProjectRoot: proc.ProjectRoot,
+			Host:        proc.Host,
+			Port:        proc.Port,
+			Ready:       ready,
+		})
+	}
+}
+
+// isPortAccepting reports whether something is listening on the local port
+func isPortAccepting(port int) bool {
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("localhost:%d", port), readinessDialTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
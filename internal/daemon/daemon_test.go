@@ -9,6 +9,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/sahithyandev/faa/internal/proxy"
 )
 
 // containsAny checks if s contains any of the substrings
@@ -38,6 +40,23 @@ func TestSocketPath(t *testing.T) {
 	}
 }
 
+func TestAdminSocketPath(t *testing.T) {
+	adminPath, err := AdminSocketPath()
+	if err != nil {
+		t.Fatalf("AdminSocketPath() failed: %v", err)
+	}
+
+	sockPath, err := SocketPath()
+	if err != nil {
+		t.Fatalf("SocketPath() failed: %v", err)
+	}
+
+	expected := filepath.Join(filepath.Dir(sockPath), "caddy-admin.sock")
+	if adminPath != expected {
+		t.Errorf("AdminSocketPath() = %s, want %s", adminPath, expected)
+	}
+}
+
 func TestLockPath(t *testing.T) {
 	lockPath, err := LockPath()
 	if err != nil {
@@ -715,3 +734,114 @@ func TestDaemonGetRoute(t *testing.T) {
 		t.Fatal("Daemon didn't shutdown in time")
 	}
 }
+
+func TestDaemonRotateCAWithoutProxy(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+	d := New(registry, nil)
+
+	req, err := NewRequest(MessageTypeRotateCA, nil)
+	if err != nil {
+		t.Fatalf("NewRequest() failed: %v", err)
+	}
+	resp := d.handleRotateCA(req)
+	if resp.Ok {
+		t.Error("Expected rotating the CA to fail without a proxy")
+	}
+}
+
+func TestDaemonGetCertificate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+	if err := registry.UpsertRoute("app.localhost", 3000); err != nil {
+		t.Fatalf("UpsertRoute() failed: %v", err)
+	}
+	d := New(registry, nil)
+
+	for host, reason := range map[string]string{
+		"missing.localhost": "an unknown host",
+		"app.local":         "a routed host without a proxy",
+	} {
+		req, err := NewRequest(MessageTypeGetCertificate, &GetCertificateData{Host: host})
+		if err != nil {
+			t.Fatalf("NewRequest() failed: %v", err)
+		}
+		if resp := d.handleGetCertificate(req); resp.Ok {
+			t.Errorf("Expected get_certificate to fail for %s", reason)
+		}
+	}
+}
+
+func TestDaemonIssueClientCertificate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+
+	tests := []struct {
+		reason string
+		proxy  *proxy.Proxy
+		days   int
+	}{
+		{"a daemon without a proxy", nil, 30},
+		{"a zero lifetime", proxy.New(), 0},
+		// Caddy hasn't created the local CA in the fresh home directory
+		{"a missing CA", proxy.New(), 30},
+	}
+	for _, tt := range tests {
+		d := New(registry, tt.proxy)
+		req, err := NewRequest(MessageTypeIssueClientCertificate, &IssueClientCertificateData{Name: "alice", Days: tt.days})
+		if err != nil {
+			t.Fatalf("NewRequest() failed: %v", err)
+		}
+		if resp := d.handleIssueClientCertificate(req); resp.Ok {
+			t.Errorf("Expected issue_client_certificate to fail for %s", tt.reason)
+		}
+	}
+}
+
+func TestCheckPeer(t *testing.T) {
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "ctl.sock"))
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	client, err := net.Dial("unix", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	server, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+	defer server.Close()
+
+	// The daemon's own user may ask for keys
+	if err := checkPeer(server, MessageTypeGetCertificate); err != nil {
+		t.Errorf("checkPeer() for the daemon's user failed: %v", err)
+	}
+
+	// Connections whose user can't be checked only get other requests
+	pipe, other := net.Pipe()
+	defer pipe.Close()
+	defer other.Close()
+	for _, msgType := range []MessageType{MessageTypeGetCertificate, MessageTypeIssueClientCertificate, MessageTypeRotateCA, MessageTypeGetCapture} {
+		if err := checkPeer(pipe, msgType); err == nil {
+			t.Errorf("Expected %s to be refused when the user can't be checked", msgType)
+		}
+	}
+	if err := checkPeer(pipe, MessageTypeStatus); err != nil {
+		t.Errorf("checkPeer() for status failed: %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+// faa dashboard: polls the management API and renders routes, processes,
+// recent requests and log tails. All values are inserted as text, never HTML.
+"use strict";
+
+const REFRESH_INTERVAL_MS = 2000;
+const TOKEN_KEY = "faa.apiToken";
+
+let token = sessionStorage.getItem(TOKEN_KEY) || "";
+let selectedHost = "";
+let timer = null;
+
+// Take the token from the URL fragment (#token=...) set by 'faa dashboard'
+// and remove it from the address bar so it is not bookmarked or shared.
+(function readTokenFromFragment() {
+  const params = new URLSearchParams(location.hash.slice(1));
+  const fromURL = params.get("token");
+  if (fromURL) {
+    token = fromURL;
+    sessionStorage.setItem(TOKEN_KEY, token);
+    history.replaceState(null, "", location.pathname + location.search);
+  }
+})();
+
+async function api(method, path) {
+  const resp = await fetch(path, {
+    method,
+    headers: { Authorization: "Bearer " + token },
+  });
+  if (resp.status === 401) {
+    showLogin();
+    throw new Error("unauthorized");
+  }
+  const body = await resp.json();
+  if (!resp.ok) {
+    throw new Error(body.error || resp.statusText);
+  }
+  return body;
+}
+
+function el(tag, text, className) {
+  const node = document.createElement(tag);
+  if (text !== undefined) {
+    node.textContent = text;
+  }
+  if (className) {
+    node.className = className;
+  }
+  return node;
+}
+
+function formatUptime(startedAt) {
+  const seconds = Math.max(0, Math.floor((Date.now() - Date.parse(startedAt)) / 1000));
+  const h = Math.floor(seconds / 3600);
+  const m = Math.floor((seconds % 3600) / 60);
+  const s = seconds % 60;
+  if (h > 0) {
+    return `${h}h ${m}m`;
+  }
+  if (m > 0) {
+    return `${m}m ${s}s`;
+  }
+  return `${s}s`;
+}
+
+function statusClass(status) {
+  if (status >= 500) {
+    return "fail";
+  }
+  if (status >= 400) {
+    return "warn";
+  }
+  return "ok";
+}
+
+function button(label, onClick) {
+  const b = el("button", label);
+  b.addEventListener("click", (event) => {
+    event.stopPropagation();
+    onClick();
+  });
+  return b;
+}
+
+async function control(action, proc) {
+  try {
+    await api("POST", `/api/processes/${action}?projectRoot=${encodeURIComponent(proc.projectRoot)}`);
+  } catch (err) {
+    alert(`Failed to ${action} ${proc.host}: ${err.message}`);
+  }
+  refresh();
+}
+
+function renderProcesses(status) {
+  const tbody = document.querySelector("#processes tbody");
+  tbody.replaceChildren();
+
+  const processes = (status.processes || []).slice().sort((a, b) => a.host.localeCompare(b.host));
+  document.getElementById("no-processes").hidden = processes.length > 0;
+
+  for (const proc of processes) {
+    const ready = status.ready && status.ready[proc.host];
+    const row = el("tr", undefined, "clickable");
+    if (proc.host === selectedHost) {
+      row.classList.add("selected");
+    }
+    row.addEventListener("click", () => {
+      selectedHost = proc.host;
+      refresh();
+    });
+
+    const link = el("a", proc.host);
+    link.href = `https://${proc.host}`;
+    link.target = "_blank";
+    link.rel = "noopener";
+    link.addEventListener("click", (event) => event.stopPropagation());
+    const hostCell = el("td");
+    hostCell.append(link);
+
+    const actions = el("td");
+    actions.append(
+      button("Open", () => window.open(`https://${proc.host}`, "_blank", "noopener")),
+      button("Restart", () => control("restart", proc)),
+      button("Stop", () => control("stop", proc)),
+    );
+
+    row.append(
+      hostCell,
+      el("td", String(proc.port)),
+      el("td", String(proc.pid)),
+      el("td", formatUptime(proc.startedAt)),
+      el("td", ready ? "ready" : "starting", ready ? "ok" : "warn"),
+      actions,
+    );
+    tbody.append(row);
+  }
+
+  if (!processes.some((proc) => proc.host === selectedHost)) {
+    selectedHost = processes.length > 0 ? processes[0].host : "";
+  }
+}
+
+function renderRoutes(status) {
+  const tbody = document.querySelector("#routes tbody");
+  tbody.replaceChildren();
+
+  const routes = (status.routes || []).slice().sort((a, b) => a.host.localeCompare(b.host));
+  for (const route of routes) {
+    const row = el("tr");
+    row.append(el("td", route.host), el("td", String(route.port)));
+    tbody.append(row);
+  }
+}
+
+async function renderDetails() {
+  const details = document.getElementById("details");
+  if (!selectedHost) {
+    details.hidden = true;
+    return;
+  }
+  details.hidden = false;
+  document.getElementById("details-title").textContent = selectedHost;
+
+  const query = `host=${encodeURIComponent(selectedHost)}`;
+  const [requests, log] = await Promise.all([
+    api("GET", `/api/requests?${query}&limit=20`),
+    api("GET", `/api/logs?${query}&lines=200`),
+  ]);
+
+  const tbody = document.querySelector("#requests tbody");
+  tbody.replaceChildren();
+  for (const req of requests) {
+    const row = el("tr");
+    row.title = req.error || "";
+    row.append(
+      el("td", new Date(req.time).toLocaleTimeString()),
+      el("td", req.method),
+      el("td", req.path),
+      el("td", String(req.status), statusClass(req.status)),
+      el("td", `${req.durationMs.toFixed(1)} ms`),
+    );
+    tbody.append(row);
+  }
+
+  const pre = document.getElementById("log");
+  const atBottom = pre.scrollTop + pre.clientHeight >= pre.scrollHeight - 4;
+  pre.textContent = log.lines.join("\n");
+  if (atBottom) {
+    pre.scrollTop = pre.scrollHeight;
+  }
+}
+
+function setDaemonState(text, className) {
+  const badge = document.getElementById("daemon-state");
+  badge.textContent = text;
+  badge.className = `badge ${className}`;
+}
+
+async function refresh() {
+  try {
+    const status = await api("GET", "/api/status");
+    setDaemonState("daemon running", "ok");
+    renderProcesses(status);
+    renderRoutes(status);
+    await renderDetails();
+  } catch (err) {
+    if (err.message !== "unauthorized") {
+      setDaemonState(`error: ${err.message}`, "fail");
+    }
+  }
+}
+
+function showLogin() {
+  clearInterval(timer);
+  timer = null;
+  sessionStorage.removeItem(TOKEN_KEY);
+  document.getElementById("main").hidden = true;
+  document.getElementById("login").hidden = false;
+  setDaemonState("not connected", "muted");
+}
+
+function start() {
+  document.getElementById("login").hidden = true;
+  document.getElementById("main").hidden = false;
+  refresh();
+  timer = setInterval(refresh, REFRESH_INTERVAL_MS);
+}
+
+document.getElementById("login-form").addEventListener("submit", (event) => {
+  event.preventDefault();
+  token = document.getElementById("token-input").value.trim();
+  sessionStorage.setItem(TOKEN_KEY, token);
+  start();
+});
+
+if (token) {
+  start();
+} else {
+  showLogin();
+}
@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/sahithyandev/faa/internal/resolver"
+)
+
+// startDNS starts the DNS server for the domains in the config, if any
+func (d *Daemon) startDNS() error {
+	domains := d.dnsDomains()
+	if len(domains) == 0 {
+		return nil
+	}
+
+	server := resolver.New(domains)
+	if err := server.Start(d.config.DNS.Listen); err != nil {
+		return err
+	}
+	d.dnsServer = server
+	slog.Info("DNS server started", "listen", server.Addr(), "domains", strings.Join(domains, ","))
+	return nil
+}
+
+// stopDNS stops the DNS server if it is running
+func (d *Daemon) stopDNS() {
+	if d.dnsServer != nil {
+		_ = d.dnsServer.Stop()
+	}
+}
+
+// dnsDomains returns the domains besides .localhost and .local that routes
+// are served under
+func (d *Daemon) dnsDomains() []string {
+	if d.config == nil {
+		return nil
+	}
+	return d.config.DNS.Domains
+}
+
+// canonicalHost returns the route host an alias in a DNS domain stands
+// for, such as my-app.localhost for my-app.test, or host itself if it
+// isn't an alias
+func (d *Daemon) canonicalHost(host string) string {
+	host = strings.ToLower(host)
+	for _, domain := range d.dnsDomains() {
+		if name, ok := strings.CutSuffix(host, "."+domain); ok && name != "" {
+			return name + ".localhost"
+		}
+	}
+	return host
+}
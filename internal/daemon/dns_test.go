@@ -0,0 +1,54 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/sahithyandev/faa/internal/config"
+)
+
+func TestDaemonDNS(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+	if err := registry.UpsertRoute("app.localhost", 3000); err != nil {
+		t.Fatalf("UpsertRoute() failed: %v", err)
+	}
+	d := New(registry, nil)
+
+	// Without domains there is nothing to serve
+	if err := d.startDNS(); err != nil || d.dnsServer != nil {
+		t.Fatalf("startDNS() without domains = %v, server %v; want neither", err, d.dnsServer)
+	}
+	if got := d.canonicalHost("app.test"); got != "app.test" {
+		t.Errorf("canonicalHost(app.test) without domains = %s", got)
+	}
+
+	d.SetConfig(&config.Config{DNS: config.DNSConfig{Domains: []string{"test"}, Listen: "127.0.0.1:0"}})
+	if err := d.startDNS(); err != nil {
+		t.Fatalf("startDNS() failed: %v", err)
+	}
+	defer d.stopDNS()
+
+	req := new(dns.Msg)
+	req.SetQuestion("app.test.", dns.TypeA)
+	reply, err := dns.Exchange(req, d.dnsServer.Addr())
+	if err != nil {
+		t.Fatalf("DNS query failed: %v", err)
+	}
+	if len(reply.Answer) != 1 || !reply.Answer[0].(*dns.A).A.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("reply = %v, want 127.0.0.1", reply)
+	}
+
+	if got := d.canonicalHost("App.Test"); got != "app.localhost" {
+		t.Errorf("canonicalHost(App.Test) = %s, want app.localhost", got)
+	}
+	host, err := d.routeHost("app.test")
+	if err != nil || host != "app.localhost" {
+		t.Errorf("routeHost(app.test) = %q, %v; want app.localhost", host, err)
+	}
+}
@@ -0,0 +1,179 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// EventType identifies the kind of change an Event describes
+type EventType string
+
+const (
+	// EventRouteAdded is published when a route is created or its port changes
+	EventRouteAdded EventType = "route_added"
+	// EventRouteRemoved is published when a route is deleted
+	EventRouteRemoved EventType = "route_removed"
+	// EventProcessStarted is published when a process is registered for a project
+	EventProcessStarted EventType = "process_started"
+	// EventProcessExited is published when a process is cleared or found dead
+	EventProcessExited EventType = "process_exited"
+	// EventProcessRestarted is published when a project registers a new process
+	// while a previous one is still recorded
+	EventProcessRestarted EventType = "process_restarted"
+	// EventReadinessChanged is published when a process port starts or stops
+	// accepting connections
+	EventReadinessChanged EventType = "readiness_changed"
+	// EventCARotated is published when the exported root CA certificate changes
+	EventCARotated EventType = "ca_rotated"
+	// EventRestartRequested is published when a client asks for a project's
+	// process to be restarted; the 'faa run' owning the process acts on it
+	EventRestartRequested EventType = "restart_requested"
+	// EventStopRequested is published when a client asks for a project's
+	// process to be stopped; the 'faa run' owning the process acts on it
+	EventStopRequested EventType = "stop_requested"
+)
+
+// eventBufferSize is the number of events buffered per subscriber.
+// Subscribers that fall further behind are disconnected.
+const eventBufferSize = 64
+
+// Event is a single change notification pushed to subscribers
+type Event struct {
+	Type EventType       `json:"type"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// RouteEventData is the payload of route events
+type RouteEventData struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+// ProcessEventData is the payload of process events
+type ProcessEventData struct {
+	ProjectRoot string `json:"projectRoot"`
+	PID         int    `json:"pid"`
+	Host        string `json:"host"`
+	Port        int    `json:"port"`
+}
+
+// ReadinessEventData is the payload of readiness_changed events
+type ReadinessEventData struct {
+	ProjectRoot string `json:"projectRoot"`
+	Host        string `json:"host"`
+	Port        int    `json:"port"`
+	Ready       bool   `json:"ready"`
+}
+
+// CARotatedEventData is the payload of ca_rotated events
+type CARotatedEventData struct {
+	Path string `json:"path"`
+}
+
+// subscriber is a single event stream consumer
+type subscriber struct {
+	events chan *Event
+	types  map[EventType]bool
+}
+
+// eventBus fans out events to subscribers
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+}
+
+// newEventBus creates an empty event bus
+func newEventBus() *eventBus {
+	return &eventBus{
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// subscribe registers a new subscriber for the given event types.
+// An empty types list subscribes to all events.
+func (b *eventBus) subscribe(types []EventType) *subscriber {
+	sub := &subscriber{
+		events: make(chan *Event, eventBufferSize),
+	}
+	if len(types) > 0 {
+		sub.types = make(map[EventType]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// unsubscribe removes a subscriber and closes its channel
+func (b *eventBus) unsubscribe(sub *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// publish sends an event to every interested subscriber without blocking.
+// A subscriber whose buffer is full is dropped so it can reconnect and
+// resynchronise instead of silently missing events.
+func (b *eventBus) publish(eventType EventType, data interface{}) {
+	event := &Event{
+		Type: eventType,
+		Time: time.Now(),
+	}
+	if data != nil {
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			return
+		}
+		event.Data = jsonData
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers {
+		if sub.types != nil && !sub.types[eventType] {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			delete(b.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// watchRoutes calls sync now and after every route change until ctx is
+// cancelled
+func (d *Daemon) watchRoutes(ctx context.Context, sync func()) {
+	for {
+		// Subscribe before syncing so no change is missed in between
+		sub := d.events.subscribe([]EventType{EventRouteAdded, EventRouteRemoved})
+		sync()
+		for open := true; open; {
+			select {
+			case <-ctx.Done():
+				d.events.unsubscribe(sub)
+				return
+			case _, open = <-sub.events:
+				if open {
+					sync()
+				}
+			}
+		}
+		// The bus dropped a subscriber that fell behind; sync everything
+		// again with a new one
+	}
+}
@@ -0,0 +1,141 @@
+package daemon
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestEventBusPublishSubscribe(t *testing.T) {
+	bus := newEventBus()
+	all := bus.subscribe(nil)
+	routesOnly := bus.subscribe([]EventType{EventRouteAdded})
+
+	bus.publish(EventProcessStarted, &ProcessEventData{ProjectRoot: "/p", PID: 1})
+	bus.publish(EventRouteAdded, &RouteEventData{Host: "app.localhost", Port: 3000})
+
+	if got := (<-all.events).Type; got != EventProcessStarted {
+		t.Errorf("first event = %s, want %s", got, EventProcessStarted)
+	}
+	if got := (<-all.events).Type; got != EventRouteAdded {
+		t.Errorf("second event = %s, want %s", got, EventRouteAdded)
+	}
+
+	event := <-routesOnly.events
+	if event.Type != EventRouteAdded {
+		t.Fatalf("filtered subscriber got %s, want %s", event.Type, EventRouteAdded)
+	}
+	var data RouteEventData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		t.Fatalf("Failed to unmarshal event data: %v", err)
+	}
+	if data.Host != "app.localhost" || data.Port != 3000 {
+		t.Errorf("event data = %+v, want app.localhost:3000", data)
+	}
+	select {
+	case extra := <-routesOnly.events:
+		t.Errorf("filtered subscriber got unexpected event %s", extra.Type)
+	default:
+	}
+
+	bus.unsubscribe(all)
+	if _, ok := <-all.events; ok {
+		t.Error("channel should be closed after unsubscribe")
+	}
+	// Unsubscribing twice must not panic
+	bus.unsubscribe(all)
+}
+
+func TestEventBusDropsSlowSubscriber(t *testing.T) {
+	bus := newEventBus()
+	sub := bus.subscribe(nil)
+
+	for i := 0; i < eventBufferSize+1; i++ {
+		bus.publish(EventRouteAdded, nil)
+	}
+
+	count := 0
+	for range sub.events {
+		count++
+	}
+	if count != eventBufferSize {
+		t.Errorf("received %d events before disconnect, want %d", count, eventBufferSize)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Override HOME for testing
+	originalHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", originalHome)
+	os.Setenv("HOME", tmpDir)
+
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+
+	d := New(registry, nil)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- d.Start()
+	}()
+
+	// Wait for daemon to start
+	time.Sleep(100 * time.Millisecond)
+
+	sub, err := Subscribe()
+	if err != nil {
+		t.Fatalf("Subscribe() failed: %v", err)
+	}
+	defer sub.Close()
+
+	client, err := Connect()
+	if err != nil {
+		t.Fatalf("Connect() failed: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.UpsertRoute("events-app", 3000); err != nil {
+		t.Fatalf("UpsertRoute() failed: %v", err)
+	}
+	if err := client.SetProcess(&SetProcessData{
+		ProjectRoot: "/tmp/events-app",
+		PID:         os.Getpid(),
+		Host:        "events-app.localhost",
+		Port:        3000,
+	}); err != nil {
+		t.Fatalf("SetProcess() failed: %v", err)
+	}
+	if err := client.ClearProcess("/tmp/events-app"); err != nil {
+		t.Fatalf("ClearProcess() failed: %v", err)
+	}
+
+	want := []EventType{EventRouteAdded, EventProcessStarted, EventProcessExited}
+	for _, wantType := range want {
+		event, err := sub.Next()
+		if err != nil {
+			t.Fatalf("Next() failed: %v", err)
+		}
+		if event.Type != wantType {
+			t.Errorf("event type = %s, want %s", event.Type, wantType)
+		}
+	}
+
+	// Shutdown daemon; the subscription should end
+	d.Shutdown()
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Errorf("Daemon returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Daemon didn't shutdown in time")
+	}
+
+	if _, err := sub.Next(); err == nil {
+		t.Error("Next() should fail after daemon shutdown")
+	}
+}
@@ -0,0 +1,72 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+
+	"github.com/sahithyandev/faa/internal/hostsfile"
+)
+
+// hostsPath is the hosts file the daemon keeps in sync; a variable so
+// tests can use a temporary one
+var hostsPath = hostsfile.Path
+
+// watchHosts keeps the managed block in the hosts file in sync with the
+// routes until ctx is cancelled, if hosts.enabled is set
+func (d *Daemon) watchHosts(ctx context.Context) {
+	if d.config == nil || !d.config.Hosts.Enabled {
+		return
+	}
+	d.watchRoutes(ctx, d.syncHostsLogged)
+}
+
+// syncHostsLogged syncs the hosts file, logging rather than returning
+// errors
+func (d *Daemon) syncHostsLogged() {
+	changed, err := d.syncHosts()
+	if err != nil {
+		slog.Warn("failed to update the hosts file; run 'faa setup' to allow it", "path", hostsPath, "error", err)
+		return
+	}
+	if changed {
+		slog.Info("hosts file updated", "path", hostsPath)
+	}
+}
+
+// syncHosts updates the hosts block if it differs from the routes, and
+// reports whether it did. A hosts file the daemon can't write is updated
+// by 'faa hosts apply' through sudo, which 'faa setup' allows without a
+// password.
+func (d *Daemon) syncHosts() (bool, error) {
+	names, err := d.registry.HostNames()
+	if err != nil {
+		return false, fmt.Errorf("failed to load routes: %w", err)
+	}
+	content, err := os.ReadFile(hostsPath)
+	if err != nil {
+		return false, err
+	}
+	if current := hostsfile.Names(content); reflect.DeepEqual(current, names) || len(current)+len(names) == 0 {
+		return false, nil
+	}
+
+	if hostsfile.Writable(hostsPath) {
+		return hostsfile.Apply(hostsPath, names)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return false, err
+	}
+	cmd := exec.Command("sudo", "-n", executable, "hosts", "apply")
+	cmd.Stdin = strings.NewReader(strings.Join(names, "\n"))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return false, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return true, nil
+}
@@ -0,0 +1,117 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sahithyandev/faa/internal/config"
+	"github.com/sahithyandev/faa/internal/hostsfile"
+)
+
+// useHostsFile points the daemon at a temporary hosts file until the test
+// ends
+func useHostsFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(path, []byte("127.0.0.1\tlocalhost\n"), 0644); err != nil {
+		t.Fatalf("Failed to write hosts file: %v", err)
+	}
+	original := hostsPath
+	hostsPath = path
+	t.Cleanup(func() { hostsPath = original })
+	return path
+}
+
+// hostsFileNames returns the names in the block of the hosts file at path
+func hostsFileNames(t *testing.T, path string) []string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read hosts file: %v", err)
+	}
+	return hostsfile.Names(content)
+}
+
+func TestDaemonSyncHosts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := useHostsFile(t)
+
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+	d := New(registry, nil)
+
+	// Nothing to write without routes
+	if changed, err := d.syncHosts(); err != nil || changed {
+		t.Fatalf("syncHosts() without routes = %v, %v; want no change", changed, err)
+	}
+
+	if err := registry.UpsertRoute("app.localhost", 3000); err != nil {
+		t.Fatalf("UpsertRoute() failed: %v", err)
+	}
+	if changed, err := d.syncHosts(); err != nil || !changed {
+		t.Fatalf("syncHosts() = %v, %v; want a change", changed, err)
+	}
+	if got, want := hostsFileNames(t, path), []string{"app.local", "app.localhost"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("hosts block = %v, want %v", got, want)
+	}
+	if changed, err := d.syncHosts(); err != nil || changed {
+		t.Errorf("second syncHosts() = %v, %v; want no change", changed, err)
+	}
+
+	// Removing the routes removes the block
+	if err := registry.saveRoutes(map[string]int{}); err != nil {
+		t.Fatalf("saveRoutes() failed: %v", err)
+	}
+	if changed, err := d.syncHosts(); err != nil || !changed {
+		t.Fatalf("syncHosts() after clearing = %v, %v; want a change", changed, err)
+	}
+	if got := hostsFileNames(t, path); len(got) != 0 {
+		t.Errorf("hosts block = %v, want none", got)
+	}
+}
+
+func TestDaemonWatchHosts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := useHostsFile(t)
+
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+	d := New(registry, nil)
+	d.SetConfig(&config.Config{Hosts: config.HostsConfig{Enabled: true}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.watchHosts(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	req, err := NewRequest(MessageTypeUpsertRoute, &UpsertRouteData{Host: "web", Port: 3000})
+	if err != nil {
+		t.Fatalf("NewRequest() failed: %v", err)
+	}
+	if resp := d.handleUpsertRoute(req); !resp.Ok {
+		t.Fatalf("upsert_route failed: %s", resp.Error)
+	}
+
+	want := []string{"web.local", "web.localhost"}
+	deadline := time.Now().Add(2 * time.Second)
+	for !reflect.DeepEqual(hostsFileNames(t, path), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("hosts block = %v, want %v", hostsFileNames(t, path), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -3,9 +3,13 @@ package daemon
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"time"
+
+	"github.com/sahithyandev/faa/internal/proxy"
 )
 
 // MessageType represents the type of IPC message
@@ -13,15 +17,34 @@ type MessageType string
 
 const (
 	// Request message types
-	MessageTypePing         MessageType = "ping"
-	MessageTypeUpsertRoute  MessageType = "upsert_route"
-	MessageTypeGetRoute     MessageType = "get_route"
-	MessageTypeListRoutes   MessageType = "list_routes"
-	MessageTypeSetProcess   MessageType = "set_process"
-	MessageTypeGetProcess   MessageType = "get_process"
-	MessageTypeClearProcess MessageType = "clear_process"
-	MessageTypeStatus       MessageType = "status"
-	MessageTypeStop         MessageType = "stop"
+	MessageTypePing           MessageType = "ping"
+	MessageTypeUpsertRoute    MessageType = "upsert_route"
+	MessageTypeGetRoute       MessageType = "get_route"
+	MessageTypeListRoutes     MessageType = "list_routes"
+	MessageTypeSetProcess     MessageType = "set_process"
+	MessageTypeGetProcess     MessageType = "get_process"
+	MessageTypeClearProcess   MessageType = "clear_process"
+	MessageTypeStatus         MessageType = "status"
+	MessageTypeStop           MessageType = "stop"
+	MessageTypeStopProcess    MessageType = "stop_process"
+	MessageTypeRestartProcess MessageType = "restart_process"
+	MessageTypeRecentRequests MessageType = "recent_requests"
+	MessageTypeRequestStats   MessageType = "request_stats"
+	MessageTypeTailLog        MessageType = "tail_log"
+	MessageTypeSetCapture     MessageType = "set_capture"
+	MessageTypeListCaptures   MessageType = "list_captures"
+	MessageTypeGetCapture     MessageType = "get_capture"
+	MessageTypeClearCaptures  MessageType = "clear_captures"
+	MessageTypeSetChaos       MessageType = "set_chaos"
+	MessageTypeGetChaos       MessageType = "get_chaos"
+	MessageTypeRotateCA       MessageType = "rotate_ca"
+	MessageTypeGetCertificate MessageType = "get_certificate"
+
+	MessageTypeIssueClientCertificate MessageType = "issue_client_certificate"
+
+	// MessageTypeSubscribe keeps the connection open after the response and
+	// streams newline-delimited Event messages until the client disconnects
+	MessageTypeSubscribe MessageType = "subscribe"
 )
 
 // Request represents an IPC request message
@@ -35,6 +58,10 @@ type Response struct {
 	Ok    bool            `json:"ok"`
 	Error string          `json:"error,omitempty"`
 	Data  json.RawMessage `json:"data,omitempty"`
+
+	// Status is the HTTP status of a failed request (see requestError),
+	// which the management API answers with; zero means an internal error
+	Status int `json:"status,omitempty"`
 }
 
 // PingData is empty for ping requests
@@ -81,10 +108,136 @@ type StopData struct {
 	ClearRoutes bool `json:"clearRoutes,omitempty"`
 }
 
+// ProcessControlData contains parameters for stop_process and
+// restart_process requests
+type ProcessControlData struct {
+	ProjectRoot string `json:"projectRoot"`
+}
+
+// RecentRequestsData contains parameters for recent_requests requests
+type RecentRequestsData struct {
+	Host string `json:"host"`
+
+	// Limit is the maximum number of requests to return; zero returns all
+	Limit int `json:"limit,omitempty"`
+}
+
+// TailLogData contains parameters for tail_log requests
+type TailLogData struct {
+	Host string `json:"host"`
+
+	// Lines is the number of lines to return; zero uses a default
+	Lines int `json:"lines,omitempty"`
+}
+
+// TailLogResponseData contains the last lines of a process log
+type TailLogResponseData struct {
+	Path  string   `json:"path"`
+	Lines []string `json:"lines"`
+}
+
+// SetCaptureData contains parameters for set_capture requests
+type SetCaptureData struct {
+	Host    string `json:"host"`
+	Enabled bool   `json:"enabled"`
+}
+
+// ListCapturesData contains parameters for list_captures requests
+type ListCapturesData struct {
+	Host string `json:"host"`
+
+	// Limit is the maximum number of exchanges to return; zero returns all
+	Limit int `json:"limit,omitempty"`
+}
+
+// ListCapturesResponseData contains the exchanges captured for a host
+type ListCapturesResponseData struct {
+	Host      string           `json:"host"`
+	Enabled   bool             `json:"enabled"`
+	Exchanges []proxy.Exchange `json:"exchanges"`
+}
+
+// GetCaptureData contains parameters for get_capture requests
+type GetCaptureData struct {
+	ID uint64 `json:"id"`
+}
+
+// ClearCapturesData contains parameters for clear_captures requests
+type ClearCapturesData struct {
+	Host string `json:"host"`
+}
+
+// SetChaosData contains parameters for set_chaos requests
+type SetChaosData struct {
+	Host string `json:"host"`
+
+	// Rules replaces every fault injection rule for the host; empty removes them
+	Rules []proxy.ChaosRule `json:"rules"`
+}
+
+// GetChaosData contains parameters for get_chaos requests
+type GetChaosData struct {
+	Host string `json:"host"`
+}
+
+// ChaosResponseData contains the fault injection rules for a host
+type ChaosResponseData struct {
+	Host  string            `json:"host"`
+	Rules []proxy.ChaosRule `json:"rules"`
+}
+
+// GetCertificateData contains parameters for get_certificate requests
+type GetCertificateData struct {
+	Host string `json:"host"`
+}
+
+// CertificateResponseData contains the certificate the proxy serves for a
+// host and its private key
+type CertificateResponseData struct {
+	Host string `json:"host"`
+
+	// Certificate is the PEM chain, leaf first
+	Certificate string `json:"certificate"`
+
+	// Key is the PEM private key
+	Key string `json:"key"`
+}
+
+// IssueClientCertificateData contains parameters for
+// issue_client_certificate requests
+type IssueClientCertificateData struct {
+	// Name is the certificate's common name, which identifies the client
+	Name string `json:"name"`
+
+	// Days is how long the certificate is valid for
+	Days int `json:"days"`
+}
+
+// ClientCertificateResponseData contains an issued client certificate and
+// its private key
+type ClientCertificateResponseData struct {
+	Name string `json:"name"`
+
+	// Certificate is the PEM certificate
+	Certificate string `json:"certificate"`
+
+	// Key is the PEM private key
+	Key string `json:"key"`
+}
+
+// SubscribeData contains parameters for subscribe requests
+type SubscribeData struct {
+	// Types limits the stream to the given event types; empty means all events
+	Types []EventType `json:"types,omitempty"`
+}
+
 // StatusResponseData contains the current daemon status
 type StatusResponseData struct {
 	Routes    []Route    `json:"routes"`
 	Processes []*Process `json:"processes"`
+
+	// Ready maps a process host to whether its port is accepting connections
+	Ready map[string]bool `json:"ready,omitempty"`
 }
 
 // EncodeRequest encodes a request to JSON line format
@@ -153,6 +306,36 @@ func DecodeResponse(r *bufio.Reader) (*Response, error) {
 	return &resp, nil
 }
 
+// EncodeEvent encodes an event to JSON line format
+func EncodeEvent(w io.Writer, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	// Write JSON followed by newline (JSON lines format)
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+
+	return nil
+}
+
+// DecodeEvent decodes an event from JSON line format
+func DecodeEvent(r *bufio.Reader) (*Event, error) {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event line: %w", err)
+	}
+
+	var event Event
+	if err := json.Unmarshal(line, &event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+
+	return &event, nil
+}
+
 // NewSuccessResponse creates a success response with optional data
 func NewSuccessResponse(data interface{}) (*Response, error) {
 	resp := &Response{
@@ -172,10 +355,37 @@ func NewSuccessResponse(data interface{}) (*Response, error) {
 
 // NewErrorResponse creates an error response with an error message
 func NewErrorResponse(err error) *Response {
-	return &Response{
+	resp := &Response{
 		Ok:    false,
 		Error: err.Error(),
 	}
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		resp.Status = reqErr.status
+	}
+	return resp
+}
+
+// requestError is an error caused by the request rather than by the daemon,
+// with the HTTP status the management API answers it with
+type requestError struct {
+	status int
+	err    error
+}
+
+func (e *requestError) Error() string { return e.err.Error() }
+
+func (e *requestError) Unwrap() error { return e.err }
+
+// invalidRequestError returns an error for a request with invalid data
+func invalidRequestError(format string, args ...interface{}) error {
+	return &requestError{status: http.StatusBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+// notFoundError returns an error for a request about a route, process or
+// capture that doesn't exist
+func notFoundError(format string, args ...interface{}) error {
+	return &requestError{status: http.StatusNotFound, err: fmt.Errorf(format, args...)}
 }
 
 // NewRequest creates a new request with the given type and data
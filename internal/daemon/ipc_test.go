@@ -387,3 +387,31 @@ func TestRoundTripCompleteFlow(t *testing.T) {
 
 // ErrNotFound is a sample error for testing
 var ErrNotFound = errors.New("not found")
+
+func TestEncodeDecodeEvent(t *testing.T) {
+	data, err := json.Marshal(&RouteEventData{Host: "app.localhost", Port: 3000})
+	if err != nil {
+		t.Fatalf("Failed to marshal event data: %v", err)
+	}
+	event := &Event{
+		Type: EventRouteAdded,
+		Time: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Data: data,
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeEvent(&buf, event); err != nil {
+		t.Fatalf("EncodeEvent() failed: %v", err)
+	}
+	if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		t.Error("Encoded event should end with newline")
+	}
+
+	decoded, err := DecodeEvent(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatalf("DecodeEvent() failed: %v", err)
+	}
+	if decoded.Type != event.Type || !decoded.Time.Equal(event.Time) || string(decoded.Data) != string(event.Data) {
+		t.Errorf("DecodeEvent() = %+v, want %+v", decoded, event)
+	}
+}
@@ -0,0 +1,117 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sahithyandev/faa/internal/hostsfile"
+)
+
+const (
+	// defaultTailLines is the number of log lines returned when none is requested
+	defaultTailLines = 100
+
+	// maxTailBytes limits how much of the end of a log file is read for a tail
+	maxTailBytes = 256 * 1024
+)
+
+// LogDir returns the directory holding the output logs of 'faa run' processes
+func LogDir() (string, error) {
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "logs"), nil
+}
+
+// DaemonLogPath returns the path of the daemon's own log
+func DaemonLogPath() (string, error) {
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "daemon.log"), nil
+}
+
+// LogPath returns the path of the output log for the process serving host.
+// Hosts that aren't valid route names are rejected, so a host from a client
+// can't point outside the log directory.
+func LogPath(host string) (string, error) {
+	name := normalizeHost(host)
+	if !hostsfile.ValidName(name) {
+		return "", invalidRequestError("invalid host %q", host)
+	}
+	logDir, err := LogDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(logDir, name+".log"), nil
+}
+
+// OpenLog creates (or truncates) the output log for host
+func OpenLog(host string) (*os.File, error) {
+	logPath, err := LogPath(host)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	file, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	return file, nil
+}
+
+// tailLog returns the last n lines of the file at path.
+// A missing file has no lines.
+func tailLog(path string, n int) ([]string, error) {
+	if n <= 0 {
+		n = defaultTailLines
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	offset := info.Size() - maxTailBytes
+	if offset < 0 {
+		offset = 0
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek log file: %w", err)
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log file: %w", err)
+	}
+
+	text := strings.TrimRight(string(data), "\n")
+	if text == "" {
+		return []string{}, nil
+	}
+	lines := strings.Split(text, "\n")
+	if offset > 0 {
+		// The first line was probably cut in half by the seek
+		lines = lines[1:]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
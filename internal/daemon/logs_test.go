@@ -0,0 +1,149 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDaemonLogPath(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Override HOME for testing
+	originalHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", originalHome)
+	os.Setenv("HOME", tmpDir)
+
+	logPath, err := DaemonLogPath()
+	if err != nil {
+		t.Fatalf("DaemonLogPath() failed: %v", err)
+	}
+
+	expected := filepath.Join(tmpDir, ".config", "faa", "daemon.log")
+	if logPath != expected {
+		t.Errorf("DaemonLogPath() = %s, want %s", logPath, expected)
+	}
+}
+
+func TestLogPath(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Override HOME for testing
+	originalHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", originalHome)
+	os.Setenv("HOME", tmpDir)
+
+	logPath, err := LogPath("my-app")
+	if err != nil {
+		t.Fatalf("LogPath() failed: %v", err)
+	}
+
+	expected := filepath.Join(tmpDir, ".config", "faa", "logs", "my-app.localhost.log")
+	if logPath != expected {
+		t.Errorf("LogPath() = %s, want %s", logPath, expected)
+	}
+
+	for _, host := range []string{"", "../../.ssh/id_rsa", "../daemon.local", "a/b.localhost", "My-App.localhost"} {
+		if logPath, err := LogPath(host); err == nil {
+			t.Errorf("LogPath(%q) = %s, want an error", host, logPath)
+		}
+	}
+}
+
+func TestOpenLogTruncates(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Override HOME for testing
+	originalHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", originalHome)
+	os.Setenv("HOME", tmpDir)
+
+	for _, content := range []string{"first run\n", "second\n"} {
+		file, err := OpenLog("my-app.localhost")
+		if err != nil {
+			t.Fatalf("OpenLog() failed: %v", err)
+		}
+		if _, err := file.WriteString(content); err != nil {
+			t.Fatalf("Failed to write log: %v", err)
+		}
+		file.Close()
+	}
+
+	logPath, _ := LogPath("my-app.localhost")
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	if string(data) != "second\n" {
+		t.Errorf("log content = %q, want only the latest run", string(data))
+	}
+}
+
+func TestTailLog(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "app.log")
+
+	// Missing file has no lines
+	lines, err := tailLog(logPath, 10)
+	if err != nil {
+		t.Fatalf("tailLog() on missing file failed: %v", err)
+	}
+	if len(lines) != 0 {
+		t.Errorf("Expected no lines for missing file, got %d", len(lines))
+	}
+
+	var content strings.Builder
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(&content, "line %d\n", i)
+	}
+	if err := os.WriteFile(logPath, []byte(content.String()), 0644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+
+	lines, err = tailLog(logPath, 3)
+	if err != nil {
+		t.Fatalf("tailLog() failed: %v", err)
+	}
+	expected := []string{"line 18", "line 19", "line 20"}
+	if strings.Join(lines, ",") != strings.Join(expected, ",") {
+		t.Errorf("tailLog() = %v, want %v", lines, expected)
+	}
+
+	lines, err = tailLog(logPath, 100)
+	if err != nil {
+		t.Fatalf("tailLog() failed: %v", err)
+	}
+	if len(lines) != 20 {
+		t.Errorf("Expected all 20 lines, got %d", len(lines))
+	}
+}
+
+func TestTailLogLargeFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "big.log")
+
+	// Write more than maxTailBytes so only the end of the file is read
+	line := strings.Repeat("x", 99) + "\n"
+	content := strings.Repeat(line, maxTailBytes/len(line)+50) + "last\n"
+	if err := os.WriteFile(logPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+
+	lines, err := tailLog(logPath, 0)
+	if err != nil {
+		t.Fatalf("tailLog() failed: %v", err)
+	}
+	if len(lines) != defaultTailLines {
+		t.Errorf("Expected %d lines, got %d", defaultTailLines, len(lines))
+	}
+	if lines[len(lines)-1] != "last" {
+		t.Errorf("Last line = %q, want %q", lines[len(lines)-1], "last")
+	}
+	for _, l := range lines[:len(lines)-1] {
+		if l != strings.TrimSuffix(line, "\n") {
+			t.Fatalf("Unexpected partial line %q", l)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/sahithyandev/faa/internal/config"
+	"github.com/sahithyandev/faa/internal/mdns"
+)
+
+// startMDNS starts publishing the routes' .local names over mDNS unless
+// mdns.mode is off. The names are a convenience, so failing to start is
+// logged rather than stopping the daemon.
+func (d *Daemon) startMDNS() {
+	if d.config == nil || d.config.MDNS.Mode == "" || d.config.MDNS.Mode == config.MDNSModeOff {
+		return
+	}
+
+	responder := mdns.New(d.config.MDNS.Mode == config.MDNSModeLAN)
+	if err := responder.Start(); err != nil {
+		slog.Warn("failed to start mDNS responder; .local names won't resolve through mDNS", "error", err)
+		return
+	}
+	d.mdnsResponder = responder
+	slog.Info("mDNS responder started", "mode", d.config.MDNS.Mode)
+}
+
+// stopMDNS withdraws the names and stops the responder if it is running
+func (d *Daemon) stopMDNS() {
+	if d.mdnsResponder != nil {
+		_ = d.mdnsResponder.Stop()
+	}
+}
+
+// watchMDNS keeps the published names in sync with the routes until ctx is
+// cancelled
+func (d *Daemon) watchMDNS(ctx context.Context) {
+	if d.mdnsResponder == nil {
+		return
+	}
+	d.watchRoutes(ctx, d.syncMDNS)
+}
+
+// syncMDNS publishes the .local name of every route
+func (d *Daemon) syncMDNS() {
+	names, err := d.registry.HostNames()
+	if err != nil {
+		slog.Warn("failed to load routes for mDNS", "error", err)
+		return
+	}
+	var local []string
+	for _, name := range names {
+		if strings.HasSuffix(name, ".local") {
+			local = append(local, name)
+		}
+	}
+	d.mdnsResponder.SetNames(local)
+	slog.Debug("mDNS names updated", "names", strings.Join(local, ","))
+}
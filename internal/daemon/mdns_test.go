@@ -0,0 +1,76 @@
+package daemon
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sahithyandev/faa/internal/config"
+	"github.com/sahithyandev/faa/internal/mdns"
+)
+
+func TestDaemonWatchMDNS(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() failed: %v", err)
+	}
+	if err := registry.UpsertRoute("api.localhost", 4000); err != nil {
+		t.Fatalf("UpsertRoute() failed: %v", err)
+	}
+	d := New(registry, nil)
+	d.SetConfig(&config.Config{MDNS: config.MDNSConfig{Mode: config.MDNSModeLocal}})
+	// The responder isn't started, so no socket is opened, but it keeps the
+	// names it is given
+	d.mdnsResponder = mdns.New(false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.watchMDNS(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitForNames := func(want []string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for !reflect.DeepEqual(d.mdnsResponder.Names(), want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("mDNS names = %v, want %v", d.mdnsResponder.Names(), want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitForNames([]string{"api.local"})
+
+	req, err := NewRequest(MessageTypeUpsertRoute, &UpsertRouteData{Host: "web", Port: 3000})
+	if err != nil {
+		t.Fatalf("NewRequest() failed: %v", err)
+	}
+	if resp := d.handleUpsertRoute(req); !resp.Ok {
+		t.Fatalf("upsert_route failed: %s", resp.Error)
+	}
+	waitForNames([]string{"api.local", "web.local"})
+
+	// Removing the routes withdraws every name
+	if err := registry.saveRoutes(map[string]int{}); err != nil {
+		t.Fatalf("saveRoutes() failed: %v", err)
+	}
+	d.events.publish(EventRouteRemoved, &RouteEventData{Host: "web.localhost", Port: 3000})
+	waitForNames([]string{})
+}
+
+func TestDaemonStartMDNSOff(t *testing.T) {
+	d := New(nil, nil)
+	d.SetConfig(&config.Config{MDNS: config.MDNSConfig{Mode: config.MDNSModeOff}})
+	d.startMDNS()
+	if d.mdnsResponder != nil {
+		t.Error("Expected no mDNS responder with mdns.mode off")
+	}
+}
@@ -0,0 +1,310 @@
+package daemon
+
+// openAPISpec describes the management API served by apiHandler.
+// Keep it in sync with the routes registered there.
+const openAPISpec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "faa management API",
+    "version": "1",
+    "description": "Manage faa routes and processes. Mirrors the operations of the daemon's Unix socket protocol."
+  },
+  "servers": [{"url": "https://faa.localhost"}],
+  "security": [{"bearerAuth": []}],
+  "components": {
+    "securitySchemes": {
+      "bearerAuth": {
+        "type": "http",
+        "scheme": "bearer",
+        "description": "Token stored in ~/.config/faa/api.token"
+      }
+    },
+    "schemas": {
+      "Error": {
+        "type": "object",
+        "properties": {"error": {"type": "string"}},
+        "required": ["error"]
+      },
+      "Route": {
+        "type": "object",
+        "properties": {
+          "host": {"type": "string"},
+          "port": {"type": "integer"}
+        },
+        "required": ["host", "port"]
+      },
+      "Process": {
+        "type": "object",
+        "properties": {
+          "projectRoot": {"type": "string"},
+          "pid": {"type": "integer"},
+          "host": {"type": "string"},
+          "port": {"type": "integer"},
+          "startedAt": {"type": "string", "format": "date-time"}
+        },
+        "required": ["projectRoot", "pid", "host", "port"]
+      },
+      "Status": {
+        "type": "object",
+        "properties": {
+          "routes": {"type": "array", "items": {"$ref": "#/components/schemas/Route"}},
+          "processes": {"type": "array", "items": {"$ref": "#/components/schemas/Process"}},
+          "ready": {"type": "object", "additionalProperties": {"type": "boolean"}}
+        }
+      },
+      "RequestRecord": {
+        "type": "object",
+        "properties": {
+          "time": {"type": "string", "format": "date-time"},
+          "host": {"type": "string"},
+          "method": {"type": "string"},
+          "path": {"type": "string"},
+          "status": {"type": "integer"},
+          "durationMs": {"type": "number"},
+          "error": {"type": "string"}
+        }
+      },
+      "LogTail": {
+        "type": "object",
+        "properties": {
+          "path": {"type": "string"},
+          "lines": {"type": "array", "items": {"type": "string"}}
+        }
+      },
+      "Exchange": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "integer"},
+          "time": {"type": "string", "format": "date-time"},
+          "host": {"type": "string"},
+          "method": {"type": "string"},
+          "url": {"type": "string"},
+          "proto": {"type": "string"},
+          "remoteAddr": {"type": "string"},
+          "requestHeaders": {"type": "object", "additionalProperties": {"type": "array", "items": {"type": "string"}}},
+          "requestBody": {"type": "string", "format": "byte"},
+          "requestBodySize": {"type": "integer"},
+          "requestBodyTruncated": {"type": "boolean"},
+          "status": {"type": "integer"},
+          "responseHeaders": {"type": "object", "additionalProperties": {"type": "array", "items": {"type": "string"}}},
+          "responseBody": {"type": "string", "format": "byte"},
+          "responseBodySize": {"type": "integer"},
+          "responseBodyTruncated": {"type": "boolean"},
+          "durationMs": {"type": "number"},
+          "error": {"type": "string"}
+        }
+      },
+      "ChaosRule": {
+        "type": "object",
+        "properties": {
+          "path": {"type": "string", "description": "Path pattern; a trailing * matches any suffix"},
+          "latency": {"type": "integer", "description": "Added latency in nanoseconds"},
+          "jitter": {"type": "integer", "description": "Random extra latency up to this many nanoseconds"},
+          "bandwidth": {"type": "integer", "description": "Response bytes per second"},
+          "error_rate": {"type": "number", "minimum": 0, "maximum": 1},
+          "error_status": {"type": "integer"},
+          "reset_rate": {"type": "number", "minimum": 0, "maximum": 1}
+        }
+      },
+      "Chaos": {
+        "type": "object",
+        "required": ["host"],
+        "properties": {
+          "host": {"type": "string"},
+          "rules": {"type": "array", "items": {"$ref": "#/components/schemas/ChaosRule"}}
+        }
+      },
+      "Captures": {
+        "type": "object",
+        "properties": {
+          "host": {"type": "string"},
+          "enabled": {"type": "boolean"},
+          "exchanges": {"type": "array", "items": {"$ref": "#/components/schemas/Exchange"}}
+        }
+      }
+    },
+    "responses": {
+      "Error": {
+        "description": "Error",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}
+      }
+    }
+  },
+  "paths": {
+    "/api/openapi.json": {
+      "get": {
+        "summary": "This document",
+        "security": [],
+        "responses": {"200": {"description": "OpenAPI description"}}
+      }
+    },
+    "/api/ping": {
+      "get": {
+        "summary": "Check that the daemon is alive",
+        "responses": {"200": {"description": "Pong"}, "401": {"$ref": "#/components/responses/Error"}}
+      }
+    },
+    "/api/status": {
+      "get": {
+        "summary": "Get routes, processes and readiness",
+        "responses": {
+          "200": {"description": "Status", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Status"}}}},
+          "401": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/routes": {
+      "get": {
+        "summary": "List routes",
+        "responses": {
+          "200": {"description": "Routes", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Route"}}}}},
+          "401": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/routes/{host}": {
+      "parameters": [{"name": "host", "in": "path", "required": true, "schema": {"type": "string"}}],
+      "get": {
+        "summary": "Get the route for a host",
+        "responses": {
+          "200": {"description": "Route", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Route"}}}},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "put": {
+        "summary": "Create or update the route for a host",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"type": "object", "properties": {"port": {"type": "integer"}}, "required": ["port"]}}}
+        },
+        "responses": {"200": {"description": "Route saved"}, "400": {"$ref": "#/components/responses/Error"}}
+      }
+    },
+    "/api/processes": {
+      "get": {
+        "summary": "List processes, or get one with ?projectRoot=",
+        "parameters": [{"name": "projectRoot", "in": "query", "required": false, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Process list or single process"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "put": {
+        "summary": "Register a process",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Process"}}}
+        },
+        "responses": {"200": {"description": "Process registered"}, "400": {"$ref": "#/components/responses/Error"}}
+      },
+      "delete": {
+        "summary": "Clear the process for a project",
+        "parameters": [{"name": "projectRoot", "in": "query", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "Process cleared"}, "400": {"$ref": "#/components/responses/Error"}}
+      }
+    },
+    "/api/processes/stop": {
+      "post": {
+        "summary": "Ask the faa run owning a project's dev server to stop it",
+        "parameters": [{"name": "projectRoot", "in": "query", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "Stop requested"}, "400": {"$ref": "#/components/responses/Error"}, "404": {"$ref": "#/components/responses/Error"}}
+      }
+    },
+    "/api/processes/restart": {
+      "post": {
+        "summary": "Ask the faa run owning a project's dev server to restart it",
+        "parameters": [{"name": "projectRoot", "in": "query", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "Restart requested"}, "400": {"$ref": "#/components/responses/Error"}, "404": {"$ref": "#/components/responses/Error"}}
+      }
+    },
+    "/api/requests": {
+      "get": {
+        "summary": "Recent requests proxied for a host, newest first",
+        "parameters": [
+          {"name": "host", "in": "query", "required": true, "schema": {"type": "string"}},
+          {"name": "limit", "in": "query", "required": false, "schema": {"type": "integer"}}
+        ],
+        "responses": {
+          "200": {"description": "Requests", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/RequestRecord"}}}}},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/logs": {
+      "get": {
+        "summary": "Last lines of a host's dev server output",
+        "parameters": [
+          {"name": "host", "in": "query", "required": true, "schema": {"type": "string"}},
+          {"name": "lines", "in": "query", "required": false, "schema": {"type": "integer"}}
+        ],
+        "responses": {
+          "200": {"description": "Log tail", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/LogTail"}}}},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/captures": {
+      "get": {
+        "summary": "Exchanges captured for a host, oldest first",
+        "parameters": [
+          {"name": "host", "in": "query", "required": true, "schema": {"type": "string"}},
+          {"name": "limit", "in": "query", "required": false, "schema": {"type": "integer"}}
+        ],
+        "responses": {
+          "200": {"description": "Captures", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Captures"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/captures/{id}": {
+      "get": {
+        "summary": "A single captured exchange",
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}
+        ],
+        "responses": {
+          "200": {"description": "Exchange", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Exchange"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/chaos": {
+      "get": {
+        "summary": "Fault injection rules for a host",
+        "parameters": [
+          {"name": "host", "in": "query", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Rules", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Chaos"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "put": {
+        "summary": "Replace the fault injection rules for a host; no rules turns injection off",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Chaos"}}}
+        },
+        "responses": {
+          "200": {"description": "Rules updated"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/stop": {
+      "post": {
+        "summary": "Stop the daemon",
+        "requestBody": {
+          "required": false,
+          "content": {"application/json": {"schema": {"type": "object", "properties": {"clearRoutes": {"type": "boolean"}}}}}
+        },
+        "responses": {"200": {"description": "Shutdown requested"}}
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package daemon
+
+import (
+	"errors"
+	"net"
+)
+
+// peerUID returns the user ID of the process at the other end of conn
+func peerUID(conn *net.UnixConn) (uint32, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -259,6 +260,25 @@ func (r *Registry) ListRoutes() ([]Route, error) {
 	return result, nil
 }
 
+// HostNames returns every name the routes answer to, with both .localhost
+// and .local, sorted
+func (r *Registry) HostNames() ([]string, error) {
+	routes, err := r.ListRoutes()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, route := range routes {
+		names = append(names, route.Host)
+		if alternate := alternateLocalHost(route.Host); alternate != "" {
+			names = append(names, alternate)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 // SetProcess adds or updates a process entry
 func (r *Registry) SetProcess(projectRoot string, pid int, host string, port int, startedAt time.Time) error {
 	processes, err := r.loadProcesses()
@@ -333,24 +353,31 @@ func isProcessAlive(pid int) bool {
 // CleanupStaleProcesses removes processes with dead PIDs from the registry
 // Returns the number of stale processes cleaned up
 func (r *Registry) CleanupStaleProcesses() (int, error) {
+	stale, err := r.RemoveStaleProcesses()
+	return len(stale), err
+}
+
+// RemoveStaleProcesses removes processes with dead PIDs from the registry
+// and returns the removed entries
+func (r *Registry) RemoveStaleProcesses() ([]*Process, error) {
 	processes, err := r.loadProcesses()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	staleCount := 0
+	var stale []*Process
 	for projectRoot, proc := range processes {
 		if !isProcessAlive(proc.PID) {
 			delete(processes, projectRoot)
-			staleCount++
+			stale = append(stale, proc)
 		}
 	}
 
-	if staleCount > 0 {
+	if len(stale) > 0 {
 		if err := r.saveProcesses(processes); err != nil {
-			return staleCount, err
+			return stale, err
 		}
 	}
 
-	return staleCount, nil
+	return stale, nil
 }
@@ -3,6 +3,7 @@ package daemon
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -173,6 +174,22 @@ func TestListRoutes(t *testing.T) {
 	}
 }
 
+func TestHostNames(t *testing.T) {
+	reg := &Registry{configDir: t.TempDir()}
+
+	reg.UpsertRoute("web.localhost", 3000)
+	reg.UpsertRoute("api.local", 3001)
+
+	names, err := reg.HostNames()
+	if err != nil {
+		t.Fatalf("HostNames() failed: %v", err)
+	}
+	want := []string{"api.local", "api.localhost", "web.local", "web.localhost"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("HostNames() = %v, want %v", names, want)
+	}
+}
+
 func TestSetProcess(t *testing.T) {
 	tmpDir := t.TempDir()
 	reg := &Registry{configDir: tmpDir}
@@ -775,3 +792,24 @@ func TestUpsertRouteNormalization(t *testing.T) {
 		t.Errorf("Route 'myapp.localhost' = port %d, want 12345", port)
 	}
 }
+
+func TestRemoveStaleProcesses(t *testing.T) {
+	tmpDir := t.TempDir()
+	reg := &Registry{configDir: tmpDir}
+
+	startTime := time.Now()
+	if err := reg.SetProcess("/home/user/alive", os.Getpid(), "alive.local", 3000, startTime); err != nil {
+		t.Fatalf("SetProcess() failed: %v", err)
+	}
+	if err := reg.SetProcess("/home/user/dead", 999999, "dead.local", 3001, startTime); err != nil {
+		t.Fatalf("SetProcess() failed: %v", err)
+	}
+
+	stale, err := reg.RemoveStaleProcesses()
+	if err != nil {
+		t.Fatalf("RemoveStaleProcesses() failed: %v", err)
+	}
+	if len(stale) != 1 || stale[0].ProjectRoot != "/home/user/dead" {
+		t.Errorf("RemoveStaleProcesses() = %+v, want only /home/user/dead", stale)
+	}
+}
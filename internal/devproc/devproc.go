@@ -3,6 +3,7 @@ package devproc
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -28,6 +29,12 @@ type Process struct {
 // The stdio of the child process is forwarded to the parent process.
 // The process is started in a new process group for proper signal handling.
 func Start(command []string, cwd string, env map[string]string) (*Process, error) {
+	return StartWithLog(command, cwd, env, nil)
+}
+
+// StartWithLog is like Start, but additionally copies the child's stdout and
+// stderr to log when it is non-nil.
+func StartWithLog(command []string, cwd string, env map[string]string, log io.Writer) (*Process, error) {
 	if len(command) == 0 {
 		return nil, fmt.Errorf("command cannot be empty")
 	}
@@ -54,6 +61,10 @@ func Start(command []string, cwd string, env map[string]string) (*Process, error
 	// Forward stdio to parent
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	if log != nil {
+		cmd.Stdout = io.MultiWriter(os.Stdout, log)
+		cmd.Stderr = io.MultiWriter(os.Stderr, log)
+	}
 	cmd.Stdin = os.Stdin
 
 	// Set process group attributes for Unix systems
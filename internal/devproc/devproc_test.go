@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -112,6 +113,38 @@ func TestStart_WithArguments(t *testing.T) {
 	}
 }
 
+func TestStartWithLog(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "output.log")
+	log, err := os.Create(logPath)
+	if err != nil {
+		t.Fatalf("Failed to create log file: %v", err)
+	}
+	defer log.Close()
+
+	proc, err := StartWithLog([]string{"sh", "-c", "echo out; echo err >&2"}, "", nil, log)
+	if err != nil {
+		t.Fatalf("StartWithLog failed: %v", err)
+	}
+
+	select {
+	case err := <-proc.Wait:
+		if err != nil {
+			t.Errorf("Process exited with error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Process did not complete in time")
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	output := string(data)
+	if !strings.Contains(output, "out\n") || !strings.Contains(output, "err\n") {
+		t.Errorf("log = %q, want both stdout and stderr", output)
+	}
+}
+
 func TestStop(t *testing.T) {
 	// Start a long-running process
 	proc, err := Start([]string{"sleep", "60"}, "", nil)
@@ -0,0 +1,293 @@
+// Package har converts exchanges captured by the proxy into HTTP Archive
+// (HAR 1.2) documents, which browser devtools and most HTTP tools can import.
+//
+// See http://www.softwareishard.com/blog/har-12-spec/ for the format.
+package har
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sahithyandev/faa/internal/proxy"
+	"github.com/sahithyandev/faa/internal/version"
+)
+
+// maxDecodedSize limits how large a compressed body may grow when decoded
+const maxDecodedSize = 1024 * 1024
+
+// HAR is the top-level HAR document
+type HAR struct {
+	Log Log `json:"log"`
+}
+
+// Log is the root of the exported data
+type Log struct {
+	Version string  `json:"version"`
+	Creator Creator `json:"creator"`
+	Entries []Entry `json:"entries"`
+}
+
+// Creator identifies the application that created the log
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Entry is a single request and response
+type Entry struct {
+	StartedDateTime string   `json:"startedDateTime"`
+	Time            float64  `json:"time"`
+	Request         Request  `json:"request"`
+	Response        Response `json:"response"`
+	Cache           struct{} `json:"cache"`
+	Timings         Timings  `json:"timings"`
+	Comment         string   `json:"comment,omitempty"`
+}
+
+// Request describes the captured request
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []Cookie    `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	QueryString []NameValue `json:"queryString"`
+	PostData    *PostData   `json:"postData,omitempty"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+}
+
+// Response describes the captured response
+type Response struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []Cookie    `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	Content     Content     `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+}
+
+// NameValue is a header or query string parameter
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Cookie is a request or response cookie
+type Cookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// PostData is the body of a request
+type PostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// Content is the body of a response
+type Content struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// Timings breaks down the time spent on the exchange. faa only measures the
+// total time through the proxy, which is reported as wait.
+type Timings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// FromExchanges builds a HAR document from captured exchanges
+func FromExchanges(exchanges []proxy.Exchange) *HAR {
+	doc := &HAR{
+		Log: Log{
+			Version: "1.2",
+			Creator: Creator{Name: "faa", Version: version.Short()},
+			Entries: make([]Entry, 0, len(exchanges)),
+		},
+	}
+	for i := range exchanges {
+		doc.Log.Entries = append(doc.Log.Entries, FromExchange(&exchanges[i]))
+	}
+	return doc
+}
+
+// FromExchange converts a single captured exchange into a HAR entry
+func FromExchange(exchange *proxy.Exchange) Entry {
+	entry := Entry{
+		StartedDateTime: exchange.Time.Format(time.RFC3339Nano),
+		Time:            exchange.DurationMs,
+		Timings:         Timings{Wait: exchange.DurationMs},
+		Comment:         exchange.Error,
+	}
+
+	requestURL := "https://" + exchange.Host + exchange.URL
+	entry.Request = Request{
+		Method:      exchange.Method,
+		URL:         requestURL,
+		HTTPVersion: exchange.Proto,
+		Cookies:     requestCookies(exchange.RequestHeaders),
+		Headers:     headerList(exchange.RequestHeaders),
+		QueryString: queryList(exchange.URL),
+		HeadersSize: -1,
+		BodySize:    exchange.RequestBodySize,
+	}
+	if exchange.RequestBodySize > 0 {
+		postData := &PostData{
+			MimeType: exchange.RequestHeaders.Get("Content-Type"),
+			Text:     string(exchange.RequestBody),
+		}
+		if exchange.RequestBodyTruncated {
+			postData.Comment = "truncated"
+		}
+		entry.Request.PostData = postData
+	}
+
+	entry.Response = Response{
+		Status:      exchange.Status,
+		StatusText:  http.StatusText(exchange.Status),
+		HTTPVersion: exchange.Proto,
+		Cookies:     responseCookies(exchange.ResponseHeaders),
+		Headers:     headerList(exchange.ResponseHeaders),
+		RedirectURL: exchange.ResponseHeaders.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    exchange.ResponseBodySize,
+		Content:     content(exchange),
+	}
+
+	return entry
+}
+
+// content builds the response content, decoding compressed bodies and
+// base64-encoding binary ones as the spec requires
+func content(exchange *proxy.Exchange) Content {
+	c := Content{
+		Size:     exchange.ResponseBodySize,
+		MimeType: exchange.ResponseHeaders.Get("Content-Type"),
+	}
+	if exchange.ResponseBodyTruncated {
+		c.Comment = "truncated"
+	}
+
+	body, _ := DecodeBody(exchange.ResponseHeaders, exchange.ResponseBody, exchange.ResponseBodyTruncated)
+	if len(body) == 0 {
+		return c
+	}
+	if IsText(exchange.ResponseHeaders.Get("Content-Type"), body) {
+		c.Text = string(body)
+	} else {
+		c.Text = base64.StdEncoding.EncodeToString(body)
+		c.Encoding = "base64"
+	}
+	return c
+}
+
+// DecodeBody removes gzip content encoding from a captured body.
+// Truncated bodies and unsupported encodings are returned unchanged with
+// decoded set to false.
+func DecodeBody(headers http.Header, body []byte, truncated bool) (decoded []byte, ok bool) {
+	encoding := strings.ToLower(strings.TrimSpace(headers.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" {
+		return body, true
+	}
+	if truncated || encoding != "gzip" {
+		return body, false
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return body, false
+	}
+	defer reader.Close()
+
+	decoded, err = io.ReadAll(io.LimitReader(reader, maxDecodedSize))
+	if err != nil {
+		return body, false
+	}
+	return decoded, true
+}
+
+// IsText reports whether a body with the given content type is text that can
+// be shown as-is
+func IsText(contentType string, body []byte) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "json"),
+		strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/xml",
+		mediaType == "application/javascript",
+		mediaType == "application/x-www-form-urlencoded":
+		return utf8.Valid(body)
+	case mediaType == "":
+		return utf8.Valid(body) && !bytes.ContainsRune(body, 0)
+	default:
+		return false
+	}
+}
+
+// headerList flattens headers into name/value pairs sorted by name
+func headerList(headers http.Header) []NameValue {
+	list := make([]NameValue, 0, len(headers))
+	for name, values := range headers {
+		for _, value := range values {
+			list = append(list, NameValue{Name: name, Value: value})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// queryList returns the query string parameters of a request URI
+func queryList(requestURI string) []NameValue {
+	list := []NameValue{}
+	u, err := url.ParseRequestURI(requestURI)
+	if err != nil {
+		return list
+	}
+	for name, values := range u.Query() {
+		for _, value := range values {
+			list = append(list, NameValue{Name: name, Value: value})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// requestCookies parses the Cookie headers of a request
+func requestCookies(headers http.Header) []Cookie {
+	req := http.Request{Header: headers}
+	cookies := []Cookie{}
+	for _, c := range req.Cookies() {
+		cookies = append(cookies, Cookie{Name: c.Name, Value: c.Value})
+	}
+	return cookies
+}
+
+// responseCookies parses the Set-Cookie headers of a response
+func responseCookies(headers http.Header) []Cookie {
+	resp := http.Response{Header: headers}
+	cookies := []Cookie{}
+	for _, c := range resp.Cookies() {
+		cookies = append(cookies, Cookie{Name: c.Name, Value: c.Value})
+	}
+	return cookies
+}
@@ -0,0 +1,148 @@
+package har
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sahithyandev/faa/internal/proxy"
+)
+
+// gzipBytes compresses data with gzip
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatalf("gzip write failed: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip close failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestFromExchange(t *testing.T) {
+	exchange := &proxy.Exchange{
+		ID:     7,
+		Time:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Host:   "app.localhost",
+		Method: http.MethodPost,
+		URL:    "/callback?code=abc&state=xyz",
+		Proto:  "HTTP/2.0",
+		RequestHeaders: http.Header{
+			"Content-Type": {"application/json"},
+			"Cookie":       {"session=123"},
+		},
+		RequestBody:     []byte(`{"a":1}`),
+		RequestBodySize: 7,
+		Status:          http.StatusFound,
+		ResponseHeaders: http.Header{
+			"Location":   {"/home"},
+			"Set-Cookie": {"token=xyz; Path=/"},
+		},
+		DurationMs: 12.5,
+	}
+
+	entry := FromExchange(exchange)
+
+	if entry.Request.URL != "https://app.localhost/callback?code=abc&state=xyz" {
+		t.Errorf("URL = %s", entry.Request.URL)
+	}
+	if len(entry.Request.QueryString) != 2 || entry.Request.QueryString[0].Name != "code" {
+		t.Errorf("QueryString = %+v", entry.Request.QueryString)
+	}
+	if len(entry.Request.Cookies) != 1 || entry.Request.Cookies[0].Value != "123" {
+		t.Errorf("Request cookies = %+v", entry.Request.Cookies)
+	}
+	if entry.Request.PostData == nil || entry.Request.PostData.Text != `{"a":1}` {
+		t.Errorf("PostData = %+v", entry.Request.PostData)
+	}
+	if entry.Response.StatusText != "Found" || entry.Response.RedirectURL != "/home" {
+		t.Errorf("Response = %d %s -> %s", entry.Response.Status, entry.Response.StatusText, entry.Response.RedirectURL)
+	}
+	if len(entry.Response.Cookies) != 1 || entry.Response.Cookies[0].Name != "token" {
+		t.Errorf("Response cookies = %+v", entry.Response.Cookies)
+	}
+	if entry.StartedDateTime != "2024-05-01T12:00:00Z" || entry.Time != 12.5 {
+		t.Errorf("Timing = %s %f", entry.StartedDateTime, entry.Time)
+	}
+}
+
+func TestFromExchangesDocument(t *testing.T) {
+	doc := FromExchanges([]proxy.Exchange{{Host: "app.localhost", URL: "/", Method: "GET"}})
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded map[string]map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded["log"]["version"] != "1.2" {
+		t.Errorf("version = %v, want 1.2", decoded["log"]["version"])
+	}
+	entries, ok := decoded["log"]["entries"].([]interface{})
+	if !ok || len(entries) != 1 {
+		t.Fatalf("entries = %v", decoded["log"]["entries"])
+	}
+}
+
+func TestContentEncoding(t *testing.T) {
+	tests := []struct {
+		name         string
+		headers      http.Header
+		body         []byte
+		truncated    bool
+		wantText     string
+		wantEncoding string
+	}{
+		{
+			name:     "plain text",
+			headers:  http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+			body:     []byte("<h1>hi</h1>"),
+			wantText: "<h1>hi</h1>",
+		},
+		{
+			name:     "gzip",
+			headers:  http.Header{"Content-Type": {"application/json"}, "Content-Encoding": {"gzip"}},
+			body:     gzipBytes(t, `{"ok":true}`),
+			wantText: `{"ok":true}`,
+		},
+		{
+			name:         "binary",
+			headers:      http.Header{"Content-Type": {"image/png"}},
+			body:         []byte{0x89, 'P', 'N', 'G', 0},
+			wantText:     base64.StdEncoding.EncodeToString([]byte{0x89, 'P', 'N', 'G', 0}),
+			wantEncoding: "base64",
+		},
+		{
+			name:         "truncated gzip",
+			headers:      http.Header{"Content-Encoding": {"gzip"}},
+			body:         gzipBytes(t, "hello")[:5],
+			truncated:    true,
+			wantText:     base64.StdEncoding.EncodeToString(gzipBytes(t, "hello")[:5]),
+			wantEncoding: "base64",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := content(&proxy.Exchange{
+				ResponseHeaders:       tt.headers,
+				ResponseBody:          tt.body,
+				ResponseBodySize:      int64(len(tt.body)),
+				ResponseBodyTruncated: tt.truncated,
+			})
+			if c.Text != tt.wantText || c.Encoding != tt.wantEncoding {
+				t.Errorf("content = %q (%s), want %q (%s)", c.Text, c.Encoding, tt.wantText, tt.wantEncoding)
+			}
+		})
+	}
+}
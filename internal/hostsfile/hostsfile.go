@@ -0,0 +1,201 @@
+// Package hostsfile maintains a block of loopback entries for the routed
+// hosts in /etc/hosts, for tools that don't resolve *.localhost themselves,
+// such as older Java, some Node versions and containers using the host's
+// network.
+//
+// Only the lines between the block's markers are ever changed, and the
+// file is replaced atomically, so a reader never sees it half written,
+// unless it is bind mounted and can only be rewritten in place.
+package hostsfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Path is the system hosts file
+const Path = "/etc/hosts"
+
+// MaxNames is the most names a block holds, which keeps a privileged
+// caller from being made to write an arbitrarily large file
+const MaxNames = 4096
+
+const (
+	beginMarker = "# BEGIN faa managed block; changes here are overwritten"
+	endMarker   = "# END faa managed block"
+)
+
+// ValidName reports whether name may be put in the block: a lower case
+// host name under .localhost or .local, which nothing else resolves
+func ValidName(name string) bool {
+	if len(name) > 253 || !(strings.HasSuffix(name, ".localhost") || strings.HasSuffix(name, ".local")) {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Block returns the managed block mapping names to 127.0.0.1 and ::1, or ""
+// for no names
+func Block(names []string) string {
+	names = normalize(names)
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(beginMarker + "\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "127.0.0.1\t%s\n", name)
+		fmt.Fprintf(&b, "::1\t%s\n", name)
+	}
+	b.WriteString(endMarker + "\n")
+	return b.String()
+}
+
+// normalize returns names sorted without duplicates
+func normalize(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	var result []string
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// split returns the content before and after the managed block, and the
+// block itself. A block without an end marker runs to the end of the file.
+func split(content []byte) (before, block, after []byte) {
+	start := bytes.Index(content, []byte(beginMarker))
+	if start < 0 || (start > 0 && content[start-1] != '\n') {
+		return content, nil, nil
+	}
+	rest := content[start:]
+	end := bytes.Index(rest, []byte(endMarker))
+	if end < 0 {
+		return content[:start], rest, nil
+	}
+	end += len(endMarker)
+	if end < len(rest) && rest[end] == '\n' {
+		end++
+	}
+	return content[:start], rest[:end], rest[end:]
+}
+
+// Names returns the names in the managed block of content
+func Names(content []byte) []string {
+	_, block, _ := split(content)
+	var names []string
+	for _, line := range strings.Split(string(block), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && !strings.HasPrefix(fields[0], "#") {
+			names = append(names, fields[1:]...)
+		}
+	}
+	return normalize(names)
+}
+
+// Update returns content with its managed block replaced by one for names,
+// added at the end if there was none, or removed if names is empty
+func Update(content []byte, names []string) []byte {
+	before, _, after := split(content)
+	block := Block(names)
+
+	var b bytes.Buffer
+	b.Write(before)
+	if block != "" {
+		if b.Len() > 0 && !bytes.HasSuffix(b.Bytes(), []byte("\n")) {
+			b.WriteByte('\n')
+		}
+		b.WriteString(block)
+	}
+	b.Write(after)
+	return b.Bytes()
+}
+
+// Writable reports whether the hosts file at path can be replaced without
+// root
+func Writable(path string) bool {
+	return unix.Access(path, unix.W_OK) == nil && unix.Access(filepath.Dir(path), unix.W_OK) == nil
+}
+
+// Apply replaces the managed block of the hosts file at path with one for
+// names, and reports whether the file changed. The new file is written
+// next to it and renamed over it, keeping its mode and owner.
+func Apply(path string, names []string) (bool, error) {
+	if len(names) > MaxNames {
+		return false, fmt.Errorf("too many names: %d (at most %d)", len(names), MaxNames)
+	}
+	for _, name := range names {
+		if !ValidName(name) {
+			return false, fmt.Errorf("invalid name: %q", name)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	updated := Update(content, names)
+	if bytes.Equal(content, updated) {
+		return false, nil
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), ".hosts-faa-*")
+	if err != nil {
+		return false, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(updated); err != nil {
+		temp.Close()
+		return false, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return false, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return false, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Chmod(temp.Name(), info.Mode().Perm()); err != nil {
+		return false, err
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && os.Geteuid() == 0 {
+		if err := os.Chown(temp.Name(), int(stat.Uid), int(stat.Gid)); err != nil {
+			return false, err
+		}
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		// A bind-mounted hosts file, as in containers, can't be replaced,
+		// so it is rewritten in place
+		if errors.Is(err, syscall.EBUSY) {
+			return true, os.WriteFile(path, updated, info.Mode().Perm())
+		}
+		return false, fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,105 @@
+package hostsfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const systemHosts = "127.0.0.1\tlocalhost\n::1\tlocalhost ip6-localhost\n"
+
+func TestValidName(t *testing.T) {
+	for _, name := range []string{"app.localhost", "my-app.local", "api.v2.localhost"} {
+		if !ValidName(name) {
+			t.Errorf("ValidName(%q) = false, want true", name)
+		}
+	}
+	for _, name := range []string{
+		"", "localhost", "example.com", "App.localhost", "app..localhost",
+		"-app.localhost", "app .localhost", "app.localhost\n1.2.3.4 example.com",
+	} {
+		if ValidName(name) {
+			t.Errorf("ValidName(%q) = true, want false", name)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	names := []string{"web.localhost", "app.localhost", "app.localhost"}
+	content := Update([]byte(systemHosts), names)
+
+	want := systemHosts + beginMarker + "\n" +
+		"127.0.0.1\tapp.localhost\n::1\tapp.localhost\n" +
+		"127.0.0.1\tweb.localhost\n::1\tweb.localhost\n" +
+		endMarker + "\n"
+	if string(content) != want {
+		t.Fatalf("Update() = %q, want %q", content, want)
+	}
+	if got := Names(content); !reflect.DeepEqual(got, []string{"app.localhost", "web.localhost"}) {
+		t.Errorf("Names() = %v", got)
+	}
+
+	// Lines the user adds after the block are kept when it changes
+	content = append(content, "10.0.0.5\tnas\n"...)
+	content = Update(content, []string{"api.local"})
+	if !strings.HasPrefix(string(content), systemHosts) || !strings.HasSuffix(string(content), endMarker+"\n10.0.0.5\tnas\n") {
+		t.Errorf("Update() lost lines outside the block: %q", content)
+	}
+	if got := Names(content); !reflect.DeepEqual(got, []string{"api.local"}) {
+		t.Errorf("Names() = %v, want [api.local]", got)
+	}
+
+	// No names removes the block
+	if got := string(Update(content, nil)); got != systemHosts+"10.0.0.5\tnas\n" {
+		t.Errorf("Update(nil) = %q", got)
+	}
+
+	// A file without a trailing newline gets one before the block
+	if got := string(Update([]byte("127.0.0.1 localhost"), []string{"app.localhost"})); !strings.HasPrefix(got, "127.0.0.1 localhost\n"+beginMarker) {
+		t.Errorf("Update() = %q", got)
+	}
+}
+
+func TestApply(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(path, []byte(systemHosts), 0644); err != nil {
+		t.Fatalf("Failed to write hosts file: %v", err)
+	}
+	if !Writable(path) && os.Geteuid() != 0 {
+		t.Fatal("Expected a temp hosts file to be writable")
+	}
+
+	changed, err := Apply(path, []string{"app.localhost"})
+	if err != nil || !changed {
+		t.Fatalf("Apply() = %v, %v; want a change", changed, err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read hosts file: %v", err)
+	}
+	if got := Names(content); !reflect.DeepEqual(got, []string{"app.localhost"}) {
+		t.Errorf("Names() after Apply() = %v", got)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("mode after Apply() = %v, %v; want 0644", info.Mode(), err)
+	}
+
+	if changed, err := Apply(path, []string{"app.localhost"}); err != nil || changed {
+		t.Errorf("Apply() with the same names = %v, %v; want no change", changed, err)
+	}
+
+	if _, err := Apply(path, []string{"example.com"}); err == nil {
+		t.Error("Expected an error for a name outside .localhost and .local")
+	}
+	if _, err := Apply(filepath.Join(t.TempDir(), "missing"), nil); err == nil {
+		t.Error("Expected an error for a missing hosts file")
+	}
+
+	// No temp files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the hosts file", len(entries))
+	}
+}
@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "daemon.log")
+	r, err := OpenRotatingFile(path)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile() failed: %v", err)
+	}
+	defer r.Close()
+	r.MaxSize = 20
+	r.MaxBackups = 2
+
+	for _, line := range []string{"first line\n", "second line\n", "third line\n", "fourth line\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+
+	// Each line pushed the file past MaxSize, so each is in its own file
+	// and the oldest was dropped
+	expected := map[string]string{
+		path:        "fourth line\n",
+		path + ".1": "third line\n",
+		path + ".2": "second line\n",
+	}
+	for file, content := range expected {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", filepath.Base(file), data, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("Only MaxBackups backups should be kept")
+	}
+}
+
+func TestRotatingFileAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.log")
+	if err := os.WriteFile(path, []byte("earlier\n"), 0644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+
+	r, err := OpenRotatingFile(path)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile() failed: %v", err)
+	}
+	if _, err := r.Write([]byte("later\n")); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	if string(data) != "earlier\nlater\n" {
+		t.Errorf("Log = %q, want the earlier content kept", data)
+	}
+
+	if _, err := r.Write([]byte("closed\n")); err == nil {
+		t.Error("Write() should fail after Close()")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"":      slog.LevelInfo,
+		"debug": slog.LevelDebug,
+		"INFO":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+	}
+	for name, want := range tests {
+		level, err := ParseLevel(name)
+		if err != nil {
+			t.Errorf("ParseLevel(%q) failed: %v", name, err)
+		} else if level != want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", name, level, want)
+		}
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel() should fail for an unknown level")
+	}
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+	logger.Debug("hidden")
+	logger.Info("route applied", "host", "app.localhost", "port", 3000)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 line, got %d: %q", len(lines), buf.String())
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("Log line is not JSON: %v", err)
+	}
+	if entry["msg"] != "route applied" || entry["level"] != "INFO" || entry["host"] != "app.localhost" {
+		t.Errorf("Unexpected log entry: %v", entry)
+	}
+}
@@ -0,0 +1,224 @@
+package mdns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	groupV4 = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: Port}
+	groupV6 = &net.UDPAddr{IP: net.ParseIP("ff02::fb"), Port: Port}
+)
+
+// conn is a socket on the mDNS port of one IP version
+type conn interface {
+	// readFrom reads a packet and returns its size and sender
+	readFrom(b []byte) (int, *net.UDPAddr, error)
+	// writeTo sends b to a single address
+	writeTo(b []byte, dst *net.UDPAddr) error
+	// multicast sends b to the group out of ifi
+	multicast(b []byte, ifi *net.Interface) error
+	// join joins the group on ifi
+	join(ifi *net.Interface) error
+	close() error
+}
+
+// listen opens a UDP socket on port that other mDNS responders on this
+// machine can bind too
+func listen(network, host string, port int) (net.PacketConn, error) {
+	config := net.ListenConfig{Control: func(_, _ string, raw syscall.RawConn) error {
+		var err error
+		if controlErr := raw.Control(func(fd uintptr) {
+			err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1)
+			if err == nil {
+				err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+			}
+		}); controlErr != nil {
+			return controlErr
+		}
+		return err
+	}}
+	return config.ListenPacket(context.Background(), network, net.JoinHostPort(host, strconv.Itoa(port)))
+}
+
+// conn4 is the IPv4 socket
+type conn4 struct {
+	mu sync.Mutex // serializes choosing the interface and writing
+	pc *ipv4.PacketConn
+}
+
+func listen4(port int) (*conn4, error) {
+	c, err := listen("udp4", "0.0.0.0", port)
+	if err != nil {
+		return nil, err
+	}
+	pc := ipv4.NewPacketConn(c)
+	// RFC 6762 has packets sent with a TTL of 255, and multicast loopback
+	// lets the local resolver see announcements
+	_ = pc.SetMulticastTTL(255)
+	_ = pc.SetTTL(255)
+	_ = pc.SetMulticastLoopback(true)
+	return &conn4{pc: pc}, nil
+}
+
+func (c *conn4) readFrom(b []byte) (int, *net.UDPAddr, error) {
+	n, _, src, err := c.pc.ReadFrom(b)
+	if err != nil {
+		return 0, nil, err
+	}
+	return n, src.(*net.UDPAddr), nil
+}
+
+func (c *conn4) writeTo(b []byte, dst *net.UDPAddr) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.pc.WriteTo(b, nil, dst)
+	return err
+}
+
+func (c *conn4) multicast(b []byte, ifi *net.Interface) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.pc.SetMulticastInterface(ifi); err != nil {
+		return err
+	}
+	_, err := c.pc.WriteTo(b, nil, groupV4)
+	return err
+}
+
+func (c *conn4) join(ifi *net.Interface) error {
+	return c.pc.JoinGroup(ifi, groupV4)
+}
+
+func (c *conn4) close() error {
+	return c.pc.Close()
+}
+
+// conn6 is the IPv6 socket
+type conn6 struct {
+	mu sync.Mutex // serializes choosing the interface and writing
+	pc *ipv6.PacketConn
+}
+
+func listen6(port int) (*conn6, error) {
+	c, err := listen("udp6", "::", port)
+	if err != nil {
+		return nil, err
+	}
+	pc := ipv6.NewPacketConn(c)
+	_ = pc.SetMulticastHopLimit(255)
+	_ = pc.SetHopLimit(255)
+	_ = pc.SetMulticastLoopback(true)
+	return &conn6{pc: pc}, nil
+}
+
+func (c *conn6) readFrom(b []byte) (int, *net.UDPAddr, error) {
+	n, _, src, err := c.pc.ReadFrom(b)
+	if err != nil {
+		return 0, nil, err
+	}
+	return n, src.(*net.UDPAddr), nil
+}
+
+func (c *conn6) writeTo(b []byte, dst *net.UDPAddr) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.pc.WriteTo(b, nil, dst)
+	return err
+}
+
+func (c *conn6) multicast(b []byte, ifi *net.Interface) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.pc.SetMulticastInterface(ifi); err != nil {
+		return err
+	}
+	_, err := c.pc.WriteTo(b, nil, groupV6)
+	return err
+}
+
+func (c *conn6) join(ifi *net.Interface) error {
+	return c.pc.JoinGroup(ifi, groupV6)
+}
+
+func (c *conn6) close() error {
+	return c.pc.Close()
+}
+
+// isAlreadyJoined reports whether err is from joining a group twice
+func isAlreadyJoined(err error) bool {
+	return errors.Is(err, syscall.EADDRINUSE)
+}
+
+// isLocal reports whether ip is an address of this machine
+func isLocal(ip net.IP) bool {
+	if ip.IsLoopback() {
+		return true
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// interfaceOf returns the interface src can be reached on: the one named by
+// its zone, or the one with a subnet containing it
+func interfaceOf(src *net.UDPAddr) *net.Interface {
+	if src.Zone != "" {
+		if ifi, err := net.InterfaceByName(src.Zone); err == nil {
+			return ifi
+		}
+	}
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	for i := range interfaces {
+		addrs, err := interfaces[i].Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.Contains(src.IP) {
+				return &interfaces[i]
+			}
+		}
+	}
+	return nil
+}
+
+// interfaceAddresses returns the addresses of ifi to answer with: the
+// loopback ones on a loopback interface, and otherwise its IPv4 addresses
+// and IPv6 addresses that aren't link-local, which would need a zone
+func interfaceAddresses(ifi *net.Interface) []net.IP {
+	if ifi.Flags&net.FlagLoopback != 0 {
+		return []net.IP{loopbackV4, loopbackV6}
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil
+	}
+	var ips []net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		ips = append(ips, ipNet.IP)
+	}
+	return ips
+}
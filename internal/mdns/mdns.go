@@ -0,0 +1,453 @@
+// Package mdns publishes the routes' .local names over multicast DNS (RFC
+// 6762), so they resolve wherever .local is looked up through mDNS: macOS,
+// and Linux with Avahi and nss-mdns.
+//
+// By default only queries sent from this machine are answered, with the
+// loopback addresses and by unicast, so nothing about the routes leaves it.
+// The local resolver sends its queries out of the network interfaces with
+// multicast loopback on, so the group is joined on every interface to see
+// them. On the LAN, answers carry an address of the interface the querier
+// is on, and names are announced when they are added and withdrawn with
+// goodbye packets when they are removed.
+//
+// Names aren't probed for before they are used, so a name another host on
+// the LAN already has is answered for by both.
+package mdns
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Port is the mDNS port
+const Port = 5353
+
+const (
+	// ttl is the lifetime of multicast answers, which RFC 6762 recommends
+	// for address records
+	ttl = 120
+
+	// unicastTTL is the lifetime of unicast answers: the most RFC 6762
+	// allows for one-shot queries, and short so that a removed route stops
+	// resolving soon, since no goodbye reaches a unicast querier
+	unicastTTL = 10
+
+	// maxPacket is the largest mDNS packet RFC 6762 allows
+	maxPacket = 9000
+
+	// namesPerPacket keeps announcements of many names within maxPacket
+	namesPerPacket = 16
+
+	// cacheFlush marks a record as the only one of its name and type, so
+	// caches replace older ones; unicastResponse in a question asks for a
+	// unicast answer
+	cacheFlush      = 1 << 15
+	unicastResponse = 1 << 15
+
+	// joinInterval is how often the group is joined on interfaces that came
+	// up since
+	joinInterval = time.Minute
+)
+
+var (
+	loopbackV4 = net.IPv4(127, 0, 0, 1)
+	loopbackV6 = net.IPv6loopback
+)
+
+// Responder answers mDNS queries for a set of .local names
+type Responder struct {
+	lan  bool
+	port int
+
+	mu    sync.Mutex
+	names map[string]bool // fully qualified, lower case
+	conns []conn
+	done  chan struct{}
+	wg    sync.WaitGroup
+
+	// joined are the interfaces names are announced on: those the group
+	// was joined on, except loopback ones
+	joined []net.Interface
+}
+
+// New creates a responder with no names. With lan set it answers queries
+// from other hosts and announces its names; otherwise it only answers
+// queries from this machine.
+func New(lan bool) *Responder {
+	return &Responder{lan: lan, port: Port, names: make(map[string]bool)}
+}
+
+// Start listens on the mDNS port over IPv4 and, where available, IPv6, and
+// answers queries until Stop is called. The port is shared with other
+// responders, such as Avahi or mDNSResponder.
+func (r *Responder) Start() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.done != nil {
+		return errors.New("mDNS responder already started")
+	}
+
+	c4, err := listen4(r.port)
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d/udp: %w", r.port, err)
+	}
+	r.conns = []conn{c4}
+	if c6, err := listen6(r.port); err == nil {
+		r.conns = append(r.conns, c6)
+	} else {
+		slog.Debug("mDNS over IPv6 unavailable", "error", err)
+	}
+
+	r.done = make(chan struct{})
+	r.joinLocked()
+	for _, c := range r.conns {
+		r.wg.Add(1)
+		go r.serve(c)
+	}
+	r.wg.Add(1)
+	go r.rejoin()
+
+	if r.lan {
+		r.announceLocked(r.sortedNamesLocked())
+	}
+	return nil
+}
+
+// Stop withdraws the names on the LAN and closes the sockets
+func (r *Responder) Stop() error {
+	r.mu.Lock()
+	if r.done == nil {
+		r.mu.Unlock()
+		return nil
+	}
+	if r.lan {
+		r.goodbyeLocked(r.sortedNamesLocked())
+	}
+	close(r.done)
+	var errs []error
+	for _, c := range r.conns {
+		errs = append(errs, c.close())
+	}
+	r.conns = nil
+	r.mu.Unlock()
+
+	r.wg.Wait()
+
+	r.mu.Lock()
+	r.done = nil
+	r.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// SetNames replaces the names answered for. Names outside .local are
+// ignored. On the LAN, added names are announced and removed ones
+// withdrawn.
+func (r *Responder) SetNames(names []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = dns.Fqdn(strings.ToLower(name))
+		if strings.HasSuffix(name, ".local.") {
+			next[name] = true
+		}
+	}
+	var added, removed []string
+	for name := range next {
+		if !r.names[name] {
+			added = append(added, name)
+		}
+	}
+	for name := range r.names {
+		if !next[name] {
+			removed = append(removed, name)
+		}
+	}
+	r.names = next
+
+	if r.lan && r.done != nil {
+		r.goodbyeLocked(removed)
+		r.announceLocked(added)
+	}
+}
+
+// Names returns the names answered for, without the trailing dot
+func (r *Responder) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := r.sortedNamesLocked()
+	for i, name := range names {
+		names[i] = strings.TrimSuffix(name, ".")
+	}
+	return names
+}
+
+// has reports whether name is answered for
+func (r *Responder) has(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.names[strings.ToLower(name)]
+}
+
+// serve answers the queries arriving on c until it is closed
+func (r *Responder) serve(c conn) {
+	defer r.wg.Done()
+	buf := make([]byte, maxPacket)
+	for {
+		n, src, err := c.readFrom(buf)
+		if err != nil {
+			select {
+			case <-r.done:
+				return
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			slog.Debug("failed to read mDNS packet", "error", err)
+			continue
+		}
+		if err := r.handle(c, buf[:n], src); err != nil {
+			slog.Debug("failed to answer mDNS query", "from", src.String(), "error", err)
+		}
+	}
+}
+
+// handle answers the query in packet from src, if it asks for one of the
+// names and may be answered
+func (r *Responder) handle(c conn, packet []byte, src *net.UDPAddr) error {
+	var query dns.Msg
+	if err := query.Unpack(packet); err != nil || query.Response || query.Opcode != dns.OpcodeQuery {
+		return nil
+	}
+	if !r.lan && !isLocal(src.IP) {
+		return nil
+	}
+
+	// A query from another port is a one-shot query from a plain DNS
+	// client, which gets a unicast DNS reply
+	oneShot := src.Port != Port
+	reply := r.answer(&query, oneShot, r.addresses(src))
+	if reply == nil {
+		return nil
+	}
+	packed, err := reply.Pack()
+	if err != nil {
+		return err
+	}
+
+	// Loopback answers mustn't reach the LAN, where a multicast on a
+	// loopback interface may go
+	unicast := oneShot || !r.lan || src.IP.IsLoopback()
+	for _, q := range query.Question {
+		if q.Qclass&unicastResponse != 0 {
+			unicast = true
+		}
+	}
+	if unicast {
+		return c.writeTo(packed, src)
+	}
+	ifi := interfaceOf(src)
+	if ifi == nil {
+		return nil
+	}
+	return c.multicast(packed, ifi)
+}
+
+// answer builds the reply to query with addrs for the names it asks about,
+// or returns nil if it asks about none of them
+func (r *Responder) answer(query *dns.Msg, oneShot bool, addrs []net.IP) *dns.Msg {
+	lifetime, class := uint32(ttl), uint16(dns.ClassINET|cacheFlush)
+	if oneShot || !r.lan {
+		lifetime = unicastTTL
+	}
+	if oneShot {
+		// Caches of plain DNS clients don't know the cache-flush bit
+		class = dns.ClassINET
+	}
+
+	var answers []dns.RR
+	for _, q := range query.Question {
+		qclass := q.Qclass &^ unicastResponse
+		if (qclass != dns.ClassINET && qclass != dns.ClassANY) || !r.has(q.Name) {
+			continue
+		}
+		answers = append(answers, records(strings.ToLower(q.Name), q.Qtype, addrs, lifetime, class)...)
+	}
+	if len(answers) == 0 {
+		return nil
+	}
+
+	reply := &dns.Msg{Answer: answers}
+	reply.Response = true
+	reply.Authoritative = true
+	if oneShot {
+		reply.Id = query.Id
+		for _, q := range query.Question {
+			q.Qclass &^= unicastResponse
+			reply.Question = append(reply.Question, q)
+		}
+	}
+	return reply
+}
+
+// records returns the address records of name of qtype, A, AAAA or ANY
+func records(name string, qtype uint16, addrs []net.IP, lifetime uint32, class uint16) []dns.RR {
+	var rrs []dns.RR
+	for _, addr := range addrs {
+		header := dns.RR_Header{Name: name, Class: class, Ttl: lifetime}
+		if v4 := addr.To4(); v4 != nil {
+			if qtype == dns.TypeA || qtype == dns.TypeANY {
+				header.Rrtype = dns.TypeA
+				rrs = append(rrs, &dns.A{Hdr: header, A: v4})
+			}
+		} else if qtype == dns.TypeAAAA || qtype == dns.TypeANY {
+			header.Rrtype = dns.TypeAAAA
+			rrs = append(rrs, &dns.AAAA{Hdr: header, AAAA: addr})
+		}
+	}
+	return rrs
+}
+
+// addresses returns the addresses to answer src with: the loopback ones
+// unless answering the LAN, and there those of the interface src is on
+func (r *Responder) addresses(src *net.UDPAddr) []net.IP {
+	if !r.lan || src.IP.IsLoopback() {
+		return []net.IP{loopbackV4, loopbackV6}
+	}
+	ifi := interfaceOf(src)
+	if ifi == nil {
+		return nil
+	}
+	return interfaceAddresses(ifi)
+}
+
+// announceLocked sends the records of names on every joined interface, and
+// again a second later as RFC 6762 asks, in case the first is lost
+func (r *Responder) announceLocked(names []string) {
+	if len(names) == 0 {
+		return
+	}
+	r.sendLocked(names, ttl)
+
+	done := r.done
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		select {
+		case <-done:
+			return
+		case <-time.After(time.Second):
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		var current []string
+		for _, name := range names {
+			if r.names[name] {
+				current = append(current, name)
+			}
+		}
+		r.sendLocked(current, ttl)
+	}()
+}
+
+// goodbyeLocked withdraws names with records of lifetime 0
+func (r *Responder) goodbyeLocked(names []string) {
+	r.sendLocked(names, 0)
+}
+
+// sendLocked multicasts the records of names with lifetime on every joined
+// interface, with that interface's addresses
+func (r *Responder) sendLocked(names []string, lifetime uint32) {
+	for _, ifi := range r.joined {
+		addrs := interfaceAddresses(&ifi)
+		for start := 0; start < len(names); start += namesPerPacket {
+			end := min(start+namesPerPacket, len(names))
+			msg := &dns.Msg{}
+			msg.Response = true
+			msg.Authoritative = true
+			for _, name := range names[start:end] {
+				msg.Answer = append(msg.Answer, records(name, dns.TypeANY, addrs, lifetime, dns.ClassINET|cacheFlush)...)
+			}
+			if len(msg.Answer) == 0 {
+				continue
+			}
+			packed, err := msg.Pack()
+			if err != nil {
+				slog.Debug("failed to pack mDNS announcement", "error", err)
+				continue
+			}
+			for _, c := range r.conns {
+				if err := c.multicast(packed, &ifi); err != nil {
+					slog.Debug("failed to send mDNS announcement", "interface", ifi.Name, "error", err)
+				}
+			}
+		}
+	}
+}
+
+// sortedNamesLocked returns the names in order
+func (r *Responder) sortedNamesLocked() []string {
+	names := make([]string, 0, len(r.names))
+	for name := range r.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// rejoin joins the group on new interfaces until Stop is called
+func (r *Responder) rejoin() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(joinInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			r.mu.Lock()
+			r.joinLocked()
+			r.mu.Unlock()
+		}
+	}
+}
+
+// joinLocked joins the group on every interface that is up. Joining one
+// again fails harmlessly, and an interface that went down and up again
+// has to be joined anew.
+func (r *Responder) joinLocked() {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		slog.Debug("failed to list network interfaces", "error", err)
+		return
+	}
+	r.joined = r.joined[:0]
+	for _, ifi := range interfaces {
+		if ifi.Flags&net.FlagUp == 0 || ifi.Flags&(net.FlagMulticast|net.FlagLoopback) == 0 {
+			continue
+		}
+		joined := false
+		for _, c := range r.conns {
+			if err := c.join(&ifi); err == nil || isAlreadyJoined(err) {
+				joined = true
+			}
+		}
+		// Multicasts sent to a loopback interface may go out of the
+		// default one instead, taking the loopback addresses to the LAN.
+		// The local resolver sees the other interfaces' announcements.
+		if joined && ifi.Flags&net.FlagLoopback == 0 {
+			r.joined = append(r.joined, ifi)
+		}
+	}
+}
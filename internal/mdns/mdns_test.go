@@ -0,0 +1,233 @@
+package mdns
+
+import (
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// fakeConn records the packets a responder sends
+type fakeConn struct {
+	mu   sync.Mutex
+	sent []*dns.Msg
+}
+
+func (c *fakeConn) readFrom([]byte) (int, *net.UDPAddr, error) { return 0, nil, net.ErrClosed }
+func (c *fakeConn) join(*net.Interface) error                  { return nil }
+func (c *fakeConn) close() error                               { return nil }
+
+func (c *fakeConn) writeTo(b []byte, _ *net.UDPAddr) error { return c.record(b) }
+
+func (c *fakeConn) multicast(b []byte, _ *net.Interface) error { return c.record(b) }
+
+func (c *fakeConn) record(b []byte) error {
+	msg := new(dns.Msg)
+	if err := msg.Unpack(b); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, msg)
+	return nil
+}
+
+// take returns the packets sent so far and forgets them
+func (c *fakeConn) take() []*dns.Msg {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sent := c.sent
+	c.sent = nil
+	return sent
+}
+
+// fakeResponder returns a started LAN responder whose packets go to c, on
+// a loopback interface
+func fakeResponder(c *fakeConn) *Responder {
+	r := New(true)
+	r.conns = []conn{c}
+	r.done = make(chan struct{})
+	r.joined = []net.Interface{{Index: 1, Name: "lo", Flags: net.FlagUp | net.FlagLoopback}}
+	return r
+}
+
+// exchange sends a one-shot query for name to the responder and returns
+// the reply, or nil if there is none
+func exchange(t *testing.T, r *Responder, name string, qtype uint16) *dns.Msg {
+	t.Helper()
+	addr := r.conns[0].(*conn4).pc.LocalAddr().(*net.UDPAddr)
+	req := new(dns.Msg)
+	req.SetQuestion(dns.Fqdn(name), qtype)
+	client := &dns.Client{Timeout: 300 * time.Millisecond}
+	reply, _, err := client.Exchange(req, net.JoinHostPort("127.0.0.1", strconv.Itoa(addr.Port)))
+	if err != nil {
+		return nil
+	}
+	return reply
+}
+
+func TestResponderOneShot(t *testing.T) {
+	r := New(false)
+	r.port = 0
+	if err := r.Start(); err != nil {
+		t.Skipf("Start() failed: %v", err)
+	}
+	defer r.Stop()
+	r.SetNames([]string{"My-App.local", "api.localhost", "web.local"})
+
+	if got, want := r.Names(), []string{"my-app.local", "web.local"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+
+	reply := exchange(t, r, "my-app.local", dns.TypeA)
+	if reply == nil || !reply.Authoritative || len(reply.Answer) != 1 || len(reply.Question) != 1 {
+		t.Fatalf("reply = %v, want one authoritative answer and the question", reply)
+	}
+	a, ok := reply.Answer[0].(*dns.A)
+	if !ok || !a.A.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("answer = %v, want 127.0.0.1", reply.Answer[0])
+	}
+	if a.Hdr.Ttl != unicastTTL || a.Hdr.Class != dns.ClassINET {
+		t.Errorf("answer TTL %d class %d, want %d without the cache-flush bit", a.Hdr.Ttl, a.Hdr.Class, unicastTTL)
+	}
+
+	reply = exchange(t, r, "web.local", dns.TypeAAAA)
+	if reply == nil || len(reply.Answer) != 1 {
+		t.Fatalf("AAAA reply = %v, want one answer", reply)
+	}
+	if aaaa, ok := reply.Answer[0].(*dns.AAAA); !ok || !aaaa.AAAA.Equal(net.IPv6loopback) {
+		t.Errorf("answer = %v, want ::1", reply.Answer[0])
+	}
+
+	// Names that aren't published get no answer at all, as mDNS asks
+	for _, name := range []string{"other.local", "api.localhost"} {
+		if reply := exchange(t, r, name, dns.TypeA); reply != nil {
+			t.Errorf("query for %s got %v, want no reply", name, reply)
+		}
+	}
+
+	// Removed names stop being answered
+	r.SetNames([]string{"web.local"})
+	if reply := exchange(t, r, "my-app.local", dns.TypeA); reply != nil {
+		t.Errorf("query for a removed name got %v, want no reply", reply)
+	}
+}
+
+func TestResponderAnswer(t *testing.T) {
+	r := New(true)
+	r.SetNames([]string{"app.local"})
+	addrs := []net.IP{net.IPv4(192, 168, 1, 5), net.ParseIP("fd00::5")}
+
+	query := new(dns.Msg)
+	query.SetQuestion("APP.local.", dns.TypeANY)
+	query.Question[0].Qclass |= unicastResponse
+	reply := r.answer(query, false, addrs)
+	if reply == nil || len(reply.Answer) != 2 {
+		t.Fatalf("reply = %v, want an A and an AAAA answer", reply)
+	}
+	// Multicast DNS replies have no ID or questions, and own their records
+	if reply.Id != 0 || len(reply.Question) != 0 {
+		t.Errorf("reply has ID %d and %d questions, want neither", reply.Id, len(reply.Question))
+	}
+	for _, rr := range reply.Answer {
+		if rr.Header().Ttl != ttl || rr.Header().Class != dns.ClassINET|cacheFlush || rr.Header().Name != "app.local." {
+			t.Errorf("answer %v, want TTL %d with the cache-flush bit", rr, ttl)
+		}
+	}
+
+	query.SetQuestion("app.local.", dns.TypeMX)
+	if reply := r.answer(query, false, addrs); reply != nil {
+		t.Errorf("MX reply = %v, want none", reply)
+	}
+}
+
+func TestResponderAnnounce(t *testing.T) {
+	c := &fakeConn{}
+	r := fakeResponder(c)
+	defer close(r.done)
+
+	r.SetNames([]string{"app.local", "web.local"})
+	sent := c.take()
+	if len(sent) != 1 || len(sent[0].Answer) != 4 {
+		t.Fatalf("announcement = %v, want A and AAAA records of both names", sent)
+	}
+	for _, rr := range sent[0].Answer {
+		if rr.Header().Ttl != ttl {
+			t.Errorf("announced %v, want TTL %d", rr, ttl)
+		}
+	}
+
+	// The announcement is repeated a second later
+	deadline := time.Now().Add(3 * time.Second)
+	for sent = c.take(); len(sent) == 0; sent = c.take() {
+		if time.Now().After(deadline) {
+			t.Fatal("announcement wasn't repeated")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// A removed name gets a goodbye with a TTL of 0
+	r.SetNames([]string{"web.local"})
+	sent = c.take()
+	if len(sent) != 1 || len(sent[0].Answer) != 2 {
+		t.Fatalf("goodbye = %v, want records of the removed name", sent)
+	}
+	for _, rr := range sent[0].Answer {
+		if rr.Header().Ttl != 0 || rr.Header().Name != "app.local." {
+			t.Errorf("goodbye has %v, want app.local. with TTL 0", rr)
+		}
+	}
+}
+
+func TestResponderStopSaysGoodbye(t *testing.T) {
+	c := &fakeConn{}
+	r := fakeResponder(c)
+	r.SetNames([]string{"app.local"})
+	c.take()
+
+	if err := r.Stop(); err != nil {
+		t.Fatalf("Stop() failed: %v", err)
+	}
+	sent := c.take()
+	if len(sent) != 1 || len(sent[0].Answer) != 2 || sent[0].Answer[0].Header().Ttl != 0 {
+		t.Errorf("packets sent on Stop = %v, want a goodbye for app.local", sent)
+	}
+}
+
+func TestResponderLocalOnly(t *testing.T) {
+	c := &fakeConn{}
+	r := New(false)
+	r.SetNames([]string{"app.local"})
+
+	query := new(dns.Msg)
+	query.SetQuestion("app.local.", dns.TypeA)
+	packet, err := query.Pack()
+	if err != nil {
+		t.Fatalf("Pack() failed: %v", err)
+	}
+	// A query from another host is ignored
+	src := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: Port}
+	if err := r.handle(c, packet, src); err != nil {
+		t.Fatalf("handle() failed: %v", err)
+	}
+	if sent := c.take(); len(sent) != 0 {
+		t.Errorf("sent %v, want nothing", sent)
+	}
+
+	// One from this machine gets a short-lived loopback answer
+	src = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: Port}
+	if err := r.handle(c, packet, src); err != nil {
+		t.Fatalf("handle() failed: %v", err)
+	}
+	sent := c.take()
+	if len(sent) != 1 || len(sent[0].Answer) != 1 {
+		t.Fatalf("sent %v, want one answer", sent)
+	}
+	if a, ok := sent[0].Answer[0].(*dns.A); !ok || !a.A.Equal(net.IPv4(127, 0, 0, 1)) || a.Hdr.Ttl != unicastTTL {
+		t.Errorf("answer = %v, want 127.0.0.1 with TTL %d", sent[0].Answer[0], unicastTTL)
+	}
+}
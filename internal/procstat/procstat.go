@@ -0,0 +1,174 @@
+// Package procstat samples CPU and memory usage of process groups from /proc.
+//
+// Dev servers started by faa run in their own process group (see devproc),
+// so usage is summed over every process in the group: a "npm run dev" that
+// spawns node is reported as a single entry.
+//
+// /proc only exists on Linux; on other platforms Supported reports false.
+package procstat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is the kernel's USER_HZ, the unit of the CPU times in
+// /proc/<pid>/stat. It is fixed at 100 in the Linux ABI.
+const clockTicks = 100
+
+// procRoot is the mount point of procfs; tests point it at a fake tree
+var procRoot = "/proc"
+
+// Sample is the resource usage of a process group at a point in time
+type Sample struct {
+	// CPUTicks is the total user and system CPU time in clock ticks
+	CPUTicks uint64
+
+	// RSSBytes is the resident memory of all processes in the group
+	RSSBytes uint64
+
+	// Processes is the number of processes in the group
+	Processes int
+
+	Time time.Time
+}
+
+// Usage is the resource usage of a process group between two samples
+type Usage struct {
+	// CPUPercent is the CPU time used as a percentage of one core
+	CPUPercent float64
+
+	RSSBytes  uint64
+	Processes int
+}
+
+// Supported reports whether process statistics can be read on this system
+func Supported() bool {
+	_, err := os.Stat(filepath.Join(procRoot, "self", "stat"))
+	return err == nil
+}
+
+// ReadGroup sums the usage of every process in the process group pgid
+func ReadGroup(pgid int) (Sample, error) {
+	sample := Sample{Time: time.Now()}
+
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return sample, fmt.Errorf("failed to read %s: %w", procRoot, err)
+	}
+
+	pageSize := uint64(os.Getpagesize())
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		stat, err := readStat(pid)
+		if err != nil || stat.pgrp != pgid {
+			// Processes may exit while the directory is being scanned
+			continue
+		}
+
+		sample.CPUTicks += stat.utime + stat.stime
+		sample.RSSBytes += stat.rssPages * pageSize
+		sample.Processes++
+	}
+
+	if sample.Processes == 0 {
+		return sample, fmt.Errorf("no processes in group %d", pgid)
+	}
+	return sample, nil
+}
+
+// procStat holds the fields of /proc/<pid>/stat used by this package
+type procStat struct {
+	pgrp     int
+	utime    uint64
+	stime    uint64
+	rssPages uint64
+}
+
+// readStat parses /proc/<pid>/stat
+func readStat(pid int) (procStat, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return procStat{}, err
+	}
+	return parseStat(string(data))
+}
+
+// parseStat parses the contents of a /proc/<pid>/stat file.
+// The command name is in parentheses and may itself contain spaces and
+// parentheses, so fields are counted from the last closing parenthesis.
+func parseStat(data string) (procStat, error) {
+	end := strings.LastIndexByte(data, ')')
+	if end < 0 {
+		return procStat{}, fmt.Errorf("malformed stat: missing command name")
+	}
+
+	// fields[0] is field 3 (state) in proc(5) numbering
+	fields := strings.Fields(data[end+1:])
+	if len(fields) < 22 {
+		return procStat{}, fmt.Errorf("malformed stat: %d fields", len(fields))
+	}
+
+	var stat procStat
+	var err error
+	if stat.pgrp, err = strconv.Atoi(fields[2]); err != nil {
+		return procStat{}, fmt.Errorf("malformed stat pgrp: %w", err)
+	}
+	if stat.utime, err = strconv.ParseUint(fields[11], 10, 64); err != nil {
+		return procStat{}, fmt.Errorf("malformed stat utime: %w", err)
+	}
+	if stat.stime, err = strconv.ParseUint(fields[12], 10, 64); err != nil {
+		return procStat{}, fmt.Errorf("malformed stat stime: %w", err)
+	}
+	if stat.rssPages, err = strconv.ParseUint(fields[21], 10, 64); err != nil {
+		return procStat{}, fmt.Errorf("malformed stat rss: %w", err)
+	}
+	return stat, nil
+}
+
+// Sampler computes CPU usage of process groups between successive calls
+type Sampler struct {
+	previous map[int]Sample
+}
+
+// NewSampler creates a new Sampler
+func NewSampler() *Sampler {
+	return &Sampler{previous: make(map[int]Sample)}
+}
+
+// Sample reads the current usage of the process group pgid.
+// CPU usage is measured since the previous call for the same group and is
+// zero on the first call.
+func (s *Sampler) Sample(pgid int) (Usage, error) {
+	current, err := ReadGroup(pgid)
+	if err != nil {
+		delete(s.previous, pgid)
+		return Usage{}, err
+	}
+
+	usage := Usage{RSSBytes: current.RSSBytes, Processes: current.Processes}
+	if previous, ok := s.previous[pgid]; ok {
+		usage.CPUPercent = cpuPercent(previous, current)
+	}
+	s.previous[pgid] = current
+	return usage, nil
+}
+
+// cpuPercent returns the CPU used between two samples as a percentage of one core
+func cpuPercent(previous, current Sample) float64 {
+	elapsed := current.Time.Sub(previous.Time).Seconds()
+	if elapsed <= 0 || current.CPUTicks < previous.CPUTicks {
+		// Processes in the group exited, so the totals are not comparable
+		return 0
+	}
+	used := float64(current.CPUTicks-previous.CPUTicks) / clockTicks
+	return used / elapsed * 100
+}
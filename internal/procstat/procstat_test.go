@@ -0,0 +1,149 @@
+package procstat
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// statLine builds a /proc/<pid>/stat line with the given fields set
+func statLine(pid int, comm string, pgrp int, utime, stime, rss uint64) string {
+	return fmt.Sprintf("%d (%s) S 1 %d %d 0 -1 4194304 100 0 0 0 %d %d 0 0 20 0 1 0 100 1000000 %d 18446744073709551615\n",
+		pid, comm, pgrp, pgrp, utime, stime, rss)
+}
+
+// writeFakeProc creates a fake procfs tree and points procRoot at it
+func writeFakeProc(t *testing.T, stats map[int]string) {
+	t.Helper()
+
+	root := t.TempDir()
+	for pid, line := range stats {
+		dir := filepath.Join(root, fmt.Sprint(pid))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(line), 0644); err != nil {
+			t.Fatalf("Failed to write stat: %v", err)
+		}
+	}
+	// Non-process entries must be ignored
+	if err := os.WriteFile(filepath.Join(root, "uptime"), []byte("1 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write uptime: %v", err)
+	}
+
+	original := procRoot
+	procRoot = root
+	t.Cleanup(func() { procRoot = original })
+}
+
+func TestParseStat(t *testing.T) {
+	stat, err := parseStat(statLine(42, "node (worker) x", 40, 150, 50, 1000))
+	if err != nil {
+		t.Fatalf("parseStat() failed: %v", err)
+	}
+
+	if stat.pgrp != 40 {
+		t.Errorf("pgrp = %d, want 40", stat.pgrp)
+	}
+	if stat.utime != 150 || stat.stime != 50 {
+		t.Errorf("utime/stime = %d/%d, want 150/50", stat.utime, stat.stime)
+	}
+	if stat.rssPages != 1000 {
+		t.Errorf("rssPages = %d, want 1000", stat.rssPages)
+	}
+}
+
+func TestParseStatMalformed(t *testing.T) {
+	for _, data := range []string{"", "42 node S 1", "42 (node) S 1 2 3"} {
+		if _, err := parseStat(data); err == nil {
+			t.Errorf("parseStat(%q) should fail", data)
+		}
+	}
+}
+
+func TestReadGroup(t *testing.T) {
+	writeFakeProc(t, map[int]string{
+		100: statLine(100, "npm", 100, 10, 5, 100),
+		101: statLine(101, "node", 100, 200, 20, 300),
+		200: statLine(200, "other", 200, 999, 999, 999),
+	})
+
+	sample, err := ReadGroup(100)
+	if err != nil {
+		t.Fatalf("ReadGroup() failed: %v", err)
+	}
+
+	if sample.Processes != 2 {
+		t.Errorf("Processes = %d, want 2", sample.Processes)
+	}
+	if sample.CPUTicks != 235 {
+		t.Errorf("CPUTicks = %d, want 235", sample.CPUTicks)
+	}
+	if want := uint64(400 * os.Getpagesize()); sample.RSSBytes != want {
+		t.Errorf("RSSBytes = %d, want %d", sample.RSSBytes, want)
+	}
+
+	if _, err := ReadGroup(300); err == nil {
+		t.Error("ReadGroup() should fail for an empty group")
+	}
+}
+
+func TestCPUPercent(t *testing.T) {
+	start := time.Now()
+	previous := Sample{CPUTicks: 100, Time: start}
+
+	// 50 ticks (0.5s of CPU) over 1 second is 50% of one core
+	current := Sample{CPUTicks: 150, Time: start.Add(time.Second)}
+	if got := cpuPercent(previous, current); math.Abs(got-50) > 0.001 {
+		t.Errorf("cpuPercent() = %f, want 50", got)
+	}
+
+	// Totals going backwards means a process exited
+	current = Sample{CPUTicks: 50, Time: start.Add(time.Second)}
+	if got := cpuPercent(previous, current); got != 0 {
+		t.Errorf("cpuPercent() = %f, want 0", got)
+	}
+}
+
+func TestSamplerLiveProcess(t *testing.T) {
+	if !Supported() {
+		t.Skip("procfs is not available")
+	}
+
+	// Sample the process group the test binary runs in
+	pgid, err := getpgid()
+	if err != nil {
+		t.Fatalf("Failed to get process group: %v", err)
+	}
+
+	sampler := NewSampler()
+	usage, err := sampler.Sample(pgid)
+	if err != nil {
+		t.Fatalf("Sample() failed: %v", err)
+	}
+	if usage.Processes < 1 {
+		t.Errorf("Processes = %d, want at least 1", usage.Processes)
+	}
+	if usage.RSSBytes == 0 {
+		t.Error("RSSBytes should be non-zero for a live process")
+	}
+	if usage.CPUPercent != 0 {
+		t.Errorf("First sample CPUPercent = %f, want 0", usage.CPUPercent)
+	}
+
+	if _, err := sampler.Sample(pgid); err != nil {
+		t.Fatalf("Second Sample() failed: %v", err)
+	}
+}
+
+// getpgid returns the process group of the current process
+func getpgid() (int, error) {
+	stat, err := readStat(os.Getpid())
+	if err != nil {
+		return 0, err
+	}
+	return stat.pgrp, nil
+}
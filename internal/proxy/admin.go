@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+// AdminOff disables Caddy's admin endpoint. faa configures Caddy
+// in-process, so the endpoint is only useful for inspecting the live
+// config.
+const AdminOff = "off"
+
+// ErrAdminInUse is returned by Start when the admin endpoint's address is
+// already taken, usually by another Caddy.
+var ErrAdminInUse = errors.New("caddy admin endpoint address is already in use")
+
+// SetAdmin sets where Caddy's admin endpoint listens: a Caddy network
+// address such as localhost:2019 or unix//path/to/admin.sock, or AdminOff.
+// Unix sockets are only accessible to the current user. The endpoint is
+// off unless set, and changes take effect on the next Start.
+func (p *Proxy) SetAdmin(address string) error {
+	if address == "" {
+		return errors.New("admin address must not be empty")
+	}
+	if address != AdminOff {
+		addr, err := caddy.ParseNetworkAddress(address)
+		if err != nil {
+			return fmt.Errorf("invalid admin address %q: %w", address, err)
+		}
+		if addr.PortRangeSize() > 1 {
+			return fmt.Errorf("invalid admin address %q: must be a single address", address)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.running {
+		return errors.New("cannot change the admin endpoint while the proxy is running")
+	}
+	p.admin = address
+	return nil
+}
+
+// adminConfig returns Caddy's admin configuration
+func (p *Proxy) adminConfig() *caddy.AdminConfig {
+	if p.admin == AdminOff {
+		return &caddy.AdminConfig{Disabled: true}
+	}
+	return &caddy.AdminConfig{Listen: p.admin}
+}
+
+// prepareAdmin removes a socket file left behind by a proxy that did not
+// shut down cleanly, which would otherwise make the listener fail
+func (p *Proxy) prepareAdmin() error {
+	if p.admin == AdminOff {
+		return nil
+	}
+	addr, err := caddy.ParseNetworkAddress(p.admin)
+	if err != nil || !addr.IsUnixNetwork() {
+		return err
+	}
+
+	if _, err := os.Stat(addr.Host); err != nil {
+		return nil
+	}
+	if conn, err := net.DialTimeout("unix", addr.Host, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("%w: %s", ErrAdminInUse, addr.Host)
+	}
+	return os.Remove(addr.Host)
+}
+
+// adminError explains a failure to start the admin endpoint. Caddy
+// formats the listener error into its own, so it can't always be unwrapped.
+func (p *Proxy) adminError(err error) error {
+	if errors.Is(err, syscall.EADDRINUSE) ||
+		(strings.Contains(err.Error(), "administration endpoint") && strings.Contains(err.Error(), "address already in use")) {
+		return fmt.Errorf("%w: %s", ErrAdminInUse, p.admin)
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetAdmin(t *testing.T) {
+	p := NewWithPorts(18094, 18457)
+	if p.admin != AdminOff {
+		t.Errorf("admin = %q, want %q by default", p.admin, AdminOff)
+	}
+
+	for _, address := range []string{"localhost:2019", "unix//tmp/faa-admin.sock", AdminOff} {
+		if err := p.SetAdmin(address); err != nil {
+			t.Errorf("SetAdmin(%q) failed: %v", address, err)
+		}
+	}
+	for _, address := range []string{"", "localhost:99999", "tcp/localhost:2019-2020"} {
+		if err := p.SetAdmin(address); err == nil {
+			t.Errorf("SetAdmin(%q) should fail", address)
+		}
+	}
+
+	p.running = true
+	if err := p.SetAdmin("localhost:2019"); err == nil {
+		t.Error("SetAdmin() should fail while the proxy is running")
+	}
+}
+
+func TestBuildConfigJSONAdmin(t *testing.T) {
+	p := NewWithPorts(18094, 18457)
+	config, err := p.buildConfigJSON()
+	if err != nil {
+		t.Fatalf("buildConfigJSON() failed: %v", err)
+	}
+	if !strings.Contains(string(config), `"admin":{"disabled":true`) {
+		t.Errorf("Admin endpoint should be disabled by default, got %s", config)
+	}
+	if strings.Contains(string(config), "2019") {
+		t.Error("Config should not use the default Caddy admin port")
+	}
+
+	if err := p.SetAdmin("unix//tmp/faa-admin.sock"); err != nil {
+		t.Fatalf("SetAdmin() failed: %v", err)
+	}
+	config, err = p.buildConfigJSON()
+	if err != nil {
+		t.Fatalf("buildConfigJSON() failed: %v", err)
+	}
+	if !strings.Contains(string(config), `"listen":"unix//tmp/faa-admin.sock"`) {
+		t.Errorf("Config should listen on the admin socket, got %s", config)
+	}
+}
+
+func TestAdminSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "admin.sock")
+
+	// A socket left behind by a crashed proxy is replaced
+	if err := os.WriteFile(socket, nil, 0600); err != nil {
+		t.Fatalf("Failed to write stale socket: %v", err)
+	}
+
+	p := NewWithPorts(18094, 18457)
+	if err := p.SetAdmin("unix/" + socket); err != nil {
+		t.Fatalf("SetAdmin() failed: %v", err)
+	}
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer p.Stop()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	resp, err := client.Get("http://localhost/config/")
+	if err != nil {
+		t.Fatalf("Admin endpoint is not serving on the socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /config/ status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestAdminInUse(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	p := NewWithPorts(18095, 18458)
+	if err := p.SetAdmin(ln.Addr().String()); err != nil {
+		t.Fatalf("SetAdmin() failed: %v", err)
+	}
+	err = p.Start(context.Background())
+	if err == nil {
+		p.Stop()
+		t.Fatal("Start() should fail when the admin address is taken")
+	}
+	if !errors.Is(err, ErrAdminInUse) {
+		t.Errorf("Start() error = %v, want ErrAdminInUse", err)
+	}
+
+	p.mu.RLock()
+	running := p.running
+	p.mu.RUnlock()
+	if running {
+		t.Error("proxy should not be running after a failed Start()")
+	}
+}
@@ -2,6 +2,7 @@
 package proxy
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -30,25 +31,33 @@ func GetCAPath() (string, error) {
 //   - macOS: ~/Library/Application Support/Caddy/pki/authorities/local/root.crt
 //   - Linux/others: ~/.local/share/caddy/pki/authorities/local/root.crt
 func GetCaddyCAPath() (string, error) {
+	caddyDataDir, err := getCaddyDataDir()
+	if err != nil {
+		return "", err
+	}
+
+	caddyCAPath := filepath.Join(caddyDataDir, "pki", "authorities", "local", "root.crt")
+
+	return caddyCAPath, nil
+}
+
+// getCaddyDataDir returns the directory Caddy keeps its PKI and
+// certificates in
+func getCaddyDataDir() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get home directory: %w", err)
 	}
 
-	var caddyDataDir string
 	// Match Caddy's AppDataDir() logic from caddy/v2/storage.go
 	switch runtime.GOOS {
 	case "darwin":
 		// macOS uses Application Support
-		caddyDataDir = filepath.Join(homeDir, "Library", "Application Support", "Caddy")
+		return filepath.Join(homeDir, "Library", "Application Support", "Caddy"), nil
 	default:
 		// Linux and other Unix systems use XDG data directory
-		caddyDataDir = filepath.Join(homeDir, ".local", "share", "caddy")
+		return filepath.Join(homeDir, ".local", "share", "caddy"), nil
 	}
-
-	caddyCAPath := filepath.Join(caddyDataDir, "pki", "authorities", "local", "root.crt")
-
-	return caddyCAPath, nil
 }
 
 // ExportCA exports the Caddy internal CA certificate to the faa config directory.
@@ -60,7 +69,12 @@ func ExportCA() error {
 	if err != nil {
 		return fmt.Errorf("failed to get Caddy CA path: %w", err)
 	}
+	return ExportCAFrom(srcPath)
+}
 
+// ExportCAFrom exports the root certificate at srcPath, such as a
+// configured CA's (see Proxy.RootCAPath), like ExportCA
+func ExportCAFrom(srcPath string) error {
 	destPath, err := GetCAPath()
 	if err != nil {
 		return fmt.Errorf("failed to get faa CA path: %w", err)
@@ -92,9 +106,9 @@ func ExportCA() error {
 			return fmt.Errorf("failed to read destination certificate: %w", err)
 		}
 
-		// If they match, we're done
+		// If they match, only the bundle may need updating
 		if string(srcContent) == string(destContent) {
-			return nil
+			return exportCABundle(srcContent)
 		}
 
 		// Files differ, need to update
@@ -111,9 +125,90 @@ func ExportCA() error {
 		return fmt.Errorf("failed to write CA certificate to %s: %w", destPath, err)
 	}
 
+	return exportCABundle(certData)
+}
+
+// systemCABundlePaths are the system CA bundles, in the order Go's
+// crypto/x509 looks for them
+var systemCABundlePaths = []string{
+	"/etc/ssl/certs/ca-certificates.crt",                // Debian/Ubuntu/Gentoo etc.
+	"/etc/pki/tls/certs/ca-bundle.crt",                  // Fedora/RHEL 6
+	"/etc/ssl/ca-bundle.pem",                            // OpenSUSE
+	"/etc/pki/tls/cacert.pem",                           // OpenELEC
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem", // CentOS/RHEL 7
+	"/etc/ssl/cert.pem",                                 // Alpine Linux, macOS
+}
+
+// GetCABundlePath returns the path of the CA bundle holding the system's
+// root certificates and faa's CA: ~/.config/faa/ca-bundle.pem
+func GetCABundlePath() (string, error) {
+	caPath, err := GetCAPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(caPath), "ca-bundle.pem"), nil
+}
+
+// exportCABundle writes the system roots followed by the CA certificate to
+// the CA bundle, if it changed
+func exportCABundle(caCert []byte) error {
+	bundlePath, err := GetCABundlePath()
+	if err != nil {
+		return err
+	}
+
+	var system []byte
+	for _, path := range systemCABundlePaths {
+		if data, err := os.ReadFile(path); err == nil {
+			system = data
+			break
+		}
+	}
+	bundle := caBundle(system, caCert)
+
+	if current, err := os.ReadFile(bundlePath); err == nil && bytes.Equal(current, bundle) {
+		return nil
+	}
+	if err := os.WriteFile(bundlePath, bundle, 0644); err != nil {
+		return fmt.Errorf("failed to write CA bundle to %s: %w", bundlePath, err)
+	}
 	return nil
 }
 
+// caBundle appends the CA certificate to the system roots
+func caBundle(system, caCert []byte) []byte {
+	var b bytes.Buffer
+	b.Write(system)
+	if len(system) > 0 && !bytes.HasSuffix(system, []byte("\n")) {
+		b.WriteByte('\n')
+	}
+	b.WriteString("# faa local development CA\n")
+	b.Write(caCert)
+	return b.Bytes()
+}
+
+// CAEnv returns environment variables that make dev servers trust faa's
+// CA, so HTTPS requests between local projects work. NODE_EXTRA_CA_CERTS
+// adds the CA to Node's roots, while REQUESTS_CA_BUNDLE (Python requests)
+// and SSL_CERT_FILE (OpenSSL, Python, Ruby, Go) replace the default roots,
+// so they point at the CA bundle. Variables whose file hasn't been
+// exported yet are left out.
+func CAEnv() map[string]string {
+	env := make(map[string]string)
+	if caPath, err := GetCAPath(); err == nil {
+		if _, err := os.Stat(caPath); err == nil {
+			env["NODE_EXTRA_CA_CERTS"] = caPath
+		}
+	}
+	if bundlePath, err := GetCABundlePath(); err == nil {
+		if _, err := os.Stat(bundlePath); err == nil {
+			env["REQUESTS_CA_BUNDLE"] = bundlePath
+			env["SSL_CERT_FILE"] = bundlePath
+		}
+	}
+	return env
+}
+
 // TryExportCA attempts to export the CA certificate, silently ignoring ALL errors.
 // This is useful for best-effort scenarios where CA export is desired but not required.
 // All failures (missing CA, permission errors, etc.) are silently ignored.
@@ -123,10 +218,10 @@ func TryExportCA() {
 	}
 }
 
-// ExportCAWithRetry attempts to export the CA certificate with retry logic.
+// ExportCAWithRetry attempts to export the CA certificate at srcPath with retry logic.
 // It waits for Caddy to generate the CA certificate if it doesn't exist yet.
 // This should be called after routes are applied to give Caddy time to generate certs.
-func ExportCAWithRetry(maxAttempts int, delayBetweenAttempts time.Duration) error {
+func ExportCAWithRetry(srcPath string, maxAttempts int, delayBetweenAttempts time.Duration) error {
 	var lastErr error
 
 	for i := 0; i < maxAttempts; i++ {
@@ -134,7 +229,7 @@ func ExportCAWithRetry(maxAttempts int, delayBetweenAttempts time.Duration) erro
 			time.Sleep(delayBetweenAttempts)
 		}
 
-		err := ExportCA()
+		err := ExportCAFrom(srcPath)
 		if err == nil {
 			return nil
 		}
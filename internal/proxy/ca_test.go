@@ -147,3 +147,65 @@ func TestCAPathDeterminism(t *testing.T) {
 		t.Errorf("GetCAPath() is not deterministic: %s != %s", path1, path2)
 	}
 }
+
+func TestCABundle(t *testing.T) {
+	caCert := []byte("-----BEGIN CERTIFICATE-----\nFAA\n-----END CERTIFICATE-----\n")
+
+	bundle := string(caBundle([]byte("-----BEGIN CERTIFICATE-----\nSYSTEM\n-----END CERTIFICATE-----"), caCert))
+	if !strings.HasPrefix(bundle, "-----BEGIN CERTIFICATE-----\nSYSTEM\n-----END CERTIFICATE-----\n# faa") {
+		t.Errorf("Expected the system roots first on their own lines, got %q", bundle)
+	}
+	if !strings.HasSuffix(bundle, string(caCert)) {
+		t.Errorf("Expected the bundle to end with the CA certificate, got %q", bundle)
+	}
+
+	// Without system roots the bundle is just the CA
+	bundle = string(caBundle(nil, caCert))
+	if bundle != "# faa local development CA\n"+string(caCert) {
+		t.Errorf("caBundle(nil) = %q", bundle)
+	}
+}
+
+func TestCAEnv(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	if env := CAEnv(); len(env) != 0 {
+		t.Errorf("Expected no variables before the CA is exported, got %v", env)
+	}
+
+	caddyCA, err := GetCaddyCAPath()
+	if err != nil {
+		t.Fatalf("GetCaddyCAPath() failed: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(caddyCA), 0755); err != nil {
+		t.Fatalf("Failed to create Caddy CA directory: %v", err)
+	}
+	caCert := []byte("-----BEGIN CERTIFICATE-----\nMOCK CERTIFICATE DATA\n-----END CERTIFICATE-----\n")
+	if err := os.WriteFile(caddyCA, caCert, 0644); err != nil {
+		t.Fatalf("Failed to write Caddy CA: %v", err)
+	}
+	if err := ExportCA(); err != nil {
+		t.Fatalf("ExportCA() failed: %v", err)
+	}
+
+	caPath, _ := GetCAPath()
+	bundlePath, _ := GetCABundlePath()
+	env := CAEnv()
+	if env["NODE_EXTRA_CA_CERTS"] != caPath {
+		t.Errorf("NODE_EXTRA_CA_CERTS = %q, want %q", env["NODE_EXTRA_CA_CERTS"], caPath)
+	}
+	for _, key := range []string{"REQUESTS_CA_BUNDLE", "SSL_CERT_FILE"} {
+		if env[key] != bundlePath {
+			t.Errorf("%s = %q, want %q", key, env[key], bundlePath)
+		}
+	}
+
+	bundle, err := os.ReadFile(bundlePath)
+	if err != nil {
+		t.Fatalf("Failed to read CA bundle: %v", err)
+	}
+	if !strings.HasSuffix(string(bundle), string(caCert)) {
+		t.Error("Expected the CA bundle to end with the CA certificate")
+	}
+}
@@ -0,0 +1,204 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+const (
+	// RootExpiryWarning is how long before the root CA expires it is warned
+	// about. Caddy doesn't renew its root, so it has to be rotated.
+	RootExpiryWarning = 30 * 24 * time.Hour
+
+	// IntermediateExpiryWarning is how long before the intermediate expires
+	// it is warned about. Caddy renews the intermediate, which lasts a week,
+	// once a fifth of its lifetime is left, so one this close to expiry
+	// means renewal is failing.
+	IntermediateExpiryWarning = 24 * time.Hour
+)
+
+// CertificateInfo describes a CA certificate
+type CertificateInfo struct {
+	Path      string    `json:"path"`
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	SHA256    string    `json:"sha256"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+}
+
+// GetCaddyIntermediatePath returns the path to the intermediate certificate
+// Caddy signs leaf certificates with, next to its root
+func GetCaddyIntermediatePath() (string, error) {
+	rootPath, err := GetCaddyCAPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(rootPath), "intermediate.crt"), nil
+}
+
+// ReadCertificateInfo reads the PEM certificate at path
+func ReadCertificateInfo(path string) (*CertificateInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s is not a PEM certificate", path)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return &CertificateInfo{
+		Path:      path,
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		SHA256:    Fingerprint(cert.Raw),
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+	}, nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of a DER certificate as
+// colon-separated uppercase hex, as browsers show it
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// ExpiryWarning returns a warning if the certificate has expired or expires
+// within window of now, and an empty string otherwise
+func (c *CertificateInfo) ExpiryWarning(name string, window time.Duration, now time.Time) string {
+	left := c.NotAfter.Sub(now)
+	switch {
+	case left <= 0:
+		return fmt.Sprintf("%s expired on %s", name, c.NotAfter.Local().Format(time.DateOnly))
+	case left <= window:
+		return fmt.Sprintf("%s expires in %s, on %s", name, formatRemaining(left), c.NotAfter.Local().Format(time.DateOnly))
+	default:
+		return ""
+	}
+}
+
+// formatRemaining formats the time left before expiry in days, or hours
+// within a day
+func formatRemaining(d time.Duration) string {
+	if d < 24*time.Hour {
+		hours := int(d.Hours())
+		if hours == 1 {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", hours)
+	}
+	days := int(d.Hours() / 24)
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+// CAExpiryWarnings returns warnings for the exported root and Caddy's
+// intermediate certificate expiring soon. A configured CA (see TLSOptions)
+// signs certificates itself, so only its root is checked. Certificates
+// that don't exist yet or can't be read are skipped.
+func CAExpiryWarnings(configured bool, now time.Time) []string {
+	var warnings []string
+	if caPath, err := GetCAPath(); err == nil {
+		if root, err := ReadCertificateInfo(caPath); err == nil {
+			if warning := root.ExpiryWarning("root CA certificate", RootExpiryWarning, now); warning != "" {
+				if configured {
+					warning += "; replace the CA configured in tls.ca"
+				} else {
+					warning += "; rotate it with 'faa ca rotate'"
+				}
+				warnings = append(warnings, warning)
+			}
+		}
+	}
+	if configured {
+		return warnings
+	}
+	if interPath, err := GetCaddyIntermediatePath(); err == nil {
+		if inter, err := ReadCertificateInfo(interPath); err == nil {
+			if warning := inter.ExpiryWarning("intermediate CA certificate", IntermediateExpiryWarning, now); warning != "" {
+				warnings = append(warnings, warning+"; Caddy has not renewed it, check the daemon log")
+			}
+		}
+	}
+	return warnings
+}
+
+// ErrConfiguredCA is returned by RotateCA when certificates are issued by a
+// configured CA rather than Caddy's local one
+var ErrConfiguredCA = errors.New("certificates are issued by a configured CA, which faa can't rotate")
+
+// RotateCA replaces Caddy's local CA with a new one. Its root, intermediate
+// and the leaf certificates it issued are removed, and a running proxy
+// generates new ones; leaf certificates are issued again on first use. The
+// new root is not exported. A configured CA (see TLSOptions) can't be
+// rotated.
+func (p *Proxy) RotateCA() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.tls.CA != nil {
+		return ErrConfiguredCA
+	}
+	if !p.running {
+		return removeLocalCA()
+	}
+
+	// Stopping drops the CA and certificates Caddy keeps in memory. The
+	// admin endpoint outlives caddy.Stop, so the config is loaded again
+	// rather than starting Caddy from scratch.
+	streams.closeAll()
+	if err := caddy.Stop(); err != nil {
+		return fmt.Errorf("failed to stop Caddy: %w", err)
+	}
+	removeErr := removeLocalCA()
+
+	configJSON, err := p.buildConfigJSON()
+	if err == nil {
+		err = caddy.Load(configJSON, true)
+	}
+	if err != nil {
+		p.running = false
+		liveRoutes.CompareAndSwap(p.table, nil)
+		return fmt.Errorf("failed to restart proxy: %w", err)
+	}
+	return removeErr
+}
+
+// removeLocalCA removes Caddy's local authority and the certificates it
+// issued
+func removeLocalCA() error {
+	caddyDataDir, err := getCaddyDataDir()
+	if err != nil {
+		return err
+	}
+	for _, dir := range []string{
+		filepath.Join(caddyDataDir, "pki", "authorities", "local"),
+		filepath.Join(caddyDataDir, "certificates", "local"),
+	} {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", dir, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package proxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestCA writes a self-signed CA certificate valid until notAfter
+func writeTestCA(t *testing.T, path, name string, notAfter time.Time) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create certificate directory: %v", err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	return der
+}
+
+func TestReadCertificateInfo(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "root.pem")
+	notAfter := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	der := writeTestCA(t, path, "faa test root", notAfter)
+
+	info, err := ReadCertificateInfo(path)
+	if err != nil {
+		t.Fatalf("ReadCertificateInfo() failed: %v", err)
+	}
+	if info.Subject != "CN=faa test root" || info.Issuer != info.Subject {
+		t.Errorf("Subject = %q, Issuer = %q, want a self-signed CN=faa test root", info.Subject, info.Issuer)
+	}
+	if !info.NotAfter.Equal(notAfter) {
+		t.Errorf("NotAfter = %v, want %v", info.NotAfter, notAfter)
+	}
+	if info.Path != path {
+		t.Errorf("Path = %q, want %q", info.Path, path)
+	}
+
+	sum := sha256.Sum256(der)
+	if got := strings.ReplaceAll(info.SHA256, ":", ""); got != strings.ToUpper(hex.EncodeToString(sum[:])) {
+		t.Errorf("SHA256 = %s, want the certificate's SHA-256", info.SHA256)
+	}
+	if len(info.SHA256) != 32*3-1 {
+		t.Errorf("SHA256 = %s, want 32 colon-separated bytes", info.SHA256)
+	}
+
+	if _, err := ReadCertificateInfo(filepath.Join(dir, "missing.pem")); !os.IsNotExist(err) {
+		t.Errorf("Expected a not-exist error for a missing certificate, got %v", err)
+	}
+	notPEM := filepath.Join(dir, "bad.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := ReadCertificateInfo(notPEM); err == nil {
+		t.Error("Expected an error for a file that isn't PEM")
+	}
+}
+
+func TestExpiryWarning(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		left     time.Duration
+		expected string
+	}{
+		{"far off", 90 * 24 * time.Hour, ""},
+		{"days", 10*24*time.Hour + time.Hour, "root expires in 10 days"},
+		{"one day", 24*time.Hour + time.Minute, "root expires in 1 day"},
+		{"hours", 5*time.Hour + time.Minute, "root expires in 5 hours"},
+		{"expired", -time.Hour, "root expired on"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert := &CertificateInfo{NotAfter: now.Add(tt.left)}
+			got := cert.ExpiryWarning("root", RootExpiryWarning, now)
+			if tt.expected == "" {
+				if got != "" {
+					t.Errorf("ExpiryWarning() = %q, want none", got)
+				}
+				return
+			}
+			if !strings.HasPrefix(got, tt.expected) {
+				t.Errorf("ExpiryWarning() = %q, want it to start with %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCAExpiryWarnings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	now := time.Now()
+
+	if warnings := CAExpiryWarnings(false, now); len(warnings) != 0 {
+		t.Errorf("Expected no warnings without certificates, got %q", warnings)
+	}
+
+	caPath, err := GetCAPath()
+	if err != nil {
+		t.Fatalf("GetCAPath() failed: %v", err)
+	}
+	interPath, err := GetCaddyIntermediatePath()
+	if err != nil {
+		t.Fatalf("GetCaddyIntermediatePath() failed: %v", err)
+	}
+	writeTestCA(t, caPath, "root", now.Add(5*24*time.Hour))
+	writeTestCA(t, interPath, "intermediate", now.Add(5*24*time.Hour))
+
+	// A five-day-old intermediate is within Caddy's renewal window, not
+	// close enough to warn about
+	warnings := CAExpiryWarnings(false, now)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "faa ca rotate") {
+		t.Fatalf("Expected a root warning suggesting rotation, got %q", warnings)
+	}
+
+	writeTestCA(t, interPath, "intermediate", now.Add(time.Hour))
+	warnings = CAExpiryWarnings(false, now)
+	if len(warnings) != 2 || !strings.HasPrefix(warnings[1], "intermediate CA certificate expires") {
+		t.Errorf("Expected an intermediate warning, got %q", warnings)
+	}
+
+	// A configured CA signs certificates with its root, so Caddy's
+	// intermediate isn't used
+	warnings = CAExpiryWarnings(true, now)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "tls.ca") {
+		t.Errorf("Expected only a root warning naming tls.ca, got %q", warnings)
+	}
+}
+
+func TestRotateCAWhenStopped(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	rootPath, err := GetCaddyCAPath()
+	if err != nil {
+		t.Fatalf("GetCaddyCAPath() failed: %v", err)
+	}
+	writeTestCA(t, rootPath, "root", time.Now().Add(time.Hour))
+	dataDir, err := getCaddyDataDir()
+	if err != nil {
+		t.Fatalf("getCaddyDataDir() failed: %v", err)
+	}
+	leafDir := filepath.Join(dataDir, "certificates", "local", "demo.localhost")
+	if err := os.MkdirAll(leafDir, 0755); err != nil {
+		t.Fatalf("Failed to create %s: %v", leafDir, err)
+	}
+	other := filepath.Join(dataDir, "certificates", "acme")
+	if err := os.MkdirAll(other, 0755); err != nil {
+		t.Fatalf("Failed to create %s: %v", other, err)
+	}
+
+	p := New()
+	if err := p.RotateCA(); err != nil {
+		t.Fatalf("RotateCA() failed: %v", err)
+	}
+	for _, path := range []string{rootPath, leafDir} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed, got %v", path, err)
+		}
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("Expected certificates from other issuers to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+const (
+	// captureLogSize is the number of exchanges kept per host
+	captureLogSize = 200
+
+	// captureBodyLimit is the maximum number of body bytes kept for each
+	// request and response; longer bodies are truncated
+	captureBodyLimit = 64 * 1024
+)
+
+func init() {
+	caddy.RegisterModule(Capture{})
+}
+
+// Exchange is a captured request and its response
+type Exchange struct {
+	ID         uint64    `json:"id"`
+	Time       time.Time `json:"time"`
+	Host       string    `json:"host"`
+	Method     string    `json:"method"`
+	URL        string    `json:"url"`
+	Proto      string    `json:"proto"`
+	RemoteAddr string    `json:"remoteAddr"`
+
+	RequestHeaders       http.Header `json:"requestHeaders"`
+	RequestBody          []byte      `json:"requestBody,omitempty"`
+	RequestBodySize      int64       `json:"requestBodySize"`
+	RequestBodyTruncated bool        `json:"requestBodyTruncated,omitempty"`
+
+	Status                int         `json:"status"`
+	ResponseHeaders       http.Header `json:"responseHeaders"`
+	ResponseBody          []byte      `json:"responseBody,omitempty"`
+	ResponseBodySize      int64       `json:"responseBodySize"`
+	ResponseBodyTruncated bool        `json:"responseBodyTruncated,omitempty"`
+
+	DurationMs float64 `json:"durationMs"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// captureLog keeps the most recent exchanges for each host.
+// Like requestLog it is package-level state shared by Capture instances.
+type captureLog struct {
+	mu     sync.Mutex
+	nextID uint64
+	hosts  map[string][]*Exchange
+}
+
+var captures = newCaptureLog()
+
+// newCaptureLog creates an empty capture log
+func newCaptureLog() *captureLog {
+	return &captureLog{hosts: make(map[string][]*Exchange)}
+}
+
+// add assigns the exchange an ID and stores it, discarding the oldest
+// exchange for the host when full
+func (l *captureLog) add(exchange *Exchange) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.nextID++
+	exchange.ID = l.nextID
+
+	exchanges := append(l.hosts[exchange.Host], exchange)
+	if len(exchanges) > captureLogSize {
+		exchanges = exchanges[len(exchanges)-captureLogSize:]
+	}
+	l.hosts[exchange.Host] = exchanges
+}
+
+// list returns up to limit of the latest exchanges for host, oldest first.
+// A limit of zero or less returns every stored exchange.
+func (l *captureLog) list(host string, limit int) []Exchange {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	exchanges := l.hosts[host]
+	if limit > 0 && limit < len(exchanges) {
+		exchanges = exchanges[len(exchanges)-limit:]
+	}
+
+	result := make([]Exchange, len(exchanges))
+	for i, exchange := range exchanges {
+		result[i] = *exchange
+	}
+	return result
+}
+
+// get returns the exchange with the given ID
+func (l *captureLog) get(id uint64) (Exchange, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, exchanges := range l.hosts {
+		for _, exchange := range exchanges {
+			if exchange.ID == id {
+				return *exchange, true
+			}
+		}
+	}
+	return Exchange{}, false
+}
+
+// clear removes every exchange for host
+func (l *captureLog) clear(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.hosts, host)
+}
+
+// CapturedExchanges returns up to limit of the latest exchanges captured
+// for host, oldest first. A limit of zero or less returns all of them.
+func CapturedExchanges(host string, limit int) []Exchange {
+	return captures.list(host, limit)
+}
+
+// CapturedExchange returns the captured exchange with the given ID
+func CapturedExchange(id uint64) (Exchange, bool) {
+	return captures.get(id)
+}
+
+// ClearCaptured removes every exchange captured for host
+func ClearCaptured(host string) {
+	captures.clear(host)
+}
+
+// Capture is a Caddy HTTP handler that records request and response
+// headers, size-capped bodies, timing and status of requests to hosts with
+// capture enabled (see Proxy.SetCapture). Other requests pass through.
+type Capture struct{}
+
+// CaddyModule returns the Caddy module information
+func (Capture) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.faa_capture",
+		New: func() caddy.Module { return new(Capture) },
+	}
+}
+
+// ServeHTTP captures the exchange while passing it on to the next handler
+func (Capture) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	if route, ok := currentRoute(r); !ok || !route.capture {
+		return next.ServeHTTP(w, r)
+	}
+
+	start := time.Now()
+
+	exchange := &Exchange{
+		Time:           start,
+		Host:           requestHost(r),
+		Method:         r.Method,
+		URL:            r.URL.RequestURI(),
+		Proto:          r.Proto,
+		RemoteAddr:     r.RemoteAddr,
+		RequestHeaders: r.Header.Clone(),
+	}
+
+	var requestBody *cappedBuffer
+	if r.Body != nil && r.Body != http.NoBody {
+		requestBody = &cappedBuffer{limit: captureBodyLimit}
+		r.Body = &teeReadCloser{ReadCloser: r.Body, w: requestBody}
+	}
+
+	cw := &captureWriter{
+		ResponseWriterWrapper: &caddyhttp.ResponseWriterWrapper{ResponseWriter: w},
+		body:                  &cappedBuffer{limit: captureBodyLimit},
+	}
+
+	err := serveNext(next, cw, r)
+
+	exchange.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	exchange.Status = cw.status
+	exchange.ResponseHeaders = cw.Header().Clone()
+	if requestBody != nil {
+		exchange.RequestBody = requestBody.Bytes()
+		exchange.RequestBodySize = requestBody.total
+		exchange.RequestBodyTruncated = requestBody.truncated()
+	}
+	exchange.ResponseBody = cw.body.Bytes()
+	exchange.ResponseBodySize = cw.body.total
+	exchange.ResponseBodyTruncated = cw.body.truncated()
+
+	switch {
+	case err == errAborted:
+		exchange.Error = err.Error()
+	case err != nil:
+		// Caddy writes the error response after the handler chain returns
+		exchange.Status = http.StatusInternalServerError
+		cause := err
+		var handlerErr caddyhttp.HandlerError
+		if errors.As(err, &handlerErr) {
+			if handlerErr.StatusCode != 0 {
+				exchange.Status = handlerErr.StatusCode
+			}
+			if handlerErr.Err != nil {
+				cause = handlerErr.Err
+			}
+		}
+		exchange.Error = cause.Error()
+	case exchange.Status == 0:
+		exchange.Status = http.StatusOK
+	}
+
+	captures.add(exchange)
+
+	if err == errAborted {
+		panic(http.ErrAbortHandler)
+	}
+	return err
+}
+
+// cappedBuffer keeps the first limit bytes written to it and counts the rest
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int
+	total int64
+}
+
+// Write implements io.Writer and never fails
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.total += int64(len(p))
+	if room := b.limit - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// truncated reports whether more bytes were written than were kept
+func (b *cappedBuffer) truncated() bool {
+	return b.total > int64(b.Len())
+}
+
+// teeReadCloser copies everything read from the request body to w
+type teeReadCloser struct {
+	io.ReadCloser
+	w io.Writer
+}
+
+// Read reads from the body and records what was read
+func (t *teeReadCloser) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		_, _ = t.w.Write(p[:n])
+	}
+	return n, err
+}
+
+// captureWriter records the status code and body written by later handlers
+type captureWriter struct {
+	*caddyhttp.ResponseWriterWrapper
+	status int
+	body   *cappedBuffer
+}
+
+// WriteHeader records the status code before writing it
+func (cw *captureWriter) WriteHeader(status int) {
+	if cw.status == 0 && status >= 200 {
+		cw.status = status
+	}
+	cw.ResponseWriterWrapper.WriteHeader(status)
+}
+
+// Write records the body before writing it
+func (cw *captureWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	n, err := cw.ResponseWriterWrapper.Write(p)
+	_, _ = cw.body.Write(p[:n])
+	return n, err
+}
+
+// ReadFrom routes io.Copy through Write so the body is recorded; the
+// embedded wrapper's ReadFrom would write to the underlying writer directly
+func (cw *captureWriter) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(writerOnly{cw}, r)
+}
+
+// writerOnly hides every method except Write, so io.Copy cannot recurse
+// into ReadFrom
+type writerOnly struct {
+	io.Writer
+}
+
+// Interface guards
+var (
+	_ caddy.Module                = (*Capture)(nil)
+	_ caddyhttp.MiddlewareHandler = (*Capture)(nil)
+)
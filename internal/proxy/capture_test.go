@@ -0,0 +1,163 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+func TestCaptureLog(t *testing.T) {
+	l := newCaptureLog()
+
+	for i := 0; i < captureLogSize+5; i++ {
+		l.add(&Exchange{Host: "app.localhost", URL: fmt.Sprintf("/%d", i)})
+	}
+	l.add(&Exchange{Host: "other.localhost", URL: "/other"})
+
+	all := l.list("app.localhost", 0)
+	if len(all) != captureLogSize {
+		t.Fatalf("Expected %d exchanges, got %d", captureLogSize, len(all))
+	}
+	if all[0].URL != "/5" {
+		t.Errorf("Oldest exchanges should be discarded, first is %s", all[0].URL)
+	}
+	if last := all[len(all)-1]; last.URL != fmt.Sprintf("/%d", captureLogSize+4) {
+		t.Errorf("Newest exchange should come last, got %s", last.URL)
+	}
+
+	limited := l.list("app.localhost", 2)
+	if len(limited) != 2 || limited[1].ID != all[len(all)-1].ID {
+		t.Errorf("Limit should keep the latest exchanges, got %+v", limited)
+	}
+
+	other := l.list("other.localhost", 0)
+	if len(other) != 1 {
+		t.Fatalf("Expected 1 exchange for other host, got %d", len(other))
+	}
+	if got, ok := l.get(other[0].ID); !ok || got.URL != "/other" {
+		t.Errorf("get(%d) = %+v, %v", other[0].ID, got, ok)
+	}
+	if _, ok := l.get(1); ok {
+		t.Error("Discarded exchanges should not be found")
+	}
+
+	l.clear("app.localhost")
+	if got := l.list("app.localhost", 0); len(got) != 0 {
+		t.Errorf("Expected no exchanges after clear, got %d", len(got))
+	}
+}
+
+func TestCappedBuffer(t *testing.T) {
+	b := &cappedBuffer{limit: 5}
+	b.Write([]byte("abc"))
+	b.Write([]byte("defgh"))
+
+	if b.String() != "abcde" {
+		t.Errorf("Buffer = %q, want %q", b.String(), "abcde")
+	}
+	if b.total != 8 || !b.truncated() {
+		t.Errorf("total = %d, truncated = %v, want 8 and true", b.total, b.truncated())
+	}
+}
+
+func TestCaptureServeHTTP(t *testing.T) {
+	body := strings.Repeat("x", captureBodyLimit+10)
+	req := httptest.NewRequest(http.MethodPost, "/hook?src=test", strings.NewReader(body))
+	req.Host = "capture.localhost:443"
+	req.Header.Set("X-Signature", "abc")
+	useRoutes(t, map[string]hostRoute{"capture.localhost": {port: 3000, capture: true}})
+
+	next := caddyhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		// The upstream must still receive the whole request body
+		received, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		if len(received) != len(body) {
+			t.Errorf("Upstream received %d bytes, want %d", len(received), len(body))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, err = io.Copy(w, bytes.NewReader([]byte(`{"ok":true}`)))
+		return err
+	})
+
+	rec := httptest.NewRecorder()
+	if err := (Capture{}).ServeHTTP(rec, req, next); err != nil {
+		t.Fatalf("ServeHTTP() failed: %v", err)
+	}
+	if rec.Body.String() != `{"ok":true}` {
+		t.Errorf("Client received %q", rec.Body.String())
+	}
+
+	exchanges := CapturedExchanges("capture.localhost", 1)
+	if len(exchanges) != 1 {
+		t.Fatalf("Expected 1 exchange, got %d", len(exchanges))
+	}
+	exchange := exchanges[0]
+
+	if exchange.Method != http.MethodPost || exchange.URL != "/hook?src=test" {
+		t.Errorf("Unexpected request line: %s %s", exchange.Method, exchange.URL)
+	}
+	if exchange.RequestHeaders.Get("X-Signature") != "abc" {
+		t.Error("Request headers not captured")
+	}
+	if len(exchange.RequestBody) != captureBodyLimit || !exchange.RequestBodyTruncated {
+		t.Errorf("Request body = %d bytes, truncated = %v", len(exchange.RequestBody), exchange.RequestBodyTruncated)
+	}
+	if exchange.RequestBodySize != int64(len(body)) {
+		t.Errorf("RequestBodySize = %d, want %d", exchange.RequestBodySize, len(body))
+	}
+	if exchange.Status != http.StatusCreated {
+		t.Errorf("Status = %d, want %d", exchange.Status, http.StatusCreated)
+	}
+	if exchange.ResponseHeaders.Get("Content-Type") != "application/json" {
+		t.Error("Response headers not captured")
+	}
+	if string(exchange.ResponseBody) != `{"ok":true}` || exchange.ResponseBodyTruncated {
+		t.Errorf("Response body = %q", exchange.ResponseBody)
+	}
+	ClearCaptured("capture.localhost")
+}
+
+func TestCaptureServeHTTPError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "capture-error.localhost"
+	useRoutes(t, map[string]hostRoute{"capture-error.localhost": {port: 3000, capture: true}})
+
+	next := caddyhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return caddyhttp.Error(http.StatusBadGateway, fmt.Errorf("connection refused"))
+	})
+
+	if err := (Capture{}).ServeHTTP(httptest.NewRecorder(), req, next); err == nil {
+		t.Fatal("ServeHTTP() should pass on the handler error")
+	}
+
+	exchanges := CapturedExchanges("capture-error.localhost", 0)
+	if len(exchanges) != 1 {
+		t.Fatalf("Expected 1 exchange, got %d", len(exchanges))
+	}
+	if exchanges[0].Status != http.StatusBadGateway || exchanges[0].Error != "connection refused" {
+		t.Errorf("Exchange = %d %q, want 502 connection refused", exchanges[0].Status, exchanges[0].Error)
+	}
+	ClearCaptured("capture-error.localhost")
+}
+
+func TestCaptureSkipsHostsWithoutCapture(t *testing.T) {
+	useRoutes(t, map[string]hostRoute{"quiet.localhost": {port: 3000}})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "quiet.localhost"
+	if err := (Capture{}).ServeHTTP(httptest.NewRecorder(), req, okHandler("ok")); err != nil {
+		t.Fatalf("ServeHTTP() failed: %v", err)
+	}
+	if exchanges := CapturedExchanges("quiet.localhost", 0); len(exchanges) != 0 {
+		t.Errorf("Expected no exchanges without capture enabled, got %d", len(exchanges))
+	}
+}
@@ -0,0 +1,212 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+const (
+	// DefaultChaosStatus is the status of injected errors when none is set
+	DefaultChaosStatus = http.StatusServiceUnavailable
+
+	// throttleInterval is how often a throttled response writes a chunk
+	throttleInterval = 100 * time.Millisecond
+)
+
+func init() {
+	caddy.RegisterModule(Chaos{})
+}
+
+// ChaosRule describes the faults injected into requests to a host.
+// A rule with an empty Path applies to every request.
+type ChaosRule struct {
+	// Path limits the rule to matching request paths. A trailing * matches
+	// any suffix (/api/*); other patterns use path.Match syntax.
+	Path string `json:"path,omitempty"`
+
+	// Latency is added before the request is forwarded, plus a random
+	// amount of up to Jitter
+	Latency caddy.Duration `json:"latency,omitempty"`
+	Jitter  caddy.Duration `json:"jitter,omitempty"`
+
+	// Bandwidth limits the response body to this many bytes per second
+	Bandwidth int64 `json:"bandwidth,omitempty"`
+
+	// ErrorRate is the fraction of requests (0 to 1) answered with
+	// ErrorStatus instead of being forwarded
+	ErrorRate   float64 `json:"error_rate,omitempty"`
+	ErrorStatus int     `json:"error_status,omitempty"`
+
+	// ResetRate is the fraction of requests (0 to 1) whose connection is
+	// reset without a response
+	ResetRate float64 `json:"reset_rate,omitempty"`
+}
+
+// Validate checks that the rule's values are in range
+func (rule ChaosRule) Validate() error {
+	switch {
+	case rule.Latency < 0 || rule.Jitter < 0:
+		return fmt.Errorf("latency and jitter must not be negative")
+	case rule.Bandwidth < 0:
+		return fmt.Errorf("bandwidth must not be negative")
+	case rule.ErrorRate < 0 || rule.ErrorRate > 1:
+		return fmt.Errorf("error rate must be between 0 and 1")
+	case rule.ResetRate < 0 || rule.ResetRate > 1:
+		return fmt.Errorf("reset rate must be between 0 and 1")
+	case rule.ErrorStatus != 0 && (rule.ErrorStatus < 400 || rule.ErrorStatus > 599):
+		return fmt.Errorf("error status must be between 400 and 599")
+	}
+	if rule.Path != "" && !strings.HasSuffix(rule.Path, "*") {
+		if _, err := path.Match(rule.Path, "/"); err != nil {
+			return fmt.Errorf("invalid path pattern %q: %w", rule.Path, err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the rule applies to a request path
+func (rule ChaosRule) matches(requestPath string) bool {
+	switch {
+	case rule.Path == "":
+		return true
+	case strings.HasSuffix(rule.Path, "*") && !strings.ContainsAny(rule.Path[:len(rule.Path)-1], "*?["):
+		return strings.HasPrefix(requestPath, rule.Path[:len(rule.Path)-1])
+	default:
+		matched, _ := path.Match(rule.Path, requestPath)
+		return matched
+	}
+}
+
+// SortChaosRules returns a copy of rules in the order Chaos tries them:
+// longer, more specific paths first, host-wide rules last
+func SortChaosRules(rules []ChaosRule) []ChaosRule {
+	sorted := append([]ChaosRule(nil), rules...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].Path) > len(sorted[j].Path)
+	})
+	return sorted
+}
+
+// Chaos is a Caddy HTTP handler that injects latency, bandwidth limits,
+// errors and connection resets into requests to hosts with rules set by
+// Proxy.SetChaos. The first rule matching the request path applies;
+// SetChaos orders path rules before host-wide ones.
+type Chaos struct{}
+
+// CaddyModule returns the Caddy module information
+func (Chaos) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.faa_chaos",
+		New: func() caddy.Module { return new(Chaos) },
+	}
+}
+
+// ServeHTTP applies the first matching rule to the request
+func (Chaos) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	route, _ := currentRoute(r)
+	rule, ok := matchChaosRule(route.chaos, r.URL.Path)
+	if !ok {
+		return next.ServeHTTP(w, r)
+	}
+
+	if delay := time.Duration(rule.Latency); delay > 0 || rule.Jitter > 0 {
+		if rule.Jitter > 0 {
+			delay += rand.N(time.Duration(rule.Jitter))
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			timer.Stop()
+			return r.Context().Err()
+		}
+	}
+
+	if rule.ResetRate > 0 && rand.Float64() < rule.ResetRate {
+		// Same as Caddy's abort directive: the connection is closed
+		// (or the HTTP/2 stream reset) without a response
+		panic(http.ErrAbortHandler)
+	}
+
+	if rule.ErrorRate > 0 && rand.Float64() < rule.ErrorRate {
+		status := rule.ErrorStatus
+		if status == 0 {
+			status = DefaultChaosStatus
+		}
+		return caddyhttp.Error(status, errors.New("injected by faa chaos"))
+	}
+
+	if rule.Bandwidth > 0 {
+		w = &throttledWriter{
+			ResponseWriterWrapper: &caddyhttp.ResponseWriterWrapper{ResponseWriter: w},
+			chunk:                 max(1, int(rule.Bandwidth*int64(throttleInterval)/int64(time.Second))),
+			done:                  r.Context().Done(),
+		}
+	}
+
+	return next.ServeHTTP(w, r)
+}
+
+// matchChaosRule returns the first of rules that applies to requestPath
+func matchChaosRule(rules []ChaosRule, requestPath string) (ChaosRule, bool) {
+	for _, rule := range rules {
+		if rule.matches(requestPath) {
+			return rule, true
+		}
+	}
+	return ChaosRule{}, false
+}
+
+// throttledWriter writes the response body in chunks, pausing between them
+// to limit the bandwidth
+type throttledWriter struct {
+	*caddyhttp.ResponseWriterWrapper
+	chunk int
+	done  <-chan struct{}
+}
+
+// Write writes p at the throttled rate
+func (tw *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(tw.chunk, len(p))
+		m, err := tw.ResponseWriterWrapper.Write(p[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+
+		// Push the chunk to the client now rather than when the buffer fills
+		_ = http.NewResponseController(tw.ResponseWriterWrapper).Flush()
+
+		select {
+		case <-time.After(throttleInterval):
+		case <-tw.done:
+			return written, errors.New("client went away")
+		}
+	}
+	return written, nil
+}
+
+// ReadFrom routes io.Copy through the throttled Write; the embedded
+// wrapper's ReadFrom would bypass it
+func (tw *throttledWriter) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(writerOnly{tw}, r)
+}
+
+// Interface guards
+var (
+	_ caddy.Module                = (*Chaos)(nil)
+	_ caddyhttp.MiddlewareHandler = (*Chaos)(nil)
+)
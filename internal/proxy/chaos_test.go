@@ -0,0 +1,154 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+// okHandler is a next handler that writes body with a 200 status
+func okHandler(body string) caddyhttp.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		_, err := w.Write([]byte(body))
+		return err
+	}
+}
+
+func TestChaosRuleMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"", "/anything", true},
+		{"/api/*", "/api/users", true},
+		{"/api/*", "/api", false},
+		{"/api/*", "/other", false},
+		{"/users/*/avatar", "/users/42/avatar", true},
+		{"/users/*/avatar", "/users/42/name", false},
+		{"/exact", "/exact", true},
+	}
+	for _, tt := range tests {
+		if got := (ChaosRule{Path: tt.pattern}).matches(tt.path); got != tt.want {
+			t.Errorf("matches(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestChaosRuleValidate(t *testing.T) {
+	invalid := []ChaosRule{
+		{Latency: -1},
+		{Bandwidth: -1},
+		{ErrorRate: 1.5},
+		{ResetRate: -0.1},
+		{ErrorRate: 0.5, ErrorStatus: 200},
+		{Path: "/[bad"},
+	}
+	for _, rule := range invalid {
+		if err := rule.Validate(); err == nil {
+			t.Errorf("Validate(%+v) should fail", rule)
+		}
+	}
+	if err := (ChaosRule{Path: "/api/*", ErrorRate: 1, ErrorStatus: 502}).Validate(); err != nil {
+		t.Errorf("Validate() failed for a valid rule: %v", err)
+	}
+}
+
+func TestChaosInjectError(t *testing.T) {
+	useRoutes(t, map[string]hostRoute{
+		"example.com": {port: 3000, chaos: []ChaosRule{
+			{Path: "/api/*", ErrorRate: 1, ErrorStatus: http.StatusBadGateway},
+		}},
+	})
+	c := Chaos{}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	err := c.ServeHTTP(httptest.NewRecorder(), req, okHandler("ok"))
+	var handlerErr caddyhttp.HandlerError
+	if !errors.As(err, &handlerErr) || handlerErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("ServeHTTP() error = %v, want a 502 handler error", err)
+	}
+
+	// Paths without a matching rule pass through
+	rec := httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/home", nil)
+	if err := c.ServeHTTP(rec, req, okHandler("ok")); err != nil || rec.Body.String() != "ok" {
+		t.Errorf("ServeHTTP() = %q, %v; want pass-through", rec.Body.String(), err)
+	}
+
+	// So do other hosts
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.Host = "other.localhost"
+	if err := c.ServeHTTP(rec, req, okHandler("ok")); err != nil || rec.Body.String() != "ok" {
+		t.Errorf("ServeHTTP() = %q, %v; want pass-through", rec.Body.String(), err)
+	}
+}
+
+func TestChaosInjectLatency(t *testing.T) {
+	useRoutes(t, map[string]hostRoute{
+		"example.com": {port: 3000, chaos: []ChaosRule{{Latency: caddy.Duration(50 * time.Millisecond)}}},
+	})
+	c := Chaos{}
+
+	start := time.Now()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if err := c.ServeHTTP(httptest.NewRecorder(), req, okHandler("ok")); err != nil {
+		t.Fatalf("ServeHTTP() failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Request took %v, want at least 50ms", elapsed)
+	}
+}
+
+func TestChaosThrottle(t *testing.T) {
+	// 100 bytes per second writes 10 bytes every 100ms
+	useRoutes(t, map[string]hostRoute{
+		"example.com": {port: 3000, chaos: []ChaosRule{{Bandwidth: 100}}},
+	})
+	c := Chaos{}
+
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if err := c.ServeHTTP(rec, req, okHandler(strings.Repeat("x", 30))); err != nil {
+		t.Fatalf("ServeHTTP() failed: %v", err)
+	}
+	if rec.Body.Len() != 30 {
+		t.Errorf("Body = %d bytes, want 30", rec.Body.Len())
+	}
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("Throttled response took %v, want at least 250ms", elapsed)
+	}
+}
+
+func TestChaosResetIsRecorded(t *testing.T) {
+	useRoutes(t, map[string]hostRoute{
+		"reset.chaos.localhost": {port: 3000, chaos: []ChaosRule{{ResetRate: 1}}},
+	})
+	chaos := Chaos{}
+	next := caddyhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return chaos.ServeHTTP(w, r, okHandler("ok"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "reset.chaos.localhost"
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Fatalf("Expected the abort to be re-raised, got %v", v)
+		}
+		records := RecentRequests("reset.chaos.localhost", 1)
+		if len(records) != 1 || records[0].Error != "connection reset" {
+			t.Errorf("Reset not recorded: %+v", records)
+		}
+	}()
+	_ = Recorder{}.ServeHTTP(httptest.NewRecorder(), req, next)
+	t.Fatal("ServeHTTP() should not return after a reset")
+}
@@ -0,0 +1,224 @@
+package proxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+func init() {
+	caddy.RegisterModule(ClientCertHeader{})
+}
+
+// ClientAuthMode is how the proxy authenticates clients of a host with
+// certificates
+type ClientAuthMode string
+
+const (
+	// ClientAuthRequire rejects connections without a certificate issued
+	// by faa's CA
+	ClientAuthRequire ClientAuthMode = "require"
+
+	// ClientAuthOptional asks for a certificate and verifies one if it is
+	// given, but also accepts connections without
+	ClientAuthOptional ClientAuthMode = "optional"
+)
+
+// DefaultClientSubjectHeader is the request header the subject of a
+// verified client certificate is passed to dev servers in
+const DefaultClientSubjectHeader = "X-Client-Cert-Subject"
+
+// caddyClientAuthModes are Caddy's names for the client auth modes
+var caddyClientAuthModes = map[ClientAuthMode]string{
+	ClientAuthRequire:  "require_and_verify",
+	ClientAuthOptional: "verify_if_given",
+}
+
+// validClientAuthMode reports whether mode is a known client auth mode
+func validClientAuthMode(mode ClientAuthMode) bool {
+	_, ok := caddyClientAuthModes[mode]
+	return ok
+}
+
+// tlsConnectionPolicies returns the HTTPS server's TLS connection
+// policies: one per client auth mode in use, matched by SNI, and a default
+// one for every other host. Caddy makes the server check that requests'
+// Host matches the SNI once any policy authenticates clients, so a
+// connection to another host can't be used to skip the check.
+// The caller must hold p.mu.
+func (p *Proxy) tlsConnectionPolicies() []map[string]interface{} {
+	hostsByMode := make(map[ClientAuthMode][]string)
+	for host, mode := range p.tls.ClientAuth {
+		hostsByMode[mode] = append(hostsByMode[mode], localAlternates(host)...)
+	}
+
+	policies := []map[string]interface{}{}
+	for _, mode := range []ClientAuthMode{ClientAuthRequire, ClientAuthOptional} {
+		hosts := hostsByMode[mode]
+		if len(hosts) == 0 {
+			continue
+		}
+		sort.Strings(hosts)
+		policies = append(policies, map[string]interface{}{
+			"match": map[string]interface{}{
+				"sni": hosts,
+			},
+			"client_authentication": map[string]interface{}{
+				"mode": caddyClientAuthModes[mode],
+				"ca": map[string]interface{}{
+					"provider":  "pki_root",
+					"authority": []string{p.caID},
+				},
+			},
+		})
+	}
+	return append(policies, map[string]interface{}{})
+}
+
+// localAlternates returns host and its equivalent with the other local
+// suffix (.local or .localhost), since a route answers to both
+func localAlternates(host string) []string {
+	switch {
+	case strings.HasSuffix(host, ".localhost"):
+		return []string{host, strings.TrimSuffix(host, ".localhost") + ".local"}
+	case strings.HasSuffix(host, ".local"):
+		return []string{host, strings.TrimSuffix(host, ".local") + ".localhost"}
+	default:
+		return []string{host}
+	}
+}
+
+// clientSubjectHeader returns the header verified client subjects are
+// passed in.
+// The caller must hold p.mu.
+func (p *Proxy) clientSubjectHeader() string {
+	if p.tls.ClientSubjectHeader != "" {
+		return p.tls.ClientSubjectHeader
+	}
+	return DefaultClientSubjectHeader
+}
+
+// IssueClientCertificate issues a client certificate for name from the
+// root of faa's CA, valid for lifetime or until the root expires. It
+// returns the PEM certificate and private key. Client certificates are
+// signed by the root rather than Caddy's intermediate, which only lasts a
+// week.
+func (p *Proxy) IssueClientCertificate(name string, lifetime time.Duration) (cert, key []byte, err error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, nil, errors.New("a name is required")
+	}
+	if lifetime <= 0 {
+		return nil, nil, fmt.Errorf("invalid lifetime: %s", lifetime)
+	}
+
+	pair, err := p.rootKeyPair()
+	if err != nil {
+		return nil, nil, err
+	}
+	root, err := tls.LoadX509KeyPair(pair.Certificate, pair.Key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load the CA: %w", err)
+	}
+
+	now := time.Now()
+	notAfter := now.Add(lifetime)
+	if notAfter.After(root.Leaf.NotAfter) {
+		notAfter = root.Leaf.NotAfter
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name, Organization: []string{"faa development client"}},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, root.Leaf, &privateKey.PublicKey, root.PrivateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sign client certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+// rootKeyPair returns the files of the root that issues certificates: the
+// configured CA's or Caddy's local one, whose key is next to its
+// certificate
+func (p *Proxy) rootKeyPair() (KeyPair, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.tls.CA != nil {
+		return *p.tls.CA, nil
+	}
+	rootPath, err := GetCaddyCAPath()
+	if err != nil {
+		return KeyPair{}, err
+	}
+	return KeyPair{
+		Certificate: rootPath,
+		Key:         filepath.Join(filepath.Dir(rootPath), "root.key"),
+	}, nil
+}
+
+// ClientCertHeader is a Caddy handler that passes the subject of the
+// client's verified certificate to the dev server in a request header.
+// A header sent by the client is always removed, so dev servers can rely
+// on it.
+type ClientCertHeader struct {
+	// Header is the request header to set
+	Header string `json:"header"`
+}
+
+// CaddyModule returns the Caddy module information
+func (ClientCertHeader) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.faa_client_cert",
+		New: func() caddy.Module { return new(ClientCertHeader) },
+	}
+}
+
+// ServeHTTP sets the header from the verified client certificate, if any
+func (h ClientCertHeader) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	r.Header.Del(h.Header)
+	// Unverified certificates, which Caddy isn't configured to accept,
+	// have no verified chains
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		r.Header.Set(h.Header, r.TLS.VerifiedChains[0][0].Subject.String())
+	}
+	return next.ServeHTTP(w, r)
+}
+
+// Interface guards
+var (
+	_ caddy.Module                = (*ClientCertHeader)(nil)
+	_ caddyhttp.MiddlewareHandler = (*ClientCertHeader)(nil)
+)
@@ -0,0 +1,196 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+func TestClientAuthConfig(t *testing.T) {
+	p := New()
+	if err := p.SetTLSOptions(TLSOptions{
+		ClientAuth: map[string]ClientAuthMode{
+			"admin.localhost": ClientAuthRequire,
+			"api.local":       ClientAuthRequire,
+			"app.localhost":   ClientAuthOptional,
+		},
+		ClientSubjectHeader: "X-Client",
+	}); err != nil {
+		t.Fatalf("SetTLSOptions() failed: %v", err)
+	}
+
+	configJSON, err := p.buildConfigJSON()
+	if err != nil {
+		t.Fatalf("buildConfigJSON() failed: %v", err)
+	}
+	var config struct {
+		Apps struct {
+			HTTP struct {
+				Servers map[string]struct {
+					Routes []struct {
+						Handle []map[string]interface{} `json:"handle"`
+					} `json:"routes"`
+					Policies []struct {
+						Match struct {
+							SNI []string `json:"sni"`
+						} `json:"match"`
+						ClientAuth *struct {
+							Mode string `json:"mode"`
+							CA   struct {
+								Provider  string   `json:"provider"`
+								Authority []string `json:"authority"`
+							} `json:"ca"`
+						} `json:"client_authentication"`
+					} `json:"tls_connection_policies"`
+				} `json:"servers"`
+			} `json:"http"`
+			PKI struct {
+				CAs map[string]json.RawMessage `json:"certificate_authorities"`
+			} `json:"pki"`
+		} `json:"apps"`
+	}
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	server := config.Apps.HTTP.Servers["https_server"]
+	policies := server.Policies
+	if len(policies) != 3 {
+		t.Fatalf("Expected 3 TLS connection policies, got %d", len(policies))
+	}
+	wantSNI := [][]string{
+		{"admin.local", "admin.localhost", "api.local", "api.localhost"},
+		{"app.local", "app.localhost"},
+	}
+	for i, mode := range []string{"require_and_verify", "verify_if_given"} {
+		policy := policies[i]
+		if !reflect.DeepEqual(policy.Match.SNI, wantSNI[i]) {
+			t.Errorf("policy %d matches %v, want %v", i, policy.Match.SNI, wantSNI[i])
+		}
+		if policy.ClientAuth == nil || policy.ClientAuth.Mode != mode {
+			t.Fatalf("policy %d doesn't authenticate clients with %s", i, mode)
+		}
+		if policy.ClientAuth.CA.Provider != "pki_root" || !reflect.DeepEqual(policy.ClientAuth.CA.Authority, []string{LocalCA}) {
+			t.Errorf("policy %d trusts %+v, want the local CA", i, policy.ClientAuth.CA)
+		}
+	}
+	if policies[2].ClientAuth != nil || len(policies[2].Match.SNI) != 0 {
+		t.Error("Expected the last policy to be the default one")
+	}
+	if _, ok := config.Apps.PKI.CAs[LocalCA]; !ok {
+		t.Error("Expected the local CA to be configured in the PKI app")
+	}
+
+	handle := server.Routes[0].Handle
+	if handle[0]["handler"] != "faa_client_cert" || handle[0]["header"] != "X-Client" {
+		t.Errorf("first handler = %v, want faa_client_cert setting X-Client", handle[0])
+	}
+
+	// Without client auth, every host shares the default policy
+	if err := p.SetTLSOptions(TLSOptions{}); err != nil {
+		t.Fatalf("SetTLSOptions() failed: %v", err)
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if got := p.tlsConnectionPolicies(); len(got) != 1 || len(got[0]) != 0 {
+		t.Errorf("tlsConnectionPolicies() = %v, want only the default policy", got)
+	}
+	if p.pkiAppConfig() != nil {
+		t.Error("Expected no PKI app config without client auth")
+	}
+	if got := p.clientSubjectHeader(); got != DefaultClientSubjectHeader {
+		t.Errorf("clientSubjectHeader() = %q, want %q", got, DefaultClientSubjectHeader)
+	}
+}
+
+func TestIssueClientCertificate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ca := writeTestKeyPair(t, t.TempDir(), "ca", true)
+
+	p := New()
+	if _, _, err := p.IssueClientCertificate("alice", time.Hour); err == nil {
+		t.Error("Expected an error before the local CA exists")
+	}
+
+	if err := p.SetTLSOptions(TLSOptions{CA: &ca}); err != nil {
+		t.Fatalf("SetTLSOptions() failed: %v", err)
+	}
+	certPEM, keyPEM, err := p.IssueClientCertificate("alice", 365*24*time.Hour)
+	if err != nil {
+		t.Fatalf("IssueClientCertificate() failed: %v", err)
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("The key doesn't match the certificate: %v", err)
+	}
+	cert := pair.Leaf
+	if cert.Subject.CommonName != "alice" {
+		t.Errorf("CommonName = %q, want alice", cert.Subject.CommonName)
+	}
+
+	root, err := loadKeyPair(ca)
+	if err != nil {
+		t.Fatalf("Failed to load CA: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+		t.Errorf("Client certificate doesn't verify: %v", err)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots}); err == nil {
+		t.Error("Expected the client certificate not to be valid for servers")
+	}
+	// The test CA expires within a day
+	if !cert.NotAfter.Equal(root.NotAfter) {
+		t.Errorf("NotAfter = %v, want the CA's %v", cert.NotAfter, root.NotAfter)
+	}
+
+	if _, _, err := p.IssueClientCertificate(" ", time.Hour); err == nil {
+		t.Error("Expected an error without a name")
+	}
+	if _, _, err := p.IssueClientCertificate("alice", 0); err == nil {
+		t.Error("Expected an error for a zero lifetime")
+	}
+}
+
+func TestClientCertHeaderServeHTTP(t *testing.T) {
+	verified := &x509.Certificate{Subject: pkix.Name{CommonName: "alice", Organization: []string{"faa"}}}
+	tests := []struct {
+		name  string
+		state *tls.ConnectionState
+		want  string
+	}{
+		{"plain HTTP", nil, ""},
+		{"no certificate", &tls.ConnectionState{}, ""},
+		{"verified", &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{verified}}}, "CN=alice,O=faa"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.TLS = tt.state
+		// A client can't pass a subject of its own
+		req.Header.Set("X-Client", "CN=mallory")
+
+		var got []string
+		next := caddyhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			got = r.Header.Values("X-Client")
+			return nil
+		})
+		if err := (ClientCertHeader{Header: "X-Client"}).ServeHTTP(httptest.NewRecorder(), req, next); err != nil {
+			t.Fatalf("ServeHTTP() for %s failed: %v", tt.name, err)
+		}
+		if tt.want == "" && len(got) != 0 {
+			t.Errorf("header for %s = %v, want none", tt.name, got)
+		}
+		if tt.want != "" && (len(got) != 1 || got[0] != tt.want) {
+			t.Errorf("header for %s = %v, want %q", tt.name, got, tt.want)
+		}
+	}
+}
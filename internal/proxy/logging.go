@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/caddyserver/caddy/v2"
+	_ "github.com/caddyserver/caddy/v2/modules/logging"
+)
+
+func init() {
+	caddy.RegisterModule(LogWriter{})
+}
+
+// logOutput is where Caddy's logs go once a Proxy has a log output. Caddy
+// opens log writers through its module system, so they can't be handed
+// the writer directly.
+var logOutput struct {
+	mu sync.RWMutex
+	w  io.Writer
+}
+
+// SetLogOutput sends Caddy's logs at level and above to w as JSON lines in
+// the same shape as log/slog's JSON handler. Until it is called Caddy logs
+// to stderr in its own format.
+func (p *Proxy) SetLogOutput(w io.Writer, level slog.Level) error {
+	logOutput.mu.Lock()
+	logOutput.w = w
+	logOutput.mu.Unlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.logLevel = caddyLogLevel(level)
+	return p.reload()
+}
+
+// caddyLogLevel returns the Caddy level logging at least what level does
+func caddyLogLevel(level slog.Level) string {
+	switch {
+	case level <= slog.LevelDebug:
+		return "DEBUG"
+	case level <= slog.LevelInfo:
+		return "INFO"
+	case level <= slog.LevelWarn:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// loggingConfig returns Caddy's logging configuration, or nil to keep its
+// default logging
+func (p *Proxy) loggingConfig() (*caddy.Logging, error) {
+	if p.logLevel == "" {
+		return nil, nil
+	}
+
+	config := map[string]interface{}{
+		"logs": map[string]interface{}{
+			"default": map[string]interface{}{
+				"writer": map[string]interface{}{
+					"output": "faa",
+				},
+				"encoder": map[string]interface{}{
+					"format":       "json",
+					"time_key":     "time",
+					"time_format":  "rfc3339_nano",
+					"level_format": "upper",
+				},
+				"level": p.logLevel,
+			},
+		},
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var logging caddy.Logging
+	if err := json.Unmarshal(data, &logging); err != nil {
+		return nil, fmt.Errorf("failed to build logging config: %w", err)
+	}
+	return &logging, nil
+}
+
+// LogWriter is a Caddy log writer that writes to the output set with
+// SetLogOutput
+type LogWriter struct{}
+
+// CaddyModule returns the Caddy module information
+func (LogWriter) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "caddy.logging.writers.faa",
+		New: func() caddy.Module { return new(LogWriter) },
+	}
+}
+
+// String describes where the logs go
+func (LogWriter) String() string { return "faa daemon log" }
+
+// WriterKey identifies the writer
+func (LogWriter) WriterKey() string { return "faa" }
+
+// OpenWriter returns a writer to the current log output
+func (LogWriter) OpenWriter() (io.WriteCloser, error) {
+	return logOutputWriter{}, nil
+}
+
+// logOutputWriter writes to logOutput, or stderr if it isn't set. Closing
+// it leaves the output open, since it outlives Caddy's configs.
+type logOutputWriter struct{}
+
+func (logOutputWriter) Write(p []byte) (int, error) {
+	logOutput.mu.RLock()
+	defer logOutput.mu.RUnlock()
+	if logOutput.w == nil {
+		return os.Stderr.Write(p)
+	}
+	return logOutput.w.Write(p)
+}
+
+func (logOutputWriter) Close() error { return nil }
+
+// Interface guards
+var (
+	_ caddy.Module       = (*LogWriter)(nil)
+	_ caddy.WriterOpener = (*LogWriter)(nil)
+)
@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for Caddy's concurrent writes
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestCaddyLogLevel(t *testing.T) {
+	tests := map[slog.Level]string{
+		slog.LevelDebug - 4: "DEBUG",
+		slog.LevelDebug:     "DEBUG",
+		slog.LevelInfo:      "INFO",
+		slog.LevelInfo + 2:  "WARN",
+		slog.LevelWarn:      "WARN",
+		slog.LevelError:     "ERROR",
+		slog.LevelError + 4: "ERROR",
+	}
+	for level, want := range tests {
+		if got := caddyLogLevel(level); got != want {
+			t.Errorf("caddyLogLevel(%v) = %s, want %s", level, got, want)
+		}
+	}
+}
+
+func TestBuildConfigJSONLogging(t *testing.T) {
+	p := NewWithPorts(18096, 18459)
+	configJSON, err := p.buildConfigJSON()
+	if err != nil {
+		t.Fatalf("buildConfigJSON() failed: %v", err)
+	}
+	if strings.Contains(string(configJSON), `"logging"`) {
+		t.Error("Caddy should keep its default logging until a log output is set")
+	}
+
+	defer func() {
+		logOutput.mu.Lock()
+		logOutput.w = nil
+		logOutput.mu.Unlock()
+	}()
+	if err := p.SetLogOutput(&syncBuffer{}, slog.LevelWarn); err != nil {
+		t.Fatalf("SetLogOutput() failed: %v", err)
+	}
+	configJSON, err = p.buildConfigJSON()
+	if err != nil {
+		t.Fatalf("buildConfigJSON() failed: %v", err)
+	}
+	for _, expected := range []string{`"output":"faa"`, `"format":"json"`, `"level":"WARN"`} {
+		if !strings.Contains(string(configJSON), expected) {
+			t.Errorf("Logging config is missing %s", expected)
+		}
+	}
+}
+
+func TestLogOutput(t *testing.T) {
+	var buf syncBuffer
+	defer func() {
+		logOutput.mu.Lock()
+		logOutput.w = nil
+		logOutput.mu.Unlock()
+	}()
+
+	p := NewWithPorts(18096, 18459)
+	if err := p.SetLogOutput(&buf, slog.LevelInfo); err != nil {
+		t.Fatalf("SetLogOutput() failed: %v", err)
+	}
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	p.Stop()
+
+	// Caddy logs its startup and shutdown at the info level
+	deadline := time.Now().Add(2 * time.Second)
+	for buf.String() == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) == 0 || lines[0] == "" {
+		t.Fatal("Caddy logged nothing to the log output")
+	}
+	for _, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Log line is not JSON: %s", line)
+		}
+		for _, key := range []string{"time", "level", "msg"} {
+			if _, ok := entry[key]; !ok {
+				t.Errorf("Log line is missing %q: %s", key, line)
+			}
+		}
+		if level := entry["level"]; level == "DEBUG" {
+			t.Errorf("Debug line logged at the info level: %s", line)
+		}
+	}
+}
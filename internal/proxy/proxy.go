@@ -2,8 +2,15 @@
 //
 // The proxy package enables running a fully-featured reverse proxy with:
 //   - Automatic HTTP to HTTPS redirection
-//   - Internal CA for TLS certificate generation
-//   - Dynamic route management without restarts
+//   - Internal CA for TLS certificate generation, or an existing CA or
+//     certificate files (see SetTLSOptions)
+//   - Dynamic route management without config reloads: routes live in a
+//     table read on every request, and certificates are issued on demand
+//   - Websocket and server-sent event streams that survive route changes
+//     on other hosts (see StreamOptions)
+//   - A log of recent requests per host (see RecentRequests)
+//   - Opt-in capture of full exchanges per host (see SetCapture)
+//   - Latency and fault injection per host (see SetChaos)
 //   - Thread-safe operations
 //
 // Example usage:
@@ -33,6 +40,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/caddyserver/caddy/v2"
@@ -47,19 +55,34 @@ import (
 // Proxy manages an embedded Caddy server with dynamic route configuration
 type Proxy struct {
 	mu        sync.RWMutex
-	routes    map[string]int // host -> port mapping
+	routes    map[string]int         // host -> port mapping
+	capture   map[string]bool        // hosts with traffic capture enabled
+	chaos     map[string][]ChaosRule // host -> injected faults
+	streams   StreamOptions
+	tls       TLSOptions
+	caID      string      // ID of the Caddy CA issuing certificates
+	table     *routeTable // what the running proxy serves, kept in sync with the maps above
+	admin     string      // Caddy admin endpoint address, or AdminOff
+	logLevel  string      // Caddy log level once SetLogOutput is called
 	running   bool
 	httpPort  int // HTTP port (default 80)
 	httpsPort int // HTTPS port (default 443)
+	urlPort   int // HTTPS port clients connect to, used in redirects (default 443)
 }
 
 // New creates a new Proxy instance with default ports 80 and 443
 func New() *Proxy {
 	return &Proxy{
 		routes:    make(map[string]int),
+		capture:   make(map[string]bool),
+		chaos:     make(map[string][]ChaosRule),
+		table:     newRouteTable(),
+		admin:     AdminOff,
+		caID:      LocalCA,
 		running:   false,
 		httpPort:  80,
 		httpsPort: 443,
+		urlPort:   443,
 	}
 }
 
@@ -67,9 +90,15 @@ func New() *Proxy {
 func NewWithPorts(httpPort, httpsPort int) *Proxy {
 	return &Proxy{
 		routes:    make(map[string]int),
+		capture:   make(map[string]bool),
+		chaos:     make(map[string][]ChaosRule),
+		table:     newRouteTable(),
+		admin:     AdminOff,
+		caID:      LocalCA,
 		running:   false,
 		httpPort:  httpPort,
 		httpsPort: httpsPort,
+		urlPort:   443,
 	}
 }
 
@@ -88,19 +117,30 @@ func (p *Proxy) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to build config: %w", err)
 	}
 
+	if err := p.prepareAdmin(); err != nil {
+		return fmt.Errorf("failed to start Caddy: %w", err)
+	}
+	logging, err := p.loggingConfig()
+	if err != nil {
+		return err
+	}
+
 	// Run Caddy with the configuration
 	err = caddy.Run(&caddy.Config{
-		Admin: &caddy.AdminConfig{
-			Listen: "localhost:2019",
-		},
+		Admin:   p.adminConfig(),
+		Logging: logging,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to start Caddy: %w", err)
+		return fmt.Errorf("failed to start Caddy: %w", p.adminError(err))
 	}
 
+	// Serve this proxy's routes; the config only refers to the live table
+	liveRoutes.Store(p.table)
+
 	// Load the configuration
 	err = caddy.Load(configJSON, true)
 	if err != nil {
+		liveRoutes.CompareAndSwap(p.table, nil)
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
@@ -117,8 +157,13 @@ func (p *Proxy) Stop() error {
 		return nil
 	}
 
+	// Caddy would keep upgraded connections open for streamCloseDelay
+	// after unloading the config
+	streams.closeAll()
+
 	err := caddy.Stop()
 	p.running = false
+	liveRoutes.CompareAndSwap(p.table, nil)
 
 	if err != nil {
 		return fmt.Errorf("failed to stop Caddy: %w", err)
@@ -127,25 +172,170 @@ func (p *Proxy) Stop() error {
 	return nil
 }
 
-// ApplyRoutes rebuilds the Caddy configuration with the provided routes
+// ApplyRoutes replaces all routes with the provided ones.
+// Websocket and server-sent event streams to hosts whose port changed or
+// whose route was removed are closed; streams to other hosts stay open.
 func (p *Proxy) ApplyRoutes(routes map[string]int) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if !p.running {
-		// If not running, just update routes for next start
-		p.routes = make(map[string]int)
-		for host, port := range routes {
-			p.routes[host] = port
+	for host := range p.routes {
+		if _, ok := routes[host]; !ok {
+			p.setRoute(host, 0)
 		}
-		return nil
 	}
-
-	// Update routes
-	p.routes = make(map[string]int)
 	for host, port := range routes {
+		p.setRoute(host, port)
+	}
+	return nil
+}
+
+// SetRoute adds or updates the route for a single host without touching
+// the others. Streams to host are closed if its port changed.
+func (p *Proxy) SetRoute(host string, port int) error {
+	if port <= 0 || port > 65535 {
+		return fmt.Errorf("invalid port: %d", port)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.setRoute(host, port)
+	return nil
+}
+
+// RemoveRoute removes the route for host and closes its streams
+func (p *Proxy) RemoveRoute(host string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.setRoute(host, 0)
+	return nil
+}
+
+// setRoute routes host to port, or removes its route when port is 0.
+// The caller must hold p.mu.
+func (p *Proxy) setRoute(host string, port int) {
+	previous, existed := p.routes[host]
+	if existed && previous == port {
+		return
+	}
+
+	if port == 0 {
+		delete(p.routes, host)
+	} else {
 		p.routes[host] = port
 	}
+	p.publish(host)
+
+	// Streams to the previous port point at an upstream that is going away
+	if existed {
+		streams.closeHost(host)
+	}
+}
+
+// publish copies the settings of host to the route table.
+// The caller must hold p.mu.
+func (p *Proxy) publish(host string) {
+	port, ok := p.routes[host]
+	if !ok {
+		p.table.remove(host)
+		return
+	}
+	p.table.set(host, hostRoute{
+		port:    port,
+		capture: p.capture[host],
+		chaos:   p.chaos[host],
+	})
+}
+
+// SetCapture enables or disables traffic capture for host.
+// Captured exchanges are available from CapturedExchanges.
+func (p *Proxy) SetCapture(host string, enabled bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if enabled {
+		p.capture[host] = true
+	} else {
+		delete(p.capture, host)
+	}
+
+	p.publish(host)
+	return nil
+}
+
+// SetChaos replaces the fault injection rules for host. An empty list
+// removes fault injection. Rules are ordered so that the most specific path
+// is tried first and host-wide rules last.
+func (p *Proxy) SetChaos(host string, rules []ChaosRule) error {
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(rules) == 0 {
+		delete(p.chaos, host)
+	} else {
+		p.chaos[host] = SortChaosRules(rules)
+	}
+
+	p.publish(host)
+	return nil
+}
+
+// SetStreamOptions changes how websocket and server-sent event streams
+// are proxied
+func (p *Proxy) SetStreamOptions(opts StreamOptions) error {
+	if opts.Timeout < 0 {
+		return fmt.Errorf("stream timeout must not be negative")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.streams = opts
+	return p.reload()
+}
+
+// SetDomains sets the domains besides .localhost and .local that every
+// route is also served under, such as "test" for my-app.test. A request to
+// an alias is handled as one to the routed host: it shares the route's
+// port, capture, faults and request log, and gets its own certificate.
+func (p *Proxy) SetDomains(domains []string) {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		normalized = append(normalized, strings.Trim(strings.ToLower(domain), "."))
+	}
+	p.table.setDomains(normalized)
+}
+
+// SetURLPort sets the HTTPS port clients connect to, which plain HTTP
+// requests are redirected to. It is 443 unless URLs include the proxy's
+// own HTTPS port.
+func (p *Proxy) SetURLPort(port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("invalid port: %d", port)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.urlPort = port
+	return p.reload()
+}
+
+// reload rebuilds the configuration and loads it into Caddy if the proxy is
+// running; otherwise the new settings take effect on the next start.
+// The caller must hold p.mu.
+func (p *Proxy) reload() error {
+	if !p.running {
+		return nil
+	}
 
 	// Rebuild configuration
 	configJSON, err := p.buildConfigJSON()
@@ -162,89 +352,110 @@ func (p *Proxy) ApplyRoutes(routes map[string]int) error {
 	return nil
 }
 
-// buildConfigJSON constructs the Caddy configuration JSON from current routes
+// buildConfigJSON constructs the Caddy configuration JSON. Routes are not
+// part of it: every request to a routed host goes through the same
+// handlers, which look the host up in the live route table.
 func (p *Proxy) buildConfigJSON() ([]byte, error) {
-	// Build reverse proxy handlers for each route
-	httpsRoutes := make([]map[string]interface{}, 0, len(p.routes))
-	for host, port := range p.routes {
-		route := map[string]interface{}{
+	reverseProxy := map[string]interface{}{
+		"handler": "reverse_proxy",
+		"dynamic_upstreams": map[string]interface{}{
+			"source": "faa",
+		},
+		"headers": map[string]interface{}{
+			"request": map[string]interface{}{
+				"set": map[string]interface{}{
+					"Host": []string{"localhost"},
+				},
+			},
+		},
+		"stream_close_delay": caddy.Duration(streamCloseDelay),
+	}
+	if p.streams.Timeout > 0 {
+		reverseProxy["stream_timeout"] = caddy.Duration(p.streams.Timeout)
+	}
+	if p.streams.FlushInterval != 0 {
+		reverseProxy["flush_interval"] = caddy.Duration(p.streams.FlushInterval)
+	}
+
+	httpsRoutes := []map[string]interface{}{
+		{
 			"match": []map[string]interface{}{
 				{
-					"host": []string{host},
+					"faa_route": map[string]interface{}{},
 				},
 			},
 			"handle": []map[string]interface{}{
+				// Before anything records the request, so a client can't
+				// spoof the header
 				{
-					"handler": "reverse_proxy",
-					"upstreams": []map[string]interface{}{
-						{
-							"dial": fmt.Sprintf("localhost:%d", port),
-						},
-					},
-					"headers": map[string]interface{}{
-						"request": map[string]interface{}{
-							"set": map[string]interface{}{
-								"Host": []string{"localhost"},
-							},
-						},
-					},
+					"handler": "faa_client_cert",
+					"header":  p.clientSubjectHeader(),
 				},
+				{
+					"handler": "faa_record",
+				},
+				{
+					"handler": "faa_capture",
+				},
+				// Faults are injected after capture so the inspector shows them
+				{
+					"handler": "faa_chaos",
+				},
+				reverseProxy,
 			},
-		}
-		httpsRoutes = append(httpsRoutes, route)
+		},
+	}
+
+	location := "https://{http.request.host}{http.request.uri}"
+	if p.urlPort != 443 {
+		location = fmt.Sprintf("https://{http.request.host}:%d{http.request.uri}", p.urlPort)
 	}
 
 	// Build the configuration
-	config := map[string]interface{}{
-		"admin": map[string]interface{}{
-			"listen": "localhost:2019",
-		},
-		"apps": map[string]interface{}{
-			"http": map[string]interface{}{
-				"http_port":  p.httpPort,
-				"https_port": p.httpsPort,
-				"servers": map[string]interface{}{
-					"http_redirector": map[string]interface{}{
-						"listen": []string{fmt.Sprintf(":%d", p.httpPort)},
-						"routes": []map[string]interface{}{
-							{
-								"handle": []map[string]interface{}{
-									{
-										"handler":     "static_response",
-										"status_code": 301,
-										"headers": map[string]interface{}{
-											"Location": []string{"https://{http.request.host}{http.request.uri}"},
-										},
-									},
-								},
-							},
-						},
-					},
-					"https_server": map[string]interface{}{
-						"listen": []string{fmt.Sprintf(":%d", p.httpsPort)},
-						"routes": httpsRoutes,
-						"tls_connection_policies": []map[string]interface{}{
-							{},
-						},
-					},
-				},
-			},
-			"tls": map[string]interface{}{
-				"automation": map[string]interface{}{
-					"policies": []map[string]interface{}{
+	apps := map[string]interface{}{
+		"http": map[string]interface{}{
+			"http_port":  p.httpPort,
+			"https_port": p.httpsPort,
+			"servers": map[string]interface{}{
+				"http_redirector": map[string]interface{}{
+					"listen": []string{fmt.Sprintf(":%d", p.httpPort)},
+					"routes": []map[string]interface{}{
 						{
-							"issuers": []map[string]interface{}{
+							"handle": []map[string]interface{}{
 								{
-									"module": "internal",
-									"ca":     "local",
+									"handler":     "static_response",
+									"status_code": 301,
+									"headers": map[string]interface{}{
+										"Location": []string{location},
+									},
 								},
 							},
-							"disable_ocsp_stapling": true,
 						},
 					},
 				},
+				"https_server": map[string]interface{}{
+					"listen":                  []string{fmt.Sprintf(":%d", p.httpsPort)},
+					"routes":                  httpsRoutes,
+					"tls_connection_policies": p.tlsConnectionPolicies(),
+				},
 			},
 		},
+		"tls": p.tlsAppConfig(),
+	}
+	if pki := p.pkiAppConfig(); pki != nil {
+		apps["pki"] = pki
+	}
+	config := map[string]interface{}{
+		"admin": p.adminConfig(),
+		"apps":  apps,
+	}
+
+	logging, err := p.loggingConfig()
+	if err != nil {
+		return nil, err
+	}
+	if logging != nil {
+		config["logging"] = logging
 	}
 
 	return json.Marshal(config)
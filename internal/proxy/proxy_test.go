@@ -3,6 +3,7 @@ package proxy
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -245,6 +246,200 @@ func TestBuildConfigJSON(t *testing.T) {
 	}
 }
 
+func TestBuildConfigJSONStatic(t *testing.T) {
+	p := NewWithPorts(18086, 18449)
+	empty, err := p.buildConfigJSON()
+	if err != nil {
+		t.Fatalf("buildConfigJSON() failed: %v", err)
+	}
+
+	// Routes live in the route table, so they don't change the config
+	if err := p.ApplyRoutes(map[string]int{"app.localhost": 8080, "api.localhost": 8081}); err != nil {
+		t.Fatalf("ApplyRoutes() failed: %v", err)
+	}
+	if err := p.SetCapture("app.localhost", true); err != nil {
+		t.Fatalf("SetCapture() failed: %v", err)
+	}
+	routed, err := p.buildConfigJSON()
+	if err != nil {
+		t.Fatalf("buildConfigJSON() failed: %v", err)
+	}
+	if string(routed) != string(empty) {
+		t.Error("Config should not depend on routes")
+	}
+
+	for _, module := range []string{`"faa_route"`, `"faa_record"`, `"faa_capture"`, `"faa_chaos"`, `"dynamic_upstreams"`, `"on_demand"`} {
+		if !strings.Contains(string(routed), module) {
+			t.Errorf("Config is missing %s", module)
+		}
+	}
+}
+
+func TestSetRoute(t *testing.T) {
+	p := NewWithPorts(18086, 18449)
+
+	if err := p.SetRoute("app.localhost", 3000); err != nil {
+		t.Fatalf("SetRoute() failed: %v", err)
+	}
+	if err := p.SetRoute("api.localhost", 3001); err != nil {
+		t.Fatalf("SetRoute() failed: %v", err)
+	}
+	if route, ok := p.table.lookup("app.localhost"); !ok || route.port != 3000 {
+		t.Errorf("Route table has %+v, want port 3000", route)
+	}
+
+	if err := p.SetRoute("app.localhost", 0); err == nil {
+		t.Error("SetRoute() should reject port 0")
+	}
+
+	if err := p.RemoveRoute("app.localhost"); err != nil {
+		t.Fatalf("RemoveRoute() failed: %v", err)
+	}
+	if _, ok := p.table.lookup("app.localhost"); ok {
+		t.Error("Removed route still in the route table")
+	}
+	if _, ok := p.table.lookup("api.localhost"); !ok {
+		t.Error("RemoveRoute() removed another host")
+	}
+
+	// ApplyRoutes removes hosts that are not listed
+	if err := p.ApplyRoutes(map[string]int{"web.localhost": 3002}); err != nil {
+		t.Fatalf("ApplyRoutes() failed: %v", err)
+	}
+	if _, ok := p.table.lookup("api.localhost"); ok {
+		t.Error("ApplyRoutes() should remove unlisted routes from the table")
+	}
+	if len(p.routes) != 1 || p.routes["web.localhost"] != 3002 {
+		t.Errorf("routes = %v", p.routes)
+	}
+}
+
+func TestSetCapture(t *testing.T) {
+	p := NewWithPorts(18086, 18449)
+	p.ApplyRoutes(map[string]int{"app.localhost": 8080, "other.localhost": 8081})
+
+	if err := p.SetCapture("app.localhost", true); err != nil {
+		t.Fatalf("SetCapture() failed: %v", err)
+	}
+	if route, _ := p.table.lookup("app.localhost"); !route.capture {
+		t.Error("Capture should be enabled in the route table")
+	}
+	if route, _ := p.table.lookup("other.localhost"); route.capture {
+		t.Error("Capture should only be enabled for app.localhost")
+	}
+
+	// Capture survives the route moving to another port
+	p.SetRoute("app.localhost", 9090)
+	if route, _ := p.table.lookup("app.localhost"); !route.capture || route.port != 9090 {
+		t.Errorf("Route table has %+v after the port changed", route)
+	}
+
+	if err := p.SetCapture("app.localhost", false); err != nil {
+		t.Fatalf("SetCapture() failed: %v", err)
+	}
+	if route, _ := p.table.lookup("app.localhost"); route.capture {
+		t.Error("Capture should be disabled")
+	}
+}
+
+func TestSetChaos(t *testing.T) {
+	p := NewWithPorts(18086, 18449)
+	p.ApplyRoutes(map[string]int{"app.localhost": 8080})
+
+	err := p.SetChaos("app.localhost", []ChaosRule{
+		{ErrorRate: 0.5},
+		{Path: "/api/users/*", ResetRate: 0.1},
+		{Path: "/api/*", Bandwidth: 1024},
+	})
+	if err != nil {
+		t.Fatalf("SetChaos() failed: %v", err)
+	}
+
+	// The most specific path is tried first
+	route, _ := p.table.lookup("app.localhost")
+	rules := route.chaos
+	if len(rules) != 3 || rules[0].Path != "/api/users/*" || rules[1].Path != "/api/*" || rules[2].Path != "" {
+		t.Errorf("Rules not ordered by specificity: %+v", rules)
+	}
+
+	if err := p.SetChaos("app.localhost", []ChaosRule{{ErrorRate: 2}}); err == nil {
+		t.Error("SetChaos() should reject invalid rules")
+	}
+
+	if err := p.SetChaos("app.localhost", nil); err != nil {
+		t.Fatalf("SetChaos() failed: %v", err)
+	}
+	if route, _ := p.table.lookup("app.localhost"); len(route.chaos) != 0 {
+		t.Error("Chaos rules should be removed")
+	}
+}
+
+func TestSetStreamOptions(t *testing.T) {
+	p := NewWithPorts(18086, 18449)
+	p.routes["app.localhost"] = 8080
+
+	configJSON, err := p.buildConfigJSON()
+	if err != nil {
+		t.Fatalf("buildConfigJSON() failed: %v", err)
+	}
+	config := string(configJSON)
+	if !strings.Contains(config, `"stream_close_delay":10000000000`) {
+		t.Error("Streams should be closed 10s after a reload")
+	}
+	if strings.Contains(config, `"stream_timeout"`) || strings.Contains(config, `"flush_interval"`) {
+		t.Error("Stream timeout and flush interval should be unset by default")
+	}
+
+	err = p.SetStreamOptions(StreamOptions{Timeout: time.Hour, FlushInterval: -1})
+	if err != nil {
+		t.Fatalf("SetStreamOptions() failed: %v", err)
+	}
+	configJSON, err = p.buildConfigJSON()
+	if err != nil {
+		t.Fatalf("buildConfigJSON() failed: %v", err)
+	}
+	config = string(configJSON)
+	if !strings.Contains(config, fmt.Sprintf(`"stream_timeout":%d`, time.Hour)) {
+		t.Error("Stream timeout missing from config")
+	}
+	if !strings.Contains(config, `"flush_interval":-1`) {
+		t.Error("Flush interval missing from config")
+	}
+
+	if err := p.SetStreamOptions(StreamOptions{Timeout: -time.Second}); err == nil {
+		t.Error("SetStreamOptions() should reject a negative timeout")
+	}
+}
+
+func TestSetURLPort(t *testing.T) {
+	p := NewWithPorts(18086, 18449)
+
+	configJSON, err := p.buildConfigJSON()
+	if err != nil {
+		t.Fatalf("buildConfigJSON() failed: %v", err)
+	}
+	if !strings.Contains(string(configJSON), `"Location":["https://{http.request.host}{http.request.uri}"]`) {
+		t.Error("HTTP should redirect to the standard HTTPS port by default")
+	}
+
+	if err := p.SetURLPort(18449); err != nil {
+		t.Fatalf("SetURLPort() failed: %v", err)
+	}
+	configJSON, err = p.buildConfigJSON()
+	if err != nil {
+		t.Fatalf("buildConfigJSON() failed: %v", err)
+	}
+	if !strings.Contains(string(configJSON), `"Location":["https://{http.request.host}:18449{http.request.uri}"]`) {
+		t.Error("HTTP should redirect to the URL port")
+	}
+
+	for _, port := range []int{0, 70000} {
+		if err := p.SetURLPort(port); err == nil {
+			t.Errorf("SetURLPort(%d) should fail", port)
+		}
+	}
+}
+
 func TestMultipleRoutes(t *testing.T) {
 	p := NewWithPorts(18087, 18450)
 
@@ -0,0 +1,267 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+// requestLogSize is the number of recent requests kept per host
+const requestLogSize = 100
+
+func init() {
+	caddy.RegisterModule(Recorder{})
+}
+
+// RequestRecord describes a request that passed through the proxy
+type RequestRecord struct {
+	Time       time.Time `json:"time"`
+	Host       string    `json:"host"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	DurationMs float64   `json:"durationMs"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// HostStats summarizes every request proxied for a host since the daemon started
+type HostStats struct {
+	Total  uint64 `json:"total"`
+	Errors uint64 `json:"errors"`
+
+	// LastError is the most recent request that failed with a 5xx status
+	// or a proxy error
+	LastError *RequestRecord `json:"lastError,omitempty"`
+}
+
+// requestLog keeps the most recent requests and running totals for each host.
+// Caddy instantiates handler modules from JSON config, so the log is
+// package-level state shared by every Recorder instance.
+type requestLog struct {
+	mu    sync.Mutex
+	hosts map[string][]RequestRecord
+	stats map[string]*HostStats
+}
+
+var requests = newRequestLog()
+
+// newRequestLog creates an empty request log
+func newRequestLog() *requestLog {
+	return &requestLog{
+		hosts: make(map[string][]RequestRecord),
+		stats: make(map[string]*HostStats),
+	}
+}
+
+// add records a request, discarding the oldest one for the host when full
+func (l *requestLog) add(record RequestRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records := append(l.hosts[record.Host], record)
+	if len(records) > requestLogSize {
+		records = records[len(records)-requestLogSize:]
+	}
+	l.hosts[record.Host] = records
+
+	stats := l.stats[record.Host]
+	if stats == nil {
+		stats = &HostStats{}
+		l.stats[record.Host] = stats
+	}
+	stats.Total++
+	if record.Status >= 500 || record.Error != "" {
+		stats.Errors++
+		failed := record
+		stats.LastError = &failed
+	}
+}
+
+// statsSnapshot returns a copy of the totals for every host
+func (l *requestLog) statsSnapshot() map[string]HostStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	snapshot := make(map[string]HostStats, len(l.stats))
+	for host, stats := range l.stats {
+		snapshot[host] = *stats
+	}
+	return snapshot
+}
+
+// recent returns up to limit of the latest requests for host, newest first.
+// A limit of zero or less returns every stored request.
+func (l *requestLog) recent(host string, limit int) []RequestRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records := l.hosts[host]
+	if limit <= 0 || limit > len(records) {
+		limit = len(records)
+	}
+
+	result := make([]RequestRecord, 0, limit)
+	for i := len(records) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, records[i])
+	}
+	return result
+}
+
+// RecentRequests returns up to limit of the latest requests proxied for
+// host, newest first. A limit of zero or less returns every stored request.
+func RecentRequests(host string, limit int) []RequestRecord {
+	return requests.recent(host, limit)
+}
+
+// RequestStats returns request totals for every host that has received traffic
+func RequestStats() map[string]HostStats {
+	return requests.statsSnapshot()
+}
+
+// Recorder is a Caddy HTTP handler that records every request it sees in
+// the recent request log before passing it on to the next handler.
+type Recorder struct{}
+
+// CaddyModule returns the Caddy module information
+func (Recorder) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.faa_record",
+		New: func() caddy.Module { return new(Recorder) },
+	}
+}
+
+// ServeHTTP records the request and the status of its response
+func (Recorder) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	start := time.Now()
+
+	// Streams are closed through the request context when the host's
+	// upstream changes (see Proxy.SetRoute)
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	rec := &statusRecorder{ResponseWriterWrapper: &caddyhttp.ResponseWriterWrapper{ResponseWriter: w}}
+	rec.onHeader = func(status int) {
+		if rec.untrack == nil && isStreamResponse(r, status, rec.Header()) {
+			rec.untrack = streams.add(requestHost(r), cancel)
+		}
+	}
+
+	err := serveNext(next, rec, r)
+	if rec.untrack != nil {
+		rec.untrack()
+	}
+
+	record := RequestRecord{
+		Time:       start,
+		Host:       requestHost(r),
+		Method:     r.Method,
+		Path:       r.URL.RequestURI(),
+		Status:     rec.status,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	switch {
+	case err == errAborted:
+		// The connection was reset, so there may be no response status
+		record.Error = err.Error()
+	case err != nil:
+		// Errors are written by Caddy after the handler chain returns
+		record.Status = http.StatusInternalServerError
+		cause := err
+		var handlerErr caddyhttp.HandlerError
+		if errors.As(err, &handlerErr) {
+			if handlerErr.StatusCode != 0 {
+				record.Status = handlerErr.StatusCode
+			}
+			if handlerErr.Err != nil {
+				cause = handlerErr.Err
+			}
+		}
+		record.Error = cause.Error()
+	case record.Status == 0:
+		record.Status = http.StatusOK
+	}
+	requests.add(record)
+
+	if err == errAborted {
+		panic(http.ErrAbortHandler)
+	}
+	return err
+}
+
+// errAborted is returned by serveNext when a later handler aborted the
+// connection by panicking with http.ErrAbortHandler
+var errAborted = errors.New("connection reset")
+
+// serveNext calls the next handler, turning an aborted connection into
+// errAborted so the caller can record it. Callers must re-raise the abort
+// with panic(http.ErrAbortHandler) afterwards.
+func serveNext(next caddyhttp.Handler, w http.ResponseWriter, r *http.Request) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			if v != http.ErrAbortHandler {
+				panic(v)
+			}
+			err = errAborted
+		}
+	}()
+	return next.ServeHTTP(w, r)
+}
+
+// requestHost returns the request host without a port, or the routed host
+// if it is an alias (see SetDomains)
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	if table := liveRoutes.Load(); table != nil {
+		return table.canonical(host)
+	}
+	return host
+}
+
+// statusRecorder captures the status code written by later handlers
+type statusRecorder struct {
+	*caddyhttp.ResponseWriterWrapper
+	status int
+
+	// onHeader is called with each status code before it is written
+	onHeader func(status int)
+
+	// untrack stops tracking the response as a stream, if it is one
+	untrack func()
+}
+
+// WriteHeader records the status code before writing it
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.onHeader != nil {
+		rec.onHeader(status)
+	}
+	if rec.status == 0 && status >= 200 {
+		rec.status = status
+	}
+	rec.ResponseWriterWrapper.WriteHeader(status)
+}
+
+// Write records an implicit 200 status before writing the body
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	return rec.ResponseWriterWrapper.Write(b)
+}
+
+// Interface guards
+var (
+	_ caddy.Module                = (*Recorder)(nil)
+	_ caddyhttp.MiddlewareHandler = (*Recorder)(nil)
+)
@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+func TestRequestLogRecent(t *testing.T) {
+	l := newRequestLog()
+
+	for i := 0; i < requestLogSize+10; i++ {
+		l.add(RequestRecord{Host: "app.localhost", Path: fmt.Sprintf("/%d", i)})
+	}
+	l.add(RequestRecord{Host: "other.localhost", Path: "/other"})
+
+	all := l.recent("app.localhost", 0)
+	if len(all) != requestLogSize {
+		t.Fatalf("Expected %d records, got %d", requestLogSize, len(all))
+	}
+	if all[0].Path != fmt.Sprintf("/%d", requestLogSize+9) {
+		t.Errorf("Newest record should come first, got %s", all[0].Path)
+	}
+	if all[len(all)-1].Path != "/10" {
+		t.Errorf("Oldest records should be discarded, got %s", all[len(all)-1].Path)
+	}
+
+	limited := l.recent("app.localhost", 3)
+	if len(limited) != 3 {
+		t.Errorf("Expected 3 records, got %d", len(limited))
+	}
+
+	if got := l.recent("missing.localhost", 10); len(got) != 0 {
+		t.Errorf("Expected no records for unknown host, got %d", len(got))
+	}
+}
+
+func TestRequestLogStats(t *testing.T) {
+	l := newRequestLog()
+
+	l.add(RequestRecord{Host: "app.localhost", Status: 200})
+	l.add(RequestRecord{Host: "app.localhost", Status: 502, Error: "connection refused"})
+	l.add(RequestRecord{Host: "app.localhost", Status: 404})
+	l.add(RequestRecord{Host: "other.localhost", Status: 200})
+
+	stats := l.statsSnapshot()
+	app := stats["app.localhost"]
+	if app.Total != 3 || app.Errors != 1 {
+		t.Errorf("app stats = %+v, want 3 total and 1 error", app)
+	}
+	if app.LastError == nil || app.LastError.Status != 502 {
+		t.Errorf("LastError = %+v, want the 502 request", app.LastError)
+	}
+
+	other := stats["other.localhost"]
+	if other.Total != 1 || other.Errors != 0 || other.LastError != nil {
+		t.Errorf("other stats = %+v, want 1 successful request", other)
+	}
+}
+
+func TestRecorderServeHTTP(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		next       caddyhttp.HandlerFunc
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name: "implicit ok",
+			host: "ok.recorder.localhost",
+			next: func(w http.ResponseWriter, r *http.Request) error {
+				_, err := w.Write([]byte("hello"))
+				return err
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "explicit status",
+			host: "notfound.recorder.localhost:443",
+			next: func(w http.ResponseWriter, r *http.Request) error {
+				w.WriteHeader(http.StatusNotFound)
+				return nil
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "handler error",
+			host: "error.recorder.localhost",
+			next: func(w http.ResponseWriter, r *http.Request) error {
+				return caddyhttp.Error(http.StatusBadGateway, fmt.Errorf("connection refused"))
+			},
+			wantStatus: http.StatusBadGateway,
+			wantError:  "connection refused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/path?q=1", nil)
+			req.Host = tt.host
+
+			err := Recorder{}.ServeHTTP(httptest.NewRecorder(), req, tt.next)
+			if (err != nil) != (tt.wantError != "") {
+				t.Errorf("ServeHTTP() error = %v, want error %v", err, tt.wantError != "")
+			}
+			var handlerErr caddyhttp.HandlerError
+			if err != nil && !errors.As(err, &handlerErr) {
+				t.Errorf("ServeHTTP() should pass on the handler error unchanged, got %T", err)
+			}
+
+			records := RecentRequests(requestHost(req), 1)
+			if len(records) != 1 {
+				t.Fatalf("Expected 1 record, got %d", len(records))
+			}
+			record := records[0]
+			if record.Status != tt.wantStatus {
+				t.Errorf("Status = %d, want %d", record.Status, tt.wantStatus)
+			}
+			if record.Error != tt.wantError {
+				t.Errorf("Error = %q, want %q", record.Error, tt.wantError)
+			}
+			if record.Method != http.MethodGet || record.Path != "/path?q=1" {
+				t.Errorf("Unexpected request details: %s %s", record.Method, record.Path)
+			}
+		})
+	}
+}
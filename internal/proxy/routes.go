@@ -0,0 +1,195 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp/reverseproxy"
+	"github.com/caddyserver/caddy/v2/modules/caddytls"
+)
+
+func init() {
+	caddy.RegisterModule(MatchRoute{})
+	caddy.RegisterModule(RouteUpstreams{})
+	caddy.RegisterModule(RoutePermission{})
+}
+
+// hostRoute is the live configuration of one routed host
+type hostRoute struct {
+	port    int
+	capture bool
+	chaos   []ChaosRule
+}
+
+// routeTable maps hosts to their routes. The Caddy config is the same for
+// every set of routes; the modules in this file look each request up in
+// the table instead, so changing a route is a map update rather than a
+// config reload.
+type routeTable struct {
+	mu      sync.RWMutex
+	routes  map[string]hostRoute
+	domains []string // see SetDomains
+}
+
+// newRouteTable creates an empty route table
+func newRouteTable() *routeTable {
+	return &routeTable{routes: make(map[string]hostRoute)}
+}
+
+// lookup returns the route for host, which may be an alias
+func (t *routeTable) lookup(host string) (hostRoute, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	route, ok := t.routes[t.canonicalLocked(host)]
+	return route, ok
+}
+
+// canonical returns the routed host that host is an alias of, or host
+// itself
+func (t *routeTable) canonical(host string) string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.canonicalLocked(host)
+}
+
+// canonicalLocked is canonical for callers holding t.mu
+func (t *routeTable) canonicalLocked(host string) string {
+	if _, ok := t.routes[host]; ok {
+		return host
+	}
+	for _, domain := range t.domains {
+		name, ok := strings.CutSuffix(host, "."+domain)
+		if !ok || name == "" {
+			continue
+		}
+		for _, suffix := range []string{".localhost", ".local"} {
+			if _, ok := t.routes[name+suffix]; ok {
+				return name + suffix
+			}
+		}
+	}
+	return host
+}
+
+// setDomains replaces the domains routes have aliases in
+func (t *routeTable) setDomains(domains []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.domains = domains
+}
+
+// set adds or replaces the route for host
+func (t *routeTable) set(host string, route hostRoute) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.routes[host] = route
+}
+
+// remove deletes the route for host
+func (t *routeTable) remove(host string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.routes, host)
+}
+
+// liveRoutes is the route table of the running Proxy. Caddy runs a single
+// config per process, so there is at most one.
+var liveRoutes atomic.Pointer[routeTable]
+
+// currentRoute returns the live route for the request's host
+func currentRoute(r *http.Request) (hostRoute, bool) {
+	return lookupLive(requestHost(r))
+}
+
+// lookupLive returns the live route for host
+func lookupLive(host string) (hostRoute, bool) {
+	table := liveRoutes.Load()
+	if table == nil {
+		return hostRoute{}, false
+	}
+	return table.lookup(host)
+}
+
+// MatchRoute is a Caddy request matcher that matches requests to hosts
+// with a route in the running Proxy
+type MatchRoute struct{}
+
+// CaddyModule returns the Caddy module information
+func (MatchRoute) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.matchers.faa_route",
+		New: func() caddy.Module { return new(MatchRoute) },
+	}
+}
+
+// Match reports whether the request's host has a route
+func (MatchRoute) Match(r *http.Request) bool {
+	_, ok := currentRoute(r)
+	return ok
+}
+
+// MatchWithError reports whether the request's host has a route
+func (m MatchRoute) MatchWithError(r *http.Request) (bool, error) {
+	return m.Match(r), nil
+}
+
+// RouteUpstreams is a reverse proxy upstream source that sends each
+// request to the port routed for its host
+type RouteUpstreams struct{}
+
+// CaddyModule returns the Caddy module information
+func (RouteUpstreams) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.reverse_proxy.upstreams.faa",
+		New: func() caddy.Module { return new(RouteUpstreams) },
+	}
+}
+
+// GetUpstreams returns the dev server for the request's host
+func (RouteUpstreams) GetUpstreams(r *http.Request) ([]*reverseproxy.Upstream, error) {
+	route, ok := currentRoute(r)
+	if !ok {
+		// The route was removed after the request was matched
+		return nil, fmt.Errorf("no route for %s", requestHost(r))
+	}
+	return []*reverseproxy.Upstream{
+		{Dial: "localhost:" + strconv.Itoa(route.port)},
+	}, nil
+}
+
+// RoutePermission is an on-demand TLS permission module that only allows
+// certificates for routed hosts, so certificates are issued on the first
+// connection to a new route rather than on a config reload
+type RoutePermission struct{}
+
+// CaddyModule returns the Caddy module information
+func (RoutePermission) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "tls.permission.faa",
+		New: func() caddy.Module { return new(RoutePermission) },
+	}
+}
+
+// CertificateAllowed allows certificates for routed hosts
+func (RoutePermission) CertificateAllowed(ctx context.Context, name string) error {
+	if _, ok := lookupLive(strings.ToLower(name)); !ok {
+		return fmt.Errorf("%w: no route for %s", caddytls.ErrPermissionDenied, name)
+	}
+	return nil
+}
+
+// Interface guards
+var (
+	_ caddy.Module                      = (*MatchRoute)(nil)
+	_ caddyhttp.RequestMatcher          = (*MatchRoute)(nil)
+	_ caddyhttp.RequestMatcherWithError = (*MatchRoute)(nil)
+	_ reverseproxy.UpstreamSource       = (*RouteUpstreams)(nil)
+	_ caddytls.OnDemandPermission       = (*RoutePermission)(nil)
+)
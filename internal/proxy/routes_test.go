@@ -0,0 +1,221 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2/modules/caddytls"
+)
+
+// useRoutes makes routes the live route table until the test ends
+func useRoutes(t *testing.T, routes map[string]hostRoute) {
+	t.Helper()
+
+	table := newRouteTable()
+	for host, route := range routes {
+		table.set(host, route)
+	}
+	previous := liveRoutes.Swap(table)
+	t.Cleanup(func() { liveRoutes.Store(previous) })
+}
+
+func TestRouteTable(t *testing.T) {
+	table := newRouteTable()
+
+	table.set("app.localhost", hostRoute{port: 3000})
+	if route, ok := table.lookup("app.localhost"); !ok || route.port != 3000 {
+		t.Errorf("lookup() = %+v, %v; want port 3000", route, ok)
+	}
+
+	table.set("app.localhost", hostRoute{port: 3001, capture: true})
+	if route, _ := table.lookup("app.localhost"); route.port != 3001 || !route.capture {
+		t.Errorf("set() did not replace the route: %+v", route)
+	}
+
+	table.remove("app.localhost")
+	if _, ok := table.lookup("app.localhost"); ok {
+		t.Error("lookup() should fail after remove()")
+	}
+}
+
+func TestRouteTableDomains(t *testing.T) {
+	table := newRouteTable()
+	table.set("app.localhost", hostRoute{port: 3000})
+	table.set("api.local", hostRoute{port: 4000})
+
+	// Without domains, other names are not routed
+	if _, ok := table.lookup("app.test"); ok {
+		t.Error("lookup(app.test) should fail before setDomains()")
+	}
+
+	table.setDomains([]string{"test", "dev.internal"})
+	tests := map[string]string{
+		"app.test":          "app.localhost",
+		"app.dev.internal":  "app.localhost",
+		"api.test":          "api.local",
+		"app.localhost":     "app.localhost",
+		"missing.test":      "missing.test",
+		"test":              "test",
+		"app.internal":      "app.internal",
+		"app.localhost.com": "app.localhost.com",
+	}
+	for host, want := range tests {
+		if got := table.canonical(host); got != want {
+			t.Errorf("canonical(%s) = %s, want %s", host, got, want)
+		}
+	}
+	if route, ok := table.lookup("api.dev.internal"); !ok || route.port != 4000 {
+		t.Errorf("lookup(api.dev.internal) = %+v, %v; want port 4000", route, ok)
+	}
+
+	// Requests to an alias match the routed host
+	useRoutes(t, map[string]hostRoute{"app.localhost": {port: 3000}})
+	liveRoutes.Load().setDomains([]string{"test"})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "App.Test:443"
+	if got := requestHost(req); got != "app.localhost" {
+		t.Errorf("requestHost() = %s, want app.localhost", got)
+	}
+	if !(MatchRoute{}).Match(req) {
+		t.Error("MatchRoute should match an alias of a routed host")
+	}
+}
+
+func TestRouteModules(t *testing.T) {
+	useRoutes(t, map[string]hostRoute{"app.localhost": {port: 3000}})
+
+	routed := httptest.NewRequest(http.MethodGet, "/", nil)
+	routed.Host = "App.localhost:443"
+	unrouted := httptest.NewRequest(http.MethodGet, "/", nil)
+	unrouted.Host = "missing.localhost"
+
+	if !(MatchRoute{}).Match(routed) {
+		t.Error("MatchRoute should match a routed host")
+	}
+	if (MatchRoute{}).Match(unrouted) {
+		t.Error("MatchRoute should not match an unrouted host")
+	}
+
+	upstreams, err := RouteUpstreams{}.GetUpstreams(routed)
+	if err != nil || len(upstreams) != 1 || upstreams[0].Dial != "localhost:3000" {
+		t.Errorf("GetUpstreams() = %v, %v; want localhost:3000", upstreams, err)
+	}
+	if _, err := (RouteUpstreams{}).GetUpstreams(unrouted); err == nil {
+		t.Error("GetUpstreams() should fail for an unrouted host")
+	}
+
+	ctx := context.Background()
+	if err := (RoutePermission{}).CertificateAllowed(ctx, "app.localhost"); err != nil {
+		t.Errorf("CertificateAllowed() failed for a routed host: %v", err)
+	}
+	err = RoutePermission{}.CertificateAllowed(ctx, "missing.localhost")
+	if !errors.Is(err, caddytls.ErrPermissionDenied) {
+		t.Errorf("CertificateAllowed() = %v, want permission denied", err)
+	}
+}
+
+func TestRouteModulesWithoutProxy(t *testing.T) {
+	previous := liveRoutes.Swap(nil)
+	defer liveRoutes.Store(previous)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "app.localhost"
+	if (MatchRoute{}).Match(req) {
+		t.Error("Nothing should match when no proxy is running")
+	}
+}
+
+func TestRouteChangesWithoutReload(t *testing.T) {
+	// A slow upstream, to have requests in flight while routes change
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, r.Header.Get("X-Forwarded-Host"))
+	}))
+	defer upstream.Close()
+	upstreamURL, _ := url.Parse(upstream.URL)
+	port, _ := strconv.Atoi(upstreamURL.Port())
+
+	// Use unprivileged ports for testing
+	p := NewWithPorts(18093, 18456)
+	if err := p.SetRoute("app.localhost", port); err != nil {
+		t.Fatalf("SetRoute() failed: %v", err)
+	}
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer p.Stop()
+
+	// Give it a moment to initialize
+	time.Sleep(500 * time.Millisecond)
+
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, "127.0.0.1:18456")
+			},
+		},
+	}
+	get := func(host string) (string, error) {
+		resp, err := client.Get("https://" + host + "/")
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return string(body), err
+	}
+
+	// Requests to app.localhost are in flight while other routes churn
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if body, err := get("app.localhost"); err != nil || body != "app.localhost" {
+				errs <- fmt.Errorf("in-flight request: %q, %v", body, err)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	for i := 0; i < 50; i++ {
+		if err := p.SetRoute(fmt.Sprintf("project-%d.localhost", i), port); err != nil {
+			t.Fatalf("SetRoute() failed: %v", err)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// A new route is served straight away, with a certificate issued on demand
+	if body, err := get("project-49.localhost"); err != nil || body != "project-49.localhost" {
+		t.Errorf("New route: %q, %v", body, err)
+	}
+
+	// Removed routes are no longer served
+	if err := p.RemoveRoute("project-49.localhost"); err != nil {
+		t.Fatalf("RemoveRoute() failed: %v", err)
+	}
+	if body, err := get("project-49.localhost"); err == nil && body != "" {
+		t.Errorf("Removed route still proxied: %q", body)
+	}
+}
@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// streamCloseDelay is how long Caddy keeps upgraded connections of an
+// unloaded config open. Route changes don't reload the config, but other
+// settings (see SetStreamOptions) do; the delay lets clients reconnect to
+// the new config a few at a time instead of all at once. Streams of hosts
+// whose upstream changed or whose route was removed are closed right away
+// (see streamRegistry).
+const streamCloseDelay = 10 * time.Second
+
+// StreamOptions tunes how long-lived responses such as websockets and
+// server-sent events are proxied
+type StreamOptions struct {
+	// Timeout forcibly closes upgraded connections after this long.
+	// Zero means no timeout.
+	Timeout time.Duration
+
+	// FlushInterval is how often buffered response bodies are flushed to
+	// the client; a negative value flushes after every write. Server-sent
+	// events and responses of unknown length are always flushed
+	// immediately. Zero keeps Caddy's default.
+	FlushInterval time.Duration
+}
+
+// streamRegistry tracks streaming requests per host so that they can be
+// closed when the host's upstream changes, while streams to other hosts
+// survive the config reload.
+// Like requestLog it is package-level state shared by Recorder instances.
+type streamRegistry struct {
+	mu     sync.Mutex
+	nextID uint64
+	hosts  map[string]map[uint64]context.CancelFunc
+}
+
+var streams = newStreamRegistry()
+
+// newStreamRegistry creates an empty stream registry
+func newStreamRegistry() *streamRegistry {
+	return &streamRegistry{hosts: make(map[string]map[uint64]context.CancelFunc)}
+}
+
+// add tracks a stream to host that is closed by calling cancel.
+// The returned function stops tracking it.
+func (s *streamRegistry) add(host string, cancel context.CancelFunc) (remove func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	id := s.nextID
+	if s.hosts[host] == nil {
+		s.hosts[host] = make(map[uint64]context.CancelFunc)
+	}
+	s.hosts[host][id] = cancel
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.hosts[host], id)
+		if len(s.hosts[host]) == 0 {
+			delete(s.hosts, host)
+		}
+	}
+}
+
+// closeHost closes every stream to host and returns how many were closed
+func (s *streamRegistry) closeHost(host string) int {
+	s.mu.Lock()
+	cancels := s.hosts[host]
+	delete(s.hosts, host)
+	s.mu.Unlock()
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return len(cancels)
+}
+
+// closeAll closes every tracked stream
+func (s *streamRegistry) closeAll() {
+	s.mu.Lock()
+	hosts := s.hosts
+	s.hosts = make(map[string]map[uint64]context.CancelFunc)
+	s.mu.Unlock()
+
+	for _, cancels := range hosts {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+}
+
+// count returns the number of open streams to host
+func (s *streamRegistry) count(host string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.hosts[host])
+}
+
+// isStreamResponse reports whether a response with the given status and
+// headers is long-lived: a protocol switch (websocket), a websocket over
+// HTTP/2 (extended CONNECT) or server-sent events
+func isStreamResponse(r *http.Request, status int, header http.Header) bool {
+	if status == http.StatusSwitchingProtocols {
+		return true
+	}
+	if r.Method == http.MethodConnect && status == http.StatusOK {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && mediaType == "text/event-stream"
+}
@@ -0,0 +1,305 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+func TestStreamRegistry(t *testing.T) {
+	s := newStreamRegistry()
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	ctx3, cancel3 := context.WithCancel(context.Background())
+	defer cancel1()
+	defer cancel2()
+	defer cancel3()
+
+	remove1 := s.add("app.localhost", cancel1)
+	s.add("app.localhost", cancel2)
+	s.add("other.localhost", cancel3)
+
+	if n := s.count("app.localhost"); n != 2 {
+		t.Fatalf("count() = %d, want 2", n)
+	}
+
+	remove1()
+	if n := s.count("app.localhost"); n != 1 {
+		t.Errorf("count() after remove = %d, want 1", n)
+	}
+	if ctx1.Err() != nil {
+		t.Error("Removing a stream should not close it")
+	}
+
+	if n := s.closeHost("app.localhost"); n != 1 {
+		t.Errorf("closeHost() = %d, want 1", n)
+	}
+	if ctx2.Err() == nil {
+		t.Error("closeHost() should cancel the host's streams")
+	}
+	if ctx3.Err() != nil {
+		t.Error("closeHost() should leave other hosts alone")
+	}
+
+	s.closeAll()
+	if ctx3.Err() == nil || s.count("other.localhost") != 0 {
+		t.Error("closeAll() should cancel every stream")
+	}
+}
+
+func TestIsStreamResponse(t *testing.T) {
+	get := httptest.NewRequest(http.MethodGet, "/", nil)
+	connect := httptest.NewRequest(http.MethodConnect, "/", nil)
+
+	tests := []struct {
+		name        string
+		r           *http.Request
+		status      int
+		contentType string
+		want        bool
+	}{
+		{"websocket upgrade", get, http.StatusSwitchingProtocols, "", true},
+		{"websocket over HTTP/2", connect, http.StatusOK, "", true},
+		{"server-sent events", get, http.StatusOK, "text/event-stream; charset=utf-8", true},
+		{"html", get, http.StatusOK, "text/html", false},
+		{"failed connect", connect, http.StatusBadGateway, "", false},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.contentType != "" {
+			header.Set("Content-Type", tt.contentType)
+		}
+		if got := isStreamResponse(tt.r, tt.status, header); got != tt.want {
+			t.Errorf("%s: isStreamResponse() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRecorderTracksStreams(t *testing.T) {
+	host := "events.streams.localhost"
+	started := make(chan struct{})
+
+	// An event stream that only ends when the request is cancelled
+	next := caddyhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		close(started)
+		<-r.Context().Done()
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Host = host
+	done := make(chan error, 1)
+	go func() {
+		done <- Recorder{}.ServeHTTP(httptest.NewRecorder(), req, next)
+	}()
+
+	<-started
+	if n := streams.count(host); n != 1 {
+		t.Fatalf("Expected the event stream to be tracked, got %d streams", n)
+	}
+
+	streams.closeHost(host)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("closeHost() did not end the stream")
+	}
+	if n := streams.count(host); n != 0 {
+		t.Errorf("Expected no tracked streams after the request ended, got %d", n)
+	}
+}
+
+func TestRecorderIgnoresPlainResponses(t *testing.T) {
+	host := "plain.streams.localhost"
+	next := caddyhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if n := streams.count(host); n != 0 {
+			t.Errorf("Plain request tracked as a stream before writing")
+		}
+		_, err := w.Write([]byte("hello"))
+		if n := streams.count(host); n != 0 {
+			t.Errorf("Plain response tracked as a stream")
+		}
+		return err
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = host
+	if err := (Recorder{}).ServeHTTP(httptest.NewRecorder(), req, next); err != nil {
+		t.Fatalf("ServeHTTP() failed: %v", err)
+	}
+}
+
+// hmrServer is a dev server stand-in with an echoing upgrade endpoint
+// (like a websocket HMR channel) and an endless server-sent event stream
+func hmrServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" {
+			conn, brw, err := http.NewResponseController(w).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+			brw.Flush()
+			for {
+				line, err := brw.ReadString('\n')
+				if err != nil {
+					return
+				}
+				brw.WriteString("echo " + line)
+				brw.Flush()
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; ; i++ {
+			if _, err := fmt.Fprintf(w, "data: %d\n\n", i); err != nil {
+				return
+			}
+			_ = http.NewResponseController(w).Flush()
+			select {
+			case <-time.After(50 * time.Millisecond):
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+}
+
+// openUpgrade opens an upgraded connection to host through the proxy
+func openUpgrade(t *testing.T, addr, host string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"http/1.1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect to proxy: %v", err)
+	}
+	fmt.Fprintf(conn, "GET /hmr HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n", host)
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		conn.Close()
+		t.Fatalf("Failed to read upgrade response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		t.Fatalf("Upgrade status = %d, want 101", resp.StatusCode)
+	}
+	return conn, br
+}
+
+// echo sends a line over an upgraded connection and returns the reply
+func echo(conn net.Conn, br *bufio.Reader) (string, error) {
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := fmt.Fprintf(conn, "ping\n"); err != nil {
+		return "", err
+	}
+	return br.ReadString('\n')
+}
+
+// nextEvent reads the next data line of an event stream
+func nextEvent(br *bufio.Reader) (string, error) {
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(line, "data: ") {
+			return line, nil
+		}
+	}
+}
+
+func TestStreamsSurviveRouteChanges(t *testing.T) {
+	upstream := hmrServer()
+	defer upstream.Close()
+	upstreamURL, _ := url.Parse(upstream.URL)
+	port, _ := strconv.Atoi(upstreamURL.Port())
+
+	// Use unprivileged ports for testing
+	p := NewWithPorts(18092, 18455)
+	if err := p.ApplyRoutes(map[string]int{"hmr.localhost": port}); err != nil {
+		t.Fatalf("ApplyRoutes() failed: %v", err)
+	}
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer p.Stop()
+
+	// Give it a moment to initialize
+	time.Sleep(500 * time.Millisecond)
+
+	addr := "127.0.0.1:18455"
+	conn, br := openUpgrade(t, addr, "hmr.localhost")
+	defer conn.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	resp, err := client.Get("https://hmr.localhost/events")
+	if err != nil {
+		t.Fatalf("Failed to open event stream: %v", err)
+	}
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+	if _, err := nextEvent(events); err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+
+	// Another project starting reloads the whole config
+	if err := p.ApplyRoutes(map[string]int{"hmr.localhost": port, "other.localhost": 4000}); err != nil {
+		t.Fatalf("ApplyRoutes() failed: %v", err)
+	}
+
+	if reply, err := echo(conn, br); err != nil || reply != "echo ping\n" {
+		t.Errorf("Upgraded connection broken by another route: %q, %v", reply, err)
+	}
+	if _, err := nextEvent(events); err != nil {
+		t.Errorf("Event stream broken by another route: %v", err)
+	}
+
+	// Moving the route to a new port closes its streams
+	if err := p.ApplyRoutes(map[string]int{"hmr.localhost": port + 1, "other.localhost": 4000}); err != nil {
+		t.Fatalf("ApplyRoutes() failed: %v", err)
+	}
+
+	if _, err := echo(conn, br); err == nil {
+		t.Error("Upgraded connection should be closed when its upstream changes")
+	}
+	closed := make(chan error, 1)
+	go func() {
+		for {
+			if _, err := nextEvent(events); err != nil {
+				closed <- err
+				return
+			}
+		}
+	}()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Error("Event stream should be closed when its upstream changes")
+	}
+}
@@ -0,0 +1,285 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// LocalCA is the ID of the CA Caddy creates, which faa exports and trusts
+const LocalCA = "local"
+
+// certificateTimeout bounds the handshake that fetches a host's
+// certificate, which includes issuing it
+const certificateTimeout = 30 * time.Second
+
+// KeyPair names a PEM certificate file and its private key file
+type KeyPair struct {
+	Certificate string
+	Key         string
+}
+
+// TLSOptions selects where the proxy's certificates come from. By default
+// they are issued on demand by Caddy's local CA.
+type TLSOptions struct {
+	// CA is an existing CA, such as mkcert's root or a corporate
+	// development CA, that certificates are issued from instead of
+	// Caddy's local CA. Leaf certificates are signed by it directly,
+	// since such roots often forbid intermediates.
+	CA *KeyPair
+
+	// Certificates are served for the names they are valid for, instead
+	// of issuing certificates for those hosts
+	Certificates []KeyPair
+
+	// ClientAuth maps hosts to how their clients are authenticated with
+	// certificates issued by the CA (see IssueClientCertificate). A host
+	// and its .local or .localhost equivalent share a mode.
+	ClientAuth map[string]ClientAuthMode
+
+	// ClientSubjectHeader is the request header the subject of a verified
+	// client certificate is passed in, DefaultClientSubjectHeader if empty
+	ClientSubjectHeader string
+}
+
+// SetTLSOptions changes where certificates come from. The files are read
+// to check them, and again by Caddy whenever the config is loaded.
+func (p *Proxy) SetTLSOptions(opts TLSOptions) error {
+	caID := LocalCA
+	if opts.CA != nil {
+		cert, err := loadKeyPair(*opts.CA)
+		if err != nil {
+			return err
+		}
+		if err := checkCA(cert, time.Now()); err != nil {
+			return fmt.Errorf("%s: %w", opts.CA.Certificate, err)
+		}
+		caID = customCAID(cert)
+	}
+	for _, pair := range opts.Certificates {
+		if _, err := loadKeyPair(pair); err != nil {
+			return err
+		}
+	}
+	for host, mode := range opts.ClientAuth {
+		if !validClientAuthMode(mode) {
+			return fmt.Errorf("invalid client auth mode for %s: %q", host, mode)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.tls = opts
+	p.caID = caID
+	return p.reload()
+}
+
+// RootCAPath returns the path of the root certificate the proxy's
+// certificates chain to: the configured CA's or Caddy's local one
+func (p *Proxy) RootCAPath() (string, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.tls.CA != nil {
+		return p.tls.CA.Certificate, nil
+	}
+	return GetCaddyCAPath()
+}
+
+// Certificate returns the PEM certificate chain the proxy serves for host,
+// leaf first, and the PEM private key. A certificate is issued if the host
+// doesn't have one yet, which the on-demand permission only allows for
+// routed hosts.
+func (p *Proxy) Certificate(host string) (chain, key []byte, err error) {
+	p.mu.RLock()
+	running, httpsPort, caID := p.running, p.httpsPort, p.caID
+	pairs := append([]KeyPair(nil), p.tls.Certificates...)
+	p.mu.RUnlock()
+
+	if !running {
+		return nil, nil, errors.New("proxy is not running")
+	}
+
+	// A handshake makes Caddy pick a loaded certificate or issue one
+	leaf, err := servedCertificate(host, httpsPort)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, pair := range pairs {
+		chain, err := os.ReadFile(pair.Certificate)
+		if err != nil {
+			continue
+		}
+		if block, _ := pem.Decode(chain); block == nil || !bytes.Equal(block.Bytes, leaf.Raw) {
+			continue
+		}
+		key, err := os.ReadFile(pair.Key)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read key: %w", err)
+		}
+		return chain, key, nil
+	}
+	return readIssuedCertificate(caID, host, leaf)
+}
+
+// servedCertificate returns the leaf certificate the proxy listening on
+// port serves for host
+func servedCertificate(host string, port int) (*x509.Certificate, error) {
+	dialer := &net.Dialer{Timeout: certificateTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), &tls.Config{
+		ServerName: host,
+		// The certificate is being fetched, not relied on
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a certificate for %s: %w", host, err)
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate served for %s", host)
+	}
+	return certs[0], nil
+}
+
+// readIssuedCertificate reads the certificate chain and key Caddy stored
+// for host when caID issued leaf
+func readIssuedCertificate(caID, host string, leaf *x509.Certificate) (chain, key []byte, err error) {
+	caddyDataDir, err := getCaddyDataDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	dir := filepath.Join(caddyDataDir, "certificates", caID, host)
+
+	chain, err = os.ReadFile(filepath.Join(dir, host+".crt"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+	if block, _ := pem.Decode(chain); block == nil || !bytes.Equal(block.Bytes, leaf.Raw) {
+		return nil, nil, fmt.Errorf("stored certificate for %s isn't the one served", host)
+	}
+	key, err = os.ReadFile(filepath.Join(dir, host+".key"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	return chain, key, nil
+}
+
+// loadKeyPair reads a certificate and checks that the key belongs to it
+func loadKeyPair(pair KeyPair) (*x509.Certificate, error) {
+	keyPair, err := tls.LoadX509KeyPair(pair.Certificate, pair.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s and %s: %w", pair.Certificate, pair.Key, err)
+	}
+	return keyPair.Leaf, nil
+}
+
+// checkCA reports why cert can't issue certificates at now, if it can't
+func checkCA(cert *x509.Certificate, now time.Time) error {
+	if !cert.IsCA {
+		return fmt.Errorf("not a CA certificate")
+	}
+	if cert.KeyUsage != 0 && cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return fmt.Errorf("CA certificate may not sign certificates")
+	}
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("CA certificate expired on %s", cert.NotAfter.Format(time.DateOnly))
+	}
+	return nil
+}
+
+// customCAID returns the Caddy CA ID for a configured CA. It depends on
+// the certificate, so certificates issued by a previously configured CA,
+// which Caddy stores per CA, aren't served after switching.
+func customCAID(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return fmt.Sprintf("custom-%x", sum[:4])
+}
+
+// tlsAppConfig returns the config of Caddy's TLS app.
+// The caller must hold p.mu.
+func (p *Proxy) tlsAppConfig() map[string]interface{} {
+	issuer := map[string]interface{}{
+		"module": "internal",
+		"ca":     p.caID,
+	}
+	if p.tls.CA != nil {
+		issuer["sign_with_root"] = true
+	}
+
+	app := map[string]interface{}{
+		"automation": map[string]interface{}{
+			"policies": []map[string]interface{}{
+				{
+					"issuers":               []map[string]interface{}{issuer},
+					"on_demand":             true,
+					"disable_ocsp_stapling": true,
+				},
+			},
+			"on_demand": map[string]interface{}{
+				"permission": map[string]interface{}{
+					"module": "faa",
+				},
+			},
+		},
+	}
+
+	// Loaded certificates are picked by SNI before any are issued
+	if len(p.tls.Certificates) > 0 {
+		files := make([]map[string]interface{}, 0, len(p.tls.Certificates))
+		for _, pair := range p.tls.Certificates {
+			files = append(files, map[string]interface{}{
+				"certificate": pair.Certificate,
+				"key":         pair.Key,
+			})
+		}
+		app["certificates"] = map[string]interface{}{
+			"load_files": files,
+		}
+	}
+	return app
+}
+
+// pkiAppConfig returns the config of Caddy's PKI app with the configured
+// CA, or nil when Caddy's local CA is used. Client authentication trusts
+// the CA through the PKI app, so the local CA is then configured
+// explicitly, with Caddy's defaults.
+// The caller must hold p.mu.
+func (p *Proxy) pkiAppConfig() map[string]interface{} {
+	if p.tls.CA == nil {
+		if len(p.tls.ClientAuth) == 0 {
+			return nil
+		}
+		return map[string]interface{}{
+			"certificate_authorities": map[string]interface{}{
+				LocalCA: map[string]interface{}{},
+			},
+		}
+	}
+	return map[string]interface{}{
+		"certificate_authorities": map[string]interface{}{
+			p.caID: map[string]interface{}{
+				"name": "faa configured CA",
+				"root": map[string]interface{}{
+					"certificate": p.tls.CA.Certificate,
+					"private_key": p.tls.CA.Key,
+					"format":      "pem_file",
+				},
+				// The CA is trusted already; that's why it is used
+				"install_trust": false,
+			},
+		},
+	}
+}
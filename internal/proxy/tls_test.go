@@ -0,0 +1,254 @@
+package proxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestKeyPair writes a self-signed certificate and its key to dir
+func writeTestKeyPair(t *testing.T, dir, name string, isCA bool) KeyPair {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	pair := KeyPair{
+		Certificate: filepath.Join(dir, name+".pem"),
+		Key:         filepath.Join(dir, name+"-key.pem"),
+	}
+	if err := os.WriteFile(pair.Certificate, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(pair.Key, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return pair
+}
+
+func TestSetTLSOptionsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	ca := writeTestKeyPair(t, dir, "ca", true)
+	leaf := writeTestKeyPair(t, dir, "app.localhost", false)
+
+	tests := []struct {
+		name string
+		opts TLSOptions
+	}{
+		{"missing CA", TLSOptions{CA: &KeyPair{Certificate: filepath.Join(dir, "missing.pem"), Key: ca.Key}}},
+		{"mismatched key", TLSOptions{CA: &KeyPair{Certificate: ca.Certificate, Key: leaf.Key}}},
+		{"not a CA", TLSOptions{CA: &leaf}},
+		{"missing certificate", TLSOptions{Certificates: []KeyPair{{Certificate: leaf.Certificate, Key: filepath.Join(dir, "missing.pem")}}}},
+		{"unknown client auth mode", TLSOptions{ClientAuth: map[string]ClientAuthMode{"app.localhost": "sometimes"}}},
+	}
+	for _, tt := range tests {
+		p := New()
+		if err := p.SetTLSOptions(tt.opts); err == nil {
+			t.Errorf("SetTLSOptions() should fail for %s", tt.name)
+		}
+		if p.caID != LocalCA {
+			t.Errorf("caID = %q after a failed SetTLSOptions() for %s, want %q", p.caID, tt.name, LocalCA)
+		}
+	}
+}
+
+func TestSetTLSOptions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	ca := writeTestKeyPair(t, dir, "ca", true)
+	leaf := writeTestKeyPair(t, dir, "app.localhost", false)
+
+	p := New()
+	if err := p.SetTLSOptions(TLSOptions{CA: &ca, Certificates: []KeyPair{leaf}}); err != nil {
+		t.Fatalf("SetTLSOptions() failed: %v", err)
+	}
+	if path, err := p.RootCAPath(); err != nil || path != ca.Certificate {
+		t.Errorf("RootCAPath() = %q, %v, want %q", path, err, ca.Certificate)
+	}
+	if err := p.RotateCA(); !errors.Is(err, ErrConfiguredCA) {
+		t.Errorf("RotateCA() = %v, want ErrConfiguredCA", err)
+	}
+
+	configJSON, err := p.buildConfigJSON()
+	if err != nil {
+		t.Fatalf("buildConfigJSON() failed: %v", err)
+	}
+	var config struct {
+		Apps struct {
+			TLS struct {
+				Automation struct {
+					Policies []struct {
+						Issuers []struct {
+							CA           string `json:"ca"`
+							SignWithRoot bool   `json:"sign_with_root"`
+						} `json:"issuers"`
+					} `json:"policies"`
+				} `json:"automation"`
+				Certificates struct {
+					LoadFiles []struct {
+						Certificate string `json:"certificate"`
+						Key         string `json:"key"`
+					} `json:"load_files"`
+				} `json:"certificates"`
+			} `json:"tls"`
+			PKI struct {
+				CAs map[string]struct {
+					Root struct {
+						Certificate string `json:"certificate"`
+						PrivateKey  string `json:"private_key"`
+					} `json:"root"`
+					InstallTrust *bool `json:"install_trust"`
+				} `json:"certificate_authorities"`
+			} `json:"pki"`
+		} `json:"apps"`
+	}
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	issuer := config.Apps.TLS.Automation.Policies[0].Issuers[0]
+	if issuer.CA != p.caID || !issuer.SignWithRoot {
+		t.Errorf("issuer = %+v, want CA %s signing with its root", issuer, p.caID)
+	}
+	authority, ok := config.Apps.PKI.CAs[p.caID]
+	if !ok {
+		t.Fatalf("Expected CA %s in the pki app, got %v", p.caID, config.Apps.PKI.CAs)
+	}
+	if authority.Root.Certificate != ca.Certificate || authority.Root.PrivateKey != ca.Key {
+		t.Errorf("root = %+v, want %+v", authority.Root, ca)
+	}
+	if authority.InstallTrust == nil || *authority.InstallTrust {
+		t.Error("Expected Caddy not to install the configured CA")
+	}
+	files := config.Apps.TLS.Certificates.LoadFiles
+	if len(files) != 1 || files[0].Certificate != leaf.Certificate || files[0].Key != leaf.Key {
+		t.Errorf("load_files = %+v, want %+v", files, leaf)
+	}
+
+	// Another CA gets its own ID, so certificates issued by the previous
+	// one aren't reused
+	previous := p.caID
+	other := writeTestKeyPair(t, t.TempDir(), "ca", true)
+	if err := p.SetTLSOptions(TLSOptions{CA: &other}); err != nil {
+		t.Fatalf("SetTLSOptions() failed: %v", err)
+	}
+	if p.caID == previous {
+		t.Errorf("Expected a new CA ID for another CA, got %s again", p.caID)
+	}
+
+	if err := p.SetTLSOptions(TLSOptions{}); err != nil {
+		t.Fatalf("SetTLSOptions() failed: %v", err)
+	}
+	if p.caID != LocalCA {
+		t.Errorf("caID = %q, want %q", p.caID, LocalCA)
+	}
+	if caddyPath, _ := GetCaddyCAPath(); caddyPath == "" {
+		t.Error("GetCaddyCAPath() returned an empty path")
+	} else if path, err := p.RootCAPath(); err != nil || path != caddyPath {
+		t.Errorf("RootCAPath() = %q, %v, want %q", path, err, caddyPath)
+	}
+}
+
+func TestCertificateWhenStopped(t *testing.T) {
+	p := New()
+	if _, _, err := p.Certificate("app.localhost"); err == nil {
+		t.Error("Expected an error while the proxy isn't running")
+	}
+}
+
+func TestReadIssuedCertificate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dataDir, err := getCaddyDataDir()
+	if err != nil {
+		t.Fatalf("getCaddyDataDir() failed: %v", err)
+	}
+	dir := filepath.Join(dataDir, "certificates", LocalCA, "app.localhost")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatalf("Failed to create %s: %v", dir, err)
+	}
+	pair := writeTestKeyPair(t, t.TempDir(), "app.localhost", false)
+	for src, dst := range map[string]string{pair.Certificate: "app.localhost.crt", pair.Key: "app.localhost.key"} {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", src, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, dst), data, 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", dst, err)
+		}
+	}
+	leaf, err := loadKeyPair(pair)
+	if err != nil {
+		t.Fatalf("loadKeyPair() failed: %v", err)
+	}
+
+	chain, key, err := readIssuedCertificate(LocalCA, "app.localhost", leaf)
+	if err != nil {
+		t.Fatalf("readIssuedCertificate() failed: %v", err)
+	}
+	if wantKey, _ := os.ReadFile(pair.Key); string(key) != string(wantKey) {
+		t.Error("readIssuedCertificate() returned another key")
+	}
+	if block, _ := pem.Decode(chain); block == nil || string(block.Bytes) != string(leaf.Raw) {
+		t.Error("readIssuedCertificate() returned another certificate")
+	}
+
+	// A stale stored certificate isn't returned for the one served
+	other, err := loadKeyPair(writeTestKeyPair(t, t.TempDir(), "app.localhost", false))
+	if err != nil {
+		t.Fatalf("loadKeyPair() failed: %v", err)
+	}
+	if _, _, err := readIssuedCertificate(LocalCA, "app.localhost", other); err == nil {
+		t.Error("Expected an error when the stored certificate differs")
+	}
+	if _, _, err := readIssuedCertificate("custom-00000000", "app.localhost", leaf); err == nil {
+		t.Error("Expected an error for another CA's certificates")
+	}
+}
+
+func TestCheckCA(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		cert  *x509.Certificate
+		valid bool
+	}{
+		{"CA", &x509.Certificate{IsCA: true, NotAfter: now.Add(time.Hour)}, true},
+		{"CA with cert sign", &x509.Certificate{IsCA: true, KeyUsage: x509.KeyUsageCertSign, NotAfter: now.Add(time.Hour)}, true},
+		{"leaf", &x509.Certificate{NotAfter: now.Add(time.Hour)}, false},
+		{"no cert sign", &x509.Certificate{IsCA: true, KeyUsage: x509.KeyUsageDigitalSignature, NotAfter: now.Add(time.Hour)}, false},
+		{"expired", &x509.Certificate{IsCA: true, NotAfter: now.Add(-time.Hour)}, false},
+	}
+	for _, tt := range tests {
+		if err := checkCA(tt.cert, now); (err == nil) != tt.valid {
+			t.Errorf("checkCA() for %s = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/sahithyandev/faa/internal/har"
+	"github.com/sahithyandev/faa/internal/proxy"
+)
+
+// maxDiffLines bounds the size of the line diff; larger bodies are only
+// reported as different
+const maxDiffLines = 2000
+
+// ignoredHeaders change on every response, or are added by Caddy rather
+// than the dev server, and are left out of the diff
+var ignoredHeaders = map[string]bool{
+	"Alt-Svc": true,
+	"Date":    true,
+	"Server":  true,
+}
+
+// Op is the kind of a diff line
+type Op string
+
+const (
+	OpEqual  Op = " "
+	OpRemove Op = "-"
+	OpAdd    Op = "+"
+)
+
+// Line is one line of a diff
+type Line struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// Diff describes how a replayed response differs from the captured one
+type Diff struct {
+	StatusBefore int `json:"statusBefore"`
+	StatusAfter  int `json:"statusAfter"`
+
+	// Headers lists removed and added header lines; unchanged headers are omitted
+	Headers []Line `json:"headers,omitempty"`
+
+	// Body is a line diff of the bodies, or nil if they are equal.
+	// BodyNote explains when the bodies could not be compared line by line.
+	Body     []Line `json:"body,omitempty"`
+	BodyNote string `json:"bodyNote,omitempty"`
+}
+
+// Changed reports whether the responses differ
+func (d *Diff) Changed() bool {
+	return d.StatusBefore != d.StatusAfter || len(d.Headers) > 0 || d.Body != nil || d.BodyNote != ""
+}
+
+// Compare diffs the captured response of exchange against a replay result
+func Compare(exchange *proxy.Exchange, result *Result) *Diff {
+	diff := &Diff{
+		StatusBefore: exchange.Status,
+		StatusAfter:  result.Status,
+		Headers:      diffHeaders(exchange.ResponseHeaders, result.Headers),
+	}
+
+	before, beforeOK := har.DecodeBody(exchange.ResponseHeaders, exchange.ResponseBody, exchange.ResponseBodyTruncated)
+	after, afterOK := har.DecodeBody(result.Headers, result.Body, result.BodyTruncated)
+	if bytes.Equal(before, after) {
+		return diff
+	}
+
+	beforeText := beforeOK && har.IsText(exchange.ResponseHeaders.Get("Content-Type"), before)
+	afterText := afterOK && har.IsText(result.Headers.Get("Content-Type"), after)
+	if !beforeText || !afterText {
+		diff.BodyNote = "binary bodies differ"
+		return diff
+	}
+
+	beforeLines := bodyLines(before)
+	afterLines := bodyLines(after)
+	if len(beforeLines) > maxDiffLines || len(afterLines) > maxDiffLines {
+		diff.BodyNote = "bodies differ (too large to compare line by line)"
+		return diff
+	}
+
+	lines := diffLines(beforeLines, afterLines)
+	for _, line := range lines {
+		if line.Op != OpEqual {
+			diff.Body = lines
+			break
+		}
+	}
+	return diff
+}
+
+// bodyLines splits a body into lines, pretty-printing JSON first so that
+// compact documents produce a useful diff
+func bodyLines(body []byte) []string {
+	var pretty bytes.Buffer
+	if json.Valid(body) && json.Indent(&pretty, body, "", "  ") == nil {
+		body = pretty.Bytes()
+	}
+	text := strings.TrimRight(string(body), "\r\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// diffHeaders returns the header lines only present in before or after
+func diffHeaders(before, after http.Header) []Line {
+	beforeLines := headerLines(before)
+	afterLines := headerLines(after)
+
+	var lines []Line
+	for _, line := range beforeLines {
+		if !containsLine(afterLines, line) {
+			lines = append(lines, Line{Op: OpRemove, Text: line})
+		}
+	}
+	for _, line := range afterLines {
+		if !containsLine(beforeLines, line) {
+			lines = append(lines, Line{Op: OpAdd, Text: line})
+		}
+	}
+	return lines
+}
+
+// headerLines flattens headers into sorted "Name: value" lines
+func headerLines(headers http.Header) []string {
+	var lines []string
+	for name, values := range headers {
+		if ignoredHeaders[name] {
+			continue
+		}
+		for _, value := range values {
+			lines = append(lines, name+": "+value)
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// containsLine reports whether lines contains line
+func containsLine(lines []string, line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+// diffLines computes a line diff using the longest common subsequence
+func diffLines(a, b []string) []Line {
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []Line
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, Line{Op: OpEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Op: OpRemove, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, Line{Op: OpAdd, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, Line{Op: OpRemove, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, Line{Op: OpAdd, Text: b[j]})
+	}
+	return lines
+}
@@ -0,0 +1,86 @@
+package replay
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	lines := diffLines([]string{"a", "b", "c"}, []string{"a", "x", "c", "d"})
+
+	want := []Line{
+		{OpEqual, "a"},
+		{OpRemove, "b"},
+		{OpAdd, "x"},
+		{OpEqual, "c"},
+		{OpAdd, "d"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("diffLines() = %v, want %v", lines, want)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %v, want %v", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestCompareUnchanged(t *testing.T) {
+	exchange := testExchange()
+	exchange.ResponseHeaders.Set("Date", "Mon, 01 Jan 2024 00:00:00 GMT")
+
+	diff := Compare(exchange, &Result{
+		Status:  http.StatusOK,
+		Headers: http.Header{"Content-Type": {"application/json"}, "Date": {"Tue, 02 Jan 2024 00:00:00 GMT"}},
+		Body:    []byte(`{"received":true}`),
+	})
+	if diff.Changed() {
+		t.Errorf("Expected no changes, got %+v", diff)
+	}
+}
+
+func TestCompareChanged(t *testing.T) {
+	diff := Compare(testExchange(), &Result{
+		Status:  http.StatusInternalServerError,
+		Headers: http.Header{"Content-Type": {"application/json"}, "X-Error": {"1"}},
+		Body:    []byte(`{"received":false}`),
+	})
+
+	if !diff.Changed() || diff.StatusBefore != 200 || diff.StatusAfter != 500 {
+		t.Errorf("Status change not reported: %+v", diff)
+	}
+	if len(diff.Headers) != 1 || diff.Headers[0] != (Line{OpAdd, "X-Error: 1"}) {
+		t.Errorf("Headers = %v", diff.Headers)
+	}
+
+	// JSON bodies are pretty-printed before diffing
+	wantBody := []Line{
+		{OpEqual, "{"},
+		{OpRemove, `  "received": true`},
+		{OpAdd, `  "received": false`},
+		{OpEqual, "}"},
+	}
+	if len(diff.Body) != len(wantBody) {
+		t.Fatalf("Body = %v, want %v", diff.Body, wantBody)
+	}
+	for i := range wantBody {
+		if diff.Body[i] != wantBody[i] {
+			t.Errorf("body line %d = %v, want %v", i, diff.Body[i], wantBody[i])
+		}
+	}
+}
+
+func TestCompareBinary(t *testing.T) {
+	exchange := testExchange()
+	exchange.ResponseHeaders = http.Header{"Content-Type": {"image/png"}}
+	exchange.ResponseBody = []byte{1, 2, 3}
+
+	diff := Compare(exchange, &Result{
+		Status:  http.StatusOK,
+		Headers: http.Header{"Content-Type": {"image/png"}},
+		Body:    []byte{4, 5, 6},
+	})
+	if diff.Body != nil || diff.BodyNote == "" {
+		t.Errorf("Binary bodies should only be noted as different: %+v", diff)
+	}
+}
@@ -0,0 +1,170 @@
+// Package replay re-sends requests captured by the proxy to a dev server and
+// compares the new response with the one that was captured.
+//
+// Requests are sent straight to the upstream port, the same way the proxy's
+// reverse_proxy handler forwards them, so replaying does not need the CA to
+// be trusted and is not itself captured.
+package replay
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sahithyandev/faa/internal/proxy"
+)
+
+const (
+	// maxResponseBody is the maximum number of response body bytes kept,
+	// matching what the proxy captures
+	maxResponseBody = 64 * 1024
+
+	// DefaultTimeout is how long to wait for the dev server to respond
+	DefaultTimeout = 30 * time.Second
+)
+
+// hopHeaders are connection-specific headers that must not be forwarded.
+// Content-Length is recomputed from the body that is sent.
+var hopHeaders = []string{
+	"Connection",
+	"Content-Length",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Edits are changes applied to a captured request before it is replayed
+type Edits struct {
+	// Method replaces the request method when set
+	Method string
+
+	// SetHeaders replaces the named headers; an empty value removes the header
+	SetHeaders []Header
+
+	// Body replaces the request body when set
+	Body []byte
+}
+
+// Header is a single header edit
+type Header struct {
+	Name  string
+	Value string
+}
+
+// ParseHeader parses a "Name: value" header argument. "Name:" with no value
+// removes the header, as with curl.
+func ParseHeader(s string) (Header, error) {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return Header{}, fmt.Errorf("invalid header %q: expected \"Name: value\"", s)
+	}
+	return Header{Name: http.CanonicalHeaderKey(name), Value: strings.TrimSpace(value)}, nil
+}
+
+// Result is the response to a replayed request
+type Result struct {
+	Status        int         `json:"status"`
+	Headers       http.Header `json:"headers"`
+	Body          []byte      `json:"body,omitempty"`
+	BodySize      int64       `json:"bodySize"`
+	BodyTruncated bool        `json:"bodyTruncated,omitempty"`
+	DurationMs    float64     `json:"durationMs"`
+}
+
+// NewRequest builds the request to replay exchange against the dev server
+// listening on port
+func NewRequest(exchange *proxy.Exchange, port int, edits Edits) (*http.Request, error) {
+	body := exchange.RequestBody
+	if edits.Body != nil {
+		body = edits.Body
+	} else if exchange.RequestBodyTruncated {
+		return nil, fmt.Errorf("the captured request body was truncated at %d of %d bytes; supply a body to replay it",
+			len(exchange.RequestBody), exchange.RequestBodySize)
+	}
+
+	method := exchange.Method
+	if edits.Method != "" {
+		method = strings.ToUpper(edits.Method)
+	}
+
+	url := fmt.Sprintf("http://localhost:%d%s", port, exchange.URL)
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	req.Header = exchange.RequestHeaders.Clone()
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	for _, name := range hopHeaders {
+		req.Header.Del(name)
+	}
+
+	// Match the headers the proxy sets when forwarding (see proxy.buildConfigJSON)
+	req.Host = "localhost"
+	req.Header.Set("X-Forwarded-Host", exchange.Host)
+	req.Header.Set("X-Forwarded-Proto", "https")
+
+	for _, h := range edits.SetHeaders {
+		if h.Value == "" {
+			req.Header.Del(h.Name)
+			continue
+		}
+		if h.Name == "Host" {
+			req.Host = h.Value
+			continue
+		}
+		req.Header.Set(h.Name, h.Value)
+	}
+
+	return req, nil
+}
+
+// Send sends req and reads the response. Redirects are not followed, so the
+// result can be compared with the captured response.
+func Send(req *http.Request, timeout time.Duration) (*Result, error) {
+	client := &http.Client{
+		Timeout: timeout,
+		// The captured response is the upstream's, so do the same
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		// A fresh transport keeps Accept-Encoding exactly as captured
+		Transport: &http.Transport{DisableCompression: true},
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	rest, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return &Result{
+		Status:        resp.StatusCode,
+		Headers:       resp.Header,
+		Body:          body,
+		BodySize:      int64(len(body)) + rest,
+		BodyTruncated: rest > 0,
+		DurationMs:    float64(time.Since(start).Microseconds()) / 1000,
+	}, nil
+}
@@ -0,0 +1,143 @@
+package replay
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sahithyandev/faa/internal/proxy"
+)
+
+// testExchange returns a captured webhook delivery
+func testExchange() *proxy.Exchange {
+	return &proxy.Exchange{
+		ID:     1,
+		Host:   "shop.localhost",
+		Method: http.MethodPost,
+		URL:    "/webhooks/stripe?attempt=1",
+		RequestHeaders: http.Header{
+			"Content-Type":     {"application/json"},
+			"Stripe-Signature": {"t=1,v1=abc"},
+			"Connection":       {"keep-alive"},
+			"Content-Length":   {"11"},
+		},
+		RequestBody:     []byte(`{"id":"ev"}`),
+		RequestBodySize: 11,
+		Status:          http.StatusOK,
+		ResponseHeaders: http.Header{"Content-Type": {"application/json"}},
+		ResponseBody:    []byte(`{"received":true}`),
+	}
+}
+
+// serverPort returns the port of a test server
+func serverPort(t *testing.T, server *httptest.Server) int {
+	t.Helper()
+	_, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("SplitHostPort() failed: %v", err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		t.Fatalf("Atoi() failed: %v", err)
+	}
+	return port
+}
+
+func TestParseHeader(t *testing.T) {
+	h, err := ParseHeader("x-custom:  value: with colon ")
+	if err != nil {
+		t.Fatalf("ParseHeader() failed: %v", err)
+	}
+	if h.Name != "X-Custom" || h.Value != "value: with colon" {
+		t.Errorf("ParseHeader() = %+v", h)
+	}
+
+	if h, err := ParseHeader("Cookie:"); err != nil || h.Value != "" {
+		t.Errorf("ParseHeader(\"Cookie:\") = %+v, %v; want removal", h, err)
+	}
+
+	for _, s := range []string{"novalue", ": value", "bad name: v"} {
+		if _, err := ParseHeader(s); err == nil {
+			t.Errorf("ParseHeader(%q) should fail", s)
+		}
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	req, err := NewRequest(testExchange(), 3000, Edits{
+		SetHeaders: []Header{
+			{Name: "Stripe-Signature", Value: "t=2,v1=def"},
+			{Name: "Content-Type", Value: ""},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewRequest() failed: %v", err)
+	}
+
+	if req.URL.String() != "http://localhost:3000/webhooks/stripe?attempt=1" {
+		t.Errorf("URL = %s", req.URL)
+	}
+	if req.Host != "localhost" || req.Header.Get("X-Forwarded-Host") != "shop.localhost" {
+		t.Errorf("Host = %s, X-Forwarded-Host = %s", req.Host, req.Header.Get("X-Forwarded-Host"))
+	}
+	if req.Header.Get("Stripe-Signature") != "t=2,v1=def" {
+		t.Errorf("Header edit not applied: %v", req.Header)
+	}
+	if _, ok := req.Header["Content-Type"]; ok {
+		t.Error("Empty header value should remove the header")
+	}
+	if _, ok := req.Header["Connection"]; ok {
+		t.Error("Hop-by-hop headers should not be replayed")
+	}
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != `{"id":"ev"}` {
+		t.Errorf("Body = %s", body)
+	}
+}
+
+func TestNewRequestTruncatedBody(t *testing.T) {
+	exchange := testExchange()
+	exchange.RequestBodyTruncated = true
+	exchange.RequestBodySize = 100000
+
+	if _, err := NewRequest(exchange, 3000, Edits{}); err == nil {
+		t.Error("NewRequest() should refuse to replay a truncated body")
+	}
+	if _, err := NewRequest(exchange, 3000, Edits{Body: []byte("{}")}); err != nil {
+		t.Errorf("NewRequest() with a replacement body failed: %v", err)
+	}
+}
+
+func TestSend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPut || string(body) != "edited" {
+			t.Errorf("Server received %s %q", r.Method, body)
+		}
+		w.Header().Set("Location", "/elsewhere")
+		w.WriteHeader(http.StatusFound)
+		_, _ = w.Write([]byte(strings.Repeat("x", maxResponseBody+5)))
+	}))
+	defer server.Close()
+
+	req, err := NewRequest(testExchange(), serverPort(t, server), Edits{Method: "put", Body: []byte("edited")})
+	if err != nil {
+		t.Fatalf("NewRequest() failed: %v", err)
+	}
+	result, err := Send(req, DefaultTimeout)
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+
+	// Redirects are reported, not followed
+	if result.Status != http.StatusFound {
+		t.Errorf("Status = %d, want %d", result.Status, http.StatusFound)
+	}
+	if len(result.Body) != maxResponseBody || !result.BodyTruncated || result.BodySize != maxResponseBody+5 {
+		t.Errorf("Body = %d bytes of %d, truncated = %v", len(result.Body), result.BodySize, result.BodyTruncated)
+	}
+}
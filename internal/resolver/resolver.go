@@ -0,0 +1,185 @@
+// Package resolver is a small DNS server answering for development domains
+// other than .localhost, such as .test, with the loopback addresses.
+//
+// Browsers resolve *.localhost themselves, but curl, Go, Python and most
+// other tools ask the system resolver, which only knows other domains if a
+// server answers for them. The system resolver is pointed at this one for
+// the configured domains only: through systemd-resolved's split DNS on
+// Linux and /etc/resolver on macOS (see the setup package).
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// ttl is the lifetime of answers in seconds. They never change, but a
+// short one keeps a removed domain from lingering in caches.
+const ttl = 60
+
+var (
+	loopbackV4 = net.IPv4(127, 0, 0, 1)
+	loopbackV6 = net.IPv6loopback
+)
+
+// Server answers A and AAAA queries for every name under its domains with
+// 127.0.0.1 and ::1. Other names are refused, so it can't be used as a
+// general resolver.
+type Server struct {
+	domains []string // fully qualified, lower case
+
+	mu      sync.Mutex
+	servers []*dns.Server
+	addr    string
+}
+
+// New creates a server for domains, such as "test" or "dev.internal"
+func New(domains []string) *Server {
+	s := &Server{}
+	for _, domain := range domains {
+		s.domains = append(s.domains, dns.Fqdn(strings.ToLower(domain)))
+	}
+	return s
+}
+
+// Start listens on addr over UDP and TCP and serves queries until Stop is
+// called. A port of 0 picks a free one for both; Addr returns it.
+func (s *Server) Start(addr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.servers != nil {
+		return errors.New("DNS server already started")
+	}
+
+	packetConn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s/udp: %w", addr, err)
+	}
+	// TCP gets the same port, which matters when addr asked for any
+	udpAddr := packetConn.LocalAddr().(*net.UDPAddr)
+	listener, err := net.Listen("tcp", net.JoinHostPort(udpAddr.IP.String(), fmt.Sprint(udpAddr.Port)))
+	if err != nil {
+		packetConn.Close()
+		return fmt.Errorf("failed to listen on %s/tcp: %w", addr, err)
+	}
+
+	servers := []*dns.Server{
+		{PacketConn: packetConn, Handler: s},
+		{Listener: listener, Handler: s},
+	}
+	// Wait until both serve, since a server can't be shut down before it does
+	started := make(chan struct{}, len(servers))
+	failed := make(chan error, len(servers))
+	for _, server := range servers {
+		server.NotifyStartedFunc = func() { started <- struct{}{} }
+		go func(server *dns.Server) {
+			if err := server.ActivateAndServe(); err != nil {
+				failed <- err
+				slog.Warn("DNS server stopped", "error", err)
+			}
+		}(server)
+	}
+	for range servers {
+		select {
+		case <-started:
+		case err := <-failed:
+			packetConn.Close()
+			listener.Close()
+			return fmt.Errorf("failed to serve DNS on %s: %w", addr, err)
+		}
+	}
+
+	s.servers = servers
+	s.addr = listener.Addr().String()
+	return nil
+}
+
+// Addr returns the address the server listens on, or "" before Start
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addr
+}
+
+// Stop closes the listeners
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for _, server := range s.servers {
+		if err := server.Shutdown(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.servers = nil
+	s.addr = ""
+	return errors.Join(errs...)
+}
+
+// zone returns the domain name belongs to, or "" if it is outside them
+func (s *Server) zone(name string) string {
+	name = strings.ToLower(name)
+	for _, domain := range s.domains {
+		if name == domain || strings.HasSuffix(name, "."+domain) {
+			return domain
+		}
+	}
+	return ""
+}
+
+// ServeDNS answers a query
+func (s *Server) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	_ = w.WriteMsg(s.answer(req))
+}
+
+// answer builds the reply to req
+func (s *Server) answer(req *dns.Msg) *dns.Msg {
+	reply := new(dns.Msg)
+	if req.Opcode != dns.OpcodeQuery || len(req.Question) != 1 {
+		return reply.SetRcode(req, dns.RcodeNotImplemented)
+	}
+	question := req.Question[0]
+	zone := s.zone(question.Name)
+	if zone == "" || question.Qclass != dns.ClassINET {
+		return reply.SetRcode(req, dns.RcodeRefused)
+	}
+
+	reply.SetReply(req)
+	reply.Authoritative = true
+	header := dns.RR_Header{Name: question.Name, Class: dns.ClassINET, Ttl: ttl}
+	if question.Qtype == dns.TypeA || question.Qtype == dns.TypeANY {
+		header.Rrtype = dns.TypeA
+		reply.Answer = append(reply.Answer, &dns.A{Hdr: header, A: loopbackV4})
+	}
+	if question.Qtype == dns.TypeAAAA || question.Qtype == dns.TypeANY {
+		header.Rrtype = dns.TypeAAAA
+		reply.Answer = append(reply.Answer, &dns.AAAA{Hdr: header, AAAA: loopbackV6})
+	}
+	// Other types have no records; the SOA lets resolvers cache that
+	if len(reply.Answer) == 0 {
+		reply.Ns = append(reply.Ns, soa(zone))
+	}
+	return reply
+}
+
+// soa returns the start of authority record of zone
+func soa(zone string) *dns.SOA {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: ttl},
+		Ns:      "ns." + zone,
+		Mbox:    "hostmaster." + zone,
+		Serial:  1,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  ttl,
+	}
+}
@@ -0,0 +1,91 @@
+package resolver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// query sends a question for name to the server over network
+func query(t *testing.T, s *Server, network, name string, qtype uint16) *dns.Msg {
+	t.Helper()
+	req := new(dns.Msg)
+	req.SetQuestion(dns.Fqdn(name), qtype)
+	client := &dns.Client{Net: network}
+	reply, _, err := client.Exchange(req, s.Addr())
+	if err != nil {
+		t.Fatalf("%s query for %s failed: %v", network, name, err)
+	}
+	return reply
+}
+
+func TestServer(t *testing.T) {
+	s := New([]string{"test", "Dev.Internal"})
+	if err := s.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer s.Stop()
+
+	for _, network := range []string{"udp", "tcp"} {
+		reply := query(t, s, network, "my-app.TEST", dns.TypeA)
+		if reply.Rcode != dns.RcodeSuccess || !reply.Authoritative || len(reply.Answer) != 1 {
+			t.Fatalf("%s: reply = %v, want one authoritative answer", network, reply)
+		}
+		if a, ok := reply.Answer[0].(*dns.A); !ok || !a.A.Equal(net.IPv4(127, 0, 0, 1)) {
+			t.Errorf("%s: answer = %v, want 127.0.0.1", network, reply.Answer[0])
+		}
+	}
+
+	reply := query(t, s, "udp", "api.dev.internal", dns.TypeAAAA)
+	if len(reply.Answer) != 1 {
+		t.Fatalf("AAAA reply = %v, want one answer", reply)
+	}
+	if aaaa, ok := reply.Answer[0].(*dns.AAAA); !ok || !aaaa.AAAA.Equal(net.IPv6loopback) {
+		t.Errorf("answer = %v, want ::1", reply.Answer[0])
+	}
+
+	if reply := query(t, s, "udp", "a.b.test", dns.TypeANY); len(reply.Answer) != 2 {
+		t.Errorf("ANY reply has %d answers, want 2", len(reply.Answer))
+	}
+
+	// Names without addresses of a type have an empty answer and the SOA
+	reply = query(t, s, "udp", "my-app.test", dns.TypeMX)
+	if reply.Rcode != dns.RcodeSuccess || len(reply.Answer) != 0 || len(reply.Ns) != 1 {
+		t.Errorf("MX reply = %v, want no answers and an SOA", reply)
+	}
+	if soa, ok := reply.Ns[0].(*dns.SOA); !ok || soa.Hdr.Name != "test." {
+		t.Errorf("authority = %v, want the SOA of test.", reply.Ns[0])
+	}
+
+	for _, name := range []string{"example.com", "mytest", "internal"} {
+		if reply := query(t, s, "udp", name, dns.TypeA); reply.Rcode != dns.RcodeRefused {
+			t.Errorf("rcode for %s = %s, want REFUSED", name, dns.RcodeToString[reply.Rcode])
+		}
+	}
+}
+
+func TestServerStartTwice(t *testing.T) {
+	s := New([]string{"test"})
+	if err := s.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	addr := s.Addr()
+	if err := s.Start("127.0.0.1:0"); err == nil {
+		t.Error("Expected a second Start() to fail")
+	}
+
+	// Another server can't take the port
+	other := New([]string{"test"})
+	if err := other.Start(addr); err == nil {
+		other.Stop()
+		t.Errorf("Expected Start() on %s to fail while it is in use", addr)
+	}
+
+	if err := s.Stop(); err != nil {
+		t.Errorf("Stop() failed: %v", err)
+	}
+	if s.Addr() != "" {
+		t.Errorf("Addr() = %q after Stop(), want empty", s.Addr())
+	}
+}
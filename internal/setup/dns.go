@@ -0,0 +1,194 @@
+package setup
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sahithyandev/faa/internal/config"
+)
+
+const (
+	// resolvedDropInPath points systemd-resolved at faa's DNS server for
+	// the configured domains
+	resolvedDropInPath = "/etc/systemd/resolved.conf.d/faa.conf"
+
+	// resolverDir holds the per-domain resolver files on macOS
+	resolverDir = "/etc/resolver"
+)
+
+// dnsServerAddress returns the address the system resolver reaches the DNS
+// server at: its listen address, with the loopback one for a wildcard
+func dnsServerAddress(listen string) (ip, port string) {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		host, port, _ = net.SplitHostPort(config.DefaultDNSListen)
+	}
+	if parsed := net.ParseIP(host); parsed == nil || parsed.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	return host, port
+}
+
+// resolvedDropIn returns the systemd-resolved configuration sending
+// queries for the domains, and only those, to faa's DNS server
+func resolvedDropIn(cfg config.DNSConfig) string {
+	ip, port := dnsServerAddress(cfg.Listen)
+	routes := make([]string, 0, len(cfg.Domains))
+	for _, domain := range cfg.Domains {
+		routes = append(routes, "~"+domain)
+	}
+	return fmt.Sprintf(`# Written by faa setup; resolves the dns.domains in faa's config.json
+[Resolve]
+DNS=%s
+Domains=%s
+`, net.JoinHostPort(ip, port), strings.Join(routes, " "))
+}
+
+// resolverFile returns the macOS resolver file sending queries for a domain
+// to faa's DNS server
+func resolverFile(cfg config.DNSConfig) string {
+	ip, port := dnsServerAddress(cfg.Listen)
+	return fmt.Sprintf("# Written by faa setup\nnameserver %s\nport %s\n", ip, port)
+}
+
+// dnsConfig loads the DNS settings, reporting the step as skipped when no
+// domains are configured
+func (r *runner) dnsConfig() (config.DNSConfig, bool, error) {
+	r.begin(StepDNS)
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "Checking DNS for custom domains...")
+
+	cfg, err := config.Load()
+	if err != nil {
+		return config.DNSConfig{}, false, err
+	}
+	if len(cfg.DNS.Domains) == 0 {
+		fmt.Fprintln(r.out, "✓ No custom domains in dns.domains; .localhost needs no DNS setup")
+		r.result(StatusSkipped, "no domains in dns.domains")
+		return cfg.DNS, false, nil
+	}
+	return cfg.DNS, true, nil
+}
+
+// checkResolved points systemd-resolved at the DNS server for the
+// configured domains with a drop-in file
+func (r *runner) checkResolved() error {
+	cfg, ok, err := r.dnsConfig()
+	if err != nil || !ok {
+		return err
+	}
+	domains := strings.Join(cfg.Domains, ", ")
+
+	if _, err := os.Stat(r.path("/run/systemd/resolve")); err != nil || !systemdAvailable() {
+		ip, port := dnsServerAddress(cfg.Listen)
+		fmt.Fprintln(r.out, "⚠ systemd-resolved is not running")
+		fmt.Fprintf(r.out, "  Point your resolver at %s for %s, e.g. with dnsmasq:\n", net.JoinHostPort(ip, port), domains)
+		for _, domain := range cfg.Domains {
+			fmt.Fprintf(r.out, "    server=/%s/%s#%s\n", domain, ip, port)
+		}
+		r.result(StatusMissing, "systemd-resolved is not running")
+		return nil
+	}
+
+	content := resolvedDropIn(cfg)
+	if current, err := os.ReadFile(r.path(resolvedDropInPath)); err == nil && string(current) == content {
+		fmt.Fprintf(r.out, "✓ systemd-resolved sends %s queries to faa\n", domains)
+		r.result(StatusOK, "systemd-resolved is configured by "+resolvedDropInPath)
+		return nil
+	}
+
+	if r.opts.Check {
+		r.result(StatusMissing, "systemd-resolved doesn't resolve "+domains)
+		r.suggest("sudo mkdir -p " + filepath.Dir(resolvedDropInPath))
+		r.suggest(fmt.Sprintf("printf %q | sudo tee %s", content, resolvedDropInPath))
+		r.suggest("sudo systemctl restart systemd-resolved")
+		return nil
+	}
+
+	fmt.Fprintf(r.out, "This writes %s:\n", resolvedDropInPath)
+	fmt.Fprintln(r.out)
+	fmt.Fprint(r.out, content)
+	fmt.Fprintln(r.out)
+	if !r.confirm("Send "+domains+" queries to faa?", false) {
+		fmt.Fprintln(r.out, "Skipped.")
+		r.result(StatusMissing, "systemd-resolved doesn't resolve "+domains)
+		return nil
+	}
+
+	if err := r.sudo("", "mkdir", "-p", filepath.Dir(resolvedDropInPath)); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(resolvedDropInPath), err)
+	}
+	if err := r.sudo(content, "tee", resolvedDropInPath); err != nil {
+		return fmt.Errorf("failed to write %s: %w", resolvedDropInPath, err)
+	}
+	r.record(Change{Kind: changeResolved, Path: resolvedDropInPath})
+	if err := r.sudo("", "systemctl", "restart", "systemd-resolved"); err != nil {
+		return fmt.Errorf("failed to restart systemd-resolved: %w", err)
+	}
+
+	fmt.Fprintf(r.out, "✓ systemd-resolved sends %s queries to faa\n", domains)
+	r.result(StatusChanged, "systemd-resolved configured by "+resolvedDropInPath)
+	return nil
+}
+
+// checkResolverFiles adds a file to /etc/resolver for each configured
+// domain, which macOS sends that domain's queries to
+func (r *runner) checkResolverFiles() error {
+	cfg, ok, err := r.dnsConfig()
+	if err != nil || !ok {
+		return err
+	}
+
+	content := resolverFile(cfg)
+	var missing []string
+	for _, domain := range cfg.Domains {
+		path := filepath.Join(resolverDir, domain)
+		if current, err := os.ReadFile(r.path(path)); err == nil && string(current) == content {
+			continue
+		}
+		missing = append(missing, path)
+	}
+	if len(missing) == 0 {
+		fmt.Fprintf(r.out, "✓ %s queries go to faa\n", strings.Join(cfg.Domains, ", "))
+		r.result(StatusOK, "resolver files are in "+resolverDir)
+		return nil
+	}
+
+	detail := "missing " + strings.Join(missing, ", ")
+	if r.opts.Check {
+		r.result(StatusMissing, detail)
+		r.suggest("sudo mkdir -p " + resolverDir)
+		for _, path := range missing {
+			r.suggest(fmt.Sprintf("printf %q | sudo tee %s", content, path))
+		}
+		return nil
+	}
+
+	fmt.Fprintln(r.out, "This writes:")
+	for _, path := range missing {
+		fmt.Fprintf(r.out, "  %s\n", path)
+	}
+	fmt.Fprintln(r.out)
+	if !r.confirm("Send these domains' queries to faa?", false) {
+		fmt.Fprintln(r.out, "Skipped.")
+		r.result(StatusMissing, detail)
+		return nil
+	}
+
+	if err := r.sudo("", "mkdir", "-p", resolverDir); err != nil {
+		return fmt.Errorf("failed to create %s: %w", resolverDir, err)
+	}
+	for _, path := range missing {
+		if err := r.sudo(content, "tee", path); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		r.record(Change{Kind: changeResolver, Path: path})
+	}
+
+	fmt.Fprintf(r.out, "✓ %s queries go to faa\n", strings.Join(cfg.Domains, ", "))
+	r.result(StatusChanged, "resolver files written to "+resolverDir)
+	return nil
+}
@@ -0,0 +1,94 @@
+package setup
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sahithyandev/faa/internal/config"
+)
+
+func TestDNSServerAddress(t *testing.T) {
+	tests := map[string][2]string{
+		"127.0.0.1:9053": {"127.0.0.1", "9053"},
+		"0.0.0.0:53":     {"127.0.0.1", "53"},
+		"[::]:5353":      {"127.0.0.1", "5353"},
+		"[::1]:9053":     {"::1", "9053"},
+		"":               {"127.0.0.1", "9053"},
+	}
+	for listen, want := range tests {
+		if ip, port := dnsServerAddress(listen); ip != want[0] || port != want[1] {
+			t.Errorf("dnsServerAddress(%q) = %s, %s; want %s, %s", listen, ip, port, want[0], want[1])
+		}
+	}
+}
+
+func TestResolverConfigs(t *testing.T) {
+	cfg := config.DNSConfig{Domains: []string{"test", "dev.internal"}, Listen: "127.0.0.1:9053"}
+
+	want := "# Written by faa setup; resolves the dns.domains in faa's config.json\n" +
+		"[Resolve]\nDNS=127.0.0.1:9053\nDomains=~test ~dev.internal\n"
+	if got := resolvedDropIn(cfg); got != want {
+		t.Errorf("resolvedDropIn() = %q, want %q", got, want)
+	}
+
+	want = "# Written by faa setup\nnameserver 127.0.0.1\nport 9053\n"
+	if got := resolverFile(cfg); got != want {
+		t.Errorf("resolverFile() = %q, want %q", got, want)
+	}
+}
+
+func TestCheckResolverFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	root := t.TempDir()
+
+	check := func() *Step {
+		t.Helper()
+		r := newRunner(Options{Check: true, Output: io.Discard})
+		r.root = root
+		if err := r.checkResolverFiles(); err != nil {
+			t.Fatalf("checkResolverFiles() failed: %v", err)
+		}
+		return r.step
+	}
+
+	// Nothing to do without custom domains
+	if step := check(); step.Status != StatusSkipped {
+		t.Errorf("status without domains = %q, want %q", step.Status, StatusSkipped)
+	}
+
+	configPath := filepath.Join(home, ".config", "faa", "config.json")
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		t.Fatalf("Failed to create config directory: %v", err)
+	}
+	if err := os.WriteFile(configPath, []byte(`{"dns": {"domains": ["test", "dev.internal"]}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	// One file is in place; the other is missing
+	dir := filepath.Join(root, resolverDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create resolver directory: %v", err)
+	}
+	content := resolverFile(config.DNSConfig{Listen: config.DefaultDNSListen})
+	if err := os.WriteFile(filepath.Join(dir, "test"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write resolver file: %v", err)
+	}
+	step := check()
+	if step.Status != StatusMissing {
+		t.Fatalf("status = %q, want %q", step.Status, StatusMissing)
+	}
+	// mkdir and one tee
+	if len(step.Actions) != 2 {
+		t.Errorf("actions = %q, want the commands writing /etc/resolver/dev.internal", step.Actions)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "dev.internal"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write resolver file: %v", err)
+	}
+	if step := check(); step.Status != StatusOK {
+		t.Errorf("status = %q, want %q with every file in place", step.Status, StatusOK)
+	}
+}
@@ -0,0 +1,136 @@
+package setup
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/sahithyandev/faa/internal/config"
+	"github.com/sahithyandev/faa/internal/hostsfile"
+)
+
+// sudoersPath lets the daemon run 'faa hosts apply' as root without a
+// password
+const sudoersPath = "/etc/sudoers.d/faa-hosts"
+
+// sudoersEscaper escapes the characters sudoers treats specially in a
+// command
+var sudoersEscaper = strings.NewReplacer(`\`, `\\`, " ", `\ `, ",", `\,`, ":", `\:`, "=", `\=`)
+
+// sudoersRule returns the sudoers file allowing username to run the hosts
+// helper of the faa binary at executable as root without a password. The
+// arguments are part of the rule, so no other faa command is allowed.
+// The rule is only safe for a binary that only root can replace (see
+// checkRootOwned).
+func sudoersRule(username, executable string) string {
+	return fmt.Sprintf("# Written by faa setup; lets the faa daemon update its block in %s\n%s ALL=(root) NOPASSWD: %s hosts apply\n",
+		hostsfile.Path, username, sudoersEscaper.Replace(executable))
+}
+
+// hostsHelperAllowed reports whether sudo runs the hosts helper without a
+// password
+func hostsHelperAllowed(executable string) bool {
+	return exec.Command("sudo", "-n", "-l", executable, "hosts", "apply").Run() == nil
+}
+
+// checkHostsHelper lets the daemon update the block in /etc/hosts through
+// sudo, if hosts.enabled is set
+func (r *runner) checkHostsHelper() error {
+	r.begin(StepHosts)
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "Checking /etc/hosts updates...")
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if !cfg.Hosts.Enabled {
+		fmt.Fprintln(r.out, "✓ hosts.enabled is off; faa doesn't change /etc/hosts")
+		r.result(StatusSkipped, "hosts.enabled is off")
+		return nil
+	}
+
+	executable, err := executablePath()
+	if err != nil {
+		return err
+	}
+	if os.Geteuid() == 0 || hostsfile.Writable(hostsfile.Path) {
+		fmt.Fprintf(r.out, "✓ %s is writable\n", hostsfile.Path)
+		if !r.opts.Check {
+			// Uninstalling removes the block the daemon adds
+			r.record(Change{Kind: changeHostsBlock, Path: hostsfile.Path})
+		}
+		r.result(StatusOK, hostsfile.Path+" is writable")
+		return nil
+	}
+	if hostsHelperAllowed(executable) {
+		fmt.Fprintln(r.out, "✓ The daemon can update /etc/hosts through sudo")
+		r.result(StatusOK, "sudo allows 'faa hosts apply' without a password")
+		return nil
+	}
+	// Anyone who could replace the binary would get root through the rule
+	if err := checkRootOwned(executable); err != nil {
+		fmt.Fprintf(r.out, "✗ Not letting sudo run %s without a password: %v\n", executable, err)
+		fmt.Fprintln(r.out, "  Install faa where only root can change it and run 'faa setup' from there")
+		r.result(StatusMissing, "the daemon can't update "+hostsfile.Path+"; "+executable+" can be changed by users other than root")
+		r.suggest(fmt.Sprintf("sudo install -o root -m 0755 %s /usr/local/bin/faa", executable))
+		return nil
+	}
+	current, err := user.Current()
+	if err != nil {
+		return fmt.Errorf("failed to get the current user: %w", err)
+	}
+	rule := sudoersRule(current.Username, executable)
+
+	if r.opts.Check {
+		r.result(StatusMissing, "the daemon can't update "+hostsfile.Path)
+		r.suggest(fmt.Sprintf("printf %q | sudo tee %s", rule, sudoersPath))
+		r.suggest("sudo chmod 0440 " + sudoersPath)
+		return nil
+	}
+
+	fmt.Fprintf(r.out, "This writes %s, which lets the daemon run only 'faa hosts apply' as root:\n", sudoersPath)
+	fmt.Fprintln(r.out)
+	fmt.Fprint(r.out, rule)
+	fmt.Fprintln(r.out)
+	if !r.confirm("Let the daemon update /etc/hosts?", false) {
+		fmt.Fprintln(r.out, "Skipped. 'faa hosts sync' updates it when run by hand.")
+		r.result(StatusMissing, "the daemon can't update "+hostsfile.Path)
+		return nil
+	}
+
+	// sudoers is checked before it is installed, since a broken file
+	// locks everyone out of sudo
+	temp, err := os.CreateTemp("", "faa-hosts-sudoers-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.WriteString(rule); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := r.sudo("", "visudo", "-cf", temp.Name()); err != nil {
+		return fmt.Errorf("the sudoers rule is invalid: %w", err)
+	}
+	if err := r.sudo("", "mkdir", "-p", filepath.Dir(sudoersPath)); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(sudoersPath), err)
+	}
+	if err := r.sudo("", "install", "-m", "0440", temp.Name(), sudoersPath); err != nil {
+		return fmt.Errorf("failed to write %s: %w", sudoersPath, err)
+	}
+	r.record(Change{Kind: changeSudoers, Path: sudoersPath})
+	// Uninstalling also removes the block the daemon adds
+	r.record(Change{Kind: changeHostsBlock, Path: hostsfile.Path})
+
+	fmt.Fprintln(r.out, "✓ The daemon can update /etc/hosts through sudo")
+	fmt.Fprintln(r.out, "  Restart it with 'faa stop' if it was started before hosts.enabled was set")
+	r.result(StatusChanged, "sudo allows 'faa hosts apply' without a password")
+	return nil
+}
@@ -0,0 +1,66 @@
+package setup
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sahithyandev/faa/internal/hostsfile"
+)
+
+func TestSudoersRule(t *testing.T) {
+	want := "# Written by faa setup; lets the faa daemon update its block in /etc/hosts\n" +
+		"alice ALL=(root) NOPASSWD: /usr/local/bin/faa hosts apply\n"
+	if got := sudoersRule("alice", "/usr/local/bin/faa"); got != want {
+		t.Errorf("sudoersRule() = %q, want %q", got, want)
+	}
+
+	// Characters sudoers treats specially in commands are escaped
+	got := sudoersRule("alice", "/opt/my tools/faa,v2")
+	if want := `alice ALL=(root) NOPASSWD: /opt/my\ tools/faa\,v2 hosts apply` + "\n"; got[len(got)-len(want):] != want {
+		t.Errorf("sudoersRule() = %q, want it to end with %q", got, want)
+	}
+}
+
+func TestCheckHostsHelperDisabled(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	r := newRunner(Options{Check: true, Output: io.Discard})
+	if err := r.checkHostsHelper(); err != nil {
+		t.Fatalf("checkHostsHelper() failed: %v", err)
+	}
+	if r.step.Name != StepHosts || r.step.Status != StatusSkipped {
+		t.Errorf("step = %+v, want %s skipped without hosts.enabled", r.step, StepHosts)
+	}
+}
+
+func TestCheckHostsHelperRefusesWritableBinary(t *testing.T) {
+	if os.Geteuid() == 0 || hostsfile.Writable(hostsfile.Path) {
+		t.Skip("the hosts file is writable without sudo")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	configPath := filepath.Join(home, ".config", "faa", "config.json")
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		t.Fatalf("Failed to create config directory: %v", err)
+	}
+	if err := os.WriteFile(configPath, []byte(`{"hosts": {"enabled": true}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	// The test binary is built in a temporary directory anyone can write to
+	r := newRunner(Options{Check: true, Output: io.Discard})
+	if err := r.checkHostsHelper(); err != nil {
+		t.Fatalf("checkHostsHelper() failed: %v", err)
+	}
+	if r.step.Status != StatusMissing || !strings.Contains(r.step.Detail, "users other than root") {
+		t.Errorf("step = %+v, want the sudo rule refused", r.step)
+	}
+	for _, action := range r.step.Actions {
+		if strings.Contains(action, sudoersPath) {
+			t.Errorf("Suggested installing the sudo rule: %q", action)
+		}
+	}
+}
@@ -0,0 +1,589 @@
+package setup
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sahithyandev/faa/internal/daemon"
+	"github.com/sahithyandev/faa/internal/hostsfile"
+)
+
+// Kinds of system change recorded in the setup manifest
+const (
+	changeCAAnchor     = "ca-anchor"
+	changeTrustAnchor  = "trust-anchor"
+	changeKeychain     = "keychain"
+	changeSetcap       = "setcap"
+	changeSysctl       = "sysctl"
+	changeNftables     = "nftables"
+	changeIptables     = "iptables"
+	changeLaunchDaemon = "launchdaemon"
+	changeSystemdUnit  = "systemd-unit"
+	changeNSS          = "nss"
+	changeJava         = "java"
+	changeResolved     = "resolved"
+	changeResolver     = "resolver"
+	changeSudoers      = "sudoers"
+	changeHostsBlock   = "hosts-block"
+)
+
+const (
+	// systemKeychain is the macOS keychain setup adds the CA to
+	systemKeychain = "/Library/Keychains/System.keychain"
+
+	// launchDaemonPath is the LaunchDaemon plist setup installs on macOS
+	launchDaemonPath = "/Library/LaunchDaemons/dev.localhost-dev.plist"
+)
+
+// Change is a system change made by setup, with what is needed to undo it.
+// The manifest is writable by the user, so it holds only data; the commands
+// undoing a change are built by undoSteps, which refuses paths setup doesn't
+// write to.
+type Change struct {
+	Kind string `json:"kind"`
+
+	// Path is the file the change installed or modified
+	Path string `json:"path,omitempty"`
+
+	// Rule names the iptables rule that was added (see iptablesRuleID)
+	Rule string `json:"rule,omitempty"`
+
+	// Previous is the value the change replaced, such as the earlier
+	// ip_unprivileged_port_start
+	Previous string `json:"previous,omitempty"`
+
+	// Fingerprint is the SHA-1 fingerprint of a certificate added to a
+	// keychain
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	Time time.Time `json:"time"`
+}
+
+// Manifest lists the system changes made by setup, oldest first
+type Manifest struct {
+	Changes []Change `json:"changes"`
+}
+
+// ManifestPath returns the path of the setup manifest
+// (~/.config/faa/setup-manifest.json)
+func ManifestPath() (string, error) {
+	configDir, err := daemon.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "setup-manifest.json"), nil
+}
+
+// LoadManifest loads the setup manifest, which is empty if setup hasn't
+// changed anything
+func LoadManifest() (*Manifest, error) {
+	path, err := ManifestPath()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return manifest, nil
+		}
+		return nil, fmt.Errorf("failed to read setup manifest: %w", err)
+	}
+	if len(data) == 0 {
+		return manifest, nil
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse setup manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// saveManifest saves the setup manifest with an atomic write, removing it
+// once no changes are left
+func saveManifest(manifest *Manifest) error {
+	path, err := ManifestPath()
+	if err != nil {
+		return err
+	}
+	if len(manifest.Changes) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove setup manifest: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal setup manifest: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write temp setup manifest: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("failed to rename setup manifest: %w", err)
+	}
+	return nil
+}
+
+// addChange adds a change to the manifest. A change that is already
+// recorded keeps its first entry, which knows the state before setup.
+func addChange(change Change) error {
+	manifest, err := LoadManifest()
+	if err != nil {
+		return err
+	}
+	for _, existing := range manifest.Changes {
+		if existing.key() == change.key() {
+			return nil
+		}
+	}
+	if change.Time.IsZero() {
+		change.Time = time.Now()
+	}
+	manifest.Changes = append(manifest.Changes, change)
+	return saveManifest(manifest)
+}
+
+// key identifies a change, so running setup again doesn't record it twice
+func (c Change) key() string {
+	return strings.Join([]string{c.Kind, c.Path, c.Rule}, "\x00")
+}
+
+// Description describes the change for the uninstall listing
+func (c Change) Description() string {
+	switch c.Kind {
+	case changeCAAnchor:
+		return fmt.Sprintf("CA certificate installed to %s", c.Path)
+	case changeTrustAnchor:
+		return fmt.Sprintf("CA certificate %s added as a p11-kit trust anchor", c.Fingerprint)
+	case changeKeychain:
+		return fmt.Sprintf("CA certificate %s added to %s", c.Fingerprint, c.Path)
+	case changeSetcap:
+		return fmt.Sprintf("cap_net_bind_service set on %s", c.Path)
+	case changeSysctl:
+		return fmt.Sprintf("net.ipv4.ip_unprivileged_port_start=80 saved to %s", c.Path)
+	case changeNftables:
+		return fmt.Sprintf("nftables port forwarding rules in %s", c.Path)
+	case changeIptables:
+		iptables, rule, err := parseIptablesRuleID(c.Rule)
+		if err != nil {
+			return fmt.Sprintf("iptables nat rule %q", c.Rule)
+		}
+		return fmt.Sprintf("%s nat rule %s", iptables, strings.Join(rule, " "))
+	case changeLaunchDaemon:
+		return fmt.Sprintf("LaunchDaemon %s", c.Path)
+	case changeSystemdUnit:
+		return fmt.Sprintf("systemd user unit %s", c.Path)
+	case changeNSS:
+		return fmt.Sprintf("CA certificate %q added to NSS database %s", nssNickname, c.Path)
+	case changeJava:
+		return fmt.Sprintf("CA certificate %q added to Java keystore %s", javaAlias, c.Path)
+	case changeResolved:
+		return fmt.Sprintf("systemd-resolved configuration %s", c.Path)
+	case changeResolver:
+		return fmt.Sprintf("resolver file %s", c.Path)
+	case changeSudoers:
+		return fmt.Sprintf("sudo rule for 'faa hosts apply' in %s", c.Path)
+	case changeHostsBlock:
+		return fmt.Sprintf("faa's block in %s", c.Path)
+	default:
+		return fmt.Sprintf("unknown change %q", c.Kind)
+	}
+}
+
+// undoStep is a command undoing part of a change
+type undoStep struct {
+	args []string
+	sudo bool
+
+	// optional steps may fail, for example when a unit is already
+	// disabled or iptables rules were lost on reboot
+	optional bool
+}
+
+// String returns the step as a shell command
+func (s undoStep) String() string {
+	words := make([]string, 0, len(s.args)+1)
+	if s.sudo {
+		words = append(words, "sudo")
+	}
+	for _, arg := range s.args {
+		if arg == "" || strings.ContainsAny(arg, " \t\"'$\\") {
+			arg = fmt.Sprintf("%q", arg)
+		}
+		words = append(words, arg)
+	}
+	return strings.Join(words, " ")
+}
+
+// run runs the step, as root if it needs to be
+func (s undoStep) run(r *runner) error {
+	if s.sudo {
+		return r.sudo("", s.args...)
+	}
+	return r.command(s.args[0], s.args[1:]...).Run()
+}
+
+// undoSteps returns the commands undoing the change. Commands are built
+// here from what the change is, never taken from the manifest, and paths
+// are checked against where setup makes changes, since the steps run as
+// root.
+func (c Change) undoSteps() ([]undoStep, error) {
+	switch c.Kind {
+	case changeCAAnchor:
+		update, ok := anchorUpdate(c.Path)
+		if !ok {
+			return nil, c.unexpectedPath()
+		}
+		return []undoStep{
+			{args: []string{"rm", "-f", c.Path}, sudo: true},
+			{args: []string{update}, sudo: true},
+		}, nil
+	case changeTrustAnchor:
+		if !validFingerprint(c.Fingerprint) {
+			return nil, fmt.Errorf("invalid certificate fingerprint %q", c.Fingerprint)
+		}
+		// Only the recorded certificate may be removed from the anchors
+		if fingerprint, err := certificateSHA1(c.Path); err != nil || fingerprint != c.Fingerprint {
+			return nil, fmt.Errorf("%s is not the certificate %s", c.Path, c.Fingerprint)
+		}
+		return []undoStep{
+			{args: []string{"trust", "anchor", "--remove", c.Path}, sudo: true},
+		}, nil
+	case changeKeychain:
+		if c.Path != systemKeychain {
+			return nil, c.unexpectedPath()
+		}
+		if !validFingerprint(c.Fingerprint) {
+			return nil, fmt.Errorf("invalid certificate fingerprint %q", c.Fingerprint)
+		}
+		return []undoStep{
+			{args: []string{"security", "delete-certificate", "-t", "-Z", c.Fingerprint, c.Path}, sudo: true},
+		}, nil
+	case changeSetcap:
+		// Only this binary's capability is removed
+		if executable, err := executablePath(); err != nil || c.Path != executable {
+			return nil, c.unexpectedPath()
+		}
+		// The capability is already gone if the binary was replaced
+		return []undoStep{
+			{args: []string{"setcap", "-r", c.Path}, sudo: true, optional: true},
+		}, nil
+	case changeSysctl:
+		if c.Path != sysctlConfPath {
+			return nil, c.unexpectedPath()
+		}
+		steps := []undoStep{{args: []string{"rm", "-f", c.Path}, sudo: true}}
+		if c.Previous != "" {
+			previous, err := strconv.Atoi(c.Previous)
+			if err != nil || previous < 0 || previous > 65535 {
+				return nil, fmt.Errorf("invalid ip_unprivileged_port_start %q", c.Previous)
+			}
+			steps = append(steps, undoStep{
+				args: []string{"sysctl", "-w", "net.ipv4.ip_unprivileged_port_start=" + strconv.Itoa(previous)},
+				sudo: true,
+			})
+		}
+		return steps, nil
+	case changeNftables:
+		if c.Path != forwardRulesPath {
+			return nil, c.unexpectedPath()
+		}
+		return []undoStep{
+			{args: []string{"nft", "delete", "table", "inet", "faa"}, sudo: true, optional: true},
+			{args: []string{"rm", "-f", c.Path}, sudo: true},
+		}, nil
+	case changeIptables:
+		iptables, rule, err := parseIptablesRuleID(c.Rule)
+		if err != nil {
+			return nil, err
+		}
+		return []undoStep{
+			{args: append([]string{iptables, "-t", "nat", "-D"}, rule...), sudo: true, optional: true},
+		}, nil
+	case changeLaunchDaemon:
+		if c.Path != launchDaemonPath {
+			return nil, c.unexpectedPath()
+		}
+		return []undoStep{
+			{args: []string{"launchctl", "unload", "-w", c.Path}, sudo: true, optional: true},
+			{args: []string{"rm", "-f", c.Path, "/var/log/faa-daemon.log", "/var/log/faa-daemon-error.log"}, sudo: true},
+		}, nil
+	case changeSystemdUnit:
+		unit := filepath.Base(c.Path)
+		if unit != systemdService && unit != systemdSocket {
+			return nil, c.unexpectedPath()
+		}
+		return []undoStep{
+			{args: []string{"systemctl", "--user", "disable", "--now", unit}, optional: true},
+			{args: []string{"rm", "-f", c.Path}},
+			{args: []string{"systemctl", "--user", "daemon-reload"}, optional: true},
+		}, nil
+	case changeNSS:
+		// The profile may have been deleted since
+		return []undoStep{
+			{args: []string{"certutil", "-D", "-d", "sql:" + c.Path, "-n", nssNickname}, optional: true},
+		}, nil
+	case changeJava:
+		keytool, ok := jdkKeytool(c.Path)
+		if !ok {
+			return nil, c.unexpectedPath()
+		}
+		sudo := javaStoreNeedsRoot(c.Path)
+		if sudo {
+			if err := checkRootOwned(keytool); err != nil {
+				return nil, fmt.Errorf("refusing to run %s as root: %w", keytool, err)
+			}
+		}
+		// The keystore is replaced when the JDK is upgraded
+		return []undoStep{
+			{args: keytoolArgs(keytool, c.Path, "-delete"), sudo: sudo, optional: true},
+		}, nil
+	case changeResolved:
+		if c.Path != resolvedDropInPath {
+			return nil, c.unexpectedPath()
+		}
+		return []undoStep{
+			{args: []string{"rm", "-f", c.Path}, sudo: true},
+			{args: []string{"systemctl", "restart", "systemd-resolved"}, sudo: true, optional: true},
+		}, nil
+	case changeResolver:
+		if filepath.Dir(c.Path) != resolverDir || filepath.Clean(c.Path) != c.Path || strings.HasPrefix(filepath.Base(c.Path), ".") {
+			return nil, c.unexpectedPath()
+		}
+		return []undoStep{
+			{args: []string{"rm", "-f", c.Path}, sudo: true},
+		}, nil
+	case changeSudoers:
+		if c.Path != sudoersPath {
+			return nil, c.unexpectedPath()
+		}
+		return []undoStep{
+			{args: []string{"rm", "-f", c.Path}, sudo: true},
+		}, nil
+	case changeHostsBlock:
+		if c.Path != hostsfile.Path {
+			return nil, c.unexpectedPath()
+		}
+		// The block is removed by this binary, the one being run
+		executable, err := executablePath()
+		if err != nil {
+			return nil, err
+		}
+		return []undoStep{
+			{args: []string{executable, "hosts", "clean"}, sudo: true},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown change %q", c.Kind)
+	}
+}
+
+// unexpectedPath returns the error for a change whose path isn't one setup
+// makes that kind of change to
+func (c Change) unexpectedPath() error {
+	return fmt.Errorf("unexpected path %q for a %s change", c.Path, c.Kind)
+}
+
+// validFingerprint reports whether fingerprint is a SHA-1 fingerprint as
+// certificateSHA1 returns it
+func validFingerprint(fingerprint string) bool {
+	if len(fingerprint) != 2*sha1.Size {
+		return false
+	}
+	for _, c := range fingerprint {
+		if !(c >= '0' && c <= '9' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// checkRootOwned returns an error unless path and every directory above it
+// are owned by root and writable by no one else, so that only root can
+// change what runs from path
+func checkRootOwned(path string) error {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	for dir := resolved; ; dir = filepath.Dir(dir) {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); !ok || stat.Uid != 0 {
+			return fmt.Errorf("%s is not owned by root", dir)
+		}
+		if info.Mode().Perm()&0022 != 0 {
+			return fmt.Errorf("%s is writable by group or others", dir)
+		}
+		if dir == filepath.Dir(dir) {
+			return nil
+		}
+	}
+}
+
+// Uninstall undoes the system changes recorded by setup, newest first.
+// With the Check option it only lists them and the commands that would undo
+// them.
+func Uninstall(opts Options) error {
+	r := newRunner(opts)
+
+	manifest, err := LoadManifest()
+	if err != nil {
+		return err
+	}
+	if len(manifest.Changes) == 0 {
+		fmt.Fprintln(r.out, "No system changes recorded by 'faa setup'; nothing to undo.")
+		return nil
+	}
+
+	fmt.Fprintln(r.out, "System changes made by 'faa setup':")
+	for i := len(manifest.Changes) - 1; i >= 0; i-- {
+		change := manifest.Changes[i]
+		fmt.Fprintf(r.out, "  - %s\n", change.Description())
+		steps, err := change.undoSteps()
+		if err != nil {
+			fmt.Fprintf(r.out, "      (%v)\n", err)
+			continue
+		}
+		for _, step := range steps {
+			fmt.Fprintf(r.out, "      %s\n", step)
+		}
+	}
+	fmt.Fprintln(r.out)
+
+	if opts.Check {
+		fmt.Fprintln(r.out, "Dry run; nothing was changed.")
+		return nil
+	}
+
+	if !r.confirm("Undo these changes?", false) {
+		fmt.Fprintln(r.out, "Cancelled.")
+		return nil
+	}
+
+	// Changes that couldn't be undone stay in the manifest for another try
+	var remaining []Change
+	for i := len(manifest.Changes) - 1; i >= 0; i-- {
+		change := manifest.Changes[i]
+		if err := r.undoChange(change); err != nil {
+			fmt.Fprintf(r.out, "✗ %s: %v\n", change.Description(), err)
+			remaining = append([]Change{change}, remaining...)
+			continue
+		}
+		fmt.Fprintf(r.out, "✓ Removed %s\n", change.Description())
+	}
+
+	manifest.Changes = remaining
+	if err := saveManifest(manifest); err != nil {
+		return err
+	}
+	if len(remaining) > 0 {
+		return fmt.Errorf("%d change(s) could not be undone; fix the errors above and run 'faa setup --uninstall' again", len(remaining))
+	}
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "✓ Uninstall complete!")
+	return nil
+}
+
+// RemoveCA removes the CA certificate at oldCertPath from the trust stores
+// setup added it to, so that a rotated CA can be trusted in its place.
+// Trust store entries are found by name, except in keychains and p11-kit,
+// where only the old certificate's entries are removed.
+func RemoveCA(oldCertPath string, opts Options) error {
+	r := newRunner(opts)
+
+	fingerprint, err := certificateSHA1(oldCertPath)
+	if err != nil {
+		return err
+	}
+	manifest, err := LoadManifest()
+	if err != nil {
+		return err
+	}
+
+	var remaining []Change
+	var failed int
+	for _, change := range manifest.Changes {
+		if !change.trustsCA(fingerprint) {
+			remaining = append(remaining, change)
+			continue
+		}
+
+		undo := change
+		if undo.Kind == changeTrustAnchor {
+			// The exported root has been replaced by now
+			undo.Path = oldCertPath
+		}
+		if err := r.undoChange(undo); err != nil {
+			fmt.Fprintf(r.out, "✗ %s: %v\n", change.Description(), err)
+			remaining = append(remaining, change)
+			failed++
+			continue
+		}
+		fmt.Fprintf(r.out, "✓ Removed %s\n", change.Description())
+	}
+
+	manifest.Changes = remaining
+	if err := saveManifest(manifest); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("the old CA could not be removed from %d trust store(s)", failed)
+	}
+	return nil
+}
+
+// trustsCA reports whether the change added the CA certificate with the
+// SHA-1 fingerprint to a trust store
+func (c Change) trustsCA(fingerprint string) bool {
+	switch c.Kind {
+	case changeCAAnchor, changeNSS, changeJava:
+		return true
+	case changeKeychain, changeTrustAnchor:
+		return c.Fingerprint == fingerprint
+	default:
+		return false
+	}
+}
+
+// undoChange runs the steps undoing a change
+func (r *runner) undoChange(change Change) error {
+	steps, err := change.undoSteps()
+	if err != nil {
+		return err
+	}
+	for _, step := range steps {
+		if err := step.run(r); err != nil && !step.optional {
+			return fmt.Errorf("%s failed: %w", step, err)
+		}
+	}
+	return nil
+}
+
+// certificateSHA1 returns the uppercase hex SHA-1 fingerprint of the PEM
+// certificate at path, as the macOS security tool prints it
+func certificateSHA1(path string) (string, error) {
+	der, err := certificateDER(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum(der)
+	return strings.ToUpper(hex.EncodeToString(sum[:])), nil
+}
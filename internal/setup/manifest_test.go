@@ -0,0 +1,371 @@
+package setup
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddChange(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	manifest, err := LoadManifest()
+	if err != nil {
+		t.Fatalf("LoadManifest() failed: %v", err)
+	}
+	if len(manifest.Changes) != 0 {
+		t.Fatalf("Expected no changes, got %d", len(manifest.Changes))
+	}
+
+	changes := []Change{
+		{Kind: changeSysctl, Path: sysctlConfPath, Previous: "1024"},
+		{Kind: changeIptables, Rule: iptablesRuleID("iptables", 80, 9080)},
+		{Kind: changeIptables, Rule: iptablesRuleID("ip6tables", 80, 9080)},
+		// Running setup again keeps the first entry
+		{Kind: changeSysctl, Path: sysctlConfPath, Previous: "80"},
+	}
+	for _, change := range changes {
+		if err := addChange(change); err != nil {
+			t.Fatalf("addChange() failed: %v", err)
+		}
+	}
+
+	manifest, err = LoadManifest()
+	if err != nil {
+		t.Fatalf("LoadManifest() failed: %v", err)
+	}
+	if len(manifest.Changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %d", len(manifest.Changes))
+	}
+	if manifest.Changes[0].Previous != "1024" {
+		t.Errorf("Previous = %q, want the first recorded value 1024", manifest.Changes[0].Previous)
+	}
+	if manifest.Changes[2].Rule != "ip6tables:80:9080" {
+		t.Errorf("Changes[2].Rule = %q, want ip6tables:80:9080", manifest.Changes[2].Rule)
+	}
+	if manifest.Changes[0].Time.IsZero() {
+		t.Error("Expected the change time to be set")
+	}
+
+	manifest.Changes = nil
+	if err := saveManifest(manifest); err != nil {
+		t.Fatalf("saveManifest() failed: %v", err)
+	}
+	path, err := ManifestPath()
+	if err != nil {
+		t.Fatalf("ManifestPath() failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected an empty manifest to be removed, got %v", err)
+	}
+}
+
+func TestUndoSteps(t *testing.T) {
+	const fingerprint = "0123456789ABCDEF0123456789ABCDEF01234567"
+	executable, err := executablePath()
+	if err != nil {
+		t.Fatalf("executablePath() failed: %v", err)
+	}
+	certPath := filepath.Join(t.TempDir(), "root.pem")
+	writeTestCertificate(t, certPath)
+	certFingerprint, err := certificateSHA1(certPath)
+	if err != nil {
+		t.Fatalf("certificateSHA1() failed: %v", err)
+	}
+	// A JDK the user can write to is changed without sudo
+	javaHome := t.TempDir()
+	cacerts := filepath.Join(javaHome, "jre", "lib", "security", "cacerts")
+	if err := os.MkdirAll(filepath.Dir(cacerts), 0755); err != nil {
+		t.Fatalf("Failed to create keystore directory: %v", err)
+	}
+	if err := os.WriteFile(cacerts, nil, 0644); err != nil {
+		t.Fatalf("Failed to write keystore: %v", err)
+	}
+
+	tests := []struct {
+		change   Change
+		expected []string
+	}{
+		{
+			Change{Kind: changeCAAnchor, Path: "/usr/local/share/ca-certificates/caddy-local-ca.crt"},
+			[]string{"sudo rm -f /usr/local/share/ca-certificates/caddy-local-ca.crt", "sudo update-ca-certificates"},
+		},
+		{
+			Change{Kind: changeCAAnchor, Path: "/etc/pki/ca-trust/source/anchors/caddy-local-ca.crt"},
+			[]string{"sudo rm -f /etc/pki/ca-trust/source/anchors/caddy-local-ca.crt", "sudo update-ca-trust"},
+		},
+		{
+			Change{Kind: changeTrustAnchor, Path: certPath, Fingerprint: certFingerprint},
+			[]string{"sudo trust anchor --remove " + certPath},
+		},
+		{
+			Change{Kind: changeKeychain, Path: systemKeychain, Fingerprint: fingerprint},
+			[]string{"sudo security delete-certificate -t -Z " + fingerprint + " /Library/Keychains/System.keychain"},
+		},
+		{
+			Change{Kind: changeSetcap, Path: executable},
+			[]string{"sudo setcap -r " + executable},
+		},
+		{
+			Change{Kind: changeSysctl, Path: sysctlConfPath, Previous: "1024"},
+			[]string{"sudo rm -f /etc/sysctl.d/50-faa.conf", "sudo sysctl -w net.ipv4.ip_unprivileged_port_start=1024"},
+		},
+		{
+			Change{Kind: changeSysctl, Path: sysctlConfPath},
+			[]string{"sudo rm -f /etc/sysctl.d/50-faa.conf"},
+		},
+		{
+			Change{Kind: changeNftables, Path: forwardRulesPath},
+			[]string{"sudo nft delete table inet faa", "sudo rm -f /etc/faa/forward.nft"},
+		},
+		{
+			Change{Kind: changeIptables, Rule: "ip6tables:443:9443"},
+			[]string{"sudo ip6tables -t nat -D OUTPUT -p tcp -m addrtype --dst-type LOCAL --dport 443 -j REDIRECT --to-ports 9443"},
+		},
+		{
+			Change{Kind: changeSystemdUnit, Path: "/home/user/.config/systemd/user/faa.socket"},
+			[]string{
+				"systemctl --user disable --now faa.socket",
+				"rm -f /home/user/.config/systemd/user/faa.socket",
+				"systemctl --user daemon-reload",
+			},
+		},
+		{
+			Change{Kind: changeSystemdUnit, Path: "/home/my user/.config/systemd/user/faa.service"},
+			[]string{
+				"systemctl --user disable --now faa.service",
+				`rm -f "/home/my user/.config/systemd/user/faa.service"`,
+				"systemctl --user daemon-reload",
+			},
+		},
+		{
+			Change{Kind: changeNSS, Path: "/home/user/.pki/nssdb"},
+			[]string{`certutil -D -d sql:/home/user/.pki/nssdb -n "faa local CA"`},
+		},
+		{
+			Change{Kind: changeJava, Path: cacerts},
+			[]string{filepath.Join(javaHome, "bin", "keytool") + " -delete -alias faa-local-ca -keystore " + cacerts + " -storepass changeit"},
+		},
+		{
+			Change{Kind: changeResolved, Path: resolvedDropInPath},
+			[]string{"sudo rm -f /etc/systemd/resolved.conf.d/faa.conf", "sudo systemctl restart systemd-resolved"},
+		},
+		{
+			Change{Kind: changeResolver, Path: "/etc/resolver/test"},
+			[]string{"sudo rm -f /etc/resolver/test"},
+		},
+		{
+			Change{Kind: changeSudoers, Path: sudoersPath},
+			[]string{"sudo rm -f /etc/sudoers.d/faa-hosts"},
+		},
+		{
+			Change{Kind: changeHostsBlock, Path: "/etc/hosts"},
+			[]string{"sudo " + executable + " hosts clean"},
+		},
+	}
+
+	for _, tt := range tests {
+		steps, err := tt.change.undoSteps()
+		if err != nil {
+			t.Errorf("undoSteps(%s) failed: %v", tt.change.Kind, err)
+			continue
+		}
+		var got []string
+		for _, step := range steps {
+			got = append(got, step.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("undoSteps(%s) = %q, want %q", tt.change.Kind, got, tt.expected)
+		}
+	}
+
+	if _, err := (Change{Kind: "unknown"}).undoSteps(); err == nil {
+		t.Error("Expected an error for an unknown change")
+	}
+
+	// The manifest is writable by the user, so nothing in it may choose
+	// what runs as root
+	invalid := []Change{
+		{Kind: changeCAAnchor, Path: "/etc/passwd"},
+		{Kind: changeTrustAnchor, Path: certPath, Fingerprint: fingerprint},
+		{Kind: changeKeychain, Path: systemKeychain, Fingerprint: "ABCD -Z"},
+		{Kind: changeSetcap, Path: "/usr/bin/ping"},
+		{Kind: changeSysctl, Path: "/etc/shadow"},
+		{Kind: changeSysctl, Path: sysctlConfPath, Previous: "1024 kernel.modprobe=/tmp/x"},
+		{Kind: changeNftables, Path: "/etc/nftables.conf"},
+		{Kind: changeIptables, Rule: "/tmp/evil:80:8080"},
+		{Kind: changeIptables, Rule: "iptables:22:8080"},
+		{Kind: changeLaunchDaemon, Path: "/Library/LaunchDaemons/other.plist"},
+		{Kind: changeSystemdUnit, Path: "/home/user/.config/systemd/user/other.service"},
+		{Kind: changeJava, Path: "/tmp/evil"},
+		{Kind: changeResolved, Path: "/etc/systemd/resolved.conf"},
+		{Kind: changeResolver, Path: "/etc/resolver/../sudoers"},
+		{Kind: changeSudoers, Path: "/etc/sudoers"},
+		{Kind: changeHostsBlock, Path: "/tmp/hosts"},
+	}
+	for _, change := range invalid {
+		if steps, err := change.undoSteps(); err == nil {
+			t.Errorf("undoSteps(%s %s%s%s) = %v, want an error", change.Kind, change.Path, change.Rule, change.Previous, steps)
+		}
+	}
+}
+
+func TestCheckRootOwned(t *testing.T) {
+	// Temporary directories are writable by everyone
+	path := filepath.Join(t.TempDir(), "tool")
+	if err := os.WriteFile(path, nil, 0755); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := checkRootOwned(path); err == nil {
+		t.Errorf("checkRootOwned(%s) succeeded, want an error", path)
+	}
+
+	if err := checkRootOwned("/bin/sh"); err != nil {
+		t.Errorf("checkRootOwned(/bin/sh) failed: %v", err)
+	}
+}
+
+func TestUninstallDryRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := Uninstall(Options{Check: true, Output: io.Discard}); err != nil {
+		t.Fatalf("Uninstall() with no changes failed: %v", err)
+	}
+
+	if err := addChange(Change{Kind: changeSetcap, Path: "/usr/local/bin/faa"}); err != nil {
+		t.Fatalf("addChange() failed: %v", err)
+	}
+	if err := Uninstall(Options{Check: true, Output: io.Discard}); err != nil {
+		t.Fatalf("Uninstall() dry run failed: %v", err)
+	}
+
+	manifest, err := LoadManifest()
+	if err != nil {
+		t.Fatalf("LoadManifest() failed: %v", err)
+	}
+	if len(manifest.Changes) != 1 {
+		t.Errorf("Expected a dry run to keep the manifest, got %d changes", len(manifest.Changes))
+	}
+}
+
+// writeTestCertificate writes a self-signed PEM certificate to path and
+// returns it
+func writeTestCertificate(t *testing.T, path string) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "faa test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create certificate directory: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	return data
+}
+
+func TestCertificateSHA1(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "root.crt")
+	writeTestCertificate(t, certPath)
+
+	fingerprint, err := certificateSHA1(certPath)
+	if err != nil {
+		t.Fatalf("certificateSHA1() failed: %v", err)
+	}
+	if len(fingerprint) != 40 || strings.ToUpper(fingerprint) != fingerprint {
+		t.Errorf("certificateSHA1() = %q, want 40 uppercase hex digits", fingerprint)
+	}
+
+	notCert := filepath.Join(dir, "not.crt")
+	if err := os.WriteFile(notCert, []byte("not a certificate"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := certificateSHA1(notCert); err == nil {
+		t.Error("Expected an error for a file that isn't a certificate")
+	}
+}
+
+func TestRemoveCA(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	oldCert := filepath.Join(home, "old.pem")
+	writeTestCertificate(t, oldCert)
+
+	changes := []Change{
+		{Kind: changeSysctl, Path: sysctlConfPath, Previous: "1024"},
+		// A keychain entry for another certificate isn't touched
+		{Kind: changeKeychain, Path: "/Library/Keychains/System.keychain", Fingerprint: "0123456789ABCDEF0123456789ABCDEF01234567"},
+		// Removing the certificate from an NSS database is optional, so
+		// this succeeds without certutil
+		{Kind: changeNSS, Path: filepath.Join(home, ".pki", "nssdb")},
+	}
+	for _, change := range changes {
+		if err := addChange(change); err != nil {
+			t.Fatalf("addChange() failed: %v", err)
+		}
+	}
+
+	if err := RemoveCA(oldCert, Options{Yes: true, Output: io.Discard}); err != nil {
+		t.Fatalf("RemoveCA() failed: %v", err)
+	}
+
+	manifest, err := LoadManifest()
+	if err != nil {
+		t.Fatalf("LoadManifest() failed: %v", err)
+	}
+	if len(manifest.Changes) != 2 {
+		t.Fatalf("Expected 2 changes to be kept, got %d", len(manifest.Changes))
+	}
+	for _, change := range manifest.Changes {
+		if change.Kind == changeNSS {
+			t.Error("Expected the NSS change to be removed")
+		}
+	}
+}
+
+func TestTrustsCA(t *testing.T) {
+	const fingerprint = "0123456789ABCDEF0123456789ABCDEF01234567"
+	tests := []struct {
+		change   Change
+		expected bool
+	}{
+		{Change{Kind: changeCAAnchor}, true},
+		{Change{Kind: changeNSS}, true},
+		{Change{Kind: changeJava}, true},
+		{Change{Kind: changeKeychain, Fingerprint: fingerprint}, true},
+		{Change{Kind: changeKeychain, Fingerprint: "other"}, false},
+		{Change{Kind: changeTrustAnchor, Fingerprint: fingerprint}, true},
+		{Change{Kind: changeTrustAnchor, Fingerprint: "other"}, false},
+		{Change{Kind: changeSysctl}, false},
+		{Change{Kind: changeSetcap}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.change.trustsCA(fingerprint); got != tt.expected {
+			t.Errorf("trustsCA() for %s (%s) = %v, want %v", tt.change.Kind, tt.change.Fingerprint, got, tt.expected)
+		}
+	}
+}
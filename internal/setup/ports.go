@@ -0,0 +1,290 @@
+package setup
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sahithyandev/faa/internal/config"
+)
+
+const (
+	// sysctlConfPath persists the lowered unprivileged port start
+	sysctlConfPath = "/etc/sysctl.d/50-faa.conf"
+
+	// forwardRulesPath holds the nftables rules forwarding ports 80 and 443
+	forwardRulesPath = "/etc/faa/forward.nft"
+)
+
+// unprivilegedPortStartPath is the sysctl for the lowest port any process
+// may bind; a variable so tests can point it at a temporary file
+var unprivilegedPortStartPath = "/proc/sys/net/ipv4/ip_unprivileged_port_start"
+
+// choosePortSetup asks how the proxy should get ports 80 and 443, returning
+// the chosen option or "" to skip
+func choosePortSetup(w io.Writer, reader *bufio.Reader, cfg config.ProxyConfig) string {
+	fmt.Fprintln(w, "How should faa serve https://<project>.localhost?")
+	fmt.Fprintln(w, "  1) Let the faa binary bind ports 80 and 443 (setcap; redo after every upgrade)")
+	fmt.Fprintln(w, "  2) Let every program bind ports from 80 up (net.ipv4.ip_unprivileged_port_start=80)")
+	fmt.Fprintf(w, "  3) Forward ports 80 and 443 to %d and %d (nftables or iptables)\n", cfg.HTTPPort, cfg.HTTPSPort)
+	fmt.Fprintf(w, "  4) Use https://<project>.localhost:%d URLs (no root needed)\n", cfg.HTTPSPort)
+	fmt.Fprint(w, "Choice [1-4, Enter to skip]: ")
+
+	response, err := reader.ReadString('\n')
+	if err != nil {
+		fmt.Fprintln(w)
+		return ""
+	}
+	response = strings.TrimSpace(response)
+	switch response {
+	case "1", "2", "3", "4":
+		return response
+	}
+	return ""
+}
+
+// unprivilegedPortStart returns the lowest port unprivileged processes may
+// bind
+func unprivilegedPortStart() (int, error) {
+	data, err := os.ReadFile(unprivilegedPortStartPath)
+	if err != nil {
+		return 0, err
+	}
+	port, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", unprivilegedPortStartPath, err)
+	}
+	return port, nil
+}
+
+// lowerUnprivilegedPortStart lets every process bind ports from 80 up, now
+// and after reboots
+func (r *runner) lowerUnprivilegedPortStart() error {
+	start, err := unprivilegedPortStart()
+	if err == nil && start <= 80 {
+		fmt.Fprintf(r.out, "✓ Unprivileged processes can already bind ports from %d up\n", start)
+		r.result(StatusOK, fmt.Sprintf("unprivileged processes can bind ports from %d up", start))
+		return nil
+	}
+	change := Change{Kind: changeSysctl, Path: sysctlConfPath}
+	if err == nil {
+		change.Previous = strconv.Itoa(start)
+	}
+
+	fmt.Fprintf(r.out, "Setting net.ipv4.ip_unprivileged_port_start=80 and saving it to %s...\n", sysctlConfPath)
+	if err := r.sudo("net.ipv4.ip_unprivileged_port_start = 80\n", "tee", sysctlConfPath); err != nil {
+		return fmt.Errorf("failed to write %s: %w", sysctlConfPath, err)
+	}
+	r.record(change)
+	if err := r.sudo("", "sysctl", "-w", "net.ipv4.ip_unprivileged_port_start=80"); err != nil {
+		return fmt.Errorf("failed to set net.ipv4.ip_unprivileged_port_start: %w", err)
+	}
+
+	if canBindPort(80) && canBindPort(443) {
+		fmt.Fprintln(r.out, "✓ Can bind to ports 80 and 443")
+	} else {
+		fmt.Fprintln(r.out, "⚠ Warning: ports 80 and 443 still cannot be bound")
+		fmt.Fprintln(r.out, "  They may be in use by other services")
+	}
+	r.result(StatusChanged, "set net.ipv4.ip_unprivileged_port_start=80")
+	return nil
+}
+
+// checkPortForwarding checks the forward port mode, reinstalling the
+// forwarding rules if asked since they may not survive a reboot
+func (r *runner) checkPortForwarding(cfg config.ProxyConfig) error {
+	portsFree := r.checkUnprivilegedPorts(cfg)
+	installed := portForwardingInstalled()
+
+	status, detail := StatusOK, fmt.Sprintf("ports 80 and 443 are forwarded to %d and %d", cfg.HTTPPort, cfg.HTTPSPort)
+	if !installed {
+		status, detail = StatusMissing, "port forwarding rules are not installed"
+	} else if !portsFree {
+		status, detail = StatusMissing, fmt.Sprintf("ports %d and %d are in use", cfg.HTTPPort, cfg.HTTPSPort)
+	}
+
+	if r.opts.Check {
+		r.result(status, detail)
+		return nil
+	}
+	if !r.confirm(fmt.Sprintf("Install the rules forwarding ports 80 and 443 to %d and %d?", cfg.HTTPPort, cfg.HTTPSPort), false) {
+		fmt.Fprintln(r.out, "Skipped.")
+		r.result(status, detail)
+		return nil
+	}
+	if err := r.installPortForwarding(cfg); err != nil {
+		return err
+	}
+	r.result(StatusChanged, fmt.Sprintf("ports 80 and 443 forwarded to %d and %d", cfg.HTTPPort, cfg.HTTPSPort))
+	return nil
+}
+
+// portForwardingInstalled reports whether setup installed forwarding rules.
+// Listing the rules needs root, so this goes by the rules file and the
+// setup manifest.
+func portForwardingInstalled() bool {
+	if _, err := os.Stat(forwardRulesPath); err == nil {
+		return true
+	}
+	manifest, err := LoadManifest()
+	if err != nil {
+		return false
+	}
+	for _, change := range manifest.Changes {
+		if change.Kind == changeIptables {
+			return true
+		}
+	}
+	return false
+}
+
+// checkUnprivilegedPorts checks that the proxy's unprivileged ports are
+// free, or held by the running daemon
+func (r *runner) checkUnprivilegedPorts(cfg config.ProxyConfig) bool {
+	fmt.Fprintf(r.out, "Checking proxy ports (%d/%d, %s port mode)...\n", cfg.HTTPPort, cfg.HTTPSPort, cfg.PortMode)
+	if canBindPort(cfg.HTTPPort) && canBindPort(cfg.HTTPSPort) {
+		fmt.Fprintf(r.out, "✓ Can bind to ports %d and %d\n", cfg.HTTPPort, cfg.HTTPSPort)
+		return true
+	}
+	if daemonHoldsPorts() {
+		fmt.Fprintf(r.out, "✓ Ports %d and %d are in use by the running daemon\n", cfg.HTTPPort, cfg.HTTPSPort)
+		return true
+	}
+	fmt.Fprintf(r.out, "⚠ Warning: ports %d and %d cannot be bound; they may be in use by another program\n", cfg.HTTPPort, cfg.HTTPSPort)
+	return false
+}
+
+// installPortForwarding redirects local connections to ports 80 and 443 to
+// the proxy's unprivileged ports, with nftables if available and iptables
+// otherwise
+func (r *runner) installPortForwarding(cfg config.ProxyConfig) error {
+	if _, err := exec.LookPath("nft"); err == nil {
+		fmt.Fprintf(r.out, "Installing nftables rules to %s...\n", forwardRulesPath)
+		if err := r.sudo("", "mkdir", "-p", filepath.Dir(forwardRulesPath)); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(forwardRulesPath), err)
+		}
+		if err := r.sudo(nftForwardRules(cfg.HTTPPort, cfg.HTTPSPort), "tee", forwardRulesPath); err != nil {
+			return fmt.Errorf("failed to write %s: %w", forwardRulesPath, err)
+		}
+		r.record(Change{Kind: changeNftables, Path: forwardRulesPath})
+		if err := r.sudo("", "nft", "-f", forwardRulesPath); err != nil {
+			return fmt.Errorf("failed to load nftables rules: %w", err)
+		}
+		fmt.Fprintf(r.out, "✓ Ports 80 and 443 forwarded to %d and %d\n", cfg.HTTPPort, cfg.HTTPSPort)
+		fmt.Fprintln(r.out, "  To keep the rules after a reboot, add this line to /etc/nftables.conf:")
+		fmt.Fprintf(r.out, "    include %q\n", forwardRulesPath)
+		return nil
+	}
+
+	for _, iptables := range []string{"iptables", "ip6tables"} {
+		if _, err := exec.LookPath(iptables); err != nil {
+			if iptables == "iptables" {
+				return fmt.Errorf("neither nft nor iptables is installed")
+			}
+			continue
+		}
+		fmt.Fprintf(r.out, "Installing %s rules...\n", iptables)
+		for _, forward := range [][2]int{{80, cfg.HTTPPort}, {443, cfg.HTTPSPort}} {
+			rule := iptablesForwardRule(forward[0], forward[1])
+			// Only append rules that aren't there yet
+			check := r.command("sudo", append([]string{iptables, "-t", "nat", "-C"}, rule...)...)
+			check.Stdin = os.Stdin
+			// A missing rule is the expected case, not an error to show
+			check.Stderr = nil
+			if check.Run() == nil {
+				continue
+			}
+			add := append([]string{iptables, "-t", "nat", "-A"}, rule...)
+			if err := r.sudo("", add...); err != nil {
+				return fmt.Errorf("failed to add %s rule: %w", iptables, err)
+			}
+			r.record(Change{Kind: changeIptables, Rule: iptablesRuleID(iptables, forward[0], forward[1])})
+		}
+	}
+	fmt.Fprintf(r.out, "✓ Ports 80 and 443 forwarded to %d and %d\n", cfg.HTTPPort, cfg.HTTPSPort)
+	fmt.Fprintln(r.out, "  iptables rules are lost on reboot unless saved, e.g. with iptables-persistent or 'iptables-save'")
+	return nil
+}
+
+// nftForwardRules returns an nftables ruleset redirecting connections from
+// this machine to its own ports 80 and 443 to the given ports. Loading it
+// again replaces the rules rather than adding to them.
+func nftForwardRules(httpPort, httpsPort int) string {
+	return fmt.Sprintf(`# Written by faa setup; forwards ports 80 and 443 to the faa proxy
+table inet faa {}
+flush table inet faa
+
+table inet faa {
+	chain output {
+		type nat hook output priority -100; policy accept;
+		fib daddr type local tcp dport 80 redirect to :%d
+		fib daddr type local tcp dport 443 redirect to :%d
+	}
+}
+`, httpPort, httpsPort)
+}
+
+// iptablesForwardRule returns the nat OUTPUT rule, without the -A or -C
+// command, redirecting connections from this machine to its own port from
+// to port to
+func iptablesForwardRule(from, to int) []string {
+	return []string{
+		"OUTPUT", "-p", "tcp", "-m", "addrtype", "--dst-type", "LOCAL",
+		"--dport", strconv.Itoa(from), "-j", "REDIRECT", "--to-ports", strconv.Itoa(to),
+	}
+}
+
+// iptablesRuleID names the forwarding rule from port from to port to,
+// added with iptables or ip6tables, in the setup manifest
+func iptablesRuleID(iptables string, from, to int) string {
+	return fmt.Sprintf("%s:%d:%d", iptables, from, to)
+}
+
+// parseIptablesRuleID returns the tool and the forwarding rule named by
+// id. Only rules setup adds can be named.
+func parseIptablesRuleID(id string) (iptables string, rule []string, err error) {
+	parts := strings.Split(id, ":")
+	if len(parts) == 3 && (parts[0] == "iptables" || parts[0] == "ip6tables") {
+		from, fromErr := strconv.Atoi(parts[1])
+		to, toErr := strconv.Atoi(parts[2])
+		if fromErr == nil && toErr == nil && (from == 80 || from == 443) && to > 0 && to <= 65535 {
+			return parts[0], iptablesForwardRule(from, to), nil
+		}
+	}
+	return "", nil, fmt.Errorf("invalid iptables rule %q", id)
+}
+
+// printPortModeInstructions tells the user how to switch the daemon to a
+// port mode. faa never rewrites config.json, so the user edits it.
+func (r *runner) printPortModeInstructions(mode string) {
+	path, err := config.Path()
+	if err != nil {
+		path = "~/.config/faa/config.json"
+	}
+	fmt.Fprintln(r.out)
+	fmt.Fprintf(r.out, "Add this to %s and restart the daemon with 'faa stop':\n", path)
+	fmt.Fprintln(r.out, portModeSnippet(mode))
+}
+
+// portModeSnippet returns the config.json setting selecting mode
+func portModeSnippet(mode string) string {
+	return fmt.Sprintf("{\n  \"proxy\": {\n    \"portMode\": %q\n  }\n}", mode)
+}
+
+// sudo runs a command as root. If input is given it is written to the
+// command's stdin and the output, such as tee's echo, is discarded.
+func (r *runner) sudo(input string, args ...string) error {
+	cmd := r.command("sudo", args...)
+	if input != "" {
+		cmd.Stdin = strings.NewReader(input)
+		cmd.Stdout = nil
+	} else {
+		cmd.Stdin = os.Stdin
+	}
+	return cmd.Run()
+}
@@ -0,0 +1,114 @@
+package setup
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sahithyandev/faa/internal/config"
+)
+
+func TestChoosePortSetup(t *testing.T) {
+	cfg := config.Default().Proxy
+
+	tests := map[string]string{
+		"1\n":   "1",
+		" 3 \n": "3",
+		"4\n":   "4",
+		"\n":    "",
+		"9\n":   "",
+		"":      "",
+	}
+	for input, want := range tests {
+		got := choosePortSetup(io.Discard, bufio.NewReader(strings.NewReader(input)), cfg)
+		if got != want {
+			t.Errorf("choosePortSetup(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestUnprivilegedPortStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ip_unprivileged_port_start")
+	original := unprivilegedPortStartPath
+	unprivilegedPortStartPath = path
+	defer func() { unprivilegedPortStartPath = original }()
+
+	if _, err := unprivilegedPortStart(); err == nil {
+		t.Error("unprivilegedPortStart() should fail when the sysctl is missing")
+	}
+
+	if err := os.WriteFile(path, []byte("1024\n"), 0644); err != nil {
+		t.Fatalf("Failed to write sysctl: %v", err)
+	}
+	start, err := unprivilegedPortStart()
+	if err != nil {
+		t.Fatalf("unprivilegedPortStart() failed: %v", err)
+	}
+	if start != 1024 {
+		t.Errorf("unprivilegedPortStart() = %d, want 1024", start)
+	}
+
+	if err := os.WriteFile(path, []byte("low\n"), 0644); err != nil {
+		t.Fatalf("Failed to write sysctl: %v", err)
+	}
+	if _, err := unprivilegedPortStart(); err == nil {
+		t.Error("unprivilegedPortStart() should fail for a non-numeric value")
+	}
+}
+
+func TestNftForwardRules(t *testing.T) {
+	rules := nftForwardRules(9080, 9443)
+
+	for _, expected := range []string{
+		"table inet faa",
+		"flush table inet faa",
+		"type nat hook output",
+		"tcp dport 80 redirect to :9080",
+		"tcp dport 443 redirect to :9443",
+	} {
+		if !strings.Contains(rules, expected) {
+			t.Errorf("Expected rules to contain %q", expected)
+		}
+	}
+
+	// The table is flushed before it is filled, so loading the file
+	// again doesn't duplicate the rules
+	if strings.Index(rules, "flush table") > strings.Index(rules, "chain output") {
+		t.Error("Rules should be flushed before the chain is defined")
+	}
+}
+
+func TestIptablesForwardRule(t *testing.T) {
+	for _, ports := range [][2]string{{"80", "9080"}, {"443", "9443"}} {
+		from, _ := strconv.Atoi(ports[0])
+		to, _ := strconv.Atoi(ports[1])
+		rule := strings.Join(iptablesForwardRule(from, to), " ")
+		if !strings.HasPrefix(rule, "OUTPUT ") {
+			t.Errorf("Rule %q should be in the OUTPUT chain", rule)
+		}
+		if !strings.Contains(rule, "--dport "+ports[0]+" ") || !strings.HasSuffix(rule, "--to-ports "+ports[1]) {
+			t.Errorf("Rule %q should redirect %s to %s", rule, ports[0], ports[1])
+		}
+	}
+}
+
+func TestPortModeSnippet(t *testing.T) {
+	for _, mode := range []string{config.PortModeForward, config.PortModePort} {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(portModeSnippet(mode)), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+
+		cfg, err := config.LoadFile(path)
+		if err != nil {
+			t.Fatalf("Snippet for %s is not a valid config: %v", mode, err)
+		}
+		if cfg.Proxy.PortMode != mode {
+			t.Errorf("Proxy.PortMode = %q, want %q", cfg.Proxy.PortMode, mode)
+		}
+	}
+}
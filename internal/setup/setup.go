@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"os/exec"
@@ -11,128 +12,476 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/sahithyandev/faa/internal/config"
+	"github.com/sahithyandev/faa/internal/daemon"
 	"github.com/sahithyandev/faa/internal/proxy"
 )
 
+// Setup steps, as named in the report
+const (
+	StepPorts   = "ports"
+	StepService = "service"
+	StepCA      = "ca"
+	StepNSS     = "nss"
+	StepJava    = "java"
+	StepDNS     = "dns"
+	StepHosts   = "hosts"
+)
+
+// Step statuses
+const (
+	// StatusOK means nothing needed to change
+	StatusOK = "ok"
+
+	// StatusChanged means setup made the change
+	StatusChanged = "changed"
+
+	// StatusMissing means the change is needed but wasn't made, because
+	// it was declined or only checked
+	StatusMissing = "missing"
+
+	// StatusSkipped means the step was skipped by an option or doesn't
+	// apply to this machine
+	StatusSkipped = "skipped"
+
+	// StatusFailed means the change was attempted and failed
+	StatusFailed = "failed"
+)
+
+// Options controls how setup runs
+type Options struct {
+	// Yes answers yes to every prompt, taking the default choice where
+	// there are several
+	Yes bool
+
+	// SkipPorts skips letting the proxy bind ports 80 and 443
+	SkipPorts bool
+
+	// SkipService skips installing the systemd service or LaunchDaemon
+	SkipService bool
+
+	// SkipCA skips trusting the CA certificate
+	SkipCA bool
+
+	// SkipDNS skips pointing the system resolver at the DNS server for
+	// dns.domains
+	SkipDNS bool
+
+	// SkipHosts skips letting the daemon update /etc/hosts
+	SkipHosts bool
+
+	// CAOnly only trusts the CA certificate
+	CAOnly bool
+
+	// Check reports what is missing without changing anything. For
+	// Uninstall it lists the changes without undoing them.
+	Check bool
+
+	// Output receives the progress messages; it defaults to stdout
+	Output io.Writer
+}
+
+// Report describes what setup found, changed, or would change
+type Report struct {
+	OS    string  `json:"os"`
+	Check bool    `json:"check"`
+	Steps []*Step `json:"steps"`
+}
+
+// Step is the outcome of one setup step
+type Step struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+
+	// Actions are commands that would make a missing change
+	Actions []string `json:"actions,omitempty"`
+
+	// Changes are the system changes the step made
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// Complete reports whether no step is missing or failed
+func (r *Report) Complete() bool {
+	return len(r.Incomplete()) == 0
+}
+
+// Incomplete returns the names of the steps that are missing or failed
+func (r *Report) Incomplete() []string {
+	var names []string
+	for _, step := range r.Steps {
+		if step.Status == StatusMissing || step.Status == StatusFailed {
+			names = append(names, step.Name)
+		}
+	}
+	return names
+}
+
+// runner holds the state of one setup run
+type runner struct {
+	opts   Options
+	out    io.Writer
+	reader *bufio.Reader
+	report *Report
+	step   *Step
+
+	// root is the filesystem root trust stores are looked up under, so
+	// tests can use a fake one
+	root string
+}
+
+// newRunner returns a runner reading answers from stdin
+func newRunner(opts Options) *runner {
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	return &runner{
+		opts:   opts,
+		out:    out,
+		reader: bufio.NewReader(os.Stdin),
+		report: &Report{OS: runtime.GOOS, Check: opts.Check},
+		root:   "/",
+	}
+}
+
 // Run executes the setup process for the current platform
-func Run() error {
+func Run(opts Options) (*Report, error) {
+	if opts.CAOnly && opts.SkipCA {
+		return nil, fmt.Errorf("--ca-only and --skip-ca can't be used together")
+	}
+
+	r := newRunner(opts)
+	var err error
 	switch runtime.GOOS {
 	case "linux":
-		return runLinuxSetup()
+		err = r.runLinuxSetup()
 	case "darwin":
-		return runDarwinSetup()
+		err = r.runDarwinSetup()
 	default:
-		return fmt.Errorf("setup command is not supported on %s", runtime.GOOS)
+		return nil, fmt.Errorf("setup command is not supported on %s", runtime.GOOS)
 	}
+	return r.report, err
 }
 
 // runLinuxSetup executes the setup process for Linux
-func runLinuxSetup() error {
-	fmt.Println("faa setup - Linux Development Environment")
-	fmt.Println()
+func (r *runner) runLinuxSetup() error {
+	fmt.Fprintln(r.out, "faa setup - Linux Development Environment")
+	fmt.Fprintln(r.out)
 
 	// Check privileged port binding
-	if err := checkPrivilegedPorts(); err != nil {
+	if r.opts.SkipPorts || r.opts.CAOnly {
+		r.skip(StepPorts, "privileged port binding")
+	} else if err := r.checkPrivilegedPorts(); err != nil {
+		r.fail(err)
 		return fmt.Errorf("privileged port check failed: %w", err)
 	}
+	fmt.Fprintln(r.out)
+
+	// Run the daemon as a systemd user service
+	if r.opts.SkipService || r.opts.CAOnly {
+		r.skip(StepService, "systemd user service")
+	} else if err := r.setupSystemdService(); err != nil {
+		r.fail(err)
+		return fmt.Errorf("systemd service setup failed: %w", err)
+	}
 
 	// Check and install CA trust
-	if err := checkCATrust(); err != nil {
+	if r.opts.SkipCA {
+		r.skip(StepCA, "CA certificate trust")
+	} else if err := r.checkCATrust(); err != nil {
+		r.fail(err)
 		return fmt.Errorf("CA trust setup failed: %w", err)
 	}
+	if err := r.trustStoreSteps(); err != nil {
+		return err
+	}
+
+	// Resolve the custom domains with systemd-resolved
+	if r.opts.SkipDNS || r.opts.CAOnly {
+		r.skip(StepDNS, "DNS for custom domains")
+	} else if err := r.checkResolved(); err != nil {
+		r.fail(err)
+		return fmt.Errorf("DNS setup failed: %w", err)
+	}
+
+	// Let the daemon keep its block in /etc/hosts up to date
+	if r.opts.SkipHosts || r.opts.CAOnly {
+		r.skip(StepHosts, "/etc/hosts updates")
+	} else if err := r.checkHostsHelper(); err != nil {
+		r.fail(err)
+		return fmt.Errorf("/etc/hosts setup failed: %w", err)
+	}
 
-	fmt.Println()
-	fmt.Println("✓ Setup complete!")
+	r.printSummary()
 	return nil
 }
 
 // runDarwinSetup executes the setup process for macOS
-func runDarwinSetup() error {
-	fmt.Println("faa setup - macOS Development Environment")
-	fmt.Println()
+func (r *runner) runDarwinSetup() error {
+	fmt.Fprintln(r.out, "faa setup - macOS Development Environment")
+	fmt.Fprintln(r.out)
 
 	// Setup LaunchDaemon
-	if err := setupLaunchDaemon(); err != nil {
+	if r.opts.SkipService || r.opts.CAOnly {
+		r.skip(StepService, "LaunchDaemon")
+	} else if err := r.setupLaunchDaemon(); err != nil {
+		r.fail(err)
 		return fmt.Errorf("LaunchDaemon setup failed: %w", err)
 	}
 
 	// Check and install CA trust
-	if err := checkCATrustDarwin(); err != nil {
+	if r.opts.SkipCA {
+		r.skip(StepCA, "CA certificate trust")
+	} else if err := r.checkCATrustDarwin(); err != nil {
+		r.fail(err)
 		return fmt.Errorf("CA trust setup failed: %w", err)
 	}
+	if err := r.trustStoreSteps(); err != nil {
+		return err
+	}
+
+	// Resolve the custom domains with /etc/resolver files
+	if r.opts.SkipDNS || r.opts.CAOnly {
+		r.skip(StepDNS, "DNS for custom domains")
+	} else if err := r.checkResolverFiles(); err != nil {
+		r.fail(err)
+		return fmt.Errorf("DNS setup failed: %w", err)
+	}
 
-	fmt.Println()
-	fmt.Println("✓ Setup complete!")
+	// Let the daemon keep its block in /etc/hosts up to date
+	if r.opts.SkipHosts || r.opts.CAOnly {
+		r.skip(StepHosts, "/etc/hosts updates")
+	} else if err := r.checkHostsHelper(); err != nil {
+		r.fail(err)
+		return fmt.Errorf("/etc/hosts setup failed: %w", err)
+	}
+
+	r.printSummary()
 	return nil
 }
 
-// checkPrivilegedPorts checks if the current binary can bind to ports 80 and 443
-func checkPrivilegedPorts() error {
-	fmt.Println("Checking privileged port binding (80/443)...")
+// skip adds a step skipped by an option to the report
+func (r *runner) skip(name, description string) {
+	fmt.Fprintf(r.out, "Skipping %s\n", description)
+	r.begin(name)
+	r.result(StatusSkipped, "skipped by option")
+}
 
-	// Try to bind to port 80
-	canBind80 := canBindPort(80)
-	canBind443 := canBindPort(443)
+// begin starts reporting a step
+func (r *runner) begin(name string) {
+	r.step = &Step{Name: name}
+	r.report.Steps = append(r.report.Steps, r.step)
+}
 
-	if canBind80 && canBind443 {
-		fmt.Println("✓ Can bind to ports 80 and 443")
-		return nil
+// result sets the outcome of the current step
+func (r *runner) result(status, detail string) {
+	r.step.Status = status
+	r.step.Detail = detail
+}
+
+// suggest adds a command that would make the current step's change
+func (r *runner) suggest(action string) {
+	r.step.Actions = append(r.step.Actions, action)
+}
+
+// fail marks the current step as failed
+func (r *runner) fail(err error) {
+	if r.step != nil {
+		r.result(StatusFailed, err.Error())
+	}
+}
+
+// record records a change for 'faa setup --uninstall' and the report. The
+// change has been made by then, so failing to record it only warns.
+func (r *runner) record(change Change) {
+	if err := addChange(change); err != nil {
+		fmt.Fprintf(r.out, "⚠ Warning: failed to record the change for 'faa setup --uninstall': %v\n", err)
+	}
+	if r.step != nil {
+		r.step.Changes = append(r.step.Changes, change)
+	}
+}
+
+// printSummary prints whether setup is complete
+func (r *runner) printSummary() {
+	fmt.Fprintln(r.out)
+	incomplete := r.report.Incomplete()
+	switch {
+	case len(incomplete) == 0 && r.opts.Check:
+		fmt.Fprintln(r.out, "✓ Everything is set up")
+	case len(incomplete) == 0:
+		fmt.Fprintln(r.out, "✓ Setup complete!")
+	case r.opts.Check:
+		fmt.Fprintf(r.out, "✗ Not set up: %s; run 'faa setup' to fix\n", strings.Join(incomplete, ", "))
+	default:
+		fmt.Fprintf(r.out, "⚠ Setup incomplete: %s; run 'faa setup' again to finish\n", strings.Join(incomplete, ", "))
 	}
+}
 
-	fmt.Println("✗ Cannot bind to privileged ports")
-	fmt.Println()
+// confirm asks a yes/no question, returning def if nothing can be read.
+// With the Yes option it answers yes without reading.
+func (r *runner) confirm(question string, def bool) bool {
+	choices := "[y/N]"
+	if def {
+		choices = "[Y/n]"
+	}
+	if r.opts.Yes {
+		fmt.Fprintf(r.out, "%s %s: y\n", question, choices)
+		return true
+	}
 
+	fmt.Fprintf(r.out, "%s %s: ", question, choices)
+	response, err := r.reader.ReadString('\n')
+	if err != nil {
+		// If we can't read (e.g., EOF), take the default
+		fmt.Fprintln(r.out)
+		return def
+	}
+	response = strings.TrimSpace(strings.ToLower(response))
+	if response == "" {
+		return def
+	}
+	return response == "y" || response == "yes"
+}
+
+// command returns a command whose output goes to the runner's output
+func (r *runner) command(name string, args ...string) *exec.Cmd {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = r.out
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// executablePath returns the resolved path of the running faa binary
+func executablePath() (string, error) {
 	// Get current binary path
-	binaryPath, err := os.Executable()
+	path, err := os.Executable()
 	if err != nil {
-		return fmt.Errorf("failed to get binary path: %w", err)
+		return "", fmt.Errorf("failed to get binary path: %w", err)
 	}
 
 	// Resolve symlinks
-	binaryPath, err = filepath.EvalSymlinks(binaryPath)
+	path, err = filepath.EvalSymlinks(path)
 	if err != nil {
-		return fmt.Errorf("failed to resolve binary path: %w", err)
+		return "", fmt.Errorf("failed to resolve binary path: %w", err)
 	}
+	return path, nil
+}
 
-	fmt.Println("To allow binding to privileged ports without root, run:")
-	setcapCmd := fmt.Sprintf("sudo setcap cap_net_bind_service=+ep %s", binaryPath)
-	fmt.Printf("  %s\n", setcapCmd)
-	fmt.Println()
+// checkPrivilegedPorts checks if the proxy can bind to its ports, and
+// offers the ways to allow it when it can't bind to ports 80 and 443
+func (r *runner) checkPrivilegedPorts() error {
+	r.begin(StepPorts)
 
-	// Ask user if they want to run the command
-	fmt.Print("Run this command now? [y/N]: ")
-	reader := bufio.NewReader(os.Stdin)
-	response, err := reader.ReadString('\n')
+	cfg, err := config.Load()
 	if err != nil {
-		// If we can't read (e.g., EOF), default to "no"
-		fmt.Println()
-		fmt.Println("Skipped. You can run the command manually later.")
+		return err
+	}
+	switch cfg.Proxy.PortMode {
+	case config.PortModeForward:
+		return r.checkPortForwarding(cfg.Proxy)
+	case config.PortModePort:
+		if r.checkUnprivilegedPorts(cfg.Proxy) {
+			r.result(StatusOK, fmt.Sprintf("proxy uses ports %d and %d", cfg.Proxy.HTTPPort, cfg.Proxy.HTTPSPort))
+		} else {
+			r.result(StatusMissing, fmt.Sprintf("ports %d and %d are in use", cfg.Proxy.HTTPPort, cfg.Proxy.HTTPSPort))
+		}
 		return nil
 	}
 
-	response = strings.TrimSpace(strings.ToLower(response))
-	if response == "y" || response == "yes" {
-		fmt.Println("Running setcap command...")
-		cmd := exec.Command("sudo", "setcap", "cap_net_bind_service=+ep", binaryPath)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		cmd.Stdin = os.Stdin
-
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to run setcap: %w", err)
+	fmt.Fprintln(r.out, "Checking privileged port binding (80/443)...")
+
+	// Try to bind to port 80
+	canBind80 := canBindPort(80)
+	canBind443 := canBindPort(443)
+
+	if canBind80 && canBind443 {
+		fmt.Fprintln(r.out, "✓ Can bind to ports 80 and 443")
+		r.result(StatusOK, "can bind to ports 80 and 443")
+		return nil
+	}
+	if daemonHoldsPorts() {
+		fmt.Fprintln(r.out, "✓ Ports 80 and 443 are in use by the running daemon")
+		r.result(StatusOK, "ports 80 and 443 are in use by the running daemon")
+		return nil
+	}
+
+	fmt.Fprintln(r.out, "✗ Cannot bind to privileged ports")
+	fmt.Fprintln(r.out)
+
+	if r.opts.Check {
+		r.result(StatusMissing, "cannot bind to ports 80 and 443")
+		if path, err := executablePath(); err == nil {
+			r.suggest(fmt.Sprintf("sudo setcap cap_net_bind_service=+ep %s", path))
 		}
+		return nil
+	}
 
-		// Verify the capability was set
-		if canBindPort(80) && canBindPort(443) {
-			fmt.Println("✓ Capability set successfully")
-		} else {
-			fmt.Println("⚠ Warning: Capability was set but ports still cannot be bound")
-			fmt.Println("  This may be due to ports already in use by other services")
+	// The first choice is the default with --yes
+	choice := "1"
+	if !r.opts.Yes {
+		choice = choosePortSetup(r.out, r.reader, cfg.Proxy)
+	}
+	switch choice {
+	case "1":
+		return r.allowPrivilegedPorts()
+	case "2":
+		return r.lowerUnprivilegedPortStart()
+	case "3":
+		if err := r.installPortForwarding(cfg.Proxy); err != nil {
+			return err
 		}
-	} else {
-		fmt.Println("Skipped. You can run the command manually later.")
+		r.printPortModeInstructions(config.PortModeForward)
+		r.result(StatusChanged, "ports 80 and 443 forwarded; set proxy.portMode to \"forward\"")
+	case "4":
+		r.printPortModeInstructions(config.PortModePort)
+		r.result(StatusMissing, "set proxy.portMode to \"port\" in config.json")
+	default:
+		fmt.Fprintln(r.out, "Skipped. You can run 'faa setup' again later.")
+		r.result(StatusMissing, "cannot bind to ports 80 and 443")
+	}
+	return nil
+}
+
+// allowPrivilegedPorts gives the faa binary cap_net_bind_service
+func (r *runner) allowPrivilegedPorts() error {
+	path, err := executablePath()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(r.out, "To allow binding to privileged ports without root, run:")
+	setcapCmd := fmt.Sprintf("sudo setcap cap_net_bind_service=+ep %s", path)
+	fmt.Fprintf(r.out, "  %s\n", setcapCmd)
+	fmt.Fprintln(r.out)
+
+	// Ask user if they want to run the command
+	if !r.confirm("Run this command now?", false) {
+		fmt.Fprintln(r.out, "Skipped. You can run the command manually later.")
+		r.result(StatusMissing, "cannot bind to ports 80 and 443")
+		r.suggest(setcapCmd)
+		return nil
+	}
+
+	fmt.Fprintln(r.out, "Running setcap command...")
+	cmd := r.command("sudo", "setcap", "cap_net_bind_service=+ep", path)
+	cmd.Stdin = os.Stdin
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run setcap: %w", err)
 	}
+	r.record(Change{Kind: changeSetcap, Path: path})
 
+	// Verify the capability was set
+	if canBindPort(80) && canBindPort(443) {
+		fmt.Fprintln(r.out, "✓ Capability set successfully")
+	} else {
+		fmt.Fprintln(r.out, "⚠ Warning: Capability was set but ports still cannot be bound")
+		fmt.Fprintln(r.out, "  This may be due to ports already in use by other services")
+	}
+	fmt.Fprintln(r.out, "  The capability is lost when the binary is replaced; run 'faa setup' again after upgrading")
+	r.result(StatusChanged, "set cap_net_bind_service on "+path)
 	return nil
 }
 
@@ -147,10 +496,18 @@ func canBindPort(port int) bool {
 	return true
 }
 
+// daemonHoldsPorts reports whether a running daemon may be why the proxy's
+// ports can't be bound
+func daemonHoldsPorts() bool {
+	socketPath, err := daemon.SocketPath()
+	return err == nil && daemonRunning(socketPath)
+}
+
 // checkCATrust checks and installs the Caddy root CA certificate
-func checkCATrust() error {
-	fmt.Println()
-	fmt.Println("Checking CA certificate trust...")
+func (r *runner) checkCATrust() error {
+	r.begin(StepCA)
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "Checking CA certificate trust...")
 
 	// Get CA certificate path from proxy package
 	caCertPath, err := proxy.GetCAPath()
@@ -160,175 +517,138 @@ func checkCATrust() error {
 
 	// Check if the certificate exists
 	if _, err := os.Stat(caCertPath); os.IsNotExist(err) {
-		fmt.Println("⚠ CA certificate not found")
-		fmt.Printf("  Expected location: %s\n", caCertPath)
-		fmt.Println()
-		fmt.Println("The certificate will be created automatically when you start the daemon.")
-		fmt.Println("After starting the daemon, run 'faa setup' again to install the certificate.")
-		fmt.Println()
-		fmt.Println("You can check the certificate path with: faa ca-path")
+		r.printCANotFound(caCertPath)
 		return nil
 	}
 
-	fmt.Printf("✓ Found Caddy root CA: %s\n", caCertPath)
+	fmt.Fprintf(r.out, "✓ Found Caddy root CA: %s\n", caCertPath)
 
 	// Detect trust store location
-	trustStores := []struct {
-		path        string
-		description string
-		installFunc func(string) error
-	}{
-		{
-			path:        "/usr/local/share/ca-certificates",
-			description: "Debian/Ubuntu",
-			installFunc: installDebianCA,
-		},
-		{
-			path:        "/etc/pki/ca-trust/source/anchors",
-			description: "RHEL/CentOS/Fedora",
-			installFunc: installRHELCA,
-		},
-	}
-
-	var selectedStore *struct {
-		path        string
-		description string
-		installFunc func(string) error
-	}
-
-	for i := range trustStores {
-		if _, err := os.Stat(trustStores[i].path); err == nil {
-			selectedStore = &trustStores[i]
-			break
-		}
+	store := detectTrustStore(r.root)
+	if store == nil {
+		fmt.Fprintln(r.out)
+		fmt.Fprintln(r.out, "✗ Could not detect system trust store")
+		fmt.Fprintln(r.out)
+		r.printManualCAInstructions(caCertPath)
+		r.result(StatusMissing, "could not detect the system trust store")
+		return nil
 	}
 
-	if selectedStore == nil {
-		fmt.Println()
-		fmt.Println("✗ Could not detect system trust store")
-		fmt.Println()
-		printManualCAInstructions(caCertPath)
-		return nil
+	switch store.method {
+	case trustP11Kit:
+		return r.checkCATrustP11Kit(store, caCertPath)
+	case trustNixOS:
+		return r.checkCATrustNixOS(store, caCertPath)
 	}
 
-	fmt.Printf("✓ Detected trust store: %s (%s)\n", selectedStore.path, selectedStore.description)
+	fmt.Fprintf(r.out, "✓ Detected trust store: %s (%s)\n", store.anchors, store.description)
 
 	// Check if already installed
-	destPath := filepath.Join(selectedStore.path, "caddy-local-ca.crt")
-	if _, err := os.Stat(destPath); err == nil {
-		// Check if it's the same certificate
-		if filesAreEqual(caCertPath, destPath) {
-			fmt.Println("✓ CA certificate is already installed and up to date")
-			return nil
-		}
-		fmt.Println("⚠ CA certificate exists but differs from current Caddy CA")
+	destPath := filepath.Join(store.anchors, caAnchorName)
+	if filesAreEqual(caCertPath, r.path(destPath)) {
+		fmt.Fprintln(r.out, "✓ CA certificate is already installed and up to date")
+		r.result(StatusOK, "CA certificate is trusted via "+destPath)
+		return nil
 	}
-
-	// Ask user if they want to install
-	fmt.Println()
-	fmt.Print("Install Caddy root CA to system trust store? [y/N]: ")
-	reader := bufio.NewReader(os.Stdin)
-	response, err := reader.ReadString('\n')
-	if err != nil {
-		// If we can't read (e.g., EOF), default to "no"
-		fmt.Println()
-		fmt.Println("Skipped. To install manually:")
-		printManualCAInstructions(caCertPath)
+	// A CA configured in tls.ca, such as mkcert's or one IT installed, is
+	// usually trusted already
+	if der, err := certificateDER(caCertPath); err == nil && r.bundleHas(store, der) {
+		fmt.Fprintln(r.out, "✓ CA certificate is already trusted")
+		r.result(StatusOK, "CA certificate is in "+store.bundle)
 		return nil
 	}
+	if _, err := os.Stat(r.path(destPath)); err == nil {
+		fmt.Fprintln(r.out, "⚠ CA certificate exists but differs from current Caddy CA")
+	}
 
-	response = strings.TrimSpace(strings.ToLower(response))
-	if response == "y" || response == "yes" {
-		if err := selectedStore.installFunc(caCertPath); err != nil {
-			fmt.Printf("✗ Failed to install certificate: %v\n", err)
-			fmt.Println()
-			printManualCAInstructions(caCertPath)
-			return nil
+	if r.opts.Check {
+		r.result(StatusMissing, "CA certificate is not in "+store.anchors)
+		for _, action := range store.installCommands(caCertPath) {
+			r.suggest(action)
 		}
-		fmt.Println("✓ CA certificate installed successfully")
-	} else {
-		fmt.Println("Skipped. To install manually:")
-		printManualCAInstructions(caCertPath)
+		return nil
 	}
 
-	return nil
-}
-
-// installDebianCA installs the CA certificate on Debian/Ubuntu systems
-func installDebianCA(caCertPath string) error {
-	destPath := "/usr/local/share/ca-certificates/caddy-local-ca.crt"
-
-	// Copy certificate
-	fmt.Printf("Installing certificate to %s...\n", destPath)
-	cmd := exec.Command("sudo", "cp", caCertPath, destPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to copy certificate: %w", err)
+	if !r.hasCommand(store.update) {
+		fmt.Fprintf(r.out, "✗ %s not found; install it with: %s\n", store.update, store.install)
+		r.result(StatusMissing, store.update+" is not installed")
+		return nil
 	}
 
-	// Update CA certificates
-	fmt.Println("Updating CA certificates...")
-	cmd = exec.Command("sudo", "update-ca-certificates")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to update CA certificates: %w", err)
+	// Ask user if they want to install
+	fmt.Fprintln(r.out)
+	if !r.confirm("Install Caddy root CA to system trust store?", false) {
+		fmt.Fprintln(r.out, "Skipped. To install manually:")
+		r.printManualCAInstructions(caCertPath)
+		r.result(StatusMissing, "CA certificate is not in "+store.anchors)
+		return nil
 	}
 
+	if err := r.installCA(caCertPath, destPath, store.update); err != nil {
+		fmt.Fprintf(r.out, "✗ Failed to install certificate: %v\n", err)
+		fmt.Fprintln(r.out)
+		r.printManualCAInstructions(caCertPath)
+		r.result(StatusFailed, err.Error())
+		return nil
+	}
+	fmt.Fprintln(r.out, "✓ CA certificate installed successfully")
+	r.result(StatusChanged, "CA certificate installed to "+destPath)
 	return nil
 }
 
-// installRHELCA installs the CA certificate on RHEL/CentOS/Fedora systems
-func installRHELCA(caCertPath string) error {
-	destPath := "/etc/pki/ca-trust/source/anchors/caddy-local-ca.crt"
+// printCANotFound explains that the CA is created by the daemon
+func (r *runner) printCANotFound(caCertPath string) {
+	fmt.Fprintln(r.out, "⚠ CA certificate not found")
+	fmt.Fprintf(r.out, "  Expected location: %s\n", caCertPath)
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "The certificate will be created automatically when you start the daemon.")
+	fmt.Fprintln(r.out, "After starting the daemon, run 'faa setup' again to install the certificate.")
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "You can check the certificate path with: faa ca-path")
+	r.result(StatusMissing, "CA certificate not created yet; start the daemon first")
+}
 
+// installCA copies the CA certificate into a trust store and rebuilds the
+// trusted bundle with update
+func (r *runner) installCA(caCertPath, destPath, update string) error {
 	// Copy certificate
-	fmt.Printf("Installing certificate to %s...\n", destPath)
-	cmd := exec.Command("sudo", "cp", caCertPath, destPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	fmt.Fprintf(r.out, "Installing certificate to %s...\n", destPath)
+	cmd := r.command("sudo", "cp", caCertPath, destPath)
 	cmd.Stdin = os.Stdin
 
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to copy certificate: %w", err)
 	}
+	r.record(Change{Kind: changeCAAnchor, Path: destPath})
 
-	// Update CA trust
-	fmt.Println("Updating CA trust...")
-	cmd = exec.Command("sudo", "update-ca-trust")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to update CA trust: %w", err)
+	// Update the trusted bundle
+	fmt.Fprintf(r.out, "Running %s...\n", update)
+	if err := r.command("sudo", update).Run(); err != nil {
+		return fmt.Errorf("failed to run %s: %w", update, err)
 	}
 
 	return nil
 }
 
 // printManualCAInstructions prints manual instructions for installing the CA certificate
-func printManualCAInstructions(caCertPath string) {
-	fmt.Println()
-	fmt.Println("Manual CA Certificate Installation:")
-	fmt.Println()
-	fmt.Println("For Debian/Ubuntu:")
-	fmt.Printf("  sudo cp %s /usr/local/share/ca-certificates/caddy-local-ca.crt\n", caCertPath)
-	fmt.Println("  sudo update-ca-certificates")
-	fmt.Println()
-	fmt.Println("For RHEL/CentOS/Fedora:")
-	fmt.Printf("  sudo cp %s /etc/pki/ca-trust/source/anchors/caddy-local-ca.crt\n", caCertPath)
-	fmt.Println("  sudo update-ca-trust")
-	fmt.Println()
-	fmt.Println("For Arch Linux:")
-	fmt.Printf("  sudo cp %s /etc/ca-certificates/trust-source/anchors/caddy-local-ca.crt\n", caCertPath)
-	fmt.Println("  sudo trust extract-compat")
-	fmt.Println()
-	fmt.Println("After installation, verify with:")
-	fmt.Println("  curl -v https://<your-project>.localhost")
+func (r *runner) printManualCAInstructions(caCertPath string) {
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "Manual CA Certificate Installation:")
+	for _, store := range trustStores {
+		fmt.Fprintln(r.out)
+		fmt.Fprintf(r.out, "For %s:\n", store.description)
+		if store.method == trustNixOS {
+			fmt.Fprintln(r.out, "  Add the certificate to security.pki.certificates in configuration.nix")
+			fmt.Fprintln(r.out, "  and run: sudo nixos-rebuild switch")
+			continue
+		}
+		for _, command := range store.installCommands(caCertPath) {
+			fmt.Fprintf(r.out, "  %s\n", command)
+		}
+	}
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "After installation, verify with:")
+	fmt.Fprintln(r.out, "  curl -v https://<your-project>.localhost")
 }
 
 // filesAreEqual checks if two files have the same content
@@ -347,19 +667,13 @@ func filesAreEqual(path1, path2 string) bool {
 }
 
 // setupLaunchDaemon sets up the macOS LaunchDaemon for faa
-func setupLaunchDaemon() error {
-	fmt.Println("Setting up LaunchDaemon for faa daemon...")
-
-	// Get the current binary path
-	binaryPath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get binary path: %w", err)
-	}
+func (r *runner) setupLaunchDaemon() error {
+	r.begin(StepService)
+	fmt.Fprintln(r.out, "Setting up LaunchDaemon for faa daemon...")
 
-	// Resolve symlinks
-	binaryPath, err = filepath.EvalSymlinks(binaryPath)
+	binaryPath, err := executablePath()
 	if err != nil {
-		return fmt.Errorf("failed to resolve binary path: %w", err)
+		return err
 	}
 
 	// Get the socket path for daemon communication
@@ -371,53 +685,49 @@ func setupLaunchDaemon() error {
 
 	// Generate the LaunchDaemon plist
 	plistContent := generateLaunchDaemonPlist(binaryPath, socketDir)
-	plistPath := "/Library/LaunchDaemons/dev.localhost-dev.plist"
+	plistPath := launchDaemonPath
 
 	// Check if LaunchDaemon is already installed
 	if _, err := os.Stat(plistPath); err == nil {
-		fmt.Printf("✓ LaunchDaemon plist already exists at %s\n", plistPath)
+		fmt.Fprintf(r.out, "✓ LaunchDaemon plist already exists at %s\n", plistPath)
 
 		// Check if it needs updating
 		currentContent, err := os.ReadFile(plistPath)
 		if err == nil && string(currentContent) == plistContent {
-			fmt.Println("✓ LaunchDaemon configuration is up to date")
+			fmt.Fprintln(r.out, "✓ LaunchDaemon configuration is up to date")
 
 			// Check if daemon is loaded
 			if isLaunchDaemonLoaded() {
-				fmt.Println("✓ LaunchDaemon is loaded and running")
+				fmt.Fprintln(r.out, "✓ LaunchDaemon is loaded and running")
+				r.result(StatusOK, "LaunchDaemon is loaded from "+plistPath)
 				return nil
 			}
 		} else {
-			fmt.Println("⚠ LaunchDaemon configuration differs from expected")
+			fmt.Fprintln(r.out, "⚠ LaunchDaemon configuration differs from expected")
 		}
 	}
 
-	fmt.Println()
-	fmt.Println("This will:")
-	fmt.Printf("  1. Create LaunchDaemon plist at %s\n", plistPath)
-	fmt.Printf("  2. Configure daemon to run as root with socket at %s\n", socketPath)
-	fmt.Println("  3. Load the LaunchDaemon with launchctl")
-	fmt.Println()
-	fmt.Print("Proceed with LaunchDaemon setup? [y/N]: ")
-
-	reader := bufio.NewReader(os.Stdin)
-	response, err := reader.ReadString('\n')
-	if err != nil {
-		fmt.Println()
-		fmt.Println("Skipped. You can set up the LaunchDaemon manually later.")
-		printManualLaunchDaemonInstructions(binaryPath, plistPath, plistContent)
+	if r.opts.Check {
+		r.result(StatusMissing, "LaunchDaemon is not installed or not loaded")
 		return nil
 	}
 
-	response = strings.TrimSpace(strings.ToLower(response))
-	if response != "y" && response != "yes" {
-		fmt.Println("Skipped. You can set up the LaunchDaemon manually later.")
-		printManualLaunchDaemonInstructions(binaryPath, plistPath, plistContent)
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "This will:")
+	fmt.Fprintf(r.out, "  1. Create LaunchDaemon plist at %s\n", plistPath)
+	fmt.Fprintf(r.out, "  2. Configure daemon to run as root with socket at %s\n", socketPath)
+	fmt.Fprintln(r.out, "  3. Load the LaunchDaemon with launchctl")
+	fmt.Fprintln(r.out)
+
+	if !r.confirm("Proceed with LaunchDaemon setup?", false) {
+		fmt.Fprintln(r.out, "Skipped. You can set up the LaunchDaemon manually later.")
+		r.printManualLaunchDaemonInstructions(binaryPath, plistPath, plistContent)
+		r.result(StatusMissing, "LaunchDaemon is not installed or not loaded")
 		return nil
 	}
 
 	// Write the plist file
-	fmt.Printf("Creating LaunchDaemon plist at %s...\n", plistPath)
+	fmt.Fprintf(r.out, "Creating LaunchDaemon plist at %s...\n", plistPath)
 	tmpPlistPath := filepath.Join(os.TempDir(), "dev.localhost-dev.plist")
 	if err := os.WriteFile(tmpPlistPath, []byte(plistContent), 0644); err != nil {
 		return fmt.Errorf("failed to write temporary plist: %w", err)
@@ -425,63 +735,48 @@ func setupLaunchDaemon() error {
 	defer os.Remove(tmpPlistPath)
 
 	// Copy to system location with sudo
-	cmd := exec.Command("sudo", "cp", tmpPlistPath, plistPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd := r.command("sudo", "cp", tmpPlistPath, plistPath)
 	cmd.Stdin = os.Stdin
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to copy plist to system location: %w", err)
 	}
+	r.record(Change{Kind: changeLaunchDaemon, Path: plistPath})
 
 	// Set proper ownership and permissions
-	cmd = exec.Command("sudo", "chown", "root:wheel", plistPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	if err := r.command("sudo", "chown", "root:wheel", plistPath).Run(); err != nil {
 		return fmt.Errorf("failed to set plist ownership: %w", err)
 	}
 
-	cmd = exec.Command("sudo", "chmod", "644", plistPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	if err := r.command("sudo", "chmod", "644", plistPath).Run(); err != nil {
 		return fmt.Errorf("failed to set plist permissions: %w", err)
 	}
 
 	// Create socket directory with proper permissions
-	fmt.Printf("Creating socket directory at %s...\n", socketDir)
-	cmd = exec.Command("sudo", "mkdir", "-p", socketDir)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	fmt.Fprintf(r.out, "Creating socket directory at %s...\n", socketDir)
+	if err := r.command("sudo", "mkdir", "-p", socketDir).Run(); err != nil {
 		return fmt.Errorf("failed to create socket directory: %w", err)
 	}
 
 	// Set socket directory permissions to allow user access (0755)
-	cmd = exec.Command("sudo", "chmod", "755", socketDir)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	if err := r.command("sudo", "chmod", "755", socketDir).Run(); err != nil {
 		return fmt.Errorf("failed to set socket directory permissions: %w", err)
 	}
 
 	// Load the LaunchDaemon
-	fmt.Println("Loading LaunchDaemon with launchctl...")
-	cmd = exec.Command("sudo", "launchctl", "load", "-w", plistPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	fmt.Fprintln(r.out, "Loading LaunchDaemon with launchctl...")
+	if err := r.command("sudo", "launchctl", "load", "-w", plistPath).Run(); err != nil {
 		return fmt.Errorf("failed to load LaunchDaemon: %w", err)
 	}
 
-	fmt.Println("✓ LaunchDaemon setup complete")
-	fmt.Println()
-	fmt.Println("The faa daemon will now start automatically on boot.")
-	fmt.Printf("Socket location: %s\n", socketPath)
-	fmt.Println()
-	fmt.Println("To unload the daemon:")
-	fmt.Printf("  sudo launchctl unload %s\n", plistPath)
+	fmt.Fprintln(r.out, "✓ LaunchDaemon setup complete")
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "The faa daemon will now start automatically on boot.")
+	fmt.Fprintf(r.out, "Socket location: %s\n", socketPath)
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "To unload the daemon:")
+	fmt.Fprintf(r.out, "  sudo launchctl unload %s\n", plistPath)
 
+	r.result(StatusChanged, "LaunchDaemon installed to "+plistPath)
 	return nil
 }
 
@@ -528,31 +823,32 @@ func isLaunchDaemonLoaded() bool {
 }
 
 // printManualLaunchDaemonInstructions prints manual setup instructions
-func printManualLaunchDaemonInstructions(binaryPath, plistPath, plistContent string) {
-	fmt.Println()
-	fmt.Println("Manual LaunchDaemon Setup:")
-	fmt.Println()
-	fmt.Println("1. Create the plist file:")
-	fmt.Printf("   Save the following content to %s\n", plistPath)
-	fmt.Println()
-	fmt.Println(plistContent)
-	fmt.Println()
-	fmt.Println("2. Set proper permissions:")
-	fmt.Printf("   sudo chown root:wheel %s\n", plistPath)
-	fmt.Printf("   sudo chmod 644 %s\n", plistPath)
-	fmt.Println()
-	fmt.Println("3. Create socket directory:")
-	fmt.Println("   sudo mkdir -p /var/run/faa")
-	fmt.Println("   sudo chmod 755 /var/run/faa")
-	fmt.Println()
-	fmt.Println("4. Load the LaunchDaemon:")
-	fmt.Printf("   sudo launchctl load -w %s\n", plistPath)
+func (r *runner) printManualLaunchDaemonInstructions(binaryPath, plistPath, plistContent string) {
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "Manual LaunchDaemon Setup:")
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "1. Create the plist file:")
+	fmt.Fprintf(r.out, "   Save the following content to %s\n", plistPath)
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, plistContent)
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "2. Set proper permissions:")
+	fmt.Fprintf(r.out, "   sudo chown root:wheel %s\n", plistPath)
+	fmt.Fprintf(r.out, "   sudo chmod 644 %s\n", plistPath)
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "3. Create socket directory:")
+	fmt.Fprintln(r.out, "   sudo mkdir -p /var/run/faa")
+	fmt.Fprintln(r.out, "   sudo chmod 755 /var/run/faa")
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "4. Load the LaunchDaemon:")
+	fmt.Fprintf(r.out, "   sudo launchctl load -w %s\n", plistPath)
 }
 
 // checkCATrustDarwin checks and installs the Caddy root CA certificate on macOS
-func checkCATrustDarwin() error {
-	fmt.Println()
-	fmt.Println("Checking CA certificate trust...")
+func (r *runner) checkCATrustDarwin() error {
+	r.begin(StepCA)
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "Checking CA certificate trust...")
 
 	// Get CA certificate path from proxy package
 	caCertPath, err := proxy.GetCAPath()
@@ -562,65 +858,67 @@ func checkCATrustDarwin() error {
 
 	// Check if the certificate exists
 	if _, err := os.Stat(caCertPath); os.IsNotExist(err) {
-		fmt.Println("⚠ CA certificate not found")
-		fmt.Printf("  Expected location: %s\n", caCertPath)
-		fmt.Println()
-		fmt.Println("The certificate will be created automatically when the daemon starts.")
-		fmt.Println("After starting the daemon, run 'faa setup' again to install the certificate.")
-		fmt.Println()
-		fmt.Println("You can check the certificate path with: faa ca-path")
+		r.printCANotFound(caCertPath)
 		return nil
 	}
 
-	fmt.Printf("✓ Found CA certificate: %s\n", caCertPath)
+	fmt.Fprintf(r.out, "✓ Found CA certificate: %s\n", caCertPath)
 
 	// Check if certificate is already trusted
 	if isCertificateTrustedDarwin(caCertPath) {
-		fmt.Println("✓ CA certificate is already trusted in System keychain")
+		fmt.Fprintln(r.out, "✓ CA certificate is already trusted in System keychain")
+		r.result(StatusOK, "CA certificate is trusted in the System keychain")
 		return nil
 	}
 
-	fmt.Println()
-	fmt.Println("This will install the Caddy root CA to the System keychain.")
-	fmt.Println("This allows your browser to trust HTTPS certificates for local development domains.")
-	fmt.Println()
-	fmt.Print("Install CA certificate to System keychain? [y/N]: ")
-
-	reader := bufio.NewReader(os.Stdin)
-	response, err := reader.ReadString('\n')
-	if err != nil {
-		fmt.Println()
-		fmt.Println("Skipped. To install manually:")
-		printManualCATrustInstructionsDarwin(caCertPath)
+	addCmd := fmt.Sprintf("sudo security add-trusted-cert -d -r trustRoot -k %s %s", systemKeychain, caCertPath)
+	if r.opts.Check {
+		r.result(StatusMissing, "CA certificate is not trusted in the System keychain")
+		r.suggest(addCmd)
 		return nil
 	}
 
-	response = strings.TrimSpace(strings.ToLower(response))
-	if response != "y" && response != "yes" {
-		fmt.Println("Skipped. To install manually:")
-		printManualCATrustInstructionsDarwin(caCertPath)
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "This will install the Caddy root CA to the System keychain.")
+	fmt.Fprintln(r.out, "This allows your browser to trust HTTPS certificates for local development domains.")
+	fmt.Fprintln(r.out)
+
+	if !r.confirm("Install CA certificate to System keychain?", false) {
+		fmt.Fprintln(r.out, "Skipped. To install manually:")
+		r.printManualCATrustInstructionsDarwin(caCertPath)
+		r.result(StatusMissing, "CA certificate is not trusted in the System keychain")
+		r.suggest(addCmd)
 		return nil
 	}
 
 	// Install certificate using security command
-	fmt.Println("Installing CA certificate to System keychain...")
-	cmd := exec.Command("sudo", "security", "add-trusted-cert",
+	fmt.Fprintln(r.out, "Installing CA certificate to System keychain...")
+	cmd := r.command("sudo", "security", "add-trusted-cert",
 		"-d",              // Add to admin cert store
 		"-r", "trustRoot", // Set trust policy to root
-		"-k", "/Library/Keychains/System.keychain", // System keychain
+		"-k", systemKeychain, // System keychain
 		caCertPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
 
 	if err := cmd.Run(); err != nil {
-		fmt.Printf("✗ Failed to install certificate: %v\n", err)
-		fmt.Println()
-		printManualCATrustInstructionsDarwin(caCertPath)
+		fmt.Fprintf(r.out, "✗ Failed to install certificate: %v\n", err)
+		fmt.Fprintln(r.out)
+		r.printManualCATrustInstructionsDarwin(caCertPath)
+		r.result(StatusFailed, err.Error())
 		return nil
 	}
 
-	fmt.Println("✓ CA certificate installed successfully")
+	// The keychain entry is removed by fingerprint, since the CA file
+	// may have changed by the time it is uninstalled
+	fingerprint, err := certificateSHA1(caCertPath)
+	if err != nil {
+		fmt.Fprintf(r.out, "⚠ Warning: failed to record the change for 'faa setup --uninstall': %v\n", err)
+	} else {
+		r.record(Change{Kind: changeKeychain, Path: systemKeychain, Fingerprint: fingerprint})
+	}
+
+	fmt.Fprintln(r.out, "✓ CA certificate installed successfully")
+	r.result(StatusChanged, "CA certificate added to the System keychain")
 	return nil
 }
 
@@ -637,7 +935,7 @@ func isCertificateTrustedDarwin(certPath string) bool {
 
 	// Search for certificate in System keychain
 	cmd = exec.Command("security", "find-certificate", "-a", "-c", "Caddy Local Authority",
-		"/Library/Keychains/System.keychain")
+		systemKeychain)
 	output, err = cmd.CombinedOutput()
 	if err != nil {
 		return false
@@ -648,20 +946,20 @@ func isCertificateTrustedDarwin(certPath string) bool {
 }
 
 // printManualCATrustInstructionsDarwin prints manual CA trust instructions for macOS
-func printManualCATrustInstructionsDarwin(caCertPath string) {
-	fmt.Println()
-	fmt.Println("Manual CA Certificate Installation for macOS:")
-	fmt.Println()
-	fmt.Println("Option 1 - Using security command:")
-	fmt.Printf("  sudo security add-trusted-cert -d -r trustRoot -k /Library/Keychains/System.keychain %s\n", caCertPath)
-	fmt.Println()
-	fmt.Println("Option 2 - Using Keychain Access app:")
-	fmt.Println("  1. Open Keychain Access.app")
-	fmt.Printf("  2. Drag and drop %s into the System keychain\n", caCertPath)
-	fmt.Println("  3. Double-click the certificate")
-	fmt.Println("  4. Expand the 'Trust' section")
-	fmt.Println("  5. Set 'When using this certificate' to 'Always Trust'")
-	fmt.Println()
-	fmt.Println("After installation, verify with:")
-	fmt.Println("  curl -v https://<your-project>.localhost")
+func (r *runner) printManualCATrustInstructionsDarwin(caCertPath string) {
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "Manual CA Certificate Installation for macOS:")
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "Option 1 - Using security command:")
+	fmt.Fprintf(r.out, "  sudo security add-trusted-cert -d -r trustRoot -k /Library/Keychains/System.keychain %s\n", caCertPath)
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "Option 2 - Using Keychain Access app:")
+	fmt.Fprintln(r.out, "  1. Open Keychain Access.app")
+	fmt.Fprintf(r.out, "  2. Drag and drop %s into the System keychain\n", caCertPath)
+	fmt.Fprintln(r.out, "  3. Double-click the certificate")
+	fmt.Fprintln(r.out, "  4. Expand the 'Trust' section")
+	fmt.Fprintln(r.out, "  5. Set 'When using this certificate' to 'Always Trust'")
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "After installation, verify with:")
+	fmt.Fprintln(r.out, "  curl -v https://<your-project>.localhost")
 }
@@ -1,6 +1,9 @@
 package setup
 
 import (
+	"bufio"
+	"bytes"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -72,10 +75,125 @@ func TestCheckPrivilegedPorts(t *testing.T) {
 }
 
 func TestCheckCATrust(t *testing.T) {
-	// This test just ensures the function doesn't panic
-	// The actual behavior depends on the environment and requires user interaction
-	// We can't fully test it in an automated test
-	t.Skip("Skipping interactive test")
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	caCertPath := filepath.Join(home, ".config", "faa", "root.pem")
+	caCert := writeTestCertificate(t, caCertPath)
+
+	// Each fake root filesystem is set up with files (path to content);
+	// paths ending in a slash are directories
+	tests := []struct {
+		name     string
+		files    map[string]string
+		status   string
+		contains string
+	}{
+		{
+			name:   "no trust store",
+			files:  map[string]string{},
+			status: StatusMissing,
+		},
+		{
+			name:     "Debian missing",
+			files:    map[string]string{"/usr/local/share/ca-certificates/": ""},
+			status:   StatusMissing,
+			contains: "sudo cp " + caCertPath + " /usr/local/share/ca-certificates/caddy-local-ca.crt",
+		},
+		{
+			name:   "Debian installed",
+			files:  map[string]string{"/usr/local/share/ca-certificates/caddy-local-ca.crt": string(caCert)},
+			status: StatusOK,
+		},
+		{
+			name: "Debian trusted elsewhere",
+			files: map[string]string{
+				"/usr/local/share/ca-certificates/":  "",
+				"/etc/ssl/certs/ca-certificates.crt": string(caCert),
+			},
+			status: StatusOK,
+		},
+		{
+			name: "Alpine",
+			files: map[string]string{
+				"/etc/alpine-release":               "3.20.0\n",
+				"/usr/local/share/ca-certificates/": "",
+			},
+			status:   StatusMissing,
+			contains: "sudo update-ca-certificates",
+		},
+		{
+			name:     "openSUSE",
+			files:    map[string]string{"/etc/pki/trust/anchors/": ""},
+			status:   StatusMissing,
+			contains: "/etc/pki/trust/anchors/caddy-local-ca.crt",
+		},
+		{
+			name:     "Arch missing",
+			files:    map[string]string{"/etc/ca-certificates/trust-source/": ""},
+			status:   StatusMissing,
+			contains: "sudo trust anchor --store " + caCertPath,
+		},
+		{
+			name: "Arch installed",
+			files: map[string]string{
+				"/etc/ca-certificates/trust-source/": "",
+				"/etc/ssl/certs/ca-certificates.crt": string(caCert),
+			},
+			status: StatusOK,
+		},
+		{
+			name:     "NixOS missing",
+			files:    map[string]string{"/etc/NIXOS": ""},
+			status:   StatusMissing,
+			contains: "security.pki.certificates = [",
+		},
+		{
+			name: "NixOS installed",
+			files: map[string]string{
+				"/etc/NIXOS":                         "",
+				"/etc/ssl/certs/ca-certificates.crt": string(caCert),
+			},
+			status: StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for path, content := range tt.files {
+				if strings.HasSuffix(path, "/") {
+					if err := os.MkdirAll(filepath.Join(root, path), 0755); err != nil {
+						t.Fatalf("Failed to create %s: %v", path, err)
+					}
+					continue
+				}
+				path = filepath.Join(root, path)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatalf("Failed to write %s: %v", path, err)
+				}
+			}
+
+			r := newRunner(Options{Check: true, Output: io.Discard})
+			r.root = root
+			if err := r.checkCATrust(); err != nil {
+				t.Fatalf("checkCATrust() failed: %v", err)
+			}
+
+			step := r.report.Steps[0]
+			if step.Status != tt.status {
+				t.Errorf("status = %q (%s), want %q", step.Status, step.Detail, tt.status)
+			}
+			if tt.contains != "" && !strings.Contains(strings.Join(step.Actions, "\n"), tt.contains) {
+				t.Errorf("actions = %q, want them to contain %q", step.Actions, tt.contains)
+			}
+			if tt.status == StatusOK && len(step.Actions) != 0 {
+				t.Errorf("Expected no actions when the CA is trusted, got %q", step.Actions)
+			}
+		})
+	}
 }
 
 func TestGenerateLaunchDaemonPlist(t *testing.T) {
@@ -136,9 +254,104 @@ func TestRun(t *testing.T) {
 		t.Skip("Skipping interactive test")
 	default:
 		// Unsupported platform should return an error
-		err := Run()
+		_, err := Run(Options{})
 		if err == nil {
 			t.Error("Expected error for unsupported platform")
 		}
 	}
 }
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input    string
+		yes      bool
+		def      bool
+		expected bool
+	}{
+		{"y\n", false, false, true},
+		{"YES\n", false, false, true},
+		{"n\n", false, true, false},
+		{"\n", false, false, false},
+		{"\n", false, true, true},
+		{"", false, true, true},
+		{"", false, false, false},
+		{"", true, false, true},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		r := newRunner(Options{Yes: tt.yes, Output: &out})
+		r.reader = bufio.NewReader(strings.NewReader(tt.input))
+		if got := r.confirm("Proceed?", tt.def); got != tt.expected {
+			t.Errorf("confirm(%q, yes=%v, default=%v) = %v, want %v", tt.input, tt.yes, tt.def, got, tt.expected)
+		}
+		if tt.yes && !strings.Contains(out.String(), "Proceed? [y/N]: y") {
+			t.Errorf("Expected the answer to be printed with --yes, got %q", out.String())
+		}
+	}
+}
+
+func TestReportIncomplete(t *testing.T) {
+	report := &Report{Steps: []*Step{
+		{Name: StepPorts, Status: StatusOK},
+		{Name: StepService, Status: StatusSkipped},
+		{Name: StepCA, Status: StatusChanged},
+	}}
+	if !report.Complete() {
+		t.Errorf("Expected report to be complete, incomplete steps: %v", report.Incomplete())
+	}
+
+	report.Steps[0].Status = StatusMissing
+	report.Steps[2].Status = StatusFailed
+	if report.Complete() {
+		t.Error("Expected report with missing and failed steps to be incomplete")
+	}
+	if got := strings.Join(report.Incomplete(), ","); got != "ports,ca" {
+		t.Errorf("Incomplete() = %s, want ports,ca", got)
+	}
+}
+
+func TestRunCheck(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("Setup is not supported on this platform")
+	}
+	t.Setenv("HOME", t.TempDir())
+
+	if _, err := Run(Options{CAOnly: true, SkipCA: true, Output: io.Discard}); err == nil {
+		t.Error("Expected an error for --ca-only with --skip-ca")
+	}
+
+	// Without a CA certificate the CA step is missing, and nothing is
+	// changed or prompted for
+	report, err := Run(Options{Check: true, CAOnly: true, Output: io.Discard})
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if !report.Check {
+		t.Error("Expected a check report")
+	}
+
+	statuses := make(map[string]string)
+	for _, step := range report.Steps {
+		statuses[step.Name] = step.Status
+	}
+	if statuses[StepService] != StatusSkipped {
+		t.Errorf("service status = %q, want %q", statuses[StepService], StatusSkipped)
+	}
+	if statuses[StepCA] != StatusMissing {
+		t.Errorf("ca status = %q, want %q", statuses[StepCA], StatusMissing)
+	}
+	if statuses[StepNSS] != StatusSkipped {
+		t.Errorf("nss status = %q, want %q without browser profiles", statuses[StepNSS], StatusSkipped)
+	}
+	if report.Complete() {
+		t.Error("Expected the report to be incomplete without a CA certificate")
+	}
+
+	path, err := ManifestPath()
+	if err != nil {
+		t.Fatalf("ManifestPath() failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected a check not to record any changes")
+	}
+}
@@ -0,0 +1,241 @@
+package setup
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sahithyandev/faa/internal/daemon"
+)
+
+const (
+	// systemdService and systemdSocket are the names of the systemd user
+	// units running the daemon
+	systemdService = "faa.service"
+	systemdSocket  = "faa.socket"
+)
+
+// SystemdUnitDir returns the directory systemd loads user units from
+func SystemdUnitDir() (string, error) {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		configHome = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configHome, "systemd", "user"), nil
+}
+
+// SystemdServiceInstalled reports whether faa setup installed the systemd
+// user service
+func SystemdServiceInstalled() bool {
+	dir, err := SystemdUnitDir()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(dir, systemdService))
+	return err == nil
+}
+
+// systemdAvailable reports whether this machine is running systemd
+func systemdAvailable() bool {
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		return false
+	}
+	_, err := exec.LookPath("systemctl")
+	return err == nil
+}
+
+// setupSystemdService installs systemd user units that run the daemon,
+// either at login or, with socket activation, on the first connection to
+// the control socket. The daemon's output goes to the journal.
+func (r *runner) setupSystemdService() error {
+	r.begin(StepService)
+	fmt.Fprintln(r.out, "Checking systemd user service...")
+
+	if !systemdAvailable() {
+		fmt.Fprintln(r.out, "⚠ systemd is not running; start the daemon with 'faa daemon' or let 'faa run' start it")
+		r.result(StatusSkipped, "systemd is not running")
+		return nil
+	}
+
+	binaryPath, err := executablePath()
+	if err != nil {
+		return err
+	}
+
+	socketPath, err := daemon.SocketPath()
+	if err != nil {
+		return err
+	}
+
+	unitDir, err := SystemdUnitDir()
+	if err != nil {
+		return err
+	}
+	servicePath := filepath.Join(unitDir, systemdService)
+	socketUnitPath := filepath.Join(unitDir, systemdSocket)
+
+	// Keep whichever mode was chosen before
+	_, err = os.Stat(socketUnitPath)
+	socketActivated := err == nil
+	if _, err := os.Stat(servicePath); err == nil {
+		upToDate := filesMatch(servicePath, generateSystemdService(binaryPath, socketActivated))
+		if socketActivated {
+			upToDate = upToDate && filesMatch(socketUnitPath, generateSystemdSocket(socketPath))
+		}
+		if upToDate {
+			fmt.Fprintln(r.out, "✓ systemd user service is installed and up to date")
+			r.result(StatusOK, "systemd user service is installed in "+unitDir)
+			return nil
+		}
+		fmt.Fprintln(r.out, "⚠ systemd user service differs from expected")
+	}
+
+	if r.opts.Check {
+		r.result(StatusMissing, "systemd user service is not installed or out of date")
+		return nil
+	}
+
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "faa can install a systemd user service so the daemon runs without")
+	fmt.Fprintln(r.out, "'faa daemon &' and its output goes to the journal.")
+	if !r.confirm("Install the systemd user service?", false) {
+		fmt.Fprintln(r.out, "Skipped. You can run 'faa setup' again later.")
+		r.result(StatusMissing, "systemd user service is not installed or out of date")
+		return nil
+	}
+
+	socketActivated = r.confirm("Start the daemon on first use (socket activation) instead of at login?", true)
+
+	if err := os.MkdirAll(unitDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", unitDir, err)
+	}
+	fmt.Fprintf(r.out, "Writing %s...\n", servicePath)
+	if err := os.WriteFile(servicePath, []byte(generateSystemdService(binaryPath, socketActivated)), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", servicePath, err)
+	}
+	r.record(Change{Kind: changeSystemdUnit, Path: servicePath})
+
+	// Switching modes disables the unit that is no longer used
+	unit := systemdService
+	if socketActivated {
+		unit = systemdSocket
+		fmt.Fprintf(r.out, "Writing %s...\n", socketUnitPath)
+		if err := os.WriteFile(socketUnitPath, []byte(generateSystemdSocket(socketPath)), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", socketUnitPath, err)
+		}
+		r.record(Change{Kind: changeSystemdUnit, Path: socketUnitPath})
+	} else if _, err := os.Stat(socketUnitPath); err == nil {
+		_ = r.systemctl("disable", "--now", systemdSocket)
+		if err := os.Remove(socketUnitPath); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", socketUnitPath, err)
+		}
+	}
+
+	if err := r.systemctl("daemon-reload"); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+
+	// Starting the units now would take the control socket from a running
+	// daemon, so in that case they are only enabled
+	if daemonRunning(socketPath) {
+		if err := r.systemctl("enable", unit); err != nil {
+			return fmt.Errorf("failed to enable %s: %w", unit, err)
+		}
+		fmt.Fprintf(r.out, "✓ Enabled %s; it takes over after 'faa stop' and\n", unit)
+		fmt.Fprintf(r.out, "    systemctl --user start %s\n", unit)
+	} else {
+		if err := r.systemctl("enable", "--now", unit); err != nil {
+			return fmt.Errorf("failed to enable %s: %w", unit, err)
+		}
+		fmt.Fprintf(r.out, "✓ Enabled and started %s\n", unit)
+	}
+
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "View daemon logs with:")
+	fmt.Fprintln(r.out, "  faa daemon logs")
+	r.result(StatusChanged, fmt.Sprintf("enabled %s in %s", unit, unitDir))
+	return nil
+}
+
+// filesMatch reports whether the file at path has the given content
+func filesMatch(path, content string) bool {
+	current, err := os.ReadFile(path)
+	return err == nil && string(current) == content
+}
+
+// daemonRunning reports whether a daemon is accepting connections on
+// socketPath
+func daemonRunning(socketPath string) bool {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// systemctl runs systemctl for the user's service manager
+func (r *runner) systemctl(args ...string) error {
+	return r.command("systemctl", append([]string{"--user"}, args...)...).Run()
+}
+
+// generateSystemdService generates the daemon's service unit. A
+// socket-activated service is started by its socket, so it isn't installed
+// into a target itself.
+func generateSystemdService(binaryPath string, socketActivated bool) string {
+	unit := `[Unit]
+Description=faa development server daemon
+Documentation=https://github.com/sahithyandev/faa
+`
+	if socketActivated {
+		unit += "Requires=" + systemdSocket + "\nAfter=" + systemdSocket + "\n"
+	}
+	unit += fmt.Sprintf(`
+[Service]
+Type=simple
+ExecStart=%s daemon
+Restart=on-failure
+RestartSec=2
+`, systemdQuote(binaryPath))
+	if !socketActivated {
+		unit += `
+[Install]
+WantedBy=default.target
+`
+	}
+	return unit
+}
+
+// generateSystemdSocket generates the socket unit listening on the daemon's
+// control socket
+func generateSystemdSocket(socketPath string) string {
+	return fmt.Sprintf(`[Unit]
+Description=faa daemon control socket
+Documentation=https://github.com/sahithyandev/faa
+
+[Socket]
+ListenStream=%s
+SocketMode=0600
+DirectoryMode=0755
+
+[Install]
+WantedBy=sockets.target
+`, strings.ReplaceAll(socketPath, "%", "%%"))
+}
+
+// systemdQuote quotes a path for a unit's command line if it contains
+// spaces, and escapes the specifier character
+func systemdQuote(path string) string {
+	path = strings.ReplaceAll(path, "%", "%%")
+	if strings.ContainsAny(path, " \t") {
+		return `"` + strings.ReplaceAll(path, `"`, `\"`) + `"`
+	}
+	return path
+}
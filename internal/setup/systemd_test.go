@@ -0,0 +1,83 @@
+package setup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSystemdUnitDir(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	dir, err := SystemdUnitDir()
+	if err != nil {
+		t.Fatalf("SystemdUnitDir() failed: %v", err)
+	}
+	if expected := filepath.Join(configHome, "systemd", "user"); dir != expected {
+		t.Errorf("SystemdUnitDir() = %s, want %s", dir, expected)
+	}
+
+	if SystemdServiceInstalled() {
+		t.Error("Service should not be installed yet")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create unit directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, systemdService), []byte(generateSystemdService("/usr/local/bin/faa", false)), 0644); err != nil {
+		t.Fatalf("Failed to write service: %v", err)
+	}
+	if !SystemdServiceInstalled() {
+		t.Error("Service should be installed")
+	}
+}
+
+func TestGenerateSystemdService(t *testing.T) {
+	service := generateSystemdService("/usr/local/bin/faa", false)
+	for _, expected := range []string{
+		"ExecStart=/usr/local/bin/faa daemon",
+		"Restart=on-failure",
+		"WantedBy=default.target",
+	} {
+		if !strings.Contains(service, expected) {
+			t.Errorf("Expected service to contain %q", expected)
+		}
+	}
+	if strings.Contains(service, systemdSocket) {
+		t.Error("Service started at login should not depend on the socket")
+	}
+
+	// A socket-activated service is started by its socket, not at login
+	service = generateSystemdService("/usr/local/bin/faa", true)
+	if !strings.Contains(service, "Requires=faa.socket") {
+		t.Error("Socket-activated service should require its socket")
+	}
+	if strings.Contains(service, "[Install]") {
+		t.Error("Socket-activated service should not be installed into a target")
+	}
+
+	service = generateSystemdService("/home/me/my tools/faa%1", false)
+	if !strings.Contains(service, `ExecStart="/home/me/my tools/faa%%1" daemon`) {
+		t.Errorf("Binary path should be quoted and escaped, got:\n%s", service)
+	}
+}
+
+func TestGenerateSystemdSocket(t *testing.T) {
+	socket := generateSystemdSocket("/home/me/.config/faa/ctl.sock")
+	for _, expected := range []string{
+		"ListenStream=/home/me/.config/faa/ctl.sock",
+		"SocketMode=0600",
+		"WantedBy=sockets.target",
+	} {
+		if !strings.Contains(socket, expected) {
+			t.Errorf("Expected socket to contain %q", expected)
+		}
+	}
+}
+
+func TestDaemonRunning(t *testing.T) {
+	if daemonRunning(filepath.Join(t.TempDir(), "ctl.sock")) {
+		t.Error("daemonRunning() should be false without a socket")
+	}
+}
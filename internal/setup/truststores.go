@@ -0,0 +1,614 @@
+package setup
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/sahithyandev/faa/internal/proxy"
+)
+
+const (
+	// nssNickname is the name the CA is added to NSS databases under
+	nssNickname = "faa local CA"
+
+	// javaAlias is the alias the CA is added to Java keystores under
+	javaAlias = "faa-local-ca"
+
+	// javaStorePass is the default password of the JDK's cacerts keystore
+	javaStorePass = "changeit"
+
+	// caAnchorName is the file name the CA is copied to in an anchors
+	// directory
+	caAnchorName = "caddy-local-ca.crt"
+)
+
+// How a system trust store takes the CA
+const (
+	// trustCopy copies the certificate into an anchors directory and
+	// rebuilds the bundle with the update command
+	trustCopy = iota
+
+	// trustP11Kit adds the certificate with p11-kit's trust anchor, which
+	// also rebuilds the bundle
+	trustP11Kit
+
+	// trustNixOS can't change /etc, which is built from the system
+	// configuration, so setup prints what to add to it
+	trustNixOS
+)
+
+// trustStore is a system CA trust store and how to add the CA to it
+type trustStore struct {
+	description string
+
+	// marker is a file or directory identifying the distribution
+	marker string
+
+	method int
+
+	// anchors is the directory the CA is copied to
+	anchors string
+
+	// update is the command adding the CA to the trusted bundle, and
+	// install how to get it
+	update  string
+	install string
+
+	// bundle is the extracted bundle, checked for the CA when faa's anchor
+	// file isn't there to compare, as with a CA that was trusted already
+	bundle string
+}
+
+// trustStores are the trust stores setup can install the CA into, in the
+// order they are detected. Alpine has Debian's layout but its own package
+// manager, so it comes before Debian.
+var trustStores = []trustStore{
+	{
+		description: "NixOS",
+		marker:      "/etc/NIXOS",
+		method:      trustNixOS,
+		bundle:      "/etc/ssl/certs/ca-certificates.crt",
+	},
+	{
+		description: "Alpine",
+		marker:      "/etc/alpine-release",
+		method:      trustCopy,
+		anchors:     "/usr/local/share/ca-certificates",
+		update:      "update-ca-certificates",
+		install:     "sudo apk add ca-certificates",
+		bundle:      "/etc/ssl/certs/ca-certificates.crt",
+	},
+	{
+		description: "Debian/Ubuntu",
+		marker:      "/usr/local/share/ca-certificates",
+		method:      trustCopy,
+		anchors:     "/usr/local/share/ca-certificates",
+		update:      "update-ca-certificates",
+		install:     "sudo apt install ca-certificates",
+		bundle:      "/etc/ssl/certs/ca-certificates.crt",
+	},
+	{
+		description: "RHEL/CentOS/Fedora",
+		marker:      "/etc/pki/ca-trust/source/anchors",
+		method:      trustCopy,
+		anchors:     "/etc/pki/ca-trust/source/anchors",
+		update:      "update-ca-trust",
+		install:     "sudo dnf install ca-certificates",
+		bundle:      "/etc/pki/tls/certs/ca-bundle.crt",
+	},
+	{
+		description: "openSUSE",
+		marker:      "/etc/pki/trust/anchors",
+		method:      trustCopy,
+		anchors:     "/etc/pki/trust/anchors",
+		update:      "update-ca-certificates",
+		install:     "sudo zypper install ca-certificates",
+		bundle:      "/etc/ssl/ca-bundle.pem",
+	},
+	{
+		description: "Arch Linux",
+		marker:      "/etc/ca-certificates/trust-source",
+		method:      trustP11Kit,
+		update:      "trust",
+		install:     "sudo pacman -S p11-kit",
+		bundle:      "/etc/ssl/certs/ca-certificates.crt",
+	},
+}
+
+// anchorUpdate returns the command rebuilding the trusted bundle of the
+// trust store setup copies the CA to at path, and whether there is one
+func anchorUpdate(path string) (string, bool) {
+	for _, store := range trustStores {
+		if store.method == trustCopy && path == filepath.Join(store.anchors, caAnchorName) {
+			return store.update, true
+		}
+	}
+	return "", false
+}
+
+// detectTrustStore returns the first trust store present under root
+func detectTrustStore(root string) *trustStore {
+	for i := range trustStores {
+		if _, err := os.Stat(filepath.Join(root, trustStores[i].marker)); err == nil {
+			return &trustStores[i]
+		}
+	}
+	return nil
+}
+
+// installCommands returns the commands adding the CA to the trust store,
+// which NixOS doesn't have
+func (s *trustStore) installCommands(caCertPath string) []string {
+	switch s.method {
+	case trustCopy:
+		return []string{
+			fmt.Sprintf("sudo cp %s %s", caCertPath, filepath.Join(s.anchors, caAnchorName)),
+			"sudo " + s.update,
+		}
+	case trustP11Kit:
+		return []string{"sudo trust anchor --store " + caCertPath}
+	default:
+		return nil
+	}
+}
+
+// path returns where a system path is under the runner's root
+func (r *runner) path(path string) string {
+	return filepath.Join(r.root, path)
+}
+
+// hasCommand reports whether a command is installed. The sbin directories
+// are searched too, as they aren't on every user's PATH.
+func (r *runner) hasCommand(name string) bool {
+	if r.root == "/" {
+		if _, err := exec.LookPath(name); err == nil {
+			return true
+		}
+	}
+	for _, dir := range []string{"/usr/local/sbin", "/usr/local/bin", "/usr/sbin", "/usr/bin", "/sbin", "/bin"} {
+		if _, err := os.Stat(r.path(filepath.Join(dir, name))); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// bundleHas reports whether the trust store's extracted bundle has the
+// certificate der
+func (r *runner) bundleHas(store *trustStore, der []byte) bool {
+	data, err := os.ReadFile(r.path(store.bundle))
+	return err == nil && pemContains(data, der)
+}
+
+// checkCATrustP11Kit adds the CA as a p11-kit trust anchor
+func (r *runner) checkCATrustP11Kit(store *trustStore, caCertPath string) error {
+	fmt.Fprintf(r.out, "✓ Detected trust store: p11-kit (%s)\n", store.description)
+
+	der, err := certificateDER(caCertPath)
+	if err != nil {
+		return err
+	}
+	if r.bundleHas(store, der) {
+		fmt.Fprintln(r.out, "✓ CA certificate is already a trust anchor")
+		r.result(StatusOK, "CA certificate is in "+store.bundle)
+		return nil
+	}
+
+	if r.opts.Check {
+		r.result(StatusMissing, "CA certificate is not a p11-kit trust anchor")
+		for _, action := range store.installCommands(caCertPath) {
+			r.suggest(action)
+		}
+		return nil
+	}
+
+	if !r.hasCommand(store.update) {
+		fmt.Fprintf(r.out, "✗ %s not found; install it with: %s\n", store.update, store.install)
+		r.result(StatusMissing, store.update+" is not installed")
+		return nil
+	}
+
+	fmt.Fprintln(r.out)
+	if !r.confirm("Add Caddy root CA as a system trust anchor?", false) {
+		fmt.Fprintln(r.out, "Skipped. To install manually:")
+		r.printManualCAInstructions(caCertPath)
+		r.result(StatusMissing, "CA certificate is not a p11-kit trust anchor")
+		return nil
+	}
+
+	fingerprint, err := certificateSHA1(caCertPath)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(r.out, "Running trust anchor --store...")
+	if err := r.sudo("", "trust", "anchor", "--store", caCertPath); err != nil {
+		fmt.Fprintf(r.out, "✗ Failed to add the trust anchor: %v\n", err)
+		r.result(StatusFailed, err.Error())
+		return nil
+	}
+	r.record(Change{Kind: changeTrustAnchor, Path: caCertPath, Fingerprint: fingerprint})
+	fmt.Fprintln(r.out, "✓ CA certificate added as a trust anchor")
+	r.result(StatusChanged, "CA certificate added as a p11-kit trust anchor")
+	return nil
+}
+
+// checkCATrustNixOS checks that the CA is in the system bundle, and prints
+// the configuration adding it otherwise
+func (r *runner) checkCATrustNixOS(store *trustStore, caCertPath string) error {
+	fmt.Fprintln(r.out, "✓ Detected NixOS; the trust store is built from the system configuration")
+
+	data, err := os.ReadFile(caCertPath)
+	if err != nil {
+		return fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	der, err := certificateDER(caCertPath)
+	if err != nil {
+		return err
+	}
+	if r.bundleHas(store, der) {
+		fmt.Fprintln(r.out, "✓ CA certificate is in the system bundle")
+		r.result(StatusOK, "CA certificate is in "+store.bundle)
+		return nil
+	}
+
+	snippet := nixosSnippet(data)
+	fmt.Fprintln(r.out, "✗ CA certificate is not in the system bundle")
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "Add this to your configuration.nix:")
+	fmt.Fprintln(r.out)
+	fmt.Fprint(r.out, snippet)
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "and run: sudo nixos-rebuild switch")
+	r.result(StatusMissing, "CA certificate is not in security.pki.certificates")
+	r.suggest(snippet)
+	r.suggest("sudo nixos-rebuild switch")
+	return nil
+}
+
+// nixosSnippet returns the NixOS configuration trusting the PEM
+// certificate. The certificate is inlined, since flakes can't read files
+// outside the store.
+func nixosSnippet(pemData []byte) string {
+	var b strings.Builder
+	b.WriteString("security.pki.certificates = [\n")
+	b.WriteString("  ''\n")
+	for _, line := range strings.Split(strings.TrimSpace(string(pemData)), "\n") {
+		b.WriteString("    " + strings.TrimRight(line, "\r") + "\n")
+	}
+	b.WriteString("  ''\n")
+	b.WriteString("];\n")
+	return b.String()
+}
+
+// trustStoreSteps adds the CA to the trust stores of browsers and runtimes
+// that don't use the system one. They are part of trusting the CA, so the
+// SkipCA option skips them too.
+func (r *runner) trustStoreSteps() error {
+	if r.opts.SkipCA {
+		r.skip(StepNSS, "browser trust stores")
+		r.skip(StepJava, "Java trust store")
+		return nil
+	}
+	if err := r.checkNSSTrust(); err != nil {
+		r.fail(err)
+		return fmt.Errorf("browser trust store setup failed: %w", err)
+	}
+	if err := r.checkJavaTrust(); err != nil {
+		r.fail(err)
+		return fmt.Errorf("Java trust store setup failed: %w", err)
+	}
+	return nil
+}
+
+// nssProfileGlobs returns the patterns matching the NSS databases of
+// Chromium and Firefox profiles under homeDir
+func nssProfileGlobs(homeDir string) []string {
+	if runtime.GOOS == "darwin" {
+		return []string{
+			filepath.Join(homeDir, "Library", "Application Support", "Firefox", "Profiles", "*"),
+		}
+	}
+	return []string{
+		// Chromium, Chrome and other NSS users on Linux
+		filepath.Join(homeDir, ".pki", "nssdb"),
+		filepath.Join(homeDir, ".mozilla", "firefox", "*"),
+		filepath.Join(homeDir, "snap", "firefox", "common", ".mozilla", "firefox", "*"),
+		filepath.Join(homeDir, ".var", "app", "org.mozilla.firefox", ".mozilla", "firefox", "*"),
+		filepath.Join(homeDir, "snap", "chromium", "current", ".pki", "nssdb"),
+	}
+}
+
+// nssDatabases returns the NSS databases under homeDir. Only the SQL format
+// (cert9.db) used by current browsers is supported.
+func nssDatabases(homeDir string) []string {
+	var dirs []string
+	for _, pattern := range nssProfileGlobs(homeDir) {
+		matches, _ := filepath.Glob(pattern)
+		for _, dir := range matches {
+			if _, err := os.Stat(filepath.Join(dir, "cert9.db")); err == nil {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs
+}
+
+// certificateDER returns the DER bytes of the PEM certificate at path
+func certificateDER(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s is not a PEM certificate", path)
+	}
+	return block.Bytes, nil
+}
+
+// pemContains reports whether the PEM output of a certificate tool
+// contains the certificate der
+func pemContains(output, der []byte) bool {
+	for {
+		var block *pem.Block
+		block, output = pem.Decode(output)
+		if block == nil {
+			return false
+		}
+		if block.Type == "CERTIFICATE" && bytes.Equal(block.Bytes, der) {
+			return true
+		}
+	}
+}
+
+// nssHasCA reports whether the NSS database in dir has the CA under
+// nssNickname, and whether the nickname is taken by another certificate
+func nssHasCA(dir string, der []byte) (installed, stale bool) {
+	output, err := exec.Command("certutil", "-L", "-d", "sql:"+dir, "-n", nssNickname, "-a").Output()
+	if err != nil {
+		return false, false
+	}
+	if pemContains(output, der) {
+		return true, false
+	}
+	return false, true
+}
+
+// checkNSSTrust adds the CA to the NSS databases used by Firefox and, on
+// Linux, Chromium, which don't use the system trust store
+func (r *runner) checkNSSTrust() error {
+	r.begin(StepNSS)
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "Checking browser trust stores (NSS)...")
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	dirs := nssDatabases(homeDir)
+	if len(dirs) == 0 {
+		fmt.Fprintln(r.out, "✓ No Firefox or Chromium profiles found")
+		r.result(StatusSkipped, "no NSS databases found")
+		return nil
+	}
+
+	caCertPath, der, ok := r.caCertificate()
+	if !ok {
+		return nil
+	}
+
+	if _, err := exec.LookPath("certutil"); err != nil {
+		fmt.Fprintf(r.out, "⚠ Found %d browser profile(s), but certutil is not installed\n", len(dirs))
+		fmt.Fprintln(r.out, "  Install it (libnss3-tools on Debian/Ubuntu, nss-tools on Fedora, nss on Arch and Homebrew)")
+		fmt.Fprintln(r.out, "  and run 'faa setup' again")
+		r.result(StatusMissing, "certutil is not installed")
+		return nil
+	}
+
+	var missing []string
+	for _, dir := range dirs {
+		installed, stale := nssHasCA(dir, der)
+		switch {
+		case installed:
+			fmt.Fprintf(r.out, "✓ %s trusts the CA\n", dir)
+		case stale:
+			fmt.Fprintf(r.out, "⚠ %s has an outdated faa CA\n", dir)
+			missing = append(missing, dir)
+		default:
+			fmt.Fprintf(r.out, "✗ %s doesn't trust the CA\n", dir)
+			missing = append(missing, dir)
+		}
+	}
+	if len(missing) == 0 {
+		r.result(StatusOK, fmt.Sprintf("%d NSS database(s) trust the CA", len(dirs)))
+		return nil
+	}
+
+	if r.opts.Check {
+		r.result(StatusMissing, fmt.Sprintf("%d NSS database(s) don't trust the CA", len(missing)))
+		for _, dir := range missing {
+			r.suggest(fmt.Sprintf("certutil -A -d %q -t C,, -n %q -i %s", "sql:"+dir, nssNickname, caCertPath))
+		}
+		return nil
+	}
+
+	fmt.Fprintln(r.out)
+	if !r.confirm(fmt.Sprintf("Add the CA to %d browser profile(s)? Restart the browsers afterwards.", len(missing)), false) {
+		fmt.Fprintln(r.out, "Skipped. You can run 'faa setup' again later.")
+		r.result(StatusMissing, fmt.Sprintf("%d NSS database(s) don't trust the CA", len(missing)))
+		return nil
+	}
+
+	var failed int
+	for _, dir := range missing {
+		db := "sql:" + dir
+		if _, stale := nssHasCA(dir, der); stale {
+			_ = r.command("certutil", "-D", "-d", db, "-n", nssNickname).Run()
+		}
+		if err := r.command("certutil", "-A", "-d", db, "-t", "C,,", "-n", nssNickname, "-i", caCertPath).Run(); err != nil {
+			fmt.Fprintf(r.out, "✗ Failed to add the CA to %s: %v\n", dir, err)
+			failed++
+			continue
+		}
+		r.record(Change{Kind: changeNSS, Path: dir})
+		fmt.Fprintf(r.out, "✓ Added the CA to %s\n", dir)
+	}
+	if failed > 0 {
+		r.result(StatusFailed, fmt.Sprintf("failed to add the CA to %d NSS database(s)", failed))
+		return nil
+	}
+	r.result(StatusChanged, fmt.Sprintf("added the CA to %d NSS database(s)", len(missing)))
+	return nil
+}
+
+// javaKeystore returns the keytool to use and the cacerts keystore of its
+// JDK, preferring JAVA_HOME over the keytool on PATH
+func javaKeystore() (keytool, cacerts string, ok bool) {
+	var candidates []string
+	if javaHome := os.Getenv("JAVA_HOME"); javaHome != "" {
+		candidates = append(candidates, filepath.Join(javaHome, "bin", "keytool"))
+	}
+	if path, err := exec.LookPath("keytool"); err == nil {
+		candidates = append(candidates, path)
+	}
+
+	for _, candidate := range candidates {
+		resolved, err := filepath.EvalSymlinks(candidate)
+		if err != nil {
+			continue
+		}
+		if cacerts := jdkCacerts(filepath.Dir(filepath.Dir(resolved))); cacerts != "" {
+			return resolved, cacerts, true
+		}
+	}
+	return "", "", false
+}
+
+// jdkCacerts returns the cacerts keystore of the JDK at javaHome, in the
+// layout of Java 9 and later or of Java 8
+func jdkCacerts(javaHome string) string {
+	for _, path := range []string{
+		filepath.Join(javaHome, "lib", "security", "cacerts"),
+		filepath.Join(javaHome, "jre", "lib", "security", "cacerts"),
+	} {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// javaStoreNeedsRoot reports whether changing the keystore needs root
+func javaStoreNeedsRoot(cacerts string) bool {
+	resolved, err := filepath.EvalSymlinks(cacerts)
+	if err != nil {
+		resolved = cacerts
+	}
+	return unix.Access(resolved, unix.W_OK) != nil
+}
+
+// jdkKeytool returns the keytool of the JDK whose cacerts keystore is at
+// cacerts, in either of the layouts jdkCacerts knows
+func jdkKeytool(cacerts string) (string, bool) {
+	if filepath.Base(cacerts) != "cacerts" || !strings.HasSuffix(filepath.Dir(cacerts), filepath.Join("lib", "security")) {
+		return "", false
+	}
+	javaHome := filepath.Dir(filepath.Dir(filepath.Dir(cacerts)))
+	if filepath.Base(javaHome) == "jre" {
+		javaHome = filepath.Dir(javaHome)
+	}
+	return filepath.Join(javaHome, "bin", "keytool"), true
+}
+
+// keytoolArgs returns the keytool command line for a change to the CA's
+// entry in the keystore
+func keytoolArgs(keytool, cacerts string, args ...string) []string {
+	command := append([]string{keytool}, args...)
+	return append(command, "-alias", javaAlias, "-keystore", cacerts, "-storepass", javaStorePass)
+}
+
+// checkJavaTrust adds the CA to the JDK's cacerts keystore, which the JVM
+// uses instead of the system trust store
+func (r *runner) checkJavaTrust() error {
+	r.begin(StepJava)
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "Checking Java trust store...")
+
+	keytool, cacerts, ok := javaKeystore()
+	if !ok {
+		fmt.Fprintln(r.out, "✓ No JDK found")
+		r.result(StatusSkipped, "no JDK found")
+		return nil
+	}
+	fmt.Fprintf(r.out, "✓ Found Java keystore: %s\n", cacerts)
+
+	caCertPath, der, ok := r.caCertificate()
+	if !ok {
+		return nil
+	}
+
+	output, err := exec.Command(keytool, "-exportcert", "-rfc", "-alias", javaAlias, "-keystore", cacerts, "-storepass", javaStorePass).Output()
+	installed := err == nil && pemContains(output, der)
+	stale := err == nil && !installed
+	if installed {
+		fmt.Fprintln(r.out, "✓ Java trusts the CA")
+		r.result(StatusOK, "CA certificate is in "+cacerts)
+		return nil
+	}
+
+	importStep := undoStep{
+		args: keytoolArgs(keytool, cacerts, "-importcert", "-noprompt", "-file", caCertPath),
+		sudo: javaStoreNeedsRoot(cacerts),
+	}
+	if r.opts.Check {
+		r.result(StatusMissing, "CA certificate is not in "+cacerts)
+		r.suggest(importStep.String())
+		return nil
+	}
+
+	fmt.Fprintln(r.out)
+	if !r.confirm("Add the CA to the Java keystore?", false) {
+		fmt.Fprintln(r.out, "Skipped. You can run 'faa setup' again later.")
+		r.result(StatusMissing, "CA certificate is not in "+cacerts)
+		return nil
+	}
+
+	if stale {
+		deleteStep := undoStep{args: keytoolArgs(keytool, cacerts, "-delete"), sudo: importStep.sudo}
+		_ = deleteStep.run(r)
+	}
+	if err := importStep.run(r); err != nil {
+		fmt.Fprintf(r.out, "✗ Failed to add the CA to %s: %v\n", cacerts, err)
+		r.result(StatusFailed, err.Error())
+		return nil
+	}
+	r.record(Change{Kind: changeJava, Path: cacerts})
+	fmt.Fprintln(r.out, "✓ Added the CA to the Java keystore")
+	r.result(StatusChanged, "CA certificate added to "+cacerts)
+	return nil
+}
+
+// caCertificate returns the exported CA certificate and its DER bytes. If
+// it can't be read the current step is reported as missing.
+func (r *runner) caCertificate() (string, []byte, bool) {
+	caCertPath, err := proxy.GetCAPath()
+	if err != nil {
+		r.result(StatusFailed, err.Error())
+		return "", nil, false
+	}
+	der, err := certificateDER(caCertPath)
+	if err != nil {
+		fmt.Fprintln(r.out, "⚠ CA certificate not found; start the daemon and run 'faa setup' again")
+		r.result(StatusMissing, "CA certificate not created yet; start the daemon first")
+		return "", nil, false
+	}
+	return caCertPath, der, true
+}
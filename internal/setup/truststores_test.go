@@ -0,0 +1,172 @@
+package setup
+
+import (
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestNSSDatabases(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Profile locations are for Linux")
+	}
+	home := t.TempDir()
+
+	profiles := []string{
+		filepath.Join(home, ".pki", "nssdb"),
+		filepath.Join(home, ".mozilla", "firefox", "abcd.default-release"),
+		filepath.Join(home, "snap", "firefox", "common", ".mozilla", "firefox", "efgh.default"),
+	}
+	for _, dir := range profiles {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create profile: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cert9.db"), nil, 0644); err != nil {
+			t.Fatalf("Failed to create cert9.db: %v", err)
+		}
+	}
+	// Legacy databases and directories that aren't profiles are ignored
+	legacy := filepath.Join(home, ".mozilla", "firefox", "old.default")
+	if err := os.MkdirAll(legacy, 0755); err != nil {
+		t.Fatalf("Failed to create profile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(legacy, "cert8.db"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create cert8.db: %v", err)
+	}
+
+	dirs := nssDatabases(home)
+	if len(dirs) != len(profiles) {
+		t.Fatalf("nssDatabases() = %v, want %v", dirs, profiles)
+	}
+	for i, dir := range dirs {
+		if dir != profiles[i] {
+			t.Errorf("nssDatabases()[%d] = %q, want %q", i, dir, profiles[i])
+		}
+	}
+
+	if dirs := nssDatabases(t.TempDir()); len(dirs) != 0 {
+		t.Errorf("Expected no databases in an empty home, got %v", dirs)
+	}
+}
+
+func TestJDKCacerts(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout string
+	}{
+		{"Java 9 and later", filepath.Join("lib", "security", "cacerts")},
+		{"Java 8", filepath.Join("jre", "lib", "security", "cacerts")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			javaHome := t.TempDir()
+			path := filepath.Join(javaHome, tt.layout)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("Failed to create JDK layout: %v", err)
+			}
+			if err := os.WriteFile(path, nil, 0644); err != nil {
+				t.Fatalf("Failed to create cacerts: %v", err)
+			}
+			if got := jdkCacerts(javaHome); got != path {
+				t.Errorf("jdkCacerts() = %q, want %q", got, path)
+			}
+		})
+	}
+
+	if got := jdkCacerts(t.TempDir()); got != "" {
+		t.Errorf("jdkCacerts() = %q for a directory that isn't a JDK", got)
+	}
+}
+
+func TestPEMContains(t *testing.T) {
+	ca := []byte{0x30, 0x01, 0x02}
+	other := []byte{0x30, 0x03, 0x04}
+	encode := func(der []byte) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}
+
+	// certutil and keytool print a header before the certificate
+	output := append([]byte("Alias name: faa-local-ca\n"), encode(other)...)
+	output = append(output, encode(ca)...)
+	if !pemContains(output, ca) {
+		t.Error("Expected the CA to be found after another certificate")
+	}
+	if pemContains(encode(other), ca) {
+		t.Error("Expected a different certificate not to match")
+	}
+	if pemContains([]byte("keytool error: Alias <faa-local-ca> does not exist"), ca) {
+		t.Error("Expected output without certificates not to match")
+	}
+}
+
+func TestDetectTrustStore(t *testing.T) {
+	tests := []struct {
+		markers  []string
+		expected string
+	}{
+		{[]string{"/usr/local/share/ca-certificates"}, "Debian/Ubuntu"},
+		{[]string{"/etc/alpine-release", "/usr/local/share/ca-certificates"}, "Alpine"},
+		{[]string{"/etc/pki/ca-trust/source/anchors"}, "RHEL/CentOS/Fedora"},
+		{[]string{"/etc/pki/trust/anchors"}, "openSUSE"},
+		{[]string{"/etc/ca-certificates/trust-source"}, "Arch Linux"},
+		{[]string{"/etc/NIXOS", "/etc/ssl/certs"}, "NixOS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			root := t.TempDir()
+			for _, marker := range tt.markers {
+				if err := os.MkdirAll(filepath.Join(root, marker), 0755); err != nil {
+					t.Fatalf("Failed to create %s: %v", marker, err)
+				}
+			}
+			store := detectTrustStore(root)
+			if store == nil {
+				t.Fatalf("detectTrustStore() = nil, want %s", tt.expected)
+			}
+			if store.description != tt.expected {
+				t.Errorf("detectTrustStore() = %s, want %s", store.description, tt.expected)
+			}
+		})
+	}
+
+	if store := detectTrustStore(t.TempDir()); store != nil {
+		t.Errorf("detectTrustStore() = %s for an empty root", store.description)
+	}
+}
+
+func TestNixOSSnippet(t *testing.T) {
+	pemData := []byte("-----BEGIN CERTIFICATE-----\r\nMIIB\r\n-----END CERTIFICATE-----\r\n")
+	expected := `security.pki.certificates = [
+  ''
+    -----BEGIN CERTIFICATE-----
+    MIIB
+    -----END CERTIFICATE-----
+  ''
+];
+`
+	if got := nixosSnippet(pemData); got != expected {
+		t.Errorf("nixosSnippet() = %q, want %q", got, expected)
+	}
+}
+
+func TestHasCommand(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "usr", "sbin"), 0755); err != nil {
+		t.Fatalf("Failed to create usr/sbin: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "usr", "sbin", "update-ca-certificates"), nil, 0755); err != nil {
+		t.Fatalf("Failed to create command: %v", err)
+	}
+
+	r := &runner{root: root}
+	if !r.hasCommand("update-ca-certificates") {
+		t.Error("Expected a command in /usr/sbin to be found")
+	}
+	if r.hasCommand("update-ca-trust") {
+		t.Error("Expected a missing command not to be found")
+	}
+}